@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"net"
 	"os"
+	"surfs/internal/auth"
 	"surfs/internal/block"
+	"surfs/internal/transport"
 
 	"github.com/BurntSushi/toml"
 
@@ -17,21 +19,35 @@ import (
 )
 
 type blockStore struct {
-	Host    string
-	Port    uint
-	DataDir string
+	Host         string
+	Port         uint
+	DataDir      string
+	MaxBlockSize uint64
+
+	// Where blocks are kept: local (a file per block in the data directory), pack (segment files in the
+	// data directory), memory or s3.
+	Backend     string
+	SegmentSize int64
+	S3          block.S3Config `toml:"s3"`
+
+	// Codec blocks are compressed with at rest: none, gzip or snappy.
+	Compression string
 }
 
 type config struct {
-	BlockStore blockStore `toml:"block-store"`
+	BlockStore blockStore          `toml:"block-store"`
+	TLS        transport.TLSConfig `toml:"tls"`
+	Auth       auth.Config         `toml:"auth"`
 }
 
 func defaultConf() config {
 	return config{
 		BlockStore: blockStore{
-			Host:    "localhost",
-			Port:    5678,
-			DataDir: "data",
+			Host:         "localhost",
+			Port:         5678,
+			DataDir:      "data",
+			MaxBlockSize: block.DefaultMaxBlockSize,
+			Backend:      "local",
 		},
 	}
 }
@@ -53,6 +69,25 @@ func validateDataDir(dataDir string) error {
 	return nil
 }
 
+// Opens the backend named in the configuration.
+func openBackend(conf blockStore) (block.Backend, error) {
+	switch conf.Backend {
+	case "", "local":
+		log.Debugf("using data directory: %s", conf.DataDir)
+		return block.NewLocalBackend(conf.DataDir)
+	case "pack":
+		log.Debugf("using packfiles in data directory: %s", conf.DataDir)
+		return block.NewPackBackend(conf.DataDir, conf.SegmentSize)
+	case "memory":
+		return block.NewMemoryBackend(), nil
+	case "s3":
+		log.Debugf("using S3 bucket %s at %s", conf.S3.Bucket, conf.S3.Endpoint)
+		return block.NewS3Backend(conf.S3)
+	default:
+		return nil, fmt.Errorf("unknown block store backend %q", conf.Backend)
+	}
+}
+
 func run(c *cli.Context) error {
 
 	var conf config
@@ -75,6 +110,22 @@ func run(c *cli.Context) error {
 		conf.BlockStore.DataDir = dataDir
 	}
 
+	if maxBlockSize := c.Uint64("max-block-size"); maxBlockSize != 0 {
+		conf.BlockStore.MaxBlockSize = maxBlockSize
+	}
+
+	if compression := c.String("compression"); compression != "" {
+		conf.BlockStore.Compression = compression
+	}
+
+	if conf.BlockStore.MaxBlockSize == 0 {
+		conf.BlockStore.MaxBlockSize = block.DefaultMaxBlockSize
+	}
+
+	if conf.BlockStore.MaxBlockSize > block.MaxBlockSizeLimit {
+		return fmt.Errorf("maximum block size of %d bytes exceeds the limit of %d bytes", conf.BlockStore.MaxBlockSize, block.MaxBlockSizeLimit)
+	}
+
 	if c.Bool("V") {
 		log.SetLevel(log.DebugLevel)
 	}
@@ -83,8 +134,6 @@ func run(c *cli.Context) error {
 		log.SetLevel(log.TraceLevel)
 	}
 
-	log.Debugf("using data directory: %s", conf.BlockStore.DataDir)
-
 	addr := fmt.Sprintf(":%d", conf.BlockStore.Port)
 	lis, err := net.Listen("tcp", addr)
 	if err != nil {
@@ -93,12 +142,55 @@ func run(c *cli.Context) error {
 
 	log.Debugf("starting block store service on %s", addr)
 
-	store, err := block.NewStore(conf.BlockStore.DataDir)
+	backend, err := openBackend(conf.BlockStore)
+	if err != nil {
+		return err
+	}
+
+	store := block.NewBackendStore(backend)
+	defer store.Close()
+
+	store.SetMaxBlockSize(conf.BlockStore.MaxBlockSize)
+	log.Debugf("using maximum block size: %d", conf.BlockStore.MaxBlockSize)
+
+	if conf.BlockStore.Compression != "" {
+		compression, err := block.ParseCompression(conf.BlockStore.Compression)
+		if err != nil {
+			return err
+		}
+
+		store.SetCompression(compression)
+		log.Debugf("using compression: %s", compression)
+	}
+
+	opts, err := conf.TLS.ServerOptions()
 	if err != nil {
 		return err
 	}
 
-	s := grpc.NewServer()
+	if conf.TLS.Enabled() {
+		log.Debugf("using TLS, client certificates required: %t", conf.TLS.ClientAuth)
+	}
+
+	if conf.Auth.Enabled() {
+		authn, err := conf.Auth.Authenticator()
+		if err != nil {
+			return err
+		}
+
+		acl, err := conf.Auth.AccessControlList()
+		if err != nil {
+			return err
+		}
+
+		log.Debugf("requiring authentication, with %d access control rules", len(conf.Auth.ACL))
+		opts = append(opts, auth.ServerOptions(authn, block.Authorize(acl))...)
+	}
+
+	// Blocks larger than gRPC's default message size limit must still be received, so that the store
+	// can decide whether to accept them.
+	opts = append(opts, grpc.MaxRecvMsgSize(block.MaxMessageSize(conf.BlockStore.MaxBlockSize)))
+	s := grpc.NewServer(opts...)
 	block.RegisterStoreServer(s, store)
 
 	if err := s.Serve(lis); err != nil {
@@ -127,6 +219,14 @@ func main() {
 			Usage: "Specifies the `PORT` the block store service is to listen on",
 			Value: 5678,
 		},
+		cli.Uint64Flag{
+			Name:  "max-block-size",
+			Usage: "Specifies the largest block, in `BYTES`, the block store accepts (default: 4194304)",
+		},
+		cli.StringFlag{
+			Name:  "compression",
+			Usage: "Specifies the `CODEC` blocks are compressed with at rest: none, gzip, snappy or zstd (default: none)",
+		},
 		cli.BoolFlag{
 			Name:  "V",
 			Usage: "Enables verbose output",
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"io"
+	"os"
+	"os/user"
+	"surfs/internal/crypt"
+	"surfs/internal/meta"
+)
+
+// Counts the bytes written to it.
+type countingWriter struct {
+	n uint64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += uint64(len(p))
+	return len(p), nil
+}
+
+// Divides a local file into blocks with this layout, and returns the hashes of the blocks along with
+// the attributes of the file to report to the metadata store. If a keyring is configured, the hashes
+// are those of the encrypted blocks, and the attributes hold the encrypted keys of the blocks instead of
+// the digest of the file, which would let the metadata store confirm guesses of its contents. The file
+// is only read once.
+func (l layout) hashFile(f *os.File, k *crypt.Keyring) ([]string, *meta.FileAttributes, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	digest := sha256.New()
+	size := &countingWriter{}
+
+	hashList, keys, err := l.sealedHashList(io.TeeReader(f, io.MultiWriter(digest, size)), k)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	attrs := &meta.FileAttributes{
+		Size:      size.n,
+		Mode:      uint32(info.Mode().Perm()),
+		Mtime:     info.ModTime().UnixNano(),
+		Creator:   creator(),
+		BlockKeys: keys,
+	}
+
+	if k == nil {
+		attrs.Digest = base64.StdEncoding.EncodeToString(digest.Sum(nil))
+	}
+
+	return hashList, attrs, nil
+}
+
+// Returns the name recorded as the creator of the files we upload, in the form user@host.
+func creator() string {
+	name := os.Getenv("USER")
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+
+	if host, err := os.Hostname(); err == nil {
+		return name + "@" + host
+	}
+
+	return name
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"surfs/internal/block"
+	"surfs/internal/crypt"
+	"surfs/internal/meta"
+
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+)
+
+// Connects to the metadata store specified in the configuration.
+func dialMetadataStore(conf *config) (*grpc.ClientConn, error) {
+	addr := fmt.Sprintf("%s:%d", conf.MetadataConf.Host, conf.MetadataConf.Port)
+	return dial(conf, addr)
+}
+
+// Connects to the block store specified in the configuration.
+func dialBlockStore(conf *config) (*grpc.ClientConn, error) {
+	addr := fmt.Sprintf("%s:%d", conf.BlockConf.Host, conf.BlockConf.Port)
+	return dial(conf, addr)
+}
+
+// Connects to a service, using TLS and presenting a token if they are configured.
+func dial(conf *config, addr string) (*grpc.ClientConn, error) {
+	opt, err := conf.TLS.DialOption()
+	if err != nil {
+		return nil, err
+	}
+
+	// Fail instead of retrying when the connection is refused for good, such as when the server's
+	// certificate is not trusted.
+	opts := append([]grpc.DialOption{opt, grpc.WithBlock(), grpc.FailOnNonTempDialError(true)}, conf.Auth.DialOptions()...)
+	return grpc.Dial(addr, opts...)
+}
+
+// Sets the hash list and attributes of a file in the metadata store, uploading any blocks the block
+// store is missing from the source, which is divided into blocks with the specified layout and
+// encrypted with the keyring, if any. If the source is nil, the blocks must already be in the block
+// store. The version must be exactly one more
+// than the current version of the file, otherwise VersionConflict is returned.
+func modifyFile(metaClient meta.MetadataStoreClient, blockClient block.StoreClient, filename string, version uint64, hashList []string, l layout, attrs *meta.FileAttributes, k *crypt.Keyring, src io.ReadSeeker) error {
+	modReq := &meta.ModifyFileRequest{
+		Filename:   filename,
+		Version:    version,
+		HashList:   hashList,
+		BlockSize:  l.blockSize,
+		Chunking:   meta.Chunking(l.chunking),
+		Attributes: attrs,
+	}
+
+	attempts := 0
+
+	for {
+		// Call the metadata store's ModifyFile() RPC to request an update of the hash list.
+		// If the ModifyFile RPC() returns with success, then we are done.
+		modRes, err := metaClient.ModifyFile(context.Background(), modReq)
+		if err != nil {
+			return err
+		}
+
+		if modRes.Success {
+			return nil
+		}
+
+		attempts++
+
+		if attempts >= MaxAttempts {
+			log.Errorf("Failed to upload file after %d attempts, aborting.", MaxAttempts)
+			return ExceededMaxRetries
+		}
+
+		// Otherwise, the ModifyFile() RPC returns a list of hashes whose corresponding
+		// blocks are missing from the block store. If the list is nil, then we have the wrong
+		// version number.
+		if modRes.MissingHashList == nil {
+			return VersionConflict
+		}
+
+		// If the list is not empty, we upload the required blocks to the block store and call
+		// ModifyFile again.
+		log.Debugf("Block store is missing %d blocks, uploading them...", len(modRes.MissingHashList))
+
+		// Without a source, the missing blocks cannot be uploaded.
+		if src == nil {
+			return BlockMissing
+		}
+
+		if err := uploadBlocks(blockClient, modRes.MissingHashList, l, k, src); err != nil {
+			return err
+		}
+	}
+}
+
+// Checks that the largest block files divided with the specified layout produce, once encrypted with
+// the keyring, if any, is accepted by the block store. With content-defined chunking, blocks can be
+// several times the block size.
+func checkBlockSize(blockClient block.StoreClient, l layout, k *crypt.Keyring) error {
+	res, err := blockClient.GetLimits(context.Background(), &block.GetLimitsRequest{})
+	if err != nil {
+		return err
+	}
+
+	size := l.maxBlockSize()
+	if k != nil {
+		size += crypt.Overhead
+	}
+
+	if size > res.MaxBlockSize {
+		return fmt.Errorf("blocks of up to %d bytes for a block size of %d bytes exceed the block store's maximum block size of %d bytes", size, l.blockSize, res.MaxBlockSize)
+	}
+
+	return nil
+}
+
+// Uploads the blocks with the specified hashes to the block store in a single stream. The source is
+// divided into blocks again from the start and encrypted with the keyring, if any, and only the
+// requested blocks are sent, so the file is never held in memory.
+func uploadBlocks(blockClient block.StoreClient, hashes []string, l layout, k *crypt.Keyring, src io.ReadSeeker) error {
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	wanted := make(map[string]struct{}, len(hashes))
+	for _, hash := range hashes {
+		wanted[hash] = struct{}{}
+	}
+
+	stream, err := blockClient.PutBlocks(context.Background())
+	if err != nil {
+		return err
+	}
+
+	c := l.newChunker(src)
+	for len(wanted) > 0 && c.Next() {
+		b, hash, _, err := sealBlock(k, c.Block(), c.Hash())
+		if err != nil {
+			stream.CloseSend()
+			return err
+		}
+
+		if _, ok := wanted[hash]; !ok {
+			continue
+		}
+
+		delete(wanted, hash)
+
+		req := &block.StoreBlockRequest{
+			Block: b,
+			Hash:  hash,
+		}
+
+		// If the block store aborts the stream, Send returns io.EOF and the actual error is returned
+		// by CloseAndRecv below.
+		if err := stream.Send(req); err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+	}
+
+	if err := c.Err(); err != nil {
+		stream.CloseSend()
+		return err
+	}
+
+	if _, err := stream.CloseAndRecv(); err != nil {
+		if computed, ok := block.IsHashMismatch(err); ok {
+			log.WithFields(log.Fields{
+				"computed": computed,
+			}).Error("Block was corrupted before reaching the block store.")
+
+			return BlockCorrupted
+		}
+
+		return err
+	}
+
+	// Any blocks we could not find must have been changed since the hash list was computed.
+	if len(wanted) > 0 {
+		return FileChanged
+	}
+
+	return nil
+}
+
+// Downloads the blocks in the hash list from the block store in a single stream and writes them to w
+// in order. The layout of the file determines the largest message the stream must accept. If the file
+// has block keys, its blocks are decrypted with them, which requires a keyring.
+func downloadBlocks(blockClient block.StoreClient, hashList []string, blockKeys [][]byte, l layout, k *crypt.Keyring, w io.Writer) error {
+	if blockKeys != nil && k == nil {
+		return EncryptionSecretRequired
+	}
+
+	// Cancelling the context ends the stream if we return before receiving every block.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	req := &block.GetBlocksRequest{Hashes: hashList}
+	stream, err := blockClient.GetBlocks(ctx, req, grpc.MaxCallRecvMsgSize(block.MaxMessageSize(l.maxBlockSize()+crypt.Overhead)))
+	if err != nil {
+		return err
+	}
+
+	for i, hash := range hashList {
+		getRes, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+
+		if !getRes.Success {
+			return BlockMissing
+		}
+
+		var wrapped []byte
+		if blockKeys != nil {
+			wrapped = blockKeys[i]
+		}
+
+		b, err := openBlock(k, getRes.Block, hash, wrapped)
+		if err != nil {
+			return err
+		}
+
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
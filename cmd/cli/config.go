@@ -1,6 +1,11 @@
 package main
 
 import (
+	"surfs/internal/auth"
+	"surfs/internal/block"
+	"surfs/internal/crypt"
+	"surfs/internal/transport"
+
 	"github.com/BurntSushi/toml"
 	"github.com/urfave/cli"
 )
@@ -8,6 +13,21 @@ import (
 type blockConfig struct {
 	Host string
 	Port uint
+
+	// The size of the blocks new files are divided into, and the name of the method used to divide
+	// them.
+	BlockSize uint64
+	Chunking  string
+
+	chunking block.Chunking
+}
+
+// Returns the layout new files are divided into.
+func (conf blockConfig) layout() layout {
+	return layout{
+		blockSize: conf.BlockSize,
+		chunking:  conf.chunking,
+	}
 }
 
 type metadataConfig struct {
@@ -15,9 +35,20 @@ type metadataConfig struct {
 	Port uint
 }
 
+type encryptionConfig struct {
+	// The file holding the secret the blocks of uploaded files are encrypted with. If unset, blocks are
+	// uploaded unencrypted and encrypted files cannot be read.
+	SecretFile string
+
+	keyring *crypt.Keyring
+}
+
 type config struct {
-	BlockConf    blockConfig    `toml:"block-store"`
-	MetadataConf metadataConfig `toml:"metadata-store"`
+	BlockConf    blockConfig         `toml:"block-store"`
+	MetadataConf metadataConfig      `toml:"metadata-store"`
+	TLS          transport.TLSConfig `toml:"tls"`
+	Auth         auth.Config         `toml:"auth"`
+	Encryption   encryptionConfig    `toml:"encryption"`
 }
 
 func getConfig(c *cli.Context) (*config, error) {
@@ -28,5 +59,41 @@ func getConfig(c *cli.Context) (*config, error) {
 		return nil, err
 	}
 
+	if blockSize := c.GlobalUint64("block-size"); blockSize != 0 {
+		conf.BlockConf.BlockSize = blockSize
+	}
+
+	if conf.BlockConf.BlockSize == 0 {
+		conf.BlockConf.BlockSize = block.DefaultBlockSize
+	}
+
+	if chunking := c.GlobalString("chunking"); chunking != "" {
+		conf.BlockConf.Chunking = chunking
+	}
+
+	if conf.BlockConf.Chunking != "" {
+		chunking, err := block.ParseChunking(conf.BlockConf.Chunking)
+		if err != nil {
+			return nil, err
+		}
+
+		conf.BlockConf.chunking = chunking
+	}
+
+	if secretFile := c.GlobalString("encryption-secret-file"); secretFile != "" {
+		conf.Encryption.SecretFile = secretFile
+	}
+
+	if conf.Encryption.SecretFile != "" {
+		secret, err := auth.ReadSecretFile(conf.Encryption.SecretFile)
+		if err != nil {
+			return nil, err
+		}
+
+		if conf.Encryption.keyring, err = crypt.NewKeyring(secret); err != nil {
+			return nil, err
+		}
+	}
+
 	return &conf, nil
 }
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"io"
+	"surfs/internal/block"
+	"surfs/internal/crypt"
+)
+
+// Returns the form in which a block of a file is uploaded, and its hash. If a keyring is configured,
+// the block is encrypted and its encrypted key is returned as well; otherwise the block is uploaded as
+// it is.
+func sealBlock(k *crypt.Keyring, b []byte, hash string) ([]byte, string, []byte, error) {
+	if k == nil {
+		return b, hash, nil, nil
+	}
+
+	sealed, key, err := k.Seal(b)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	hash = block.Hash(sealed)
+	wrapped, err := k.WrapKey(key, hash)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return sealed, hash, wrapped, nil
+}
+
+// Returns the contents of a downloaded block. If the file's blocks are encrypted, the block is
+// decrypted with its encrypted key, which requires a keyring.
+func openBlock(k *crypt.Keyring, b []byte, hash string, wrapped []byte) ([]byte, error) {
+	if wrapped == nil {
+		return b, nil
+	}
+
+	if k == nil {
+		return nil, EncryptionSecretRequired
+	}
+
+	key, err := k.UnwrapKey(wrapped, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	return crypt.Open(key, b)
+}
+
+// Returns the hashes of the blocks of the contents of the specified reader with this layout, as they
+// are uploaded, along with the encrypted keys of the blocks if a keyring is configured.
+func (l layout) sealedHashList(r io.Reader, k *crypt.Keyring) ([]string, [][]byte, error) {
+	if k == nil {
+		hashList, err := l.hashList(r)
+		return hashList, nil, err
+	}
+
+	hashList := make([]string, 0, 64)
+	keys := make([][]byte, 0, 64)
+
+	c := l.newChunker(r)
+	for c.Next() {
+		_, hash, key, err := sealBlock(k, c.Block(), c.Hash())
+		if err != nil {
+			return nil, nil, err
+		}
+
+		hashList = append(hashList, hash)
+		keys = append(keys, key)
+	}
+
+	if err := c.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	return hashList, keys, nil
+}
@@ -5,5 +5,10 @@ import "errors"
 var (
 	VersionConflict = errors.New("version conflict")
 	NotFound        = errors.New("not found")
+	BlockCorrupted  = errors.New("block corrupted")
+	BlockMissing    = errors.New("block missing from block store")
+	FileChanged     = errors.New("file changed during upload")
+
+	EncryptionSecretRequired = errors.New("file is encrypted, but no encryption secret is configured")
 	//RequiredArgument = errors.New("")
 )
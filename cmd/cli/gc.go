@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"surfs/internal/meta"
+
+	"github.com/urfave/cli"
+)
+
+// CollectGarbage deletes the blocks in the block store that are no longer referenced by any file.
+func CollectGarbage(c *cli.Context) error {
+
+	conf, err := getConfig(c)
+	if err != nil {
+		return err
+	}
+
+	conn, err := dialMetadataStore(conf)
+	if err != nil {
+		return err
+	}
+
+	defer conn.Close()
+
+	client := meta.NewMetadataStoreClient(conn)
+
+	req := &meta.CollectGarbageRequest{
+		GracePeriod: int64(c.Duration("grace-period")),
+		DryRun:      c.Bool("dry-run"),
+	}
+
+	res, err := client.CollectGarbage(context.Background(), req)
+	if err != nil {
+		return err
+	}
+
+	if req.DryRun {
+		fmt.Printf("%d unreferenced block(s) of %d older than the grace period.\n", res.Unreferenced, res.Candidates)
+		return nil
+	}
+
+	fmt.Printf("Deleted %d unreferenced block(s) of %d older than the grace period.\n", res.Deleted, res.Candidates)
+	return nil
+}
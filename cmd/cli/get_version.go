@@ -8,7 +8,6 @@ import (
 
 	log "github.com/sirupsen/logrus"
 	"github.com/urfave/cli"
-	"google.golang.org/grpc"
 )
 
 func GetVersion(c *cli.Context) error {
@@ -24,8 +23,7 @@ func GetVersion(c *cli.Context) error {
 		return err
 	}
 
-	addr := fmt.Sprintf("%s:%d", conf.MetadataConf.Host, conf.MetadataConf.Port)
-	conn, err := grpc.Dial(addr, grpc.WithInsecure(), grpc.WithBlock())
+	conn, err := dialMetadataStore(conf)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"surfs/internal/block"
+	"surfs/internal/meta"
+	"text/tabwriter"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+)
+
+// History lists the current and retained previous versions of a file.
+func History(c *cli.Context) error {
+
+	filename := c.Args().First()
+	if filename == "" {
+		return errors.New("must specify a file")
+	}
+
+	conf, err := getConfig(c)
+	if err != nil {
+		return err
+	}
+
+	conn, err := dialMetadataStore(conf)
+	if err != nil {
+		return err
+	}
+
+	defer conn.Close()
+
+	client := meta.NewMetadataStoreClient(conn)
+
+	res, err := client.ListVersions(context.Background(), &meta.ListVersionsRequest{Filename: filename})
+	if err != nil {
+		return err
+	}
+
+	if len(res.Versions) == 0 {
+		fmt.Println(NotFound)
+		return NotFound
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tMODIFIED\tBLOCKS")
+
+	for _, v := range res.Versions {
+		blocks := strconv.FormatUint(v.NumBlocks, 10)
+		if v.Deleted {
+			blocks = "deleted"
+		}
+
+		fmt.Fprintf(w, "%d\t%s\t%s\n", v.Version, formatTime(v.ModTime), blocks)
+	}
+
+	return w.Flush()
+}
+
+// Restore creates a new version of a file with the contents of a previous version.
+func Restore(c *cli.Context) error {
+
+	filename := c.Args().First()
+	if filename == "" {
+		return errors.New("must specify a file")
+	}
+
+	version, err := strconv.ParseUint(c.Args().Get(1), 10, 64)
+	if err != nil {
+		return errors.New("must specify a valid version")
+	}
+
+	conf, err := getConfig(c)
+	if err != nil {
+		return err
+	}
+
+	metaConn, err := dialMetadataStore(conf)
+	if err != nil {
+		return err
+	}
+
+	defer metaConn.Close()
+
+	metaClient := meta.NewMetadataStoreClient(metaConn)
+
+	blockConn, err := dialBlockStore(conf)
+	if err != nil {
+		return err
+	}
+
+	defer blockConn.Close()
+
+	blockClient := block.NewStoreClient(blockConn)
+
+	old, err := metaClient.ReadFileVersion(context.Background(), &meta.ReadFileVersionRequest{
+		Filename: filename,
+		Version:  version,
+	})
+	if err != nil {
+		return err
+	}
+
+	if remoteHashList(old) == nil {
+		return fmt.Errorf("version %d is a deletion of the file", version)
+	}
+
+	// The restored version keeps the attributes of the old one.
+	statRes, err := metaClient.StatFile(context.Background(), &meta.StatFileRequest{
+		Filename: filename,
+		Version:  version,
+	})
+	if err != nil {
+		return err
+	}
+
+	readRes, err := metaClient.ReadFile(context.Background(), &meta.ReadFileRequest{Filename: filename})
+	if err != nil {
+		return err
+	}
+
+	// The blocks of retained versions are never garbage collected, so there is nothing to upload.
+	if err := modifyFile(metaClient, blockClient, filename, readRes.Version+1, old.HashList, remoteLayout(old), statRes.Attributes, nil, nil); err != nil {
+		if err == VersionConflict {
+			log.Errorf("Version conflict, please try again.")
+		}
+
+		return err
+	}
+
+	fmt.Printf("Restored version %d as version %d.\n", version, readRes.Version+1)
+	return nil
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"surfs/internal/block"
+)
+
+// Name of the file, relative to the base directory, in which sync records the state of each file as
+// of the last sync.
+const IndexFilename = ".surfs-index"
+
+// Hash list field of an index record for an empty file. Hashes are standard Base64, which never
+// contains a dash.
+const emptyHashList = "-"
+
+// The state of a file as of the last sync.
+type indexEntry struct {
+	version uint64
+
+	// The hash list of the file, or nil if the file was deleted.
+	hashList []string
+
+	// How the file was divided into blocks.
+	layout layout
+}
+
+// Mapping of filename to the state of the file as of the last sync.
+type index map[string]indexEntry
+
+// Loads the index from the specified file. If the file does not exist, an empty index is returned.
+// Each record in the index is a CSV line holding the filename, the version, the space-separated
+// hash list, the block size, and the chunking method of a file. The hash list is empty for a deleted
+// file and emptyHashList for an empty one. Records written before the block size or chunking method
+// were recorded lack those fields, and their files were divided into fixed-size blocks of the legacy
+// size.
+func loadIndex(path string) (index, error) {
+	idx := make(index)
+
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return idx, nil
+		}
+
+		return nil, err
+	}
+
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			return idx, nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		if len(record) < 3 || len(record) > 5 {
+			return nil, fmt.Errorf("malformed index record for %q", record[0])
+		}
+
+		version, err := strconv.ParseUint(record[1], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+
+		var hashList []string
+		switch record[2] {
+		case "":
+			// The file was deleted.
+		case emptyHashList:
+			hashList = []string{}
+		default:
+			hashList = strings.Split(record[2], " ")
+		}
+
+		l := layout{blockSize: block.LegacyBlockSize}
+		if len(record) >= 4 {
+			l.blockSize, err = strconv.ParseUint(record[3], 10, 64)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if len(record) >= 5 {
+			l.chunking, err = block.ParseChunking(record[4])
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		idx[record[0]] = indexEntry{
+			version:  version,
+			hashList: hashList,
+			layout:   l,
+		}
+	}
+}
+
+// Formats a hash list for an index record. An empty field marks a deleted file, so the empty hash
+// list of an empty file is written as a placeholder that no hash can be mistaken for.
+func formatHashList(hashList []string) string {
+	if hashList != nil && len(hashList) == 0 {
+		return emptyHashList
+	}
+
+	return strings.Join(hashList, " ")
+}
+
+// Saves the index to the specified file, replacing it atomically.
+func (idx index) save(path string) error {
+	names := make([]string, 0, len(idx))
+	for name := range idx {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	f, err := ioutil.TempFile(filepath.Dir(path), IndexFilename)
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(f.Name())
+
+	w := csv.NewWriter(f)
+	for _, name := range names {
+		entry := idx[name]
+		record := []string{
+			name,
+			strconv.FormatUint(entry.version, 10),
+			formatHashList(entry.hashList),
+			strconv.FormatUint(entry.layout.blockSize, 10),
+			entry.layout.chunking.String(),
+		}
+
+		if err := w.Write(record); err != nil {
+			f.Close()
+			return err
+		}
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), path)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"io"
+	"surfs/internal/block"
+	"surfs/internal/meta"
+)
+
+// How a file is divided into blocks.
+type layout struct {
+	blockSize uint64
+	chunking  block.Chunking
+}
+
+// Returns the layout of a file as recorded in the metadata store. The chunking methods of the
+// metadata store have the same values as those of the block package.
+func remoteLayout(res *meta.ReadFileResponse) layout {
+	return layout{
+		blockSize: res.BlockSize,
+		chunking:  block.Chunking(res.Chunking),
+	}
+}
+
+// Returns the hash list of a file as read from the metadata store, or nil if the file was deleted or
+// never created. The hash list of an empty file is empty but not nil, which the wire cannot tell apart
+// from nil, so the metadata store reports whether the file exists.
+func remoteHashList(res *meta.ReadFileResponse) []string {
+	if !res.Exists {
+		return nil
+	}
+
+	if res.HashList == nil {
+		return []string{}
+	}
+
+	return res.HashList
+}
+
+// Creates a chunker that divides the contents of the specified reader into blocks with this layout.
+func (l layout) newChunker(r io.Reader) *block.Chunker {
+	return l.chunking.NewChunker(r, l.blockSize)
+}
+
+// Returns the hashes of the blocks of the contents of the specified reader with this layout.
+func (l layout) hashList(r io.Reader) ([]string, error) {
+	return l.chunking.HashList(r, l.blockSize)
+}
+
+// Returns the size of the largest block of a file with this layout.
+func (l layout) maxBlockSize() uint64 {
+	return l.chunking.MaxBlockSize(l.blockSize)
+}
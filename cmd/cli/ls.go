@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"surfs/internal/meta"
+
+	"github.com/urfave/cli"
+)
+
+// List lists the files and directories under a path, or the whole store if no path is specified.
+// Directories are suffixed with a slash. With --recursive, every file below the path is listed instead.
+func List(c *cli.Context) error {
+
+	conf, err := getConfig(c)
+	if err != nil {
+		return err
+	}
+
+	conn, err := dialMetadataStore(conf)
+	if err != nil {
+		return err
+	}
+
+	defer conn.Close()
+
+	client := meta.NewMetadataStoreClient(conn)
+
+	req := &meta.ListFilesRequest{
+		Path:      c.Args().First(),
+		Recursive: c.Bool("recursive"),
+	}
+
+	for {
+		res, err := client.ListFiles(context.Background(), req)
+		if err != nil {
+			return err
+		}
+
+		for _, info := range res.Files {
+			if info.Directory {
+				fmt.Println(info.Name + "/")
+			} else {
+				fmt.Println(info.Name)
+			}
+		}
+
+		if res.NextPageToken == "" {
+			return nil
+		}
+
+		req.PageToken = res.NextPageToken
+	}
+}
@@ -2,6 +2,8 @@ package main
 
 import (
 	"os"
+	"surfs/internal/meta"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/urfave/cli"
@@ -33,6 +35,19 @@ func main() {
 			Name:  "metadata-store-port",
 			Usage: "Specifies the `PORT` of the Surfs block store service (default: 5679).",
 		},
+		cli.Uint64Flag{
+			Name:  "block-size",
+			Usage: "Specifies the size, in `BYTES`, of the blocks new files are divided into (default: 4096).",
+		},
+		cli.StringFlag{
+			Name:  "chunking",
+			Usage: "Specifies the `METHOD` used to divide new files into blocks, either fixed or content-defined (default: fixed).",
+		},
+		cli.StringFlag{
+			Name:      "encryption-secret-file",
+			Usage:     "Encrypts the blocks of uploaded files, and decrypts those of encrypted files, with the secret in `FILE`.",
+			TakesFile: true,
+		},
 		cli.StringFlag{
 			Name:      "config, c",
 			Usage:     "Specifies a configuration `FILE`",
@@ -64,6 +79,115 @@ func main() {
 			Usage:  "Retrieve a file from Surfs.",
 			Action: read,
 		},
+		{
+			Name:      "mv",
+			Usage:     "Rename a file without transferring its blocks.",
+			ArgsUsage: "SRC DEST",
+			Action:    Move,
+		},
+		{
+			Name:      "cp",
+			Usage:     "Copy a file without transferring its blocks.",
+			ArgsUsage: "SRC DEST",
+			Action:    Copy,
+		},
+		{
+			Name:      "sync",
+			Usage:     "Synchronize a local directory with Surfs.",
+			ArgsUsage: "DIR",
+			Action:    Sync,
+		},
+		{
+			Name:      "history",
+			Usage:     "List the current and previous versions of a file.",
+			ArgsUsage: "FILE",
+			Action:    History,
+		},
+		{
+			Name:      "restore",
+			Usage:     "Create a new version of a file with the contents of a previous version.",
+			ArgsUsage: "FILE VERSION",
+			Action:    Restore,
+		},
+		{
+			Name:      "stat",
+			Usage:     "Describe a file without downloading it.",
+			ArgsUsage: "FILE",
+			Action:    Stat,
+			Flags: []cli.Flag{
+				cli.Uint64Flag{
+					Name:  "version",
+					Usage: "Describe the retained `VERSION` of the file instead of the current one.",
+				},
+			},
+		},
+		{
+			Name:      "ls",
+			Usage:     "List the files and directories under a path.",
+			ArgsUsage: "[PATH]",
+			Action:    List,
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "recursive, r",
+					Usage: "List every file below the path, instead of only its immediate entries.",
+				},
+			},
+		},
+		{
+			Name:      "watch",
+			Usage:     "Print changes to files under a path as they are committed.",
+			ArgsUsage: "[PATH]",
+			Action:    Watch,
+			Flags: []cli.Flag{
+				cli.Uint64Flag{
+					Name:  "since",
+					Usage: "Start with the changes after store `VERSION`, as printed in the first column, instead of only new changes.",
+				},
+				cli.StringFlag{
+					Name:  "incarnation",
+					Usage: "Fail unless the store is still at incarnation `ID`, as printed on the first line, which --since VERSION came from.",
+				},
+			},
+		},
+		{
+			Name:      "issue-token",
+			Usage:     "Print a signed token that authenticates a principal.",
+			ArgsUsage: "PRINCIPAL",
+			Action:    IssueToken,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:      "secret-file",
+					Usage:     "Sign the token with the secret in `FILE`, which the servers verify tokens with.",
+					TakesFile: true,
+				},
+				cli.DurationFlag{
+					Name:  "ttl",
+					Usage: "Specifies the `DURATION` for which the token is valid.",
+					Value: 24 * time.Hour,
+				},
+			},
+		},
+		{
+			Name:   "stats",
+			Usage:  "Print the number and size of the blocks in the block store.",
+			Action: Stats,
+		},
+		{
+			Name:   "gc",
+			Usage:  "Delete blocks that are no longer referenced by any file.",
+			Action: CollectGarbage,
+			Flags: []cli.Flag{
+				cli.DurationFlag{
+					Name:  "grace-period",
+					Usage: "Only delete blocks not stored or checked for within this `DURATION`, so that uploads in progress are not affected.",
+					Value: meta.DefaultGCGracePeriod,
+				},
+				cli.BoolFlag{
+					Name:  "dry-run",
+					Usage: "Count the unreferenced blocks without deleting them.",
+				},
+			},
+		},
 	}
 
 	if err := app.Run(os.Args); err != nil {
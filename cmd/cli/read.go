@@ -12,7 +12,6 @@ import (
 	"surfs/internal/meta"
 
 	"github.com/urfave/cli"
-	"google.golang.org/grpc"
 )
 
 func read(c *cli.Context) error {
@@ -45,8 +44,7 @@ func read(c *cli.Context) error {
 	}
 
 	// Set up metadata store client.
-	addr := fmt.Sprintf("%s:%d", conf.MetadataConf.Host, conf.MetadataConf.Port)
-	conn, err := grpc.Dial(addr, grpc.WithInsecure(), grpc.WithBlock())
+	conn, err := dialMetadataStore(conf)
 	if err != nil {
 		return err
 	}
@@ -56,8 +54,7 @@ func read(c *cli.Context) error {
 	client := meta.NewMetadataStoreClient(conn)
 
 	// Set up block store client.
-	blockAddr := fmt.Sprintf("%s:%d", conf.BlockConf.Host, conf.BlockConf.Port)
-	blockConn, err := grpc.Dial(blockAddr, grpc.WithInsecure(), grpc.WithBlock())
+	blockConn, err := dialBlockStore(conf)
 	if err != nil {
 		return err
 	}
@@ -75,7 +72,7 @@ func read(c *cli.Context) error {
 		return err
 	}
 
-	if readRes.HashList == nil {
+	if remoteHashList(readRes) == nil {
 		fmt.Println(NotFound)
 		return NotFound
 	}
@@ -91,21 +88,8 @@ func read(c *cli.Context) error {
 
 	// Download all the blocks corresponding to the file and write them to the
 	// destination file.
-	for _, hash := range readRes.HashList {
-		getReq := &block.GetBlockRequest{Hash: hash}
-		getRes, err := blockClient.GetBlock(context.Background(), getReq)
-		if err != nil {
-			return err
-		}
-
-		if !getRes.Success {
-			return errors.New("block missing from block store")
-		}
-
-		_, err = wr.Write(getRes.Block)
-		if err != nil {
-			return err
-		}
+	if err := downloadBlocks(blockClient, readRes.HashList, readRes.BlockKeys, remoteLayout(readRes), conf.Encryption.keyring, wr); err != nil {
+		return err
 	}
 
 	return wr.Flush()
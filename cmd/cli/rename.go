@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"surfs/internal/meta"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Move renames a file in Surfs. No blocks are transferred.
+func Move(c *cli.Context) error {
+	return transferFile(c, true)
+}
+
+// Copy copies a file in Surfs. The copy shares the blocks of the original, so none are transferred.
+func Copy(c *cli.Context) error {
+	return transferFile(c, false)
+}
+
+// Renames or copies the file named by the first argument to the name given by the second.
+func transferFile(c *cli.Context, rename bool) error {
+
+	source := c.Args().Get(0)
+	if source == "" {
+		return SrcRequired
+	}
+
+	destination := c.Args().Get(1)
+	if destination == "" {
+		return DestRequired
+	}
+
+	conf, err := getConfig(c)
+	if err != nil {
+		return err
+	}
+
+	conn, err := dialMetadataStore(conf)
+	if err != nil {
+		return err
+	}
+
+	defer conn.Close()
+
+	client := meta.NewMetadataStoreClient(conn)
+
+	srcRes, err := client.GetVersion(context.Background(), &meta.GetVersionRequest{Filename: source})
+	if err != nil {
+		return err
+	}
+
+	destRes, err := client.GetVersion(context.Background(), &meta.GetVersionRequest{Filename: destination})
+	if err != nil {
+		return err
+	}
+
+	var success bool
+	if rename {
+		var res *meta.RenameFileResponse
+		res, err = client.RenameFile(context.Background(), &meta.RenameFileRequest{
+			Source:             source,
+			SourceVersion:      srcRes.Version + 1,
+			Destination:        destination,
+			DestinationVersion: destRes.Version + 1,
+		})
+		success = res.GetSuccess()
+	} else {
+		var res *meta.CopyFileResponse
+		res, err = client.CopyFile(context.Background(), &meta.CopyFileRequest{
+			Source:             source,
+			SourceVersion:      srcRes.Version,
+			Destination:        destination,
+			DestinationVersion: destRes.Version + 1,
+		})
+		success = res.GetSuccess()
+	}
+
+	if status.Code(err) == codes.NotFound {
+		fmt.Println(NotFound)
+		return NotFound
+	} else if err != nil {
+		return err
+	}
+
+	if !success {
+		log.Error("Version conflict; please try again.")
+		return VersionConflict
+	}
+
+	fmt.Println("OK")
+	return nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"surfs/internal/block"
+	"surfs/internal/meta"
+	"text/tabwriter"
+	"time"
+
+	"github.com/urfave/cli"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Stat describes a version of a file, by default the current one, without downloading it.
+func Stat(c *cli.Context) error {
+
+	filename := c.Args().First()
+	if filename == "" {
+		return errors.New("must specify a file")
+	}
+
+	conf, err := getConfig(c)
+	if err != nil {
+		return err
+	}
+
+	conn, err := dialMetadataStore(conf)
+	if err != nil {
+		return err
+	}
+
+	defer conn.Close()
+
+	client := meta.NewMetadataStoreClient(conn)
+
+	res, err := client.StatFile(context.Background(), &meta.StatFileRequest{
+		Filename: filename,
+		Version:  c.Uint64("version"),
+	})
+	if status.Code(err) == codes.NotFound {
+		fmt.Println(NotFound)
+		return NotFound
+	} else if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(w, "File:\t%s\n", filename)
+	fmt.Fprintf(w, "Version:\t%d\n", res.Version)
+	fmt.Fprintf(w, "Committed:\t%s\n", formatTime(res.ModTime))
+
+	if res.Deleted {
+		fmt.Fprintf(w, "Deleted:\tyes\n")
+		return w.Flush()
+	}
+
+	fmt.Fprintf(w, "Blocks:\t%d of %d bytes, %s\n", res.NumBlocks, res.BlockSize, block.Chunking(res.Chunking))
+
+	// Files uploaded by clients that did not report attributes have none.
+	if attrs := res.Attributes; attrs != nil {
+		fmt.Fprintf(w, "Size:\t%d\n", attrs.Size)
+		fmt.Fprintf(w, "Mode:\t%s\n", os.FileMode(attrs.Mode))
+		fmt.Fprintf(w, "Modified:\t%s\n", formatTime(attrs.Mtime))
+		fmt.Fprintf(w, "Creator:\t%s\n", attrs.Creator)
+
+		// Encrypted files have no digest, which would reveal whether they hold some known contents.
+		if attrs.BlockKeys != nil {
+			fmt.Fprintf(w, "Encrypted:\tyes\n")
+		} else {
+			fmt.Fprintf(w, "Digest:\tsha256:%s\n", attrs.Digest)
+		}
+	}
+
+	return w.Flush()
+}
+
+// Formats a time in nanoseconds since the Unix epoch, or "-" if it is zero, which means unknown.
+func formatTime(t int64) string {
+	if t == 0 {
+		return "-"
+	}
+
+	return time.Unix(0, t).Format(time.RFC3339)
+}
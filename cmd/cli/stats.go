@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"surfs/internal/block"
+
+	"github.com/urfave/cli"
+)
+
+// Stats prints how many blocks the block store holds, and how much space they take up before and after
+// compression.
+func Stats(c *cli.Context) error {
+
+	conf, err := getConfig(c)
+	if err != nil {
+		return err
+	}
+
+	conn, err := dialBlockStore(conf)
+	if err != nil {
+		return err
+	}
+
+	defer conn.Close()
+
+	client := block.NewStoreClient(conn)
+
+	res, err := client.GetStats(context.Background(), &block.GetStatsRequest{})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%d block(s), %d compressed\n", res.Blocks, res.CompressedBlocks)
+	fmt.Printf("%d logical byte(s), %d physical byte(s)\n", res.LogicalBytes, res.PhysicalBytes)
+	return nil
+}
@@ -0,0 +1,355 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"surfs/internal/block"
+	"surfs/internal/crypt"
+	"surfs/internal/meta"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+)
+
+// Synchronizes the files in a local base directory with Surfs.
+type syncer struct {
+	baseDir     string
+	idx         index
+	metaClient  meta.MetadataStoreClient
+	blockClient block.StoreClient
+
+	// How files are divided into blocks when they are uploaded.
+	layout layout
+
+	// Encrypts the blocks of uploaded files and decrypts those of downloaded files, or nil if blocks are
+	// not encrypted.
+	keyring *crypt.Keyring
+
+	// Whether the block store has been checked to accept the blocks of uploaded files. The check is
+	// made before the first upload, so that syncs that only download do not depend on the layout.
+	blockSizeChecked bool
+}
+
+// Sync synchronizes a local base directory with Surfs. Local additions, modifications and deletions
+// since the last sync are uploaded, and remote changes are downloaded. If a file was changed both
+// locally and remotely, the remote copy wins.
+func Sync(c *cli.Context) error {
+
+	baseDir := c.Args().First()
+	if baseDir == "" {
+		return errors.New("must specify a base directory")
+	}
+
+	conf, err := getConfig(c)
+	if err != nil {
+		return err
+	}
+
+	indexPath := filepath.Join(baseDir, IndexFilename)
+	idx, err := loadIndex(indexPath)
+	if err != nil {
+		return err
+	}
+
+	metaConn, err := dialMetadataStore(conf)
+	if err != nil {
+		return err
+	}
+
+	defer metaConn.Close()
+
+	metaClient := meta.NewMetadataStoreClient(metaConn)
+
+	names, err := syncFilenames(baseDir, idx, metaClient)
+	if err != nil {
+		return err
+	}
+
+	blockConn, err := dialBlockStore(conf)
+	if err != nil {
+		return err
+	}
+
+	defer blockConn.Close()
+
+	s := &syncer{
+		baseDir:     baseDir,
+		idx:         idx,
+		metaClient:  metaClient,
+		blockClient: block.NewStoreClient(blockConn),
+		layout:      conf.BlockConf.layout(),
+		keyring:     conf.Encryption.keyring,
+	}
+
+	for _, name := range names {
+		if err := s.syncFile(name); err != nil {
+			// Save the progress made so far, so that synced files are not considered changed next time.
+			if saveErr := idx.save(indexPath); saveErr != nil {
+				log.Errorf("Failed to save index, %v", saveErr)
+			}
+
+			return err
+		}
+	}
+
+	return idx.save(indexPath)
+}
+
+// Returns the sorted names of the files to sync, which are the files in the base directory, in the
+// index, and in the root directory of the metadata store. Hidden files, including the index itself,
+// are skipped.
+func syncFilenames(baseDir string, idx index, metaClient meta.MetadataStoreClient) ([]string, error) {
+	infos, err := ioutil.ReadDir(baseDir)
+	if err != nil {
+		return nil, err
+	}
+
+	set := make(map[string]struct{}, len(infos)+len(idx))
+	for _, info := range infos {
+		if info.Mode().IsRegular() && !strings.HasPrefix(info.Name(), ".") {
+			set[info.Name()] = struct{}{}
+		}
+	}
+
+	for name := range idx {
+		set[name] = struct{}{}
+	}
+
+	// Files created remotely since the last sync are neither in the base directory nor in the index.
+	req := &meta.ListFilesRequest{}
+	for {
+		res, err := metaClient.ListFiles(context.Background(), req)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, info := range res.Files {
+			if !info.Directory && !strings.HasPrefix(info.Name, ".") {
+				set[info.Name] = struct{}{}
+			}
+		}
+
+		if res.NextPageToken == "" {
+			break
+		}
+
+		req.PageToken = res.NextPageToken
+	}
+
+	names := make([]string, 0, len(set))
+	for name := range set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// Compares two hash lists. A nil hash list, which represents a missing file, differs from the empty
+// hash list of an empty file.
+func equalHashLists(a []string, b []string) bool {
+	if (a == nil) != (b == nil) || len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Synchronizes a single file.
+func (s *syncer) syncFile(name string) error {
+	path := filepath.Join(s.baseDir, name)
+
+	entry := s.idx[name]
+
+	// Compute the hash list of the local copy of the file. A missing file has a nil hash list. To tell
+	// whether the file has changed since the last sync, it is divided into blocks the same way as it
+	// was then.
+	l := s.layout
+	if entry.hashList != nil {
+		l = entry.layout
+	}
+
+	var localHashes []string
+	var attrs *meta.FileAttributes
+	f, err := os.Open(path)
+	if err == nil {
+		defer f.Close()
+
+		localHashes, attrs, err = l.hashFile(f, s.keyring)
+		if err != nil {
+			return err
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	remote, err := s.metaClient.ReadFile(context.Background(), &meta.ReadFileRequest{Filename: name})
+	if err != nil {
+		return err
+	}
+
+	remote.HashList = remoteHashList(remote)
+
+	// If the metadata store has an older version than the last synced one, it has lost the file's
+	// metadata. Rather than treating that as a remote change, the local copy is pushed again.
+	if remote.Version < entry.version {
+		entry = indexEntry{version: remote.Version}
+	}
+
+	if equalHashLists(entry.hashList, localHashes) {
+		// The file has not changed locally, so we only need to pull any remote changes.
+		if remote.Version > entry.version {
+			return s.pull(name, remote)
+		}
+
+		return nil
+	}
+
+	// The file has changed locally. If nobody else has changed the file since the last sync, we can
+	// push the local changes. Otherwise, there is a conflict, which is resolved in favour of the server.
+	if remote.Version == entry.version {
+		err := s.push(name, entry.version+1, localHashes, l, attrs, f)
+		if err != VersionConflict {
+			return err
+		}
+
+		// Another client committed a new version after we read the file, so read it again.
+		remote, err = s.metaClient.ReadFile(context.Background(), &meta.ReadFileRequest{Filename: name})
+		if err != nil {
+			return err
+		}
+
+		remote.HashList = remoteHashList(remote)
+	}
+
+	log.WithFields(log.Fields{
+		"filename":      name,
+		"localVersion":  entry.version,
+		"remoteVersion": remote.Version,
+	}).Info("Conflict; keeping remote copy.")
+
+	return s.pull(name, remote)
+}
+
+// Uploads the local copy of a file as the specified version with the specified attributes, or deletes
+// the remote copy if the local copy is missing, in which case the file is nil. The hash list must have been computed with the
+// specified layout; if that is not the configured layout, the file is divided into blocks again before
+// uploading.
+func (s *syncer) push(name string, version uint64, hashList []string, l layout, attrs *meta.FileAttributes, f *os.File) error {
+	if f == nil {
+		res, err := s.metaClient.DeleteFile(context.Background(), &meta.DeleteFileRequest{
+			Filename: name,
+			Version:  version,
+		})
+		if err != nil {
+			return err
+		}
+
+		if !res.Success {
+			return VersionConflict
+		}
+
+		log.WithField("filename", name).Info("Deleted remote copy.")
+		s.idx[name] = indexEntry{version: version}
+
+		return nil
+	}
+
+	if l != s.layout {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+
+		var err error
+		hashList, attrs, err = s.layout.hashFile(f, s.keyring)
+		if err != nil {
+			return err
+		}
+
+		l = s.layout
+	}
+
+	if !s.blockSizeChecked {
+		if err := checkBlockSize(s.blockClient, s.layout, s.keyring); err != nil {
+			return err
+		}
+
+		s.blockSizeChecked = true
+	}
+
+	if err := modifyFile(s.metaClient, s.blockClient, name, version, hashList, l, attrs, s.keyring, f); err != nil {
+		return err
+	}
+
+	log.WithField("filename", name).Info("Uploaded local copy.")
+	s.idx[name] = indexEntry{version: version, hashList: hashList, layout: l}
+
+	return nil
+}
+
+// Replaces the local copy of a file with the remote copy, or deletes the local copy if the remote copy
+// was deleted. The hash list of the remote copy must be nil only if it was deleted; see remoteHashList.
+func (s *syncer) pull(name string, remote *meta.ReadFileResponse) error {
+	path := filepath.Join(s.baseDir, name)
+
+	if remote.HashList == nil {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+
+		log.WithField("filename", name).Info("Deleted local copy.")
+
+		if remote.Version == 0 {
+			delete(s.idx, name)
+		} else {
+			s.idx[name] = indexEntry{version: remote.Version}
+		}
+
+		return nil
+	}
+
+	// Download to a temporary file first, so that the local copy is replaced atomically.
+	f, err := ioutil.TempFile(s.baseDir, ".surfs-download")
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(f.Name())
+
+	wr := bufio.NewWriter(f)
+	if err := downloadBlocks(s.blockClient, remote.HashList, remote.BlockKeys, remoteLayout(remote), s.keyring, wr); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := wr.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(f.Name(), path); err != nil {
+		return err
+	}
+
+	log.WithField("filename", name).Info("Downloaded remote copy.")
+	s.idx[name] = indexEntry{version: remote.Version, hashList: remote.HashList, layout: remoteLayout(remote)}
+
+	return nil
+}
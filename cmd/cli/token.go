@@ -0,0 +1,37 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"surfs/internal/auth"
+
+	"github.com/urfave/cli"
+)
+
+// IssueToken prints a signed token for a principal, which servers that verify tokens with the same
+// secret accept until it expires.
+func IssueToken(c *cli.Context) error {
+
+	principal := c.Args().First()
+	if principal == "" {
+		return errors.New("must specify a principal")
+	}
+
+	secretFile := c.String("secret-file")
+	if secretFile == "" {
+		return errors.New("must specify a secret file")
+	}
+
+	secret, err := auth.ReadSecretFile(secretFile)
+	if err != nil {
+		return err
+	}
+
+	token, err := auth.SignToken(secret, principal, c.Duration("ttl"))
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(token)
+	return nil
+}
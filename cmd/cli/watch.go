@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"surfs/internal/meta"
+
+	"github.com/urfave/cli"
+)
+
+// Watch prints the changes to files at or below a path as they are committed, one per line, as the
+// store version, the type of change, the filename and the new version of the file. The first line
+// holds the store version the watch starts after, START, and the incarnation of the store, which
+// resuming the watch requires. It runs until interrupted.
+func Watch(c *cli.Context) error {
+
+	conf, err := getConfig(c)
+	if err != nil {
+		return err
+	}
+
+	conn, err := dialMetadataStore(conf)
+	if err != nil {
+		return err
+	}
+
+	defer conn.Close()
+
+	client := meta.NewMetadataStoreClient(conn)
+
+	stream, err := client.Watch(context.Background(), &meta.WatchRequest{
+		Prefix:       c.Args().First(),
+		SinceVersion: c.Uint64("since"),
+		Incarnation:  c.String("incarnation"),
+	})
+	if err != nil {
+		return err
+	}
+
+	for {
+		event, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+
+		if event.Type == meta.WatchEvent_START {
+			fmt.Printf("%d\t%s\t%s\n", event.StoreVersion, event.Type, event.Incarnation)
+			continue
+		}
+
+		fmt.Printf("%d\t%s\t%s\t%d\n", event.StoreVersion, event.Type, event.Filename, event.Version)
+	}
+}
@@ -5,18 +5,64 @@ import (
 	"fmt"
 	"net"
 	"os"
+	"strings"
+	"surfs/internal/auth"
 	"surfs/internal/meta"
+	"surfs/internal/transport"
 
+	"github.com/BurntSushi/toml"
 	"google.golang.org/grpc"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/urfave/cli"
 )
 
+type config struct {
+	TLS  transport.TLSConfig `toml:"tls"`
+	Auth auth.Config         `toml:"auth"`
+}
+
 func run(c *cli.Context) error {
 
 	log.SetLevel(log.DebugLevel)
 
+	var conf config
+	if path := c.String("config"); path != "" {
+		if _, err := toml.DecodeFile(path, &conf); err != nil {
+			return err
+		}
+	}
+
+	serverOpts, err := conf.TLS.ServerOptions()
+	if err != nil {
+		return err
+	}
+
+	// The metadata store presents the same certificate to the block store and its peers as it does to
+	// its own clients.
+	dialOpt, err := conf.TLS.DialOption()
+	if err != nil {
+		return err
+	}
+
+	if conf.Auth.Enabled() {
+		authn, err := conf.Auth.Authenticator()
+		if err != nil {
+			return err
+		}
+
+		acl, err := conf.Auth.AccessControlList()
+		if err != nil {
+			return err
+		}
+
+		serverOpts = append(serverOpts, auth.ServerOptions(authn, meta.Authorize(acl))...)
+	}
+
+	// Likewise, it presents its token to the block store and its peers, so the token's principal needs
+	// write access to the root directory wherever authentication is required.
+	dialOpts := append([]grpc.DialOption{dialOpt}, conf.Auth.DialOptions()...)
+
 	port := c.Uint64("port")
 	if port == 0 {
 		return errors.New("must specify a valid port")
@@ -32,6 +78,8 @@ func run(c *cli.Context) error {
 		return errors.New("must specify a port for the block store")
 	}
 
+	dataDir := c.String("datadir")
+
 	addr := fmt.Sprintf(":%d", port)
 	blockStoreAddr := fmt.Sprintf("%s:%d", blockStoreHost, blockStorePort)
 
@@ -40,13 +88,28 @@ func run(c *cli.Context) error {
 		return err
 	}
 
-	store, err := meta.NewStore(blockStoreAddr)
+	store, err := meta.NewStore(blockStoreAddr, dataDir, dialOpts...)
 	if err != nil {
 		return err
 	}
 	defer store.Close()
 
-	s := grpc.NewServer()
+	store.SetRetention(c.Uint("retain-versions"), c.Duration("retain-age"))
+
+	if peers := c.String("peers"); peers != "" {
+		id := c.String("address")
+		if id == "" {
+			id = fmt.Sprintf("localhost:%d", port)
+		}
+
+		log.Debugf("starting replicated metadata store %s", id)
+
+		if err := store.Replicate(id, strings.Split(peers, ",")); err != nil {
+			return err
+		}
+	}
+
+	s := grpc.NewServer(serverOpts...)
 	meta.RegisterMetadataStoreServer(s, store)
 
 	if err := s.Serve(lis); err != nil {
@@ -67,7 +130,7 @@ func main() {
 	app.Flags = []cli.Flag{
 		cli.StringFlag{
 			Name:  "datadir, D",
-			Usage: "Specifies the `DIR` where the metadata store files are located (default: ./meta).",
+			Usage: "Specifies the `DIR` where the metadata store files are located, or an empty value to keep metadata in memory only (default: ./meta).",
 			Value: "./meta",
 		},
 		cli.UintFlag{
@@ -85,6 +148,27 @@ func main() {
 			Usage: "Specifies the `PORT` of the Surfs block store service (default: 5678).",
 			Value: 5678,
 		},
+		cli.StringFlag{
+			Name:  "config, c",
+			Usage: "Specifies a configuration `FILE`, whose [tls] and [auth] sections configure TLS and authentication for the metadata store's connections.",
+		},
+		cli.StringFlag{
+			Name:  "peers",
+			Usage: "Specifies a comma-separated list of `ADDRS` of the other metadata stores in the cluster. If empty, the metadata store is not replicated.",
+		},
+		cli.StringFlag{
+			Name:  "address, a",
+			Usage: "Specifies the `ADDR` at which clients and other metadata stores in the cluster reach this one (default: localhost:PORT).",
+		},
+		cli.UintFlag{
+			Name:  "retain-versions",
+			Usage: "Specifies the `COUNT` of previous versions retained for each file. Every metadata store in a cluster must use the same value.",
+			Value: meta.DefaultRetainVersions,
+		},
+		cli.DurationFlag{
+			Name:  "retain-age",
+			Usage: "Specifies the `DURATION` for which previous versions are retained after being superseded. If zero, versions of any age are retained.",
+		},
 		cli.BoolFlag{
 			Name:  "V",
 			Usage: "Enables verbose output",
@@ -5,6 +5,8 @@ go 1.12
 require (
 	github.com/BurntSushi/toml v0.3.1
 	github.com/golang/protobuf v1.3.2
+	github.com/golang/snappy v0.0.1
+	github.com/klauspost/compress v1.9.8
 	github.com/maybetheresloop/keychain v0.0.0-20191117063635-ef9a048a79fc
 	github.com/sirupsen/logrus v1.4.2
 	github.com/stretchr/testify v1.3.0
@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Access is a kind of access to files.
+type Access int
+
+const (
+	// Reading files, and listing and watching them.
+	Read Access = iota
+
+	// Creating, modifying and deleting files. Write access implies read access.
+	Write
+)
+
+var accessNames = map[Access]string{
+	Read:  "read",
+	Write: "write",
+}
+
+func (a Access) String() string {
+	if name, ok := accessNames[a]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("Access(%d)", int(a))
+}
+
+// Parses the name of a kind of access, as returned by String.
+func ParseAccess(name string) (Access, error) {
+	for a, n := range accessNames {
+		if n == name {
+			return a, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown access %q", name)
+}
+
+// Principal matched by rules that apply to every authenticated principal.
+const AnyPrincipal = "*"
+
+// A rule granting a principal access to the files at or below a path.
+type Rule struct {
+	Principal string
+
+	// The normalized path, without leading or trailing slashes. The empty path is the root, which
+	// contains every file.
+	Prefix string
+	Access Access
+}
+
+// ACL is a list of rules. Access is denied unless a rule grants it.
+type ACL []Rule
+
+// Reports whether a principal may access the file or directory at a normalized path.
+func (acl ACL) Allowed(principal string, path string, access Access) bool {
+	for _, rule := range acl {
+		if rule.Principal != principal && rule.Principal != AnyPrincipal {
+			continue
+		}
+
+		if rule.Access < access {
+			continue
+		}
+
+		if rule.Prefix == "" || path == rule.Prefix || strings.HasPrefix(path, rule.Prefix+"/") {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,142 @@
+package auth
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthenticator_SignedTokens(t *testing.T) {
+	secret := []byte("secret")
+	authn := NewAuthenticator(nil, secret)
+
+	token, err := SignToken(secret, "alice", time.Hour)
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(token, signedTokenPrefix))
+
+	principal, err := authn.Authenticate(token)
+	assert.Nil(t, err)
+	assert.Equal(t, "alice", principal)
+
+	// Tokens signed with another secret, or tampered with, are rejected.
+	other, err := SignToken([]byte("other"), "alice", time.Hour)
+	assert.Nil(t, err)
+
+	_, err = authn.Authenticate(other)
+	assert.Equal(t, errInvalidToken, err)
+
+	bob, err := SignToken(secret, "bob", time.Hour)
+	assert.Nil(t, err)
+
+	tampered := token[:strings.LastIndexByte(token, '.')] + bob[strings.LastIndexByte(bob, '.'):]
+	_, err = authn.Authenticate(tampered)
+	assert.Equal(t, errInvalidToken, err)
+
+	for _, malformed := range []string{"", "v1.", "v1.a.b.c", "v1.!.!"} {
+		_, err = authn.Authenticate(malformed)
+		assert.Equal(t, errInvalidToken, err, malformed)
+	}
+
+	// Tokens expire after their TTL.
+	authn.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	_, err = authn.Authenticate(token)
+	assert.Equal(t, errExpiredToken, err)
+
+	forever, err := SignToken(secret, "alice", 0)
+	assert.Nil(t, err)
+
+	_, err = authn.Authenticate(forever)
+	assert.Nil(t, err)
+
+	// Without a secret, signed tokens are not accepted.
+	_, err = NewAuthenticator(nil, nil).Authenticate(forever)
+	assert.Equal(t, errInvalidToken, err)
+}
+
+func TestReadKeysFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "surfs")
+	assert.Nil(t, err)
+
+	defer os.RemoveAll(dir)
+
+	name := filepath.Join(dir, "keys")
+	assert.Nil(t, ioutil.WriteFile(name, []byte("# Principals and keys\nalice key1\n\n  bob   key2  \n"), 0600))
+
+	keys, err := ReadKeysFile(name)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"key1": "alice", "key2": "bob"}, keys)
+
+	authn := NewAuthenticator(keys, nil)
+	principal, err := authn.Authenticate("key2")
+	assert.Nil(t, err)
+	assert.Equal(t, "bob", principal)
+
+	_, err = authn.Authenticate("key3")
+	assert.Equal(t, errInvalidToken, err)
+
+	assert.Nil(t, ioutil.WriteFile(name, []byte("alice\n"), 0600))
+	_, err = ReadKeysFile(name)
+	assert.NotNil(t, err)
+}
+
+func TestACL_Allowed(t *testing.T) {
+	acl := ACL{
+		{Principal: "alice", Prefix: "", Access: Write},
+		{Principal: "bob", Prefix: "projects/bob", Access: Write},
+		{Principal: AnyPrincipal, Prefix: "public", Access: Read},
+	}
+
+	cases := []struct {
+		principal string
+		path      string
+		access    Access
+		allowed   bool
+	}{
+		{"alice", "anything", Write, true},
+		{"alice", "", Write, true},
+		{"bob", "projects/bob", Write, true},
+		{"bob", "projects/bob/file", Read, true},
+		{"bob", "projects/bobby", Read, false},
+		{"bob", "projects", Read, false},
+		{"bob", "public/file", Read, true},
+		{"bob", "public/file", Write, false},
+		{"carol", "public", Read, true},
+		{"carol", "projects/bob/file", Read, false},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.allowed, acl.Allowed(c.principal, c.path, c.access), "%s %s %s", c.principal, c.access, c.path)
+	}
+
+	assert.False(t, ACL(nil).Allowed("alice", "", Read))
+}
+
+func TestConfig_AccessControlList(t *testing.T) {
+	acl, err := Config{}.AccessControlList()
+	assert.Nil(t, err)
+	assert.True(t, acl.Allowed("anyone", "file", Write))
+
+	acl, err = Config{ACL: []RuleConfig{
+		{Principal: "bob", Prefix: "/projects//bob/", Access: "write"},
+		{Principal: "*", Prefix: "/", Access: "read"},
+	}}.AccessControlList()
+	assert.Nil(t, err)
+	assert.Equal(t, ACL{
+		{Principal: "bob", Prefix: "projects/bob", Access: Write},
+		{Principal: "*", Prefix: "", Access: Read},
+	}, acl)
+
+	for _, rc := range []RuleConfig{
+		{Principal: "bob", Prefix: "a", Access: "admin"},
+		{Principal: "bob", Prefix: "../a", Access: "read"},
+		{Prefix: "a", Access: "read"},
+	} {
+		_, err = Config{ACL: []RuleConfig{rc}}.AccessControlList()
+		assert.NotNil(t, err)
+	}
+}
@@ -0,0 +1,134 @@
+package auth
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"google.golang.org/grpc"
+)
+
+// Config configures authentication, and is read from the [auth] section of a configuration file.
+// Servers use the keys file, secret file and rules, and clients use the token.
+type Config struct {
+	// The token presented by clients, including a metadata store connecting to the block store and
+	// its peers.
+	Token string
+
+	// The file listing static keys and their principals. See ReadKeysFile.
+	KeysFile string
+
+	// The file holding the secret that signed tokens are verified with.
+	SecretFile string
+
+	// The access control rules. If there are none, every authenticated principal may read and write
+	// every file.
+	ACL []RuleConfig `toml:"acl"`
+}
+
+// A rule as written in a configuration file.
+type RuleConfig struct {
+	// The principal, or * for every principal.
+	Principal string
+
+	// The path the rule applies to, along with every file below it. An empty path or / is the root.
+	Prefix string
+
+	// Either read or write.
+	Access string
+}
+
+// Reports whether servers require authentication.
+func (c Config) Enabled() bool {
+	return c.KeysFile != "" || c.SecretFile != ""
+}
+
+// Reads a secret used to sign and verify tokens. Surrounding whitespace is ignored.
+func ReadSecretFile(name string) ([]byte, error) {
+	b, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	secret := bytes.TrimSpace(b)
+	if len(secret) == 0 {
+		return nil, fmt.Errorf("secret file %s is empty", name)
+	}
+
+	return secret, nil
+}
+
+// Creates an authenticator that accepts the configured keys and signed tokens.
+func (c Config) Authenticator() (*Authenticator, error) {
+	var keys map[string]string
+	if c.KeysFile != "" {
+		var err error
+		if keys, err = ReadKeysFile(c.KeysFile); err != nil {
+			return nil, err
+		}
+	}
+
+	var secret []byte
+	if c.SecretFile != "" {
+		var err error
+		if secret, err = ReadSecretFile(c.SecretFile); err != nil {
+			return nil, err
+		}
+	}
+
+	return NewAuthenticator(keys, secret), nil
+}
+
+// Returns the configured access control list, with normalized prefixes.
+func (c Config) AccessControlList() (ACL, error) {
+	if len(c.ACL) == 0 {
+		return ACL{{Principal: AnyPrincipal, Access: Write}}, nil
+	}
+
+	acl := make(ACL, 0, len(c.ACL))
+	for _, rc := range c.ACL {
+		if rc.Principal == "" {
+			return nil, fmt.Errorf("access control rule for %q has no principal", rc.Prefix)
+		}
+
+		access, err := ParseAccess(rc.Access)
+		if err != nil {
+			return nil, err
+		}
+
+		prefix, err := cleanPrefix(rc.Prefix)
+		if err != nil {
+			return nil, err
+		}
+
+		acl = append(acl, Rule{Principal: rc.Principal, Prefix: prefix, Access: access})
+	}
+
+	return acl, nil
+}
+
+// Normalizes the path of a rule the same way the metadata store normalizes file paths.
+func cleanPrefix(p string) (string, error) {
+	segments := make([]string, 0, strings.Count(p, "/")+1)
+	for _, segment := range strings.Split(p, "/") {
+		switch segment {
+		case "", ".":
+		case "..":
+			return "", fmt.Errorf("access control rule path %q must not contain ..", p)
+		default:
+			segments = append(segments, segment)
+		}
+	}
+
+	return strings.Join(segments, "/"), nil
+}
+
+// Returns the dial options that attach the configured token to requests, if any.
+func (c Config) DialOptions() []grpc.DialOption {
+	if c.Token == "" {
+		return nil
+	}
+
+	return []grpc.DialOption{WithToken(c.Token)}
+}
@@ -0,0 +1,3 @@
+// Package auth authenticates the clients of Surfs services with bearer tokens, and authorizes their
+// access to files with access control lists.
+package auth
@@ -0,0 +1,112 @@
+package auth
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// The metadata key carrying the bearer token of a request.
+const authorizationKey = "authorization"
+
+const bearerPrefix = "Bearer "
+
+// Authorizes a request received from a principal, returning a PERMISSION_DENIED status if the
+// principal may not make it. For streams, every message received from the client is authorized.
+type AuthorizeFunc func(principal string, req interface{}) error
+
+type principalKey struct{}
+
+// Returns the principal that made the request with the specified context.
+func PrincipalFromContext(ctx context.Context) (string, bool) {
+	principal, ok := ctx.Value(principalKey{}).(string)
+	return principal, ok
+}
+
+// Authenticates the request with the specified context, returning a context carrying its principal.
+func authenticate(ctx context.Context, authn *Authenticator) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	values := md.Get(authorizationKey)
+	if len(values) != 1 || !strings.HasPrefix(values[0], bearerPrefix) {
+		return nil, status.Error(codes.Unauthenticated, "missing bearer token")
+	}
+
+	principal, err := authn.Authenticate(strings.TrimPrefix(values[0], bearerPrefix))
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	return context.WithValue(ctx, principalKey{}, principal), nil
+}
+
+// Returns the server options that make a gRPC server authenticate every request with the specified
+// authenticator and authorize it with the specified function.
+func ServerOptions(authn *Authenticator, authorize AuthorizeFunc) []grpc.ServerOption {
+	unary := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := authenticate(ctx, authn)
+		if err != nil {
+			return nil, err
+		}
+
+		principal, _ := PrincipalFromContext(ctx)
+		if err := authorize(principal, req); err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+
+	stream := func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authenticate(ss.Context(), authn)
+		if err != nil {
+			return err
+		}
+
+		return handler(srv, &authorizedStream{ServerStream: ss, ctx: ctx, authorize: authorize})
+	}
+
+	return []grpc.ServerOption{grpc.UnaryInterceptor(unary), grpc.StreamInterceptor(stream)}
+}
+
+// A server stream whose context carries the authenticated principal, and whose received messages are
+// authorized.
+type authorizedStream struct {
+	grpc.ServerStream
+	ctx       context.Context
+	authorize AuthorizeFunc
+}
+
+func (s *authorizedStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *authorizedStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+
+	principal, _ := PrincipalFromContext(s.ctx)
+	return s.authorize(principal, m)
+}
+
+// Credentials that attach a bearer token to every request.
+type tokenCredentials string
+
+func (t tokenCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{authorizationKey: bearerPrefix + string(t)}, nil
+}
+
+// Tokens may be sent over insecure connections, since TLS is optional.
+func (t tokenCredentials) RequireTransportSecurity() bool {
+	return false
+}
+
+// Returns the dial option that attaches the specified bearer token to every request on a connection.
+func WithToken(token string) grpc.DialOption {
+	return grpc.WithPerRPCCredentials(tokenCredentials(token))
+}
@@ -0,0 +1,165 @@
+package auth
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Prefix of signed tokens, which distinguishes them from static keys and identifies their format.
+const signedTokenPrefix = "v1."
+
+var (
+	errInvalidToken = errors.New("invalid token")
+	errExpiredToken = errors.New("token has expired")
+)
+
+// The claims of a signed token.
+type claims struct {
+	// The principal the token was issued to.
+	Subject string `json:"sub"`
+
+	// The time the token expires, in seconds since the Unix epoch, or zero if it never expires.
+	Expiry int64 `json:"exp,omitempty"`
+}
+
+// Verifies bearer tokens and maps them to the principals they were issued to. Tokens are either
+// static keys listed in a keys file, or signed tokens, which are verified with a shared secret.
+type Authenticator struct {
+	// The principals of the static keys, by the SHA-256 hash of the key, so that lookups do not
+	// depend on how much of a key matches.
+	keys map[[sha256.Size]byte]string
+
+	// The secret signed tokens are verified with, or nil if signed tokens are not accepted.
+	secret []byte
+
+	// Returns the current time. Replaced in tests.
+	now func() time.Time
+}
+
+// Creates an authenticator that accepts the specified static keys, by principal, and tokens signed with
+// the specified secret. A nil secret disables signed tokens.
+func NewAuthenticator(keys map[string]string, secret []byte) *Authenticator {
+	a := &Authenticator{
+		keys:   make(map[[sha256.Size]byte]string, len(keys)),
+		secret: secret,
+		now:    time.Now,
+	}
+
+	for key, principal := range keys {
+		a.keys[sha256.Sum256([]byte(key))] = principal
+	}
+
+	return a
+}
+
+// Returns the principal a token was issued to.
+func (a *Authenticator) Authenticate(token string) (string, error) {
+	if principal, ok := a.keys[sha256.Sum256([]byte(token))]; ok {
+		return principal, nil
+	}
+
+	if a.secret == nil || !strings.HasPrefix(token, signedTokenPrefix) {
+		return "", errInvalidToken
+	}
+
+	parts := strings.Split(strings.TrimPrefix(token, signedTokenPrefix), ".")
+	if len(parts) != 2 {
+		return "", errInvalidToken
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil || !hmac.Equal(sig, sign(a.secret, parts[0])) {
+		return "", errInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return "", errInvalidToken
+	}
+
+	var c claims
+	if err := json.Unmarshal(payload, &c); err != nil || c.Subject == "" {
+		return "", errInvalidToken
+	}
+
+	if c.Expiry != 0 && a.now().Unix() >= c.Expiry {
+		return "", errExpiredToken
+	}
+
+	return c.Subject, nil
+}
+
+// Returns the signature of the encoded claims of a token.
+func sign(secret []byte, payload string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(signedTokenPrefix + payload))
+	return mac.Sum(nil)
+}
+
+// Issues a token for a principal, signed with the specified secret. The token expires after the
+// specified duration, or never if it is zero. The token has the form v1.<claims>.<signature>, where
+// the claims are JSON and the signature is an HMAC-SHA256 of the prefix and claims, both encoded as
+// unpadded URL-safe Base64.
+func SignToken(secret []byte, principal string, ttl time.Duration) (string, error) {
+	if principal == "" {
+		return "", errors.New("must specify a principal")
+	}
+
+	c := claims{Subject: principal}
+	if ttl != 0 {
+		c.Expiry = time.Now().Add(ttl).Unix()
+	}
+
+	b, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+
+	payload := base64.RawURLEncoding.EncodeToString(b)
+	return signedTokenPrefix + payload + "." + base64.RawURLEncoding.EncodeToString(sign(secret, payload)), nil
+}
+
+// Reads a keys file, returning the principals by key. Each line of the file holds a principal and its
+// key, separated by whitespace. Empty lines and lines starting with # are ignored.
+func ReadKeysFile(name string) (map[string]string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	keys := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s:%d: expected a principal and a key", name, line)
+		}
+
+		if strings.HasPrefix(fields[1], signedTokenPrefix) {
+			return nil, fmt.Errorf("%s:%d: keys must not start with %q", name, line, signedTokenPrefix)
+		}
+
+		keys[fields[1]] = fields[0]
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
@@ -0,0 +1,26 @@
+package block
+
+import (
+	"surfs/internal/auth"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Returns an authorization function that checks the requests to a block store against an access
+// control list. Blocks do not belong to paths, so storing and reading them only needs authentication,
+// while listing and deleting them, which is done by the metadata store's garbage collector, needs
+// write access to the root directory. Per-path rules therefore do not protect blocks from principals
+// that know their hashes, which is an accepted limitation that client-side encryption addresses.
+func Authorize(acl auth.ACL) auth.AuthorizeFunc {
+	return func(principal string, req interface{}) error {
+		switch req.(type) {
+		case *ListBlocksRequest, *DeleteBlocksRequest:
+			if !acl.Allowed(principal, "", auth.Write) {
+				return status.Errorf(codes.PermissionDenied, "%s may not manage blocks", principal)
+			}
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,85 @@
+package block
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"math"
+	"time"
+)
+
+// Returned by backends for blocks they do not have.
+var ErrBlockNotFound = errors.New("block not found")
+
+// Describes a block held by a backend.
+type BlockInfo struct {
+	// The size of the block in bytes.
+	Size int64
+
+	// The time the block was last stored or checked for, which protects it from garbage collection.
+	ModTime time.Time
+}
+
+// Backend holds the contents of blocks, keyed by their hash. The block store checks hashes and block
+// sizes before calling a backend, and serializes deletions with respect to the other methods, so
+// backends only need to make each method safe for concurrent use.
+type Backend interface {
+	// Stores a block. Storing a block the backend already has only marks it as recently used. A block
+	// must never be observed partially written.
+	Put(hash string, b []byte) error
+
+	// Returns the contents of a block, or ErrBlockNotFound.
+	Get(hash string) ([]byte, error)
+
+	// Returns at most the first n bytes of a block, or ErrBlockNotFound. Unlike Get, it may skip
+	// checking the integrity of the block, so it is only suitable for reading headers.
+	GetPrefix(hash string, n int) ([]byte, error)
+
+	// Reports whether the backend has a block. A block that is found is marked as recently used.
+	Has(hash string) (bool, error)
+
+	// Describes a block, or returns ErrBlockNotFound.
+	Stat(hash string) (BlockInfo, error)
+
+	// Deletes a block. Deleting a block the backend does not have is not an error.
+	Delete(hash string) error
+
+	// Calls fn with the hash and description of each block, in no particular order, stopping at the
+	// first error.
+	List(fn func(hash string, info BlockInfo) error) error
+
+	// Releases the resources held by the backend.
+	Close() error
+}
+
+// Returns the name a block is stored under by backends, which is its hash converted from Base64 to hex,
+// since Base64 may contain path separators and is case-sensitive.
+func blockName(hash string) (string, error) {
+	b, err := decodeHash(hash)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+// Decodes a Base64-encoded block hash.
+func decodeHash(hash string) ([]byte, error) {
+	b, err := base64.StdEncoding.DecodeString(hash)
+	if err != nil || len(b) < 2 || len(b) > math.MaxUint8 {
+		return nil, errInvalidHash
+	}
+
+	return b, nil
+}
+
+// Returns the hash of the block stored under the specified name, or false if it is not the name of a
+// block, such as the name of a temporary file.
+func hashForName(name string) (string, bool) {
+	b, err := hex.DecodeString(name)
+	if err != nil || len(b) < 2 {
+		return "", false
+	}
+
+	return base64.StdEncoding.EncodeToString(b), true
+}
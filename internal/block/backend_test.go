@@ -0,0 +1,415 @@
+package block
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Checks the behavior every backend must have.
+func testBackend(t *testing.T, backend Backend) {
+	b1 := []byte("block1")
+	h1 := blockHash(b1)
+
+	_, err := backend.Get(h1)
+	assert.Equal(t, ErrBlockNotFound, err)
+
+	_, err = backend.GetPrefix(h1, 3)
+	assert.Equal(t, ErrBlockNotFound, err)
+
+	_, err = backend.Stat(h1)
+	assert.Equal(t, ErrBlockNotFound, err)
+
+	ok, err := backend.Has(h1)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	assert.Nil(t, backend.Delete(h1))
+
+	assert.Nil(t, backend.Put(h1, b1))
+	assert.Nil(t, backend.Put(h1, b1))
+
+	b, err := backend.Get(h1)
+	assert.Nil(t, err)
+	assert.Equal(t, b1, b)
+
+	b, err = backend.GetPrefix(h1, 3)
+	assert.Nil(t, err)
+	assert.Equal(t, b1[:3], b)
+
+	b, err = backend.GetPrefix(h1, 100)
+	assert.Nil(t, err)
+	assert.Equal(t, b1, b)
+
+	ok, err = backend.Has(h1)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	info, err := backend.Stat(h1)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(len(b1)), info.Size)
+	assert.WithinDuration(t, time.Now(), info.ModTime, 5*time.Second)
+
+	expected := map[string]int64{h1: int64(len(b1))}
+	for i := 0; i < 5; i++ {
+		b := []byte(fmt.Sprintf("block%d", i+10))
+		assert.Nil(t, backend.Put(blockHash(b), b))
+		expected[blockHash(b)] = int64(len(b))
+	}
+
+	listed := make(map[string]int64)
+	assert.Nil(t, backend.List(func(hash string, info BlockInfo) error {
+		listed[hash] = info.Size
+		return nil
+	}))
+	assert.Equal(t, expected, listed)
+
+	assert.Nil(t, backend.Delete(h1))
+
+	_, err = backend.Get(h1)
+	assert.Equal(t, ErrBlockNotFound, err)
+
+	ok, err = backend.Has(h1)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	var count int
+	assert.Nil(t, backend.List(func(hash string, info BlockInfo) error {
+		count++
+		return nil
+	}))
+	assert.Equal(t, 5, count)
+
+	assert.Nil(t, backend.Close())
+}
+
+func TestLocalBackend(t *testing.T) {
+	dir, err := ioutil.TempDir("", "surfs")
+	assert.Nil(t, err)
+
+	defer os.RemoveAll(dir)
+
+	backend, err := NewLocalBackend(dir)
+	assert.Nil(t, err)
+
+	testBackend(t, backend)
+}
+
+func TestMemoryBackend(t *testing.T) {
+	testBackend(t, NewMemoryBackend())
+}
+
+// An object held by fakeS3.
+type fakeObject struct {
+	data    []byte
+	modTime time.Time
+}
+
+// An in-process stand-in for an S3-compatible service holding a single bucket. It checks the signature
+// of every request, and supports just the requests the S3 backend makes.
+type fakeS3 struct {
+	conf S3Config
+
+	// The largest number of objects listed in a page.
+	pageSize int
+
+	mtx     sync.Mutex
+	objects map[string]fakeObject
+
+	// The number of objects copied onto themselves.
+	copies int
+}
+
+// Starts a fake S3 service, returning the configuration of a backend using it.
+func serveFakeS3(t *testing.T) (S3Config, *fakeS3, func()) {
+	fake := &fakeS3{
+		conf: S3Config{
+			Region:    "test-region",
+			Bucket:    "surfs-test",
+			Prefix:    "blocks/",
+			AccessKey: "access",
+			SecretKey: "secret",
+		},
+		pageSize: 2,
+		objects:  make(map[string]fakeObject),
+	}
+
+	server := httptest.NewServer(fake)
+	conf := fake.conf
+	conf.Endpoint = server.URL
+
+	return conf, fake, server.Close
+}
+
+func (f *fakeS3) fail(w http.ResponseWriter, status int, code string) {
+	w.WriteHeader(status)
+	xml.NewEncoder(w).Encode(&struct {
+		XMLName xml.Name `xml:"Error"`
+		Code    string
+	}{Code: code})
+}
+
+// Checks that a request was signed with the fake's credentials.
+func (f *fakeS3) verify(r *http.Request, body []byte) bool {
+	auth := r.Header.Get("Authorization")
+
+	var signed []string
+	for _, field := range strings.Split(strings.TrimPrefix(auth, s3SigningScheme+" "), ", ") {
+		if strings.HasPrefix(field, "SignedHeaders=") {
+			signed = strings.Split(strings.TrimPrefix(field, "SignedHeaders="), ";")
+		}
+	}
+
+	now, err := time.Parse(amzDateLayout, r.Header.Get("X-Amz-Date"))
+	if err != nil {
+		return false
+	}
+
+	payloadHash := r.Header.Get("X-Amz-Content-Sha256")
+	if sum := sha256.Sum256(body); payloadHash != hex.EncodeToString(sum[:]) {
+		return false
+	}
+
+	signature := s3Signature(f.conf, now, canonicalS3Request(r, signed, payloadHash))
+	return strings.HasSuffix(auth, "Signature="+signature) && strings.Contains(auth, "Credential="+f.conf.AccessKey+"/")
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		f.fail(w, http.StatusBadRequest, "IncompleteBody")
+		return
+	}
+
+	if !f.verify(r, body) {
+		f.fail(w, http.StatusForbidden, "SignatureDoesNotMatch")
+		return
+	}
+
+	bucket := "/" + f.conf.Bucket
+	if !strings.HasPrefix(r.URL.Path, bucket) {
+		f.fail(w, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	key := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, bucket), "/")
+	if key == "" {
+		f.list(w, r)
+		return
+	}
+
+	obj, ok := f.objects[key]
+	switch r.Method {
+	case http.MethodPut:
+		if src := r.Header.Get("X-Amz-Copy-Source"); src != "" {
+			if src != bucket+"/"+key || r.Header.Get("X-Amz-Metadata-Directive") != "REPLACE" {
+				f.fail(w, http.StatusBadRequest, "InvalidRequest")
+				return
+			}
+
+			if !ok {
+				f.fail(w, http.StatusNotFound, "NoSuchKey")
+				return
+			}
+
+			obj.modTime = time.Now()
+			f.objects[key] = obj
+			f.copies++
+			fmt.Fprint(w, "<CopyObjectResult></CopyObjectResult>")
+			return
+		}
+
+		f.objects[key] = fakeObject{data: body, modTime: time.Now()}
+	case http.MethodGet, http.MethodHead:
+		if !ok {
+			f.fail(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+
+		data := obj.data
+		status := http.StatusOK
+
+		// Only ranges starting at the beginning of the object are supported.
+		var last int
+		if _, err := fmt.Sscanf(r.Header.Get("Range"), "bytes=0-%d", &last); err == nil {
+			if len(data) == 0 {
+				f.fail(w, http.StatusRequestedRangeNotSatisfiable, "InvalidRange")
+				return
+			}
+
+			if last+1 < len(data) {
+				data = data[:last+1]
+			}
+
+			status = http.StatusPartialContent
+		}
+
+		w.Header().Set("Last-Modified", obj.modTime.UTC().Format(http.TimeFormat))
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		w.WriteHeader(status)
+		w.Write(data)
+	case http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		f.fail(w, http.StatusMethodNotAllowed, "MethodNotAllowed")
+	}
+}
+
+// Lists the objects in the bucket a page at a time, continuing after the key in the continuation token.
+func (f *fakeS3) list(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if r.Method != http.MethodGet || query.Get("list-type") != "2" {
+		f.fail(w, http.StatusBadRequest, "InvalidRequest")
+		return
+	}
+
+	var keys []string
+	for key := range f.objects {
+		if strings.HasPrefix(key, query.Get("prefix")) && key > query.Get("continuation-token") {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	type content struct {
+		Key          string
+		LastModified string
+		Size         int
+	}
+
+	result := struct {
+		XMLName               xml.Name `xml:"ListBucketResult"`
+		IsTruncated           bool
+		NextContinuationToken string
+		Contents              []content
+	}{}
+
+	if len(keys) > f.pageSize {
+		keys = keys[:f.pageSize]
+		result.IsTruncated = true
+		result.NextContinuationToken = keys[len(keys)-1]
+	}
+
+	for _, key := range keys {
+		obj := f.objects[key]
+		result.Contents = append(result.Contents, content{
+			Key:          key,
+			LastModified: obj.modTime.UTC().Format(time.RFC3339),
+			Size:         len(obj.data),
+		})
+	}
+
+	xml.NewEncoder(w).Encode(&result)
+}
+
+func TestS3Backend(t *testing.T) {
+	conf, fake, stop := serveFakeS3(t)
+	defer stop()
+
+	backend, err := NewS3Backend(conf)
+	assert.Nil(t, err)
+
+	testBackend(t, backend)
+
+	// Blocks are stored under the prefix.
+	for key := range fake.objects {
+		assert.True(t, strings.HasPrefix(key, conf.Prefix), key)
+	}
+
+	// Checking for a block only updates its last modified time if that is old.
+	backend, err = NewS3Backend(conf)
+	assert.Nil(t, err)
+
+	b := []byte("touched")
+	assert.Nil(t, backend.Put(blockHash(b), b))
+
+	copies := fake.copies
+	ok, err := backend.Has(blockHash(b))
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, copies, fake.copies)
+
+	key, err := backend.(*s3Backend).key(blockHash(b))
+	assert.Nil(t, err)
+
+	fake.mtx.Lock()
+	obj := fake.objects[key]
+	obj.modTime = time.Now().Add(-time.Hour)
+	fake.objects[key] = obj
+	fake.mtx.Unlock()
+
+	ok, err = backend.Has(blockHash(b))
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, copies+1, fake.copies)
+
+	info, err := backend.Stat(blockHash(b))
+	assert.Nil(t, err)
+	assert.WithinDuration(t, time.Now(), info.ModTime, 5*time.Second)
+
+	// Requests signed with the wrong secret are rejected.
+	conf.SecretKey = "wrong"
+	backend, err = NewS3Backend(conf)
+	assert.Nil(t, err)
+
+	err = backend.Put(blockHash([]byte("block1")), []byte("block1"))
+	assert.Equal(t, "SignatureDoesNotMatch", err.(*s3Error).Code)
+
+	_, err = NewS3Backend(S3Config{Endpoint: "localhost"})
+	assert.NotNil(t, err)
+}
+
+func TestStore_Backends(t *testing.T) {
+	conf, _, stop := serveFakeS3(t)
+	defer stop()
+
+	s3, err := NewS3Backend(conf)
+	assert.Nil(t, err)
+
+	for name, backend := range map[string]Backend{"memory": NewMemoryBackend(), "s3": s3} {
+		t.Run(name, func(t *testing.T) {
+			s := NewBackendStore(backend)
+
+			b := []byte("block1")
+			res, err := s.StoreBlock(context.Background(), &StoreBlockRequest{Hash: blockHash(b), Block: b})
+			assert.Nil(t, err)
+			assert.True(t, res.Success)
+
+			client, stop := serveStore(t, s)
+			defer stop()
+
+			// The cutoff is far enough ahead to allow for backends whose modification times lag.
+			cutoff := time.Now().Add(time.Hour)
+			assert.Equal(t, []string{blockHash(b)}, listBlocks(t, client, cutoff))
+
+			del, err := s.DeleteBlocks(context.Background(), &DeleteBlocksRequest{
+				Hashes:    []string{blockHash(b)},
+				OlderThan: cutoff.UnixNano(),
+			})
+			assert.Nil(t, err)
+			assert.Equal(t, []string{blockHash(b)}, del.Deleted)
+
+			getRes, err := s.GetBlock(context.Background(), &GetBlockRequest{Hash: blockHash(b)})
+			assert.Nil(t, err)
+			assert.False(t, getRes.Success)
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"crypto/sha256"
 	"encoding/base64"
 	"io"
+	"math"
 )
 
 type Map struct {
@@ -11,7 +12,38 @@ type Map struct {
 	Blocks map[string][]byte
 }
 
-const DefaultBlockSize uint64 = 64
+// Size of the blocks a file is divided into, unless another size is configured.
+const DefaultBlockSize uint64 = 4096
+
+// Size of the blocks of files created before the block size was recorded in the file metadata.
+const LegacyBlockSize uint64 = 64
+
+// Largest block the block store accepts, unless another limit is configured.
+const DefaultMaxBlockSize uint64 = 4 << 20
+
+// Space reserved in each gRPC message for the fields that accompany a block, such as its hash.
+const messageOverhead = 1024
+
+// Largest maximum block size that can be configured, so that a message carrying a block stays within
+// the 2GB a protobuf message can hold.
+const MaxBlockSizeLimit uint64 = math.MaxInt32 - messageOverhead
+
+// Returns the largest gRPC message needed to carry a block of the specified size. Both ends of a
+// connection must accept messages of this size when blocks are larger than gRPC's default limit.
+// Blocks larger than MaxBlockSizeLimit cannot be carried, so the size is capped at the limit.
+func MaxMessageSize(blockSize uint64) int {
+	if blockSize > MaxBlockSizeLimit {
+		blockSize = MaxBlockSizeLimit
+	}
+
+	return int(blockSize) + messageOverhead
+}
+
+// Calculates the Base64-encoded SHA256 hash of the specified block, which is the hash the block store
+// knows it by.
+func Hash(block []byte) string {
+	return blockHash(block)
+}
 
 // Calculates the Base64-encoded SHA256 hash of the specified block.
 func blockHash(block []byte) string {
@@ -19,6 +51,114 @@ func blockHash(block []byte) string {
 	return base64.StdEncoding.EncodeToString(sha[:])
 }
 
+// Chunker divides the contents of a reader into blocks one at a time, so that files of any size can be
+// processed in constant memory. It is used like a bufio.Scanner:
+//
+//	c := block.NewChunker(r)
+//	for c.Next() {
+//		hash, b := c.Hash(), c.Block()
+//	}
+//	if err := c.Err(); err != nil {
+//		...
+//	}
+type Chunker struct {
+	r io.Reader
+
+	// Holds the current block, followed by any data read past its end. The buffer is as large as the
+	// largest block.
+	buf []byte
+
+	// Number of bytes of data in buf, and the length of the current block.
+	end int
+	n   int
+
+	// Returns the length of the block at the start of the data, which is either a full buffer or the
+	// remaining data at the end of the reader.
+	split func(data []byte) int
+
+	hash string
+	err  error
+	eof  bool
+}
+
+// Creates a chunker that divides the contents of the specified reader into blocks of the default size.
+func NewChunker(r io.Reader) *Chunker {
+	return NewChunkerWithSize(r, DefaultBlockSize)
+}
+
+// Creates a chunker that divides the contents of the specified reader into blocks of the specified size.
+func NewChunkerWithSize(r io.Reader, size uint64) *Chunker {
+	return &Chunker{
+		r:   r,
+		buf: make([]byte, size),
+		split: func(data []byte) int {
+			return len(data)
+		},
+	}
+}
+
+// Advances to the next block. Returns false when there are no more blocks or an error occurred.
+func (c *Chunker) Next() bool {
+	if c.err != nil {
+		return false
+	}
+
+	// Move any data read past the end of the previous block to the start of the buffer, and fill the
+	// rest of the buffer.
+	c.end = copy(c.buf, c.buf[c.n:c.end])
+	c.n = 0
+
+	if !c.eof {
+		n, err := io.ReadFull(c.r, c.buf[c.end:])
+		c.end += n
+
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			// The remaining data is the last of the reader, so the last block may be smaller than
+			// the block size.
+			c.eof = true
+		} else if err != nil {
+			c.err = err
+			return false
+		}
+	}
+
+	if c.end == 0 {
+		return false
+	}
+
+	c.n = c.split(c.buf[:c.end])
+	c.hash = blockHash(c.buf[:c.n])
+
+	return true
+}
+
+// Returns the current block. The returned slice is only valid until the next call to Next.
+func (c *Chunker) Block() []byte {
+	return c.buf[:c.n]
+}
+
+// Returns the hash of the current block.
+func (c *Chunker) Hash() string {
+	return c.hash
+}
+
+// Returns the first error that occurred while reading, if any.
+func (c *Chunker) Err() error {
+	return c.err
+}
+
+// Divides the contents of the specified reader into blocks of the default size, and returns the hashes
+// of the blocks in order. Only one block is held in memory at a time.
+func HashList(r io.Reader) ([]string, error) {
+	return HashListWithSize(r, DefaultBlockSize)
+}
+
+// Divides the contents of the specified reader into blocks of the specified size, and returns the
+// hashes of the blocks in order. Only one block is held in memory at a time.
+func HashListWithSize(r io.Reader, size uint64) ([]string, error) {
+	return FixedChunking.HashList(r, size)
+}
+
 // Divides the contents of the specified reader into blocks of the specified size. A map of hashes to blocks is
 // returned.
 func makeBlocksWithSize(r io.Reader, size uint64) (*Map, error) {
@@ -26,29 +166,25 @@ func makeBlocksWithSize(r io.Reader, size uint64) (*Map, error) {
 		Blocks: make(map[string][]byte),
 		Hashes: make([]string, 0, 64),
 	}
-	for {
-		block := make([]byte, size)
-		n, err := io.ReadFull(r, block)
-		if err != nil {
-			if err == io.EOF {
-				return m, nil
-			} else if err == io.ErrUnexpectedEOF {
-				hash := blockHash(block[:n])
-				m.Blocks[hash] = block[:n]
-				m.Hashes = append(m.Hashes, hash)
-				return m, nil
-			} else {
-				return nil, err
-			}
-		}
 
-		hash := blockHash(block)
-		m.Blocks[hash] = block
-		m.Hashes = append(m.Hashes, hash)
+	c := NewChunkerWithSize(r, size)
+	for c.Next() {
+		// The chunker reuses its buffer, so each block must be copied.
+		block := make([]byte, len(c.Block()))
+		copy(block, c.Block())
+
+		m.Blocks[c.Hash()] = block
+		m.Hashes = append(m.Hashes, c.Hash())
 	}
+
+	if err := c.Err(); err != nil {
+		return nil, err
+	}
+
+	return m, nil
 }
 
-// Divides the contents of the specified reader into blocks of 4KB. A map of hashes to block data
+// Divides the contents of the specified reader into blocks of the default size. A map of hashes to block data
 // and a slice of the hashes in order are returned.
 func MakeBlocks(r io.Reader) (*Map, error) {
 	return makeBlocksWithSize(r, DefaultBlockSize)
@@ -1 +1,73 @@
 package block
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChunker(t *testing.T) {
+	c := NewChunkerWithSize(bytes.NewReader([]byte("aaaabbbbcc")), 4)
+
+	expected := []string{"aaaa", "bbbb", "cc"}
+	for _, e := range expected {
+		assert.True(t, c.Next())
+		assert.Equal(t, []byte(e), c.Block())
+		assert.Equal(t, blockHash([]byte(e)), c.Hash())
+	}
+
+	assert.False(t, c.Next())
+	assert.Nil(t, c.Err())
+
+	// An empty reader has no blocks.
+	c = NewChunkerWithSize(bytes.NewReader(nil), 4)
+	assert.False(t, c.Next())
+	assert.Nil(t, c.Err())
+}
+
+type errReader struct{}
+
+func (errReader) Read(p []byte) (int, error) {
+	return 0, errors.New("read failed")
+}
+
+func TestChunker_Error(t *testing.T) {
+	c := NewChunkerWithSize(io.MultiReader(bytes.NewReader([]byte("aaaa")), errReader{}), 4)
+
+	assert.True(t, c.Next())
+	assert.False(t, c.Next())
+	assert.NotNil(t, c.Err())
+
+	_, err := HashList(errReader{})
+	assert.NotNil(t, err)
+}
+
+func TestMakeBlocks(t *testing.T) {
+	m, err := makeBlocksWithSize(bytes.NewReader([]byte("aaaabbbbaaaa")), 4)
+	assert.Nil(t, err)
+
+	hashA := blockHash([]byte("aaaa"))
+	hashB := blockHash([]byte("bbbb"))
+	assert.Equal(t, []string{hashA, hashB, hashA}, m.Hashes)
+	assert.Equal(t, map[string][]byte{hashA: []byte("aaaa"), hashB: []byte("bbbb")}, m.Blocks)
+}
+
+func TestHashList(t *testing.T) {
+	content := bytes.Repeat([]byte("surfs"), 100)
+
+	hashes, err := HashList(bytes.NewReader(content))
+	assert.Nil(t, err)
+
+	m, err := MakeBlocks(bytes.NewReader(content))
+	assert.Nil(t, err)
+	assert.Equal(t, m.Hashes, hashes)
+}
+
+func TestHashListWithSize(t *testing.T) {
+	hashes, err := HashListWithSize(bytes.NewReader([]byte("aaaabbbbaa")), 4)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{blockHash([]byte("aaaa")), blockHash([]byte("bbbb")), blockHash([]byte("aa"))}, hashes)
+}
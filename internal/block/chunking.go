@@ -0,0 +1,167 @@
+package block
+
+import (
+	"fmt"
+	"io"
+	"math/bits"
+)
+
+// Chunking is a method of dividing files into blocks.
+type Chunking int
+
+const (
+	// Divides files into blocks of the block size, so that every block except the last is the same
+	// size.
+	FixedChunking Chunking = iota
+
+	// Divides files into blocks at boundaries chosen by a rolling hash of their contents, so that
+	// blocks average the block size. Since boundaries depend only on nearby content, inserting or
+	// removing data only changes the blocks around the change, instead of every block after it.
+	ContentDefinedChunking
+)
+
+// Smallest average block size supported by content-defined chunking.
+const minContentDefinedSize uint64 = 64
+
+var chunkingNames = map[Chunking]string{
+	FixedChunking:          "fixed",
+	ContentDefinedChunking: "content-defined",
+}
+
+func (c Chunking) String() string {
+	if name, ok := chunkingNames[c]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("Chunking(%d)", int(c))
+}
+
+// Parses the name of a chunking method, as returned by String.
+func ParseChunking(name string) (Chunking, error) {
+	for c, n := range chunkingNames {
+		if n == name {
+			return c, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown chunking method %q", name)
+}
+
+// Creates a chunker that divides the contents of the specified reader into blocks using this method
+// and the specified block size.
+func (c Chunking) NewChunker(r io.Reader, size uint64) *Chunker {
+	if c != ContentDefinedChunking {
+		return NewChunkerWithSize(r, size)
+	}
+
+	p := newCDCParams(size)
+	return &Chunker{
+		r:     r,
+		buf:   make([]byte, p.max),
+		split: p.split,
+	}
+}
+
+// Divides the contents of the specified reader into blocks using this method and the specified block
+// size, and returns the hashes of the blocks in order. Only one block is held in memory at a time.
+func (c Chunking) HashList(r io.Reader, size uint64) ([]string, error) {
+	ch := c.NewChunker(r, size)
+
+	hashes := make([]string, 0, 64)
+	for ch.Next() {
+		hashes = append(hashes, ch.Hash())
+	}
+
+	if err := ch.Err(); err != nil {
+		return nil, err
+	}
+
+	return hashes, nil
+}
+
+// Returns the size of the largest block this method produces with the specified block size.
+func (c Chunking) MaxBlockSize(size uint64) uint64 {
+	if c != ContentDefinedChunking {
+		return size
+	}
+
+	return uint64(newCDCParams(size).max)
+}
+
+// Parameters of content-defined chunking for an average block size. This is FastCDC: a gear hash is
+// rolled over the data, and a boundary is placed where the top bits of the hash are all zero. Blocks
+// are at least a quarter and at most four times the average size. A stricter mask is used before the
+// average size and a looser one after it, which keeps block sizes close to the average.
+type cdcParams struct {
+	min, avg, max int
+	maskS, maskL  uint64
+}
+
+func newCDCParams(size uint64) cdcParams {
+	if size < minContentDefinedSize {
+		size = minContentDefinedSize
+	}
+
+	// The number of hash bits that must be zero for a boundary, so that one is expected every
+	// 2^n bytes.
+	n := uint(bits.Len64(size) - 1)
+
+	return cdcParams{
+		min:   int(size / 4),
+		avg:   int(size),
+		max:   int(size * 4),
+		maskS: ^uint64(0) << (64 - (n + 2)),
+		maskL: ^uint64(0) << (64 - (n - 2)),
+	}
+}
+
+func (p cdcParams) split(data []byte) int {
+	n := len(data)
+	if n <= p.min {
+		return n
+	}
+
+	if n > p.max {
+		n = p.max
+	}
+
+	normal := p.avg
+	if n < normal {
+		normal = n
+	}
+
+	// Boundaries are never placed within the minimum block size, so hashing starts there.
+	var hash uint64
+	i := p.min
+	for ; i < normal; i++ {
+		hash = (hash << 1) + gear[data[i]]
+		if hash&p.maskS == 0 {
+			return i + 1
+		}
+	}
+
+	for ; i < n; i++ {
+		hash = (hash << 1) + gear[data[i]]
+		if hash&p.maskL == 0 {
+			return i + 1
+		}
+	}
+
+	return n
+}
+
+// Random values mixed into the gear hash for each byte value. The values are generated from a fixed
+// seed, since changing them would change the boundaries of every block.
+var gear [256]uint64
+
+func init() {
+	// splitmix64
+	seed := uint64(0x5375726673434443)
+	for i := range gear {
+		seed += 0x9e3779b97f4a7c15
+		z := seed
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		gear[i] = z ^ (z >> 31)
+	}
+}
@@ -0,0 +1,120 @@
+package block
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Returns the number of hashes in b that are not in a.
+func countNewHashes(a []string, b []string) int {
+	set := make(map[string]struct{}, len(a))
+	for _, hash := range a {
+		set[hash] = struct{}{}
+	}
+
+	n := 0
+	for _, hash := range b {
+		if _, ok := set[hash]; !ok {
+			n++
+		}
+	}
+
+	return n
+}
+
+func randomContent(size int) []byte {
+	content := make([]byte, size)
+	rand.New(rand.NewSource(1)).Read(content)
+	return content
+}
+
+func TestParseChunking(t *testing.T) {
+	for _, c := range []Chunking{FixedChunking, ContentDefinedChunking} {
+		got, err := ParseChunking(c.String())
+		assert.Nil(t, err)
+		assert.Equal(t, c, got)
+	}
+
+	_, err := ParseChunking("rabin")
+	assert.NotNil(t, err)
+}
+
+func TestContentDefinedChunking(t *testing.T) {
+	content := randomContent(1 << 20)
+	const size = 4096
+
+	c := ContentDefinedChunking.NewChunker(bytes.NewReader(content), size)
+
+	var joined []byte
+	var hashes []string
+	for c.Next() {
+		n := uint64(len(c.Block()))
+		assert.True(t, n <= ContentDefinedChunking.MaxBlockSize(size))
+
+		// Only the last block may be smaller than the minimum size.
+		if len(joined)+len(c.Block()) < len(content) {
+			assert.True(t, n >= size/4)
+		}
+
+		joined = append(joined, c.Block()...)
+		hashes = append(hashes, c.Hash())
+	}
+
+	assert.Nil(t, c.Err())
+	assert.Equal(t, content, joined)
+
+	// Blocks should average roughly the block size.
+	avg := len(content) / len(hashes)
+	assert.True(t, avg > size/2 && avg < size*2, "average block size %d", avg)
+
+	// Chunking is deterministic.
+	again, err := ContentDefinedChunking.HashList(bytes.NewReader(content), size)
+	assert.Nil(t, err)
+	assert.Equal(t, hashes, again)
+}
+
+func TestContentDefinedChunking_Insertion(t *testing.T) {
+	content := randomContent(1 << 20)
+	const size = 4096
+
+	edits := map[string][]byte{
+		"start":  append([]byte{'x'}, content...),
+		"middle": append(append(append([]byte{}, content[:len(content)/2]...), 'x'), content[len(content)/2:]...),
+		"delete": append(append([]byte{}, content[:1000]...), content[1001:]...),
+	}
+
+	for name, edited := range edits {
+		t.Run(name, func(t *testing.T) {
+			before, err := ContentDefinedChunking.HashList(bytes.NewReader(content), size)
+			assert.Nil(t, err)
+
+			after, err := ContentDefinedChunking.HashList(bytes.NewReader(edited), size)
+			assert.Nil(t, err)
+
+			// Only the blocks around the edit should change.
+			assert.True(t, countNewHashes(before, after) <= 2, "%d new hashes", countNewHashes(before, after))
+		})
+	}
+
+	// By contrast, inserting a byte at the start with fixed chunking changes every block.
+	before, err := FixedChunking.HashList(bytes.NewReader(content), size)
+	assert.Nil(t, err)
+
+	after, err := FixedChunking.HashList(bytes.NewReader(edits["start"]), size)
+	assert.Nil(t, err)
+	assert.Equal(t, len(after), countNewHashes(before, after))
+}
+
+func TestContentDefinedChunking_Small(t *testing.T) {
+	// Content smaller than the minimum block size is a single block.
+	hashes, err := ContentDefinedChunking.HashList(bytes.NewReader([]byte("abc")), 4096)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{blockHash([]byte("abc"))}, hashes)
+
+	hashes, err = ContentDefinedChunking.HashList(bytes.NewReader(nil), 4096)
+	assert.Nil(t, err)
+	assert.Empty(t, hashes)
+}
@@ -0,0 +1,173 @@
+package block
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression is a codec blocks are compressed with at rest.
+type Compression int
+
+const (
+	// Stores blocks as they are.
+	NoCompression Compression = iota
+
+	// Compresses blocks with gzip, which compresses text well but is slow.
+	GzipCompression
+
+	// Compresses blocks with Snappy, which compresses less than gzip but is much faster.
+	SnappyCompression
+
+	// Compresses blocks with Zstandard, which compresses about as well as gzip and is much faster to
+	// decompress.
+	ZstdCompression
+)
+
+var compressionNames = map[Compression]string{
+	NoCompression:     "none",
+	GzipCompression:   "gzip",
+	SnappyCompression: "snappy",
+	ZstdCompression:   "zstd",
+}
+
+func (c Compression) String() string {
+	if name, ok := compressionNames[c]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("Compression(%d)", int(c))
+}
+
+// Parses the name of a compression codec, as returned by String.
+func ParseCompression(name string) (Compression, error) {
+	for c, n := range compressionNames {
+		if n == name {
+			return c, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown compression codec %q", name)
+}
+
+// Compression is only kept if it saves at least this fraction of a block, since decompressing costs
+// time on every read.
+const minCompressionSavings = 8
+
+// Largest uncompressed size accepted in the header of a stored block, which protects against
+// allocating huge buffers for corrupted headers.
+const maxDecompressedSize = 1 << 30
+
+// Size of the longest header of a stored block: the codec and the uncompressed size.
+const maxBlockHeaderSize = 1 + binary.MaxVarintLen64
+
+var errMalformedBlock = errors.New("malformed stored block")
+
+// Zstandard encoder and decoder shared by all blocks. Both are safe for concurrent use when encoding
+// and decoding whole blocks.
+var (
+	zstdEncoder, _ = zstd.NewWriter(nil)
+	zstdDecoder, _ = zstd.NewReader(nil, zstd.WithDecoderMaxMemory(maxDecompressedSize))
+)
+
+// Returns the form in which a block is stored: a header holding the codec and the size of the block as
+// a uvarint, followed by the block compressed with the codec, if that makes it smaller by enough to be
+// worthwhile, or else by the block itself, with NoCompression as the codec.
+func (c Compression) encode(b []byte) []byte {
+	var data []byte
+	switch c {
+	case GzipCompression:
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		w.Write(b)
+		if err := w.Close(); err == nil {
+			data = buf.Bytes()
+		}
+	case SnappyCompression:
+		data = snappy.Encode(nil, b)
+	case ZstdCompression:
+		data = zstdEncoder.EncodeAll(b, nil)
+	}
+
+	if data == nil || len(data) > len(b)-len(b)/minCompressionSavings {
+		c, data = NoCompression, b
+	}
+
+	var header [maxBlockHeaderSize]byte
+	header[0] = byte(c)
+	n := 1 + binary.PutUvarint(header[1:], uint64(len(b)))
+
+	stored := make([]byte, 0, n+len(data))
+	stored = append(stored, header[:n]...)
+	return append(stored, data...)
+}
+
+// Parses the header of a stored block, returning the codec, the size of the block and the size of the
+// header. Only the header needs to be passed.
+func parseBlockHeader(stored []byte) (Compression, uint64, int, error) {
+	if len(stored) == 0 {
+		return 0, 0, 0, errMalformedBlock
+	}
+
+	c := Compression(stored[0])
+	if _, ok := compressionNames[c]; !ok {
+		return 0, 0, 0, fmt.Errorf("unknown compression codec %d", int(c))
+	}
+
+	size, n := binary.Uvarint(stored[1:])
+	if n <= 0 || size > maxDecompressedSize {
+		return 0, 0, 0, errMalformedBlock
+	}
+
+	return c, size, 1 + n, nil
+}
+
+// Returns the contents of a block stored in the specified form.
+func decodeBlock(stored []byte) ([]byte, error) {
+	c, size, n, err := parseBlockHeader(stored)
+	if err != nil {
+		return nil, err
+	}
+
+	data := stored[n:]
+
+	var b []byte
+	switch c {
+	case NoCompression:
+		b = data
+	case GzipCompression:
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+
+		if b, err = ioutil.ReadAll(io.LimitReader(zr, int64(size)+1)); err != nil {
+			return nil, err
+		}
+	case SnappyCompression:
+		if n, err := snappy.DecodedLen(data); err != nil || uint64(n) != size {
+			return nil, errMalformedBlock
+		}
+
+		if b, err = snappy.Decode(nil, data); err != nil {
+			return nil, err
+		}
+	case ZstdCompression:
+		if b, err = zstdDecoder.DecodeAll(data, make([]byte, 0, size)); err != nil {
+			return nil, err
+		}
+	}
+
+	if uint64(len(b)) != size {
+		return nil, errMalformedBlock
+	}
+
+	return b, nil
+}
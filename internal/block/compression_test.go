@@ -0,0 +1,114 @@
+package block
+
+import (
+	"context"
+	"crypto/rand"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestParseCompression(t *testing.T) {
+	for c := range compressionNames {
+		parsed, err := ParseCompression(c.String())
+		assert.Nil(t, err)
+		assert.Equal(t, c, parsed)
+	}
+
+	_, err := ParseCompression("lz4")
+	assert.NotNil(t, err)
+}
+
+func TestCompression_Encode(t *testing.T) {
+	text := []byte(strings.Repeat("2019-11-17 12:00:00 INFO request served\n", 100))
+
+	random := make([]byte, 4096)
+	_, err := rand.Read(random)
+	assert.Nil(t, err)
+
+	decode := func(stored []byte, expected []byte, codec Compression) {
+		c, size, _, err := parseBlockHeader(stored)
+		assert.Nil(t, err)
+		assert.Equal(t, codec, c)
+		assert.Equal(t, uint64(len(expected)), size)
+
+		b, err := decodeBlock(stored)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, b)
+	}
+
+	for _, c := range []Compression{GzipCompression, SnappyCompression, ZstdCompression} {
+		stored := c.encode(text)
+		assert.True(t, len(stored) < len(text)/4, c.String())
+		decode(stored, text, c)
+
+		// Blocks that do not compress well are stored as they are.
+		decode(c.encode(random), random, NoCompression)
+		decode(c.encode([]byte{}), []byte{}, NoCompression)
+	}
+
+	decode(NoCompression.encode(text), text, NoCompression)
+
+	// Blocks with a malformed header or data are not decoded.
+	stored := GzipCompression.encode(text)
+	for _, malformed := range [][]byte{
+		nil,
+		{0xff, 0},
+		{byte(SnappyCompression), 0xff, 0xff, 0xff, 0xff, 0xff, 0x7f},
+		stored[:len(stored)-1],
+		append(NoCompression.encode(text), 'x'),
+	} {
+		_, err := decodeBlock(malformed)
+		assert.NotNil(t, err)
+	}
+}
+
+func TestStore_Compression(t *testing.T) {
+	backend := NewMemoryBackend()
+	s := NewBackendStore(backend)
+
+	text := []byte(strings.Repeat("surfs ", 500))
+	logs := []byte(strings.Repeat("GET /index.html 200\n", 100))
+	random := make([]byte, 1024)
+	_, err := rand.Read(random)
+	assert.Nil(t, err)
+
+	store := func(c Compression, b []byte) {
+		s.SetCompression(c)
+		res, err := s.StoreBlock(context.Background(), &StoreBlockRequest{Hash: blockHash(b), Block: b})
+		assert.Nil(t, err)
+		assert.True(t, res.Success)
+	}
+
+	store(GzipCompression, text)
+	store(ZstdCompression, logs)
+	store(SnappyCompression, random)
+	store(NoCompression, []byte("raw"))
+
+	// Blocks are returned as they were uploaded, whatever codec they were stored with.
+	for _, b := range [][]byte{text, logs, random, []byte("raw")} {
+		res, err := s.GetBlock(context.Background(), &GetBlockRequest{Hash: blockHash(b)})
+		assert.Nil(t, err)
+		assert.True(t, res.Success)
+		assert.Equal(t, b, res.Block)
+	}
+
+	stored, err := backend.Get(blockHash(text))
+	assert.Nil(t, err)
+	assert.True(t, len(stored) < len(text))
+
+	stats, err := s.GetStats(context.Background(), &GetStatsRequest{})
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(4), stats.Blocks)
+	assert.Equal(t, uint64(2), stats.CompressedBlocks)
+	assert.Equal(t, uint64(len(text)+len(logs)+len(random)+len("raw")), stats.LogicalBytes)
+	assert.True(t, stats.PhysicalBytes < stats.LogicalBytes-uint64(len(text)/2))
+
+	// A block that cannot be decoded is reported as lost rather than returned as it is stored.
+	assert.Nil(t, backend.Put(blockHash([]byte("lost")), []byte("lost")))
+	_, err = s.GetBlock(context.Background(), &GetBlockRequest{Hash: blockHash([]byte("lost"))})
+	assert.Equal(t, codes.DataLoss, status.Code(err))
+}
@@ -1,15 +1,51 @@
 package block
 
 import (
+	"errors"
+	"io"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"time"
 )
 
+// Prefix of the temporary files that datafiles are written to before being renamed into place.
+const tempPrefix = ".tmp-"
+
+var errInvalidHash = errors.New("invalid block hash")
+
 // A wrapper around a file that holds the data of a corresponding block.
 type datafile struct {
 	path string
 }
 
+// Returns the datafile for the block with the specified hash. Datafiles are laid out in two levels of
+// fan-out directories named after the leading bytes of the hash, e.g. a block whose hash is 0xabcdef...
+// is stored at <dataDir>/ab/cd/abcdef....
+func datafileFor(dataDir string, hash string) (datafile, error) {
+	name, err := blockName(hash)
+	if err != nil {
+		return datafile{}, err
+	}
+
+	return datafile{path: filepath.Join(dataDir, name[0:2], name[2:4], name)}, nil
+}
+
+// Sets the modification time of the datafile to the current time, which marks the block as recently
+// used and protects it from garbage collection. Returns false if the datafile does not exist.
+func (d *datafile) touch() (bool, error) {
+	now := time.Now()
+	if err := os.Chtimes(d.path, now, now); err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	return true, nil
+}
+
 // Reads the entire file to a byte slice.
 func (d *datafile) readAll() ([]byte, error) {
 	f, err := os.Open(d.path)
@@ -25,3 +61,49 @@ func (d *datafile) readAll() ([]byte, error) {
 
 	return b, nil
 }
+
+// Reads at most the first n bytes of the file.
+func (d *datafile) readPrefix(n int) ([]byte, error) {
+	f, err := os.Open(d.path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ioutil.ReadAll(io.LimitReader(f, int64(n)))
+}
+
+// Atomically writes the specified contents to the file. The contents are first written to a
+// temporary file in the same directory, which is then renamed into place, so readers never
+// observe a partially written datafile.
+func (d *datafile) writeAll(b []byte) error {
+	dir := filepath.Dir(d.path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	f, err := ioutil.TempFile(dir, tempPrefix)
+	if err != nil {
+		return err
+	}
+
+	// Clean up the temporary file if we fail before renaming it.
+	tmp := f.Name()
+	defer os.Remove(tmp)
+
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, d.path)
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -41,3 +42,54 @@ func TestReadAll(t *testing.T) {
 	}
 
 }
+
+func TestDatafileFor(t *testing.T) {
+	hash := blockHash([]byte("asdf"))
+
+	d, err := datafileFor("data", hash)
+	if err != nil {
+		t.Fatalf("failed to get datafile for hash %s, %v", hash, err)
+	}
+
+	expected := "data/f0/e4/f0e4c2f76c58916ec258f246851bea091d14d4247a2fc3e18694461b1816e13b"
+	if d.path != expected {
+		t.Fatalf("datafile path incorrect: expected =%s, got =%s", expected, d.path)
+	}
+
+	if _, err := datafileFor("data", "not/base64"); err == nil {
+		t.Fatalf("expected error for invalid hash")
+	}
+}
+
+func TestWriteAll(t *testing.T) {
+	dir, err := ioutil.TempDir("", "datafile-test")
+	if err != nil {
+		t.Fatalf("unable to create temp dir, %v", err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	d := datafile{path: filepath.Join(dir, "a", "b", "c")}
+	if err := d.writeAll([]byte("asdf")); err != nil {
+		t.Fatalf("failed to write datafile, %v", err)
+	}
+
+	got, err := d.readAll()
+	if err != nil {
+		t.Fatalf("failed to read datafile, %v", err)
+	}
+
+	if bytes.Compare([]byte("asdf"), got) != 0 {
+		t.Fatalf("datafile content incorrect: expected =%s, got =%s", []byte("asdf"), got)
+	}
+
+	// No temporary files should be left behind.
+	infos, err := ioutil.ReadDir(filepath.Dir(d.path))
+	if err != nil {
+		t.Fatalf("failed to read datafile directory, %v", err)
+	}
+
+	if len(infos) != 1 {
+		t.Fatalf("incorrect number of files in datafile directory: expected =1, got =%d", len(infos))
+	}
+}
@@ -0,0 +1,113 @@
+package block
+
+import (
+	"context"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Maximum number of hashes sent in each response of ListBlocks.
+const listBlocksBatchSize = 1024
+
+// Implemented by backends whose modification times may lag behind the last use of a block, because
+// they are only updated once they are old enough or come from another clock. Garbage collection treats
+// the blocks of such backends as used that much later than their modification times.
+type modTimeLagger interface {
+	modTimeLag() time.Duration
+}
+
+// Returns the cutoff of a garbage collection: blocks whose modification times are before it have not
+// been used since the specified time.
+func (s *Store) gcCutoff(olderThan int64) time.Time {
+	cutoff := time.Unix(0, olderThan)
+	if l, ok := s.backend.(modTimeLagger); ok {
+		cutoff = cutoff.Add(-l.modTimeLag())
+	}
+
+	return cutoff
+}
+
+// Lists, in batches, the blocks that have not been stored or checked for since the specified time.
+func (s *Store) ListBlocks(req *ListBlocksRequest, stream Store_ListBlocksServer) error {
+	olderThan := s.gcCutoff(req.OlderThan)
+
+	batch := make([]string, 0, listBlocksBatchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+
+		if err := stream.Send(&ListBlocksResponse{Hashes: batch}); err != nil {
+			return err
+		}
+
+		batch = make([]string, 0, listBlocksBatchSize)
+		return nil
+	}
+
+	var listed int
+	err := s.backend.List(func(hash string, info BlockInfo) error {
+		if !info.ModTime.Before(olderThan) {
+			return nil
+		}
+
+		listed++
+		batch = append(batch, hash)
+		if len(batch) < listBlocksBatchSize {
+			return nil
+		}
+
+		return flush()
+	})
+	if err != nil {
+		return err
+	}
+
+	log.WithFields(log.Fields{
+		"olderThan": olderThan,
+	}).Debugf("Listed %d block(s).", listed)
+
+	return flush()
+}
+
+// Deletes the specified blocks, except those that have been stored or checked for since the specified
+// time. Those blocks may have been found by a client that is about to reference them from a file, so
+// they are kept until a later collection.
+func (s *Store) DeleteBlocks(ctx context.Context, req *DeleteBlocksRequest) (*DeleteBlocksResponse, error) {
+	olderThan := s.gcCutoff(req.OlderThan)
+
+	s.gcMtx.Lock()
+	defer s.gcMtx.Unlock()
+
+	deleted := make([]string, 0, len(req.Hashes))
+	for _, hash := range req.Hashes {
+		if _, err := blockName(hash); err != nil {
+			continue
+		}
+
+		info, err := s.backend.Stat(hash)
+		if err == ErrBlockNotFound {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		if !info.ModTime.Before(olderThan) {
+			log.WithField("hash", hash).Debug("Did not delete block; block was used recently.")
+			continue
+		}
+
+		if err := s.backend.Delete(hash); err != nil {
+			return nil, err
+		}
+
+		deleted = append(deleted, hash)
+	}
+
+	log.Debugf("Deleted %d of %d block(s).", len(deleted), len(req.Hashes))
+
+	return &DeleteBlocksResponse{
+		Deleted: deleted,
+	}, nil
+}
@@ -0,0 +1,138 @@
+package block
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Sets the modification time of a block's datafile, as if it was last used at that time.
+func ageBlock(t *testing.T, s *Store, b []byte, mtime time.Time) {
+	df, err := datafileFor(s.backend.(*localBackend).dir, blockHash(b))
+	assert.Nil(t, err)
+	assert.Nil(t, os.Chtimes(df.path, mtime, mtime))
+}
+
+// Lists the blocks in the store not used since the specified time.
+func listBlocks(t *testing.T, client StoreClient, olderThan time.Time) []string {
+	stream, err := client.ListBlocks(context.Background(), &ListBlocksRequest{OlderThan: olderThan.UnixNano()})
+	assert.Nil(t, err)
+
+	var hashes []string
+	for {
+		res, err := stream.Recv()
+		if err == io.EOF {
+			return hashes
+		}
+
+		assert.Nil(t, err)
+		hashes = append(hashes, res.Hashes...)
+	}
+}
+
+func TestStore_ListBlocks(t *testing.T) {
+	old := []byte("old")
+	recent := []byte("recent")
+
+	s, cleanup := tempStore(t, old, recent)
+	defer cleanup()
+
+	client, stop := serveStore(t, s)
+	defer stop()
+
+	cutoff := time.Now().Add(-time.Hour)
+	ageBlock(t, s, old, cutoff.Add(-time.Minute))
+
+	// Temporary files left behind by failed writes are not blocks.
+	df, err := datafileFor(s.backend.(*localBackend).dir, blockHash(old))
+	assert.Nil(t, err)
+	_, err = tempBlock(filepath.Dir(df.path), tempPrefix, []byte("partial"))
+	assert.Nil(t, err)
+
+	assert.Equal(t, []string{blockHash(old)}, listBlocks(t, client, cutoff))
+	assert.ElementsMatch(t, []string{blockHash(old), blockHash(recent)}, listBlocks(t, client, time.Now().Add(time.Minute)))
+}
+
+func TestStore_ListBlocksBatches(t *testing.T) {
+	s, cleanup := tempStore(t)
+	defer cleanup()
+
+	client, stop := serveStore(t, s)
+	defer stop()
+
+	var expected []string
+	for i := 0; i < listBlocksBatchSize+10; i++ {
+		b := []byte{byte(i), byte(i >> 8)}
+		df, err := datafileFor(s.backend.(*localBackend).dir, blockHash(b))
+		assert.Nil(t, err)
+		assert.Nil(t, df.writeAll(b))
+
+		expected = append(expected, blockHash(b))
+	}
+
+	assert.ElementsMatch(t, expected, listBlocks(t, client, time.Now().Add(time.Minute)))
+}
+
+func TestStore_DeleteBlocks(t *testing.T) {
+	old := []byte("old")
+	recent := []byte("recent")
+
+	s, cleanup := tempStore(t, old, recent)
+	defer cleanup()
+
+	cutoff := time.Now().Add(-time.Hour)
+	ageBlock(t, s, old, cutoff.Add(-time.Minute))
+
+	// Recently used blocks and blocks that do not exist are not deleted.
+	res, err := s.DeleteBlocks(context.Background(), &DeleteBlocksRequest{
+		Hashes:    []string{blockHash(old), blockHash(recent), blockHash([]byte("missing")), "invalid"},
+		OlderThan: cutoff.UnixNano(),
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{blockHash(old)}, res.Deleted)
+
+	has, err := s.HasBlocks(context.Background(), &HasBlocksRequest{Hashes: []string{blockHash(old), blockHash(recent)}})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{blockHash(old)}, has.Missing)
+}
+
+func TestStore_HasBlockTouches(t *testing.T) {
+	b := []byte("block")
+
+	s, cleanup := tempStore(t, b)
+	defer cleanup()
+
+	cutoff := time.Now().Add(-time.Hour)
+	ageBlock(t, s, b, cutoff.Add(-time.Minute))
+
+	// Checking for the block marks it as used, so a collection that listed it earlier does not
+	// delete it.
+	has, err := s.HasBlock(context.Background(), &HasBlockRequest{Hash: blockHash(b)})
+	assert.Nil(t, err)
+	assert.True(t, has.Success)
+
+	res, err := s.DeleteBlocks(context.Background(), &DeleteBlocksRequest{
+		Hashes:    []string{blockHash(b)},
+		OlderThan: cutoff.UnixNano(),
+	})
+	assert.Nil(t, err)
+	assert.Empty(t, res.Deleted)
+
+	// So does storing the block again.
+	ageBlock(t, s, b, cutoff.Add(-time.Minute))
+
+	_, err = s.StoreBlock(context.Background(), &StoreBlockRequest{Block: b, Hash: blockHash(b)})
+	assert.Nil(t, err)
+
+	res, err = s.DeleteBlocks(context.Background(), &DeleteBlocksRequest{
+		Hashes:    []string{blockHash(b)},
+		OlderThan: cutoff.UnixNano(),
+	})
+	assert.Nil(t, err)
+	assert.Empty(t, res.Deleted)
+}
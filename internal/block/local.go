@@ -0,0 +1,196 @@
+package block
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Prefix of datafiles written by older versions of the block store, which named datafiles
+// randomly instead of by hash.
+const FilePrefix = "blk_"
+
+// A backend that keeps each block in a datafile in a local directory. Datafiles are named after the
+// hashes of their blocks, so no separate index is needed, and their modification times record when
+// the blocks were last used.
+type localBackend struct {
+	dir string
+}
+
+// Creates a backend that keeps blocks in the specified directory. Blocks stored by a previous instance
+// in the same directory remain available, including those stored by older versions of the block store.
+func NewLocalBackend(dir string) (Backend, error) {
+	l := &localBackend{dir: dir}
+	if err := l.migrate(); err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// Moves datafiles written by older versions of the block store into the content-addressed
+// layout, adding the header every stored block starts with.
+func (l *localBackend) migrate() error {
+	infos, err := ioutil.ReadDir(l.dir)
+	if err != nil {
+		return err
+	}
+
+	for _, info := range infos {
+		if info.IsDir() || !strings.HasPrefix(info.Name(), FilePrefix) {
+			continue
+		}
+
+		legacy := datafile{path: filepath.Join(l.dir, info.Name())}
+		b, err := legacy.readAll()
+		if err != nil {
+			return err
+		}
+
+		hash := blockHash(b)
+		df, err := datafileFor(l.dir, hash)
+		if err != nil {
+			return err
+		}
+
+		log.WithFields(log.Fields{
+			"hash": hash,
+			"from": legacy.path,
+			"to":   df.path,
+		}).Debug("Migrating block...")
+
+		// Legacy datafiles hold the block itself, without the header of stored blocks.
+		if err := df.writeAll(NoCompression.encode(b)); err != nil {
+			return err
+		}
+
+		if err := os.Remove(legacy.path); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (l *localBackend) Put(hash string, b []byte) error {
+	df, err := datafileFor(l.dir, hash)
+	if err != nil {
+		return err
+	}
+
+	// Blocks are immutable, so there is nothing to do if the block has already been stored, other
+	// than marking it as recently used.
+	ok, err := df.touch()
+	if err != nil || ok {
+		return err
+	}
+
+	return df.writeAll(b)
+}
+
+func (l *localBackend) Get(hash string) ([]byte, error) {
+	df, err := datafileFor(l.dir, hash)
+	if err != nil {
+		return nil, ErrBlockNotFound
+	}
+
+	b, err := df.readAll()
+	if os.IsNotExist(err) {
+		return nil, ErrBlockNotFound
+	}
+
+	return b, err
+}
+
+func (l *localBackend) GetPrefix(hash string, n int) ([]byte, error) {
+	df, err := datafileFor(l.dir, hash)
+	if err != nil {
+		return nil, ErrBlockNotFound
+	}
+
+	b, err := df.readPrefix(n)
+	if os.IsNotExist(err) {
+		return nil, ErrBlockNotFound
+	}
+
+	return b, err
+}
+
+func (l *localBackend) Has(hash string) (bool, error) {
+	df, err := datafileFor(l.dir, hash)
+	if err != nil {
+		return false, nil
+	}
+
+	return df.touch()
+}
+
+func (l *localBackend) Stat(hash string) (BlockInfo, error) {
+	df, err := datafileFor(l.dir, hash)
+	if err != nil {
+		return BlockInfo{}, ErrBlockNotFound
+	}
+
+	info, err := os.Stat(df.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return BlockInfo{}, ErrBlockNotFound
+		}
+
+		return BlockInfo{}, err
+	}
+
+	return BlockInfo{Size: info.Size(), ModTime: info.ModTime()}, nil
+}
+
+func (l *localBackend) Delete(hash string) error {
+	df, err := datafileFor(l.dir, hash)
+	if err != nil {
+		return nil
+	}
+
+	if err := os.Remove(df.path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+// Lists the datafiles by walking the fan-out directories of the data directory.
+func (l *localBackend) List(fn func(hash string, info BlockInfo) error) error {
+	shards, err := filepath.Glob(filepath.Join(l.dir, "[0-9a-f][0-9a-f]", "[0-9a-f][0-9a-f]"))
+	if err != nil {
+		return err
+	}
+
+	for _, shard := range shards {
+		infos, err := ioutil.ReadDir(shard)
+		if err != nil {
+			return err
+		}
+
+		for _, info := range infos {
+			if !info.Mode().IsRegular() {
+				continue
+			}
+
+			hash, ok := hashForName(info.Name())
+			if !ok {
+				continue
+			}
+
+			if err := fn(hash, BlockInfo{Size: info.Size(), ModTime: info.ModTime()}); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (l *localBackend) Close() error {
+	return nil
+}
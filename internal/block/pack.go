@@ -0,0 +1,803 @@
+package block
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Size at which a segment is sealed and a new one started, unless another size is configured.
+const DefaultSegmentSize int64 = 64 << 20
+
+// A sealed segment is compacted once less than this fraction of it holds live blocks.
+const compactionThreshold = 0.5
+
+// Extension of segment files, which are named after their sequence numbers.
+const segmentSuffix = ".pack"
+
+// Name of the index file, which is checkpointed when segments are sealed or compacted.
+const packIndexFilename = "pack.index"
+
+// Identifies the format of the index file.
+var packIndexMagic = []byte("SURFSPK1")
+
+// Kinds of records in a segment.
+const (
+	packRecordPut    byte = 1
+	packRecordDelete byte = 2
+)
+
+// Size of the fixed-size parts of a record: the kind, hash length and data length before the data, and
+// the checksum after it.
+const packRecordOverhead = 1 + 1 + 4 + 4
+
+var (
+	errCorruptRecord = errors.New("corrupt packfile record")
+	errCorruptIndex  = errors.New("corrupt packfile index")
+)
+
+// The location of a block's record in a segment.
+type packEntry struct {
+	segment uint32
+	offset  int64
+	length  int64
+
+	// The time the block was last stored or checked for. It is not persisted, so every block is
+	// treated as used when the backend is opened.
+	modTime time.Time
+}
+
+// A segment file. Records are only appended to the active segment; the others are sealed.
+type segment struct {
+	seq uint32
+	f   *os.File
+
+	// The number of bytes of records in the segment, and of those that hold live blocks.
+	size int64
+	live int64
+}
+
+// A backend that appends blocks to large segment files, so that small blocks do not each take up a
+// file. Each record in a segment is a put or a delete of a block:
+//
+//	kind (1 byte) | hash length (1 byte) | hash | data length (uint32) | data | CRC-32 (uint32)
+//
+// An index mapping hashes to records is kept in memory, and checkpointed to disk when a segment is
+// sealed, when a segment is compacted, and when the backend is closed. When opened, the backend loads
+// the checkpoint and replays the records appended after it, truncating a record that was only partly
+// written when the process stopped. Deleted blocks are reclaimed by compaction, which copies the live
+// blocks of a mostly deleted segment to the active segment and removes it. Compaction runs in the
+// background, one block at a time, so that neither deletions nor other requests wait for a whole
+// segment to be copied.
+type packBackend struct {
+	dir         string
+	segmentSize int64
+
+	mtx      sync.RWMutex
+	entries  map[string]packEntry
+	segments map[uint32]*segment
+	active   *segment
+
+	// Wakes the compactor after blocks are deleted.
+	compactCh chan struct{}
+
+	// Closed to stop the compactor, and by the compactor once it has stopped.
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+// Creates a backend that keeps blocks in segments of the specified size in the specified directory,
+// which must exist. If the size is zero, the default size is used. Blocks stored by a previous instance
+// in the same directory remain available.
+func NewPackBackend(dir string, segmentSize int64) (Backend, error) {
+	if segmentSize <= 0 {
+		segmentSize = DefaultSegmentSize
+	}
+
+	p := &packBackend{
+		dir:         dir,
+		segmentSize: segmentSize,
+		entries:     make(map[string]packEntry),
+		segments:    make(map[uint32]*segment),
+	}
+
+	if err := p.open(); err != nil {
+		p.closeSegments()
+		return nil, err
+	}
+
+	p.compactCh = make(chan struct{}, 1)
+	p.done = make(chan struct{})
+	p.stopped = make(chan struct{})
+	go p.runCompactor()
+
+	// Segments may have been left mostly deleted when the backend was last closed.
+	p.requestCompaction()
+
+	return p, nil
+}
+
+// Returns the path of the segment with the specified sequence number.
+func (p *packBackend) segmentPath(seq uint32) string {
+	return filepath.Join(p.dir, fmt.Sprintf("%08d%s", seq, segmentSuffix))
+}
+
+// Returns the sequence numbers of the segment files in the directory, in order.
+func (p *packBackend) segmentFiles() ([]uint32, error) {
+	names, err := filepath.Glob(filepath.Join(p.dir, "*"+segmentSuffix))
+	if err != nil {
+		return nil, err
+	}
+
+	seqs := make([]uint32, 0, len(names))
+	for _, name := range names {
+		seq, err := strconv.ParseUint(strings.TrimSuffix(filepath.Base(name), segmentSuffix), 10, 32)
+		if err != nil {
+			continue
+		}
+
+		seqs = append(seqs, uint32(seq))
+	}
+
+	sort.Slice(seqs, func(i, j int) bool { return seqs[i] < seqs[j] })
+	return seqs, nil
+}
+
+// Loads the checkpointed index and replays the records appended since.
+func (p *packBackend) open() error {
+	seqs, err := p.segmentFiles()
+	if err != nil {
+		return err
+	}
+
+	start, startOffset, err := p.readIndex()
+	if err == errCorruptIndex {
+		log.WithField("dir", p.dir).Warn("Packfile index is corrupt; rebuilding it from the segments.")
+		p.entries = make(map[string]packEntry)
+		start, startOffset = 0, 0
+	} else if err != nil {
+		return err
+	}
+
+	referenced := make(map[uint32]bool)
+	for _, e := range p.entries {
+		referenced[e.segment] = true
+	}
+
+	for _, seq := range seqs {
+		// Segments before the checkpoint that it does not reference were compacted, but not yet removed
+		// when the process stopped.
+		if seq < start && !referenced[seq] {
+			if err := os.Remove(p.segmentPath(seq)); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		f, err := os.OpenFile(p.segmentPath(seq), os.O_RDWR, 0644)
+		if err != nil {
+			return err
+		}
+
+		info, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return err
+		}
+
+		p.segments[seq] = &segment{seq: seq, f: f, size: info.Size()}
+		p.active = p.segments[seq]
+	}
+
+	for hash, e := range p.entries {
+		seg, ok := p.segments[e.segment]
+		if !ok || e.offset+e.length > seg.size {
+			return fmt.Errorf("packfile index references missing data for block %s", hash)
+		}
+
+		seg.live += e.length
+	}
+
+	for _, seq := range seqs {
+		seg, ok := p.segments[seq]
+		if !ok || seq < start {
+			continue
+		}
+
+		offset := int64(0)
+		if seq == start {
+			offset = startOffset
+		}
+
+		if err := p.replay(seg, offset); err != nil {
+			return err
+		}
+	}
+
+	if p.active == nil {
+		return p.startSegment(1)
+	}
+
+	return nil
+}
+
+// Applies the records of a segment from the specified offset. A partly written record at the end of the
+// active segment is truncated, since it was never acknowledged.
+func (p *packBackend) replay(seg *segment, offset int64) error {
+	r := bufio.NewReader(io.NewSectionReader(seg.f, offset, seg.size-offset))
+
+	now := time.Now()
+	for offset < seg.size {
+		kind, hash, length, err := readPackRecord(r)
+		if err != nil {
+			if seg != p.active {
+				return fmt.Errorf("segment %s: %v at offset %d", seg.f.Name(), err, offset)
+			}
+
+			log.WithFields(log.Fields{
+				"segment": seg.f.Name(),
+				"offset":  offset,
+			}).Warn("Truncating incomplete packfile record.")
+
+			if err := seg.f.Truncate(offset); err != nil {
+				return err
+			}
+
+			seg.size = offset
+			break
+		}
+
+		p.remove(hash)
+		if kind == packRecordPut {
+			p.entries[hash] = packEntry{segment: seg.seq, offset: offset, length: length, modTime: now}
+			seg.live += length
+		}
+
+		offset += length
+	}
+
+	return nil
+}
+
+// Reads a record, checking its checksum, and returns its kind, the hash of its block and its length.
+func readPackRecord(r *bufio.Reader) (byte, string, int64, error) {
+	crc := crc32.NewIEEE()
+	tr := io.TeeReader(r, crc)
+
+	var header [2]byte
+	if _, err := io.ReadFull(tr, header[:]); err != nil {
+		return 0, "", 0, errCorruptRecord
+	}
+
+	kind, hashLen := header[0], int(header[1])
+	if (kind != packRecordPut && kind != packRecordDelete) || hashLen < 2 {
+		return 0, "", 0, errCorruptRecord
+	}
+
+	raw := make([]byte, hashLen)
+	var size uint32
+	if _, err := io.ReadFull(tr, raw); err != nil {
+		return 0, "", 0, errCorruptRecord
+	}
+
+	if err := binary.Read(tr, binary.BigEndian, &size); err != nil {
+		return 0, "", 0, errCorruptRecord
+	}
+
+	if kind == packRecordDelete && size != 0 {
+		return 0, "", 0, errCorruptRecord
+	}
+
+	if _, err := io.CopyN(crc, r, int64(size)); err != nil {
+		return 0, "", 0, errCorruptRecord
+	}
+
+	var sum uint32
+	if err := binary.Read(r, binary.BigEndian, &sum); err != nil || sum != crc.Sum32() {
+		return 0, "", 0, errCorruptRecord
+	}
+
+	return kind, base64.StdEncoding.EncodeToString(raw), int64(packRecordOverhead + hashLen + int(size)), nil
+}
+
+// Encodes a record.
+func encodePackRecord(kind byte, raw []byte, data []byte) []byte {
+	buf := bytes.NewBuffer(make([]byte, 0, packRecordOverhead+len(raw)+len(data)))
+	buf.WriteByte(kind)
+	buf.WriteByte(byte(len(raw)))
+	buf.Write(raw)
+	binary.Write(buf, binary.BigEndian, uint32(len(data)))
+	buf.Write(data)
+	binary.Write(buf, binary.BigEndian, crc32.ChecksumIEEE(buf.Bytes()))
+	return buf.Bytes()
+}
+
+// Forgets the record of a block, which is no longer live.
+func (p *packBackend) remove(hash string) {
+	if e, ok := p.entries[hash]; ok {
+		p.segments[e.segment].live -= e.length
+		delete(p.entries, hash)
+	}
+}
+
+// Reads the checkpointed index into the entries, returning the position after which records must be
+// replayed. If there is no index, every record must be replayed.
+//
+// The index is the sequence number and offset of that position, the number of entries, and each entry's
+// hash length, hash, segment, offset and length, followed by a CRC-32 of the rest of the file.
+func (p *packBackend) readIndex() (uint32, int64, error) {
+	df := datafile{path: filepath.Join(p.dir, packIndexFilename)}
+	b, err := df.readAll()
+	if os.IsNotExist(err) {
+		return 0, 0, nil
+	} else if err != nil {
+		return 0, 0, err
+	}
+
+	if len(b) < len(packIndexMagic)+4 || !bytes.HasPrefix(b, packIndexMagic) {
+		return 0, 0, errCorruptIndex
+	}
+
+	body := b[:len(b)-4]
+	if crc32.ChecksumIEEE(body) != binary.BigEndian.Uint32(b[len(b)-4:]) {
+		return 0, 0, errCorruptIndex
+	}
+
+	r := bytes.NewReader(body[len(packIndexMagic):])
+
+	var header struct {
+		Segment uint32
+		Offset  int64
+		Count   uint32
+	}
+	if err := binary.Read(r, binary.BigEndian, &header); err != nil {
+		return 0, 0, errCorruptIndex
+	}
+
+	now := time.Now()
+	for i := uint32(0); i < header.Count; i++ {
+		hashLen, err := r.ReadByte()
+		if err != nil {
+			return 0, 0, errCorruptIndex
+		}
+
+		raw := make([]byte, hashLen)
+		if _, err := io.ReadFull(r, raw); err != nil {
+			return 0, 0, errCorruptIndex
+		}
+
+		var loc struct {
+			Segment uint32
+			Offset  int64
+			Length  int64
+		}
+		if err := binary.Read(r, binary.BigEndian, &loc); err != nil {
+			return 0, 0, errCorruptIndex
+		}
+
+		p.entries[base64.StdEncoding.EncodeToString(raw)] = packEntry{
+			segment: loc.Segment,
+			offset:  loc.Offset,
+			length:  loc.Length,
+			modTime: now,
+		}
+	}
+
+	return header.Segment, header.Offset, nil
+}
+
+// Checkpoints the index, so that only the records after the end of the active segment need to be
+// replayed when the backend is next opened.
+func (p *packBackend) writeIndex() error {
+	buf := bytes.NewBuffer(append([]byte(nil), packIndexMagic...))
+	binary.Write(buf, binary.BigEndian, p.active.seq)
+	binary.Write(buf, binary.BigEndian, p.active.size)
+	binary.Write(buf, binary.BigEndian, uint32(len(p.entries)))
+
+	for hash, e := range p.entries {
+		raw, err := decodeHash(hash)
+		if err != nil {
+			return err
+		}
+
+		buf.WriteByte(byte(len(raw)))
+		buf.Write(raw)
+		binary.Write(buf, binary.BigEndian, e.segment)
+		binary.Write(buf, binary.BigEndian, e.offset)
+		binary.Write(buf, binary.BigEndian, e.length)
+	}
+
+	binary.Write(buf, binary.BigEndian, crc32.ChecksumIEEE(buf.Bytes()))
+
+	df := datafile{path: filepath.Join(p.dir, packIndexFilename)}
+	return df.writeAll(buf.Bytes())
+}
+
+// Creates a segment and makes it the active one.
+func (p *packBackend) startSegment(seq uint32) error {
+	f, err := os.OpenFile(p.segmentPath(seq), os.O_RDWR|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+
+	p.active = &segment{seq: seq, f: f}
+	p.segments[seq] = p.active
+	return nil
+}
+
+// Appends a record to the active segment, sealing it first if the record would make it too large, and
+// returns the segment and offset of the record. The record is synced before returning.
+func (p *packBackend) append(rec []byte) (*segment, int64, error) {
+	if p.active.size > 0 && p.active.size+int64(len(rec)) > p.segmentSize {
+		if p.active.seq == math.MaxUint32 {
+			return nil, 0, errors.New("packfile segment sequence numbers exhausted")
+		}
+
+		if err := p.startSegment(p.active.seq + 1); err != nil {
+			return nil, 0, err
+		}
+
+		if err := p.writeIndex(); err != nil {
+			return nil, 0, err
+		}
+
+		if err := p.removeEmptySegments(); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	seg, offset := p.active, p.active.size
+	if _, err := seg.f.WriteAt(rec, offset); err != nil {
+		return nil, 0, err
+	}
+
+	if err := seg.f.Sync(); err != nil {
+		return nil, 0, err
+	}
+
+	seg.size += int64(len(rec))
+	return seg, offset, nil
+}
+
+func (p *packBackend) Put(hash string, b []byte) error {
+	raw, err := decodeHash(hash)
+	if err != nil {
+		return err
+	}
+
+	if uint64(len(b)) > math.MaxUint32 {
+		return fmt.Errorf("block of %d bytes is too large for a packfile", len(b))
+	}
+
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	// Blocks are immutable, so there is nothing to do if the block has already been stored, other than
+	// marking it as recently used.
+	if e, ok := p.entries[hash]; ok {
+		e.modTime = time.Now()
+		p.entries[hash] = e
+		return nil
+	}
+
+	rec := encodePackRecord(packRecordPut, raw, b)
+	seg, offset, err := p.append(rec)
+	if err != nil {
+		return err
+	}
+
+	p.entries[hash] = packEntry{segment: seg.seq, offset: offset, length: int64(len(rec)), modTime: time.Now()}
+	seg.live += int64(len(rec))
+	return nil
+}
+
+// Reads the data of a block from its record, checking the record's checksum.
+func (p *packBackend) read(e packEntry) ([]byte, error) {
+	rec := make([]byte, e.length)
+	if _, err := p.segments[e.segment].f.ReadAt(rec, e.offset); err != nil {
+		return nil, err
+	}
+
+	body := rec[:len(rec)-4]
+	if crc32.ChecksumIEEE(body) != binary.BigEndian.Uint32(rec[len(rec)-4:]) {
+		return nil, errCorruptRecord
+	}
+
+	return body[2+int(rec[1])+4:], nil
+}
+
+func (p *packBackend) Get(hash string) ([]byte, error) {
+	p.mtx.RLock()
+	defer p.mtx.RUnlock()
+
+	e, ok := p.entries[hash]
+	if !ok {
+		return nil, ErrBlockNotFound
+	}
+
+	return p.read(e)
+}
+
+// Reads the start of the data of a block from its record, without checking the record's checksum.
+func (p *packBackend) GetPrefix(hash string, n int) ([]byte, error) {
+	raw, err := decodeHash(hash)
+	if err != nil {
+		return nil, ErrBlockNotFound
+	}
+
+	p.mtx.RLock()
+	defer p.mtx.RUnlock()
+
+	e, ok := p.entries[hash]
+	if !ok {
+		return nil, ErrBlockNotFound
+	}
+
+	size := e.length - packRecordOverhead - int64(len(raw))
+	if int64(n) > size {
+		n = int(size)
+	}
+
+	b := make([]byte, n)
+	if _, err := p.segments[e.segment].f.ReadAt(b, e.offset+2+int64(len(raw))+4); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+func (p *packBackend) Has(hash string) (bool, error) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	e, ok := p.entries[hash]
+	if ok {
+		e.modTime = time.Now()
+		p.entries[hash] = e
+	}
+
+	return ok, nil
+}
+
+func (p *packBackend) Stat(hash string) (BlockInfo, error) {
+	p.mtx.RLock()
+	defer p.mtx.RUnlock()
+
+	e, ok := p.entries[hash]
+	if !ok {
+		return BlockInfo{}, ErrBlockNotFound
+	}
+
+	raw, err := decodeHash(hash)
+	if err != nil {
+		return BlockInfo{}, err
+	}
+
+	return BlockInfo{Size: e.length - packRecordOverhead - int64(len(raw)), ModTime: e.modTime}, nil
+}
+
+// Deletes a block by appending a delete record, and wakes the compactor to compact the sealed segments
+// that now mostly hold deleted blocks.
+func (p *packBackend) Delete(hash string) error {
+	raw, err := decodeHash(hash)
+	if err != nil {
+		return nil
+	}
+
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	_, ok := p.entries[hash]
+	if !ok {
+		return nil
+	}
+
+	if _, _, err := p.append(encodePackRecord(packRecordDelete, raw, nil)); err != nil {
+		return err
+	}
+
+	p.remove(hash)
+	p.requestCompaction()
+	return nil
+}
+
+// Wakes the compactor, unless it has already been woken.
+func (p *packBackend) requestCompaction() {
+	select {
+	case p.compactCh <- struct{}{}:
+	default:
+	}
+}
+
+// Compacts segments whenever woken, until the backend is closed.
+func (p *packBackend) runCompactor() {
+	defer close(p.stopped)
+
+	for {
+		select {
+		case <-p.compactCh:
+		case <-p.done:
+			return
+		}
+
+		if err := p.compactSegments(); err != nil {
+			log.WithField("dir", p.dir).Errorf("Failed to compact packfile segments, %v", err)
+		}
+	}
+}
+
+// Compacts the sealed segments that mostly hold deleted blocks or delete records, until there are none
+// or the backend is closed. Compaction only moves the live blocks, so the sealed segments shrink with
+// each compaction and this terminates.
+func (p *packBackend) compactSegments() error {
+	for {
+		p.mtx.RLock()
+		var victim *segment
+		for _, seg := range p.segments {
+			if seg != p.active && float64(seg.live) < compactionThreshold*float64(seg.size) {
+				victim = seg
+				break
+			}
+		}
+		p.mtx.RUnlock()
+
+		if victim == nil {
+			return nil
+		}
+
+		if done, err := p.compact(victim); err != nil || done {
+			return err
+		}
+	}
+}
+
+// Copies the live blocks of a sealed segment to the active segment, checkpoints the index, which then no
+// longer references the segment, and removes the segment. The lock is only held while each block is
+// copied. Reports whether the backend was closed before the segment was compacted.
+func (p *packBackend) compact(seg *segment) (bool, error) {
+	p.mtx.RLock()
+	var hashes []string
+	for hash, e := range p.entries {
+		if e.segment == seg.seq {
+			hashes = append(hashes, hash)
+		}
+	}
+
+	log.WithFields(log.Fields{
+		"segment": seg.f.Name(),
+		"live":    seg.live,
+		"size":    seg.size,
+		"blocks":  len(hashes),
+	}).Debug("Compacting packfile segment...")
+	p.mtx.RUnlock()
+
+	for _, hash := range hashes {
+		select {
+		case <-p.done:
+			return true, nil
+		default:
+		}
+
+		if err := p.move(hash, seg); err != nil {
+			return false, err
+		}
+	}
+
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	if err := p.writeIndex(); err != nil {
+		return false, err
+	}
+
+	return false, p.removeEmptySegments()
+}
+
+// Copies a block from a sealed segment to the active segment, unless it has been deleted since.
+func (p *packBackend) move(hash string, seg *segment) error {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	e, ok := p.entries[hash]
+	if !ok || e.segment != seg.seq {
+		return nil
+	}
+
+	b, err := p.read(e)
+	if err != nil {
+		return err
+	}
+
+	raw, err := decodeHash(hash)
+	if err != nil {
+		return err
+	}
+
+	rec := encodePackRecord(packRecordPut, raw, b)
+	dst, offset, err := p.append(rec)
+	if err != nil {
+		return err
+	}
+
+	seg.live -= e.length
+	dst.live += int64(len(rec))
+	p.entries[hash] = packEntry{segment: dst.seq, offset: offset, length: int64(len(rec)), modTime: e.modTime}
+	return nil
+}
+
+// Removes the sealed segments that hold no live blocks. Their delete records only cancel records that
+// precede the checkpoint, so they are no longer needed once it is written.
+func (p *packBackend) removeEmptySegments() error {
+	for seq, seg := range p.segments {
+		if seg == p.active || seg.live > 0 {
+			continue
+		}
+
+		delete(p.segments, seq)
+		seg.f.Close()
+		if err := os.Remove(seg.f.Name()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Lists the blocks held when the listing starts.
+func (p *packBackend) List(fn func(hash string, info BlockInfo) error) error {
+	p.mtx.RLock()
+	infos := make(map[string]BlockInfo, len(p.entries))
+	for hash, e := range p.entries {
+		raw, err := decodeHash(hash)
+		if err != nil {
+			p.mtx.RUnlock()
+			return err
+		}
+
+		infos[hash] = BlockInfo{Size: e.length - packRecordOverhead - int64(len(raw)), ModTime: e.modTime}
+	}
+	p.mtx.RUnlock()
+
+	for hash, info := range infos {
+		if err := fn(hash, info); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Closes the segment files.
+func (p *packBackend) closeSegments() {
+	for _, seg := range p.segments {
+		seg.f.Close()
+	}
+}
+
+// Stops the compactor, checkpoints the index and closes the segments.
+func (p *packBackend) Close() error {
+	close(p.done)
+	<-p.stopped
+
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	defer p.closeSegments()
+	return p.writeIndex()
+}
@@ -0,0 +1,206 @@
+package block
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Opens a pack backend with the specified segment size in a new temporary directory.
+func tempPackBackend(t *testing.T, segmentSize int64) (*packBackend, string, func()) {
+	dir, err := ioutil.TempDir("", "surfs")
+	assert.Nil(t, err)
+
+	backend, err := NewPackBackend(dir, segmentSize)
+	assert.Nil(t, err)
+
+	return backend.(*packBackend), dir, func() {
+		os.RemoveAll(dir)
+	}
+}
+
+// Returns the blocks with the specified indices, which are all the same size.
+func packTestBlocks(indices ...int) map[string][]byte {
+	blocks := make(map[string][]byte, len(indices))
+	for _, i := range indices {
+		b := []byte(fmt.Sprintf("block%04d", i))
+		blocks[blockHash(b)] = b
+	}
+
+	return blocks
+}
+
+// Checks that a backend holds exactly the specified blocks.
+func assertBlocks(t *testing.T, backend Backend, blocks map[string][]byte) {
+	var count int
+	assert.Nil(t, backend.List(func(hash string, info BlockInfo) error {
+		count++
+		assert.Contains(t, blocks, hash)
+		return nil
+	}))
+	assert.Equal(t, len(blocks), count)
+
+	for hash, expected := range blocks {
+		b, err := backend.Get(hash)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, b)
+	}
+}
+
+// Returns the number of segment files in a directory.
+func countSegments(t *testing.T, dir string) int {
+	names, err := filepath.Glob(filepath.Join(dir, "*"+segmentSuffix))
+	assert.Nil(t, err)
+	return len(names)
+}
+
+func TestPackBackend(t *testing.T) {
+	backend, _, cleanup := tempPackBackend(t, 0)
+	defer cleanup()
+
+	testBackend(t, backend)
+}
+
+func TestPackBackend_Reopen(t *testing.T) {
+	backend, dir, cleanup := tempPackBackend(t, 256)
+	defer cleanup()
+
+	blocks := packTestBlocks(0, 1, 2, 3, 4, 5, 6, 7, 8, 9)
+	for hash, b := range blocks {
+		assert.Nil(t, backend.Put(hash, b))
+	}
+
+	deleted := blockHash([]byte("block0003"))
+	assert.Nil(t, backend.Delete(deleted))
+	delete(blocks, deleted)
+
+	assert.True(t, countSegments(t, dir) > 1)
+	assert.Nil(t, backend.Close())
+
+	reopened, err := NewPackBackend(dir, 256)
+	assert.Nil(t, err)
+
+	assertBlocks(t, reopened, blocks)
+	assert.Nil(t, reopened.Close())
+}
+
+func TestPackBackend_Recovery(t *testing.T) {
+	backend, dir, cleanup := tempPackBackend(t, 256)
+	defer cleanup()
+
+	blocks := packTestBlocks(0, 1, 2, 3, 4, 5, 6, 7, 8, 9)
+	for hash, b := range blocks {
+		assert.Nil(t, backend.Put(hash, b))
+	}
+
+	deleted := blockHash([]byte("block0005"))
+	assert.Nil(t, backend.Delete(deleted))
+	delete(blocks, deleted)
+
+	// Simulate a crash in the middle of appending a record, without checkpointing the index. The blocks
+	// appended after the last checkpoint are recovered by replaying the active segment.
+	active := backend.active
+	rec := encodePackRecord(packRecordPut, []byte("partial record hash"), []byte("partial"))
+	_, err := active.f.WriteAt(rec[:len(rec)-3], active.size)
+	assert.Nil(t, err)
+
+	size := active.size
+	backend.closeSegments()
+
+	recovered, err := NewPackBackend(dir, 256)
+	assert.Nil(t, err)
+
+	assertBlocks(t, recovered, blocks)
+
+	// The partial record is truncated, and new records are appended in its place.
+	info, err := os.Stat(active.f.Name())
+	assert.Nil(t, err)
+	assert.Equal(t, size, info.Size())
+
+	more := packTestBlocks(10)
+	for hash, b := range more {
+		assert.Nil(t, recovered.Put(hash, b))
+		blocks[hash] = b
+	}
+
+	assert.Nil(t, recovered.Close())
+
+	reopened, err := NewPackBackend(dir, 256)
+	assert.Nil(t, err)
+
+	assertBlocks(t, reopened, blocks)
+	reopened.Close()
+
+	// A corrupt index is rebuilt from the segments.
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, packIndexFilename), []byte("garbage"), 0644))
+
+	rebuilt, err := NewPackBackend(dir, 256)
+	assert.Nil(t, err)
+
+	assertBlocks(t, rebuilt, blocks)
+	rebuilt.Close()
+}
+
+func TestPackBackend_Compaction(t *testing.T) {
+	backend, dir, cleanup := tempPackBackend(t, 256)
+	defer cleanup()
+
+	var indices []int
+	for i := 0; i < 40; i++ {
+		indices = append(indices, i)
+	}
+
+	blocks := packTestBlocks(indices...)
+	for _, i := range indices {
+		b := []byte(fmt.Sprintf("block%04d", i))
+		assert.Nil(t, backend.Put(blockHash(b), b))
+	}
+
+	before := countSegments(t, dir)
+	assert.True(t, before >= 5)
+
+	// Deleting most blocks frees most of the sealed segments, which are compacted away.
+	for _, i := range indices {
+		if i%8 == 0 {
+			continue
+		}
+
+		hash := blockHash([]byte(fmt.Sprintf("block%04d", i)))
+		assert.Nil(t, backend.Delete(hash))
+		delete(blocks, hash)
+	}
+
+	// Compaction runs in the background.
+	deadline := time.Now().Add(5 * time.Second)
+	for countSegments(t, dir) >= before && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	assert.True(t, countSegments(t, dir) < before)
+	assertBlocks(t, backend, blocks)
+
+	// Once it has finished, no sealed segment mostly holds deleted blocks.
+	assert.Nil(t, backend.compactSegments())
+
+	backend.mtx.RLock()
+	for _, seg := range backend.segments {
+		if seg != backend.active {
+			assert.True(t, float64(seg.live) >= compactionThreshold*float64(seg.size))
+		}
+	}
+	backend.mtx.RUnlock()
+
+	// Deleted blocks stay deleted after the compacted segments are gone.
+	assert.Nil(t, backend.Close())
+
+	reopened, err := NewPackBackend(dir, 256)
+	assert.Nil(t, err)
+
+	assertBlocks(t, reopened, blocks)
+	reopened.Close()
+}
@@ -0,0 +1,473 @@
+package block
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Region used to sign requests if none is configured, which is the one S3-compatible services such as
+// MinIO expect by default.
+const defaultS3Region = "us-east-1"
+
+// Timeout of each request to an S3-compatible service.
+const s3RequestTimeout = 30 * time.Second
+
+// Checking for a block only updates its last modified time if it is at least this old, so that most
+// checks take a HEAD request rather than a copy.
+const s3TouchInterval = 10 * time.Minute
+
+// The largest difference between the clock of the service, which sets last modified times, and the
+// local clock. S3 rejects requests signed more than 15 minutes away from its own clock.
+const s3MaxClockSkew = 15 * time.Minute
+
+// Layout of the x-amz-date header, and of the date in the credential scope of a signature.
+const (
+	amzDateLayout   = "20060102T150405Z"
+	amzShortLayout  = "20060102"
+	s3SigningScheme = "AWS4-HMAC-SHA256"
+)
+
+// S3Config configures a backend that keeps blocks in a bucket of an S3-compatible object store.
+type S3Config struct {
+	// The URL of the service, such as https://s3.us-east-1.amazonaws.com or http://localhost:9000.
+	// Buckets are addressed by path, as in <endpoint>/<bucket>/<key>.
+	Endpoint string
+
+	// The region requests are signed for. Defaults to us-east-1.
+	Region string
+
+	Bucket string
+
+	// Prepended to the keys of blocks, so that a bucket can be shared, e.g. "surfs/".
+	Prefix string
+
+	// The credentials requests are signed with. If unset, they are read from the AWS_ACCESS_KEY_ID and
+	// AWS_SECRET_ACCESS_KEY environment variables.
+	AccessKey string
+	SecretKey string
+}
+
+// A backend that keeps each block in an object named after its hash. Objects' last modified times record
+// when the blocks were last used, so checking for a block copies the object onto itself to update it,
+// unless it was updated recently. Last modified times therefore lag behind the last use of a block by up
+// to the touch interval, and come from the service's clock, which garbage collection allows for.
+type s3Backend struct {
+	conf     S3Config
+	endpoint *url.URL
+	client   *http.Client
+
+	// Returns the current time, which requests are signed with.
+	now func() time.Time
+}
+
+// Creates a backend that keeps blocks in the configured bucket, which must already exist.
+func NewS3Backend(conf S3Config) (Backend, error) {
+	if conf.Bucket == "" {
+		return nil, errors.New("must specify an S3 bucket")
+	}
+
+	endpoint, err := url.Parse(conf.Endpoint)
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid S3 endpoint %q", conf.Endpoint)
+	}
+
+	if conf.Region == "" {
+		conf.Region = defaultS3Region
+	}
+
+	if conf.AccessKey == "" && conf.SecretKey == "" {
+		conf.AccessKey = os.Getenv("AWS_ACCESS_KEY_ID")
+		conf.SecretKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+	}
+
+	return &s3Backend{
+		conf:     conf,
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: s3RequestTimeout},
+		now:      time.Now,
+	}, nil
+}
+
+// An error response from an S3-compatible service.
+type s3Error struct {
+	StatusCode int    `xml:"-"`
+	Code       string `xml:"Code"`
+	Message    string `xml:"Message"`
+}
+
+func (e *s3Error) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("s3: %s", http.StatusText(e.StatusCode))
+	}
+
+	return fmt.Sprintf("s3: %s: %s", e.Code, e.Message)
+}
+
+// Reports whether an error returned by the service means that an object does not exist.
+func isS3NotFound(err error) bool {
+	e, ok := err.(*s3Error)
+	return ok && (e.StatusCode == http.StatusNotFound || e.Code == "NoSuchKey")
+}
+
+// Returns the key of the object holding a block.
+func (s *s3Backend) key(hash string) (string, error) {
+	name, err := blockName(hash)
+	if err != nil {
+		return "", err
+	}
+
+	return s.conf.Prefix + name, nil
+}
+
+// Sends a signed request for an object, or for the bucket if the key is empty, returning the response
+// body. Responses other than 2xx are returned as an *s3Error.
+func (s *s3Backend) do(method string, key string, query url.Values, header http.Header, body []byte) (*http.Response, []byte, error) {
+	uri := "/" + s.conf.Bucket
+	if key != "" {
+		uri += "/" + key
+	}
+
+	u := *s.endpoint
+	u.Path = strings.TrimSuffix(u.Path, "/") + uri
+	u.RawPath = s3Escape(u.Path, false)
+	u.RawQuery = canonicalQuery(query)
+
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for name, values := range header {
+		req.Header[name] = values
+	}
+
+	sum := sha256.Sum256(body)
+	signS3Request(req, s.conf, hex.EncodeToString(sum[:]), s.now())
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer res.Body.Close()
+
+	b, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if res.StatusCode/100 != 2 {
+		e := &s3Error{StatusCode: res.StatusCode}
+		xml.Unmarshal(b, e)
+		return nil, nil, e
+	}
+
+	return res, b, nil
+}
+
+func (s *s3Backend) Put(hash string, b []byte) error {
+	key, err := s.key(hash)
+	if err != nil {
+		return err
+	}
+
+	// Objects are replaced atomically, and replacing a block with itself updates its last modified
+	// time, so there is no need to check whether the block exists first.
+	_, _, err = s.do(http.MethodPut, key, nil, nil, b)
+	return err
+}
+
+func (s *s3Backend) Get(hash string) ([]byte, error) {
+	key, err := s.key(hash)
+	if err != nil {
+		return nil, ErrBlockNotFound
+	}
+
+	_, b, err := s.do(http.MethodGet, key, nil, nil, nil)
+	if isS3NotFound(err) {
+		return nil, ErrBlockNotFound
+	}
+
+	return b, err
+}
+
+func (s *s3Backend) GetPrefix(hash string, n int) ([]byte, error) {
+	key, err := s.key(hash)
+	if err != nil {
+		return nil, ErrBlockNotFound
+	}
+
+	if n <= 0 {
+		_, err := s.Stat(hash)
+		return nil, err
+	}
+
+	header := http.Header{}
+	header.Set("Range", fmt.Sprintf("bytes=0-%d", n-1))
+
+	_, b, err := s.do(http.MethodGet, key, nil, header, nil)
+	if isS3NotFound(err) {
+		return nil, ErrBlockNotFound
+	} else if e, ok := err.(*s3Error); ok && e.StatusCode == http.StatusRequestedRangeNotSatisfiable {
+		// The range starts beyond the end of an empty block.
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	// Services that ignore the range return the whole object.
+	if len(b) > n {
+		b = b[:n]
+	}
+
+	return b, nil
+}
+
+func (s *s3Backend) Has(hash string) (bool, error) {
+	key, err := s.key(hash)
+	if err != nil {
+		return false, nil
+	}
+
+	info, err := s.Stat(hash)
+	if err == ErrBlockNotFound {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	if s.now().Sub(info.ModTime) < s3TouchInterval {
+		return true, nil
+	}
+
+	// Copying an object onto itself requires its metadata to change.
+	header := http.Header{}
+	header.Set("X-Amz-Copy-Source", s3Escape("/"+s.conf.Bucket+"/"+key, false))
+	header.Set("X-Amz-Metadata-Directive", "REPLACE")
+	header.Set("X-Amz-Meta-Used", strconv.FormatInt(s.now().UnixNano(), 10))
+
+	_, b, err := s.do(http.MethodPut, key, nil, header, nil)
+	if isS3NotFound(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	// A copy can fail after the service has started responding, in which case the error is in the
+	// body of a successful response.
+	if e := (&s3Error{}); xml.Unmarshal(b, e) == nil && e.Code != "" {
+		if e.Code == "NoSuchKey" {
+			return false, nil
+		}
+
+		return false, e
+	}
+
+	return true, nil
+}
+
+func (s *s3Backend) Stat(hash string) (BlockInfo, error) {
+	key, err := s.key(hash)
+	if err != nil {
+		return BlockInfo{}, ErrBlockNotFound
+	}
+
+	res, _, err := s.do(http.MethodHead, key, nil, nil, nil)
+	if isS3NotFound(err) {
+		return BlockInfo{}, ErrBlockNotFound
+	} else if err != nil {
+		return BlockInfo{}, err
+	}
+
+	modTime, err := http.ParseTime(res.Header.Get("Last-Modified"))
+	if err != nil {
+		return BlockInfo{}, fmt.Errorf("s3: invalid Last-Modified header, %v", err)
+	}
+
+	return BlockInfo{Size: res.ContentLength, ModTime: modTime}, nil
+}
+
+func (s *s3Backend) Delete(hash string) error {
+	key, err := s.key(hash)
+	if err != nil {
+		return nil
+	}
+
+	_, _, err = s.do(http.MethodDelete, key, nil, nil, nil)
+	if isS3NotFound(err) {
+		return nil
+	}
+
+	return err
+}
+
+// A page of the response to a ListObjectsV2 request.
+type s3ListResult struct {
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+	Contents              []struct {
+		Key          string    `xml:"Key"`
+		LastModified time.Time `xml:"LastModified"`
+		Size         int64     `xml:"Size"`
+	} `xml:"Contents"`
+}
+
+// Lists the objects under the prefix a page at a time.
+func (s *s3Backend) List(fn func(hash string, info BlockInfo) error) error {
+	query := url.Values{}
+	query.Set("list-type", "2")
+	query.Set("prefix", s.conf.Prefix)
+
+	for {
+		_, b, err := s.do(http.MethodGet, "", query, nil, nil)
+		if err != nil {
+			return err
+		}
+
+		var page s3ListResult
+		if err := xml.Unmarshal(b, &page); err != nil {
+			return err
+		}
+
+		for _, obj := range page.Contents {
+			hash, ok := hashForName(strings.TrimPrefix(obj.Key, s.conf.Prefix))
+			if !ok {
+				continue
+			}
+
+			if err := fn(hash, BlockInfo{Size: obj.Size, ModTime: obj.LastModified}); err != nil {
+				return err
+			}
+		}
+
+		if !page.IsTruncated {
+			return nil
+		}
+
+		query.Set("continuation-token", page.NextContinuationToken)
+	}
+}
+
+func (s *s3Backend) modTimeLag() time.Duration {
+	return s3TouchInterval + s3MaxClockSkew
+}
+
+func (s *s3Backend) Close() error {
+	return nil
+}
+
+// Signs a request with AWS Signature Version 4. Every X-Amz-* header is signed along with the host.
+func signS3Request(req *http.Request, conf S3Config, payloadHash string, now time.Time) {
+	now = now.UTC()
+	req.Header.Set("X-Amz-Date", now.Format(amzDateLayout))
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	signed := []string{"host"}
+	for name := range req.Header {
+		if name = strings.ToLower(name); strings.HasPrefix(name, "x-amz-") {
+			signed = append(signed, name)
+		}
+	}
+	sort.Strings(signed)
+
+	scope := strings.Join([]string{now.Format(amzShortLayout), conf.Region, "s3", "aws4_request"}, "/")
+	canonical := canonicalS3Request(req, signed, payloadHash)
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3SigningScheme, conf.AccessKey, scope, strings.Join(signed, ";"), s3Signature(conf, now, canonical)))
+}
+
+// Returns the canonical form of a request that is signed, with the specified signed headers, which
+// must be lowercase and sorted.
+func canonicalS3Request(req *http.Request, signed []string, payloadHash string) string {
+	var headers strings.Builder
+	for _, name := range signed {
+		value := req.Header.Get(name)
+		if name == "host" {
+			value = req.Host
+			if value == "" {
+				value = req.URL.Host
+			}
+		}
+
+		headers.WriteString(name + ":" + strings.TrimSpace(value) + "\n")
+	}
+
+	return strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		canonicalQuery(req.URL.Query()),
+		headers.String(),
+		strings.Join(signed, ";"),
+		payloadHash,
+	}, "\n")
+}
+
+// Returns the signature of a canonical request made at the specified time.
+func s3Signature(conf S3Config, now time.Time, canonical string) string {
+	date := now.UTC().Format(amzShortLayout)
+	scope := strings.Join([]string{date, conf.Region, "s3", "aws4_request"}, "/")
+
+	sum := sha256.Sum256([]byte(canonical))
+	toSign := strings.Join([]string{s3SigningScheme, now.UTC().Format(amzDateLayout), scope, hex.EncodeToString(sum[:])}, "\n")
+
+	key := []byte("AWS4" + conf.SecretKey)
+	for _, part := range []string{date, conf.Region, "s3", "aws4_request", toSign} {
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(part))
+		key = mac.Sum(nil)
+	}
+
+	return hex.EncodeToString(key)
+}
+
+// Returns the query string with its parameters sorted and escaped as required by signatures.
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var params []string
+	for _, k := range keys {
+		values := append([]string(nil), query[k]...)
+		sort.Strings(values)
+
+		for _, v := range values {
+			params = append(params, s3Escape(k, true)+"="+s3Escape(v, true))
+		}
+	}
+
+	return strings.Join(params, "&")
+}
+
+// Escapes every byte of a string other than the unreserved characters, and optionally slashes.
+func s3Escape(s string, escapeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '.', c == '_', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !escapeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+
+	return b.String()
+}
@@ -72,7 +72,11 @@ func (m *StoreBlockRequest) GetHash() string {
 }
 
 type StoreBlockResponse struct {
-	Success              bool     `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Success bool `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	// The hash computed by the block store from the uploaded block. If it does not match the hash
+	// in the request, the block is rejected with an INVALID_ARGUMENT status carrying this response
+	// as a detail.
+	Hash                 string   `protobuf:"bytes,2,opt,name=hash,proto3" json:"hash,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -110,6 +114,13 @@ func (m *StoreBlockResponse) GetSuccess() bool {
 	return false
 }
 
+func (m *StoreBlockResponse) GetHash() string {
+	if m != nil {
+		return m.Hash
+	}
+	return ""
+}
+
 type HasBlockRequest struct {
 	Hash                 string   `protobuf:"bytes,1,opt,name=hash,proto3" json:"hash,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
@@ -188,6 +199,85 @@ func (m *HasBlockResponse) GetSuccess() bool {
 	return false
 }
 
+type HasBlocksRequest struct {
+	Hashes               []string `protobuf:"bytes,1,rep,name=hashes,proto3" json:"hashes,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *HasBlocksRequest) Reset()         { *m = HasBlocksRequest{} }
+func (m *HasBlocksRequest) String() string { return proto.CompactTextString(m) }
+func (*HasBlocksRequest) ProtoMessage()    {}
+func (*HasBlocksRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a0b84a42fa06f626, []int{4}
+}
+
+func (m *HasBlocksRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_HasBlocksRequest.Unmarshal(m, b)
+}
+func (m *HasBlocksRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_HasBlocksRequest.Marshal(b, m, deterministic)
+}
+func (m *HasBlocksRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_HasBlocksRequest.Merge(m, src)
+}
+func (m *HasBlocksRequest) XXX_Size() int {
+	return xxx_messageInfo_HasBlocksRequest.Size(m)
+}
+func (m *HasBlocksRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_HasBlocksRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_HasBlocksRequest proto.InternalMessageInfo
+
+func (m *HasBlocksRequest) GetHashes() []string {
+	if m != nil {
+		return m.Hashes
+	}
+	return nil
+}
+
+type HasBlocksResponse struct {
+	// The requested hashes whose blocks are missing, in the order they were requested.
+	Missing              []string `protobuf:"bytes,1,rep,name=missing,proto3" json:"missing,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *HasBlocksResponse) Reset()         { *m = HasBlocksResponse{} }
+func (m *HasBlocksResponse) String() string { return proto.CompactTextString(m) }
+func (*HasBlocksResponse) ProtoMessage()    {}
+func (*HasBlocksResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a0b84a42fa06f626, []int{5}
+}
+
+func (m *HasBlocksResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_HasBlocksResponse.Unmarshal(m, b)
+}
+func (m *HasBlocksResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_HasBlocksResponse.Marshal(b, m, deterministic)
+}
+func (m *HasBlocksResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_HasBlocksResponse.Merge(m, src)
+}
+func (m *HasBlocksResponse) XXX_Size() int {
+	return xxx_messageInfo_HasBlocksResponse.Size(m)
+}
+func (m *HasBlocksResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_HasBlocksResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_HasBlocksResponse proto.InternalMessageInfo
+
+func (m *HasBlocksResponse) GetMissing() []string {
+	if m != nil {
+		return m.Missing
+	}
+	return nil
+}
+
 type GetBlockRequest struct {
 	Hash                 string   `protobuf:"bytes,1,opt,name=hash,proto3" json:"hash,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
@@ -199,7 +289,7 @@ func (m *GetBlockRequest) Reset()         { *m = GetBlockRequest{} }
 func (m *GetBlockRequest) String() string { return proto.CompactTextString(m) }
 func (*GetBlockRequest) ProtoMessage()    {}
 func (*GetBlockRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_a0b84a42fa06f626, []int{4}
+	return fileDescriptor_a0b84a42fa06f626, []int{6}
 }
 
 func (m *GetBlockRequest) XXX_Unmarshal(b []byte) error {
@@ -239,7 +329,7 @@ func (m *GetBlockResponse) Reset()         { *m = GetBlockResponse{} }
 func (m *GetBlockResponse) String() string { return proto.CompactTextString(m) }
 func (*GetBlockResponse) ProtoMessage()    {}
 func (*GetBlockResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_a0b84a42fa06f626, []int{5}
+	return fileDescriptor_a0b84a42fa06f626, []int{7}
 }
 
 func (m *GetBlockResponse) XXX_Unmarshal(b []byte) error {
@@ -274,34 +364,481 @@ func (m *GetBlockResponse) GetBlock() []byte {
 	return nil
 }
 
+type PutBlocksResponse struct {
+	// The number of blocks stored.
+	Stored               uint64   `protobuf:"varint,1,opt,name=stored,proto3" json:"stored,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *PutBlocksResponse) Reset()         { *m = PutBlocksResponse{} }
+func (m *PutBlocksResponse) String() string { return proto.CompactTextString(m) }
+func (*PutBlocksResponse) ProtoMessage()    {}
+func (*PutBlocksResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a0b84a42fa06f626, []int{8}
+}
+
+func (m *PutBlocksResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PutBlocksResponse.Unmarshal(m, b)
+}
+func (m *PutBlocksResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PutBlocksResponse.Marshal(b, m, deterministic)
+}
+func (m *PutBlocksResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PutBlocksResponse.Merge(m, src)
+}
+func (m *PutBlocksResponse) XXX_Size() int {
+	return xxx_messageInfo_PutBlocksResponse.Size(m)
+}
+func (m *PutBlocksResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_PutBlocksResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PutBlocksResponse proto.InternalMessageInfo
+
+func (m *PutBlocksResponse) GetStored() uint64 {
+	if m != nil {
+		return m.Stored
+	}
+	return 0
+}
+
+type GetBlocksRequest struct {
+	Hashes               []string `protobuf:"bytes,1,rep,name=hashes,proto3" json:"hashes,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetBlocksRequest) Reset()         { *m = GetBlocksRequest{} }
+func (m *GetBlocksRequest) String() string { return proto.CompactTextString(m) }
+func (*GetBlocksRequest) ProtoMessage()    {}
+func (*GetBlocksRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a0b84a42fa06f626, []int{9}
+}
+
+func (m *GetBlocksRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetBlocksRequest.Unmarshal(m, b)
+}
+func (m *GetBlocksRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetBlocksRequest.Marshal(b, m, deterministic)
+}
+func (m *GetBlocksRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetBlocksRequest.Merge(m, src)
+}
+func (m *GetBlocksRequest) XXX_Size() int {
+	return xxx_messageInfo_GetBlocksRequest.Size(m)
+}
+func (m *GetBlocksRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetBlocksRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetBlocksRequest proto.InternalMessageInfo
+
+func (m *GetBlocksRequest) GetHashes() []string {
+	if m != nil {
+		return m.Hashes
+	}
+	return nil
+}
+
+type ListBlocksRequest struct {
+	// Only blocks last stored or checked for before this time, in nanoseconds since the Unix epoch,
+	// are listed.
+	OlderThan            int64    `protobuf:"varint,1,opt,name=olderThan,proto3" json:"olderThan,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListBlocksRequest) Reset()         { *m = ListBlocksRequest{} }
+func (m *ListBlocksRequest) String() string { return proto.CompactTextString(m) }
+func (*ListBlocksRequest) ProtoMessage()    {}
+func (*ListBlocksRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a0b84a42fa06f626, []int{10}
+}
+
+func (m *ListBlocksRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListBlocksRequest.Unmarshal(m, b)
+}
+func (m *ListBlocksRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListBlocksRequest.Marshal(b, m, deterministic)
+}
+func (m *ListBlocksRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListBlocksRequest.Merge(m, src)
+}
+func (m *ListBlocksRequest) XXX_Size() int {
+	return xxx_messageInfo_ListBlocksRequest.Size(m)
+}
+func (m *ListBlocksRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListBlocksRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListBlocksRequest proto.InternalMessageInfo
+
+func (m *ListBlocksRequest) GetOlderThan() int64 {
+	if m != nil {
+		return m.OlderThan
+	}
+	return 0
+}
+
+type ListBlocksResponse struct {
+	Hashes               []string `protobuf:"bytes,1,rep,name=hashes,proto3" json:"hashes,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListBlocksResponse) Reset()         { *m = ListBlocksResponse{} }
+func (m *ListBlocksResponse) String() string { return proto.CompactTextString(m) }
+func (*ListBlocksResponse) ProtoMessage()    {}
+func (*ListBlocksResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a0b84a42fa06f626, []int{11}
+}
+
+func (m *ListBlocksResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListBlocksResponse.Unmarshal(m, b)
+}
+func (m *ListBlocksResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListBlocksResponse.Marshal(b, m, deterministic)
+}
+func (m *ListBlocksResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListBlocksResponse.Merge(m, src)
+}
+func (m *ListBlocksResponse) XXX_Size() int {
+	return xxx_messageInfo_ListBlocksResponse.Size(m)
+}
+func (m *ListBlocksResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListBlocksResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListBlocksResponse proto.InternalMessageInfo
+
+func (m *ListBlocksResponse) GetHashes() []string {
+	if m != nil {
+		return m.Hashes
+	}
+	return nil
+}
+
+type DeleteBlocksRequest struct {
+	Hashes []string `protobuf:"bytes,1,rep,name=hashes,proto3" json:"hashes,omitempty"`
+	// Blocks stored or checked for at or after this time, in nanoseconds since the Unix epoch, are
+	// not deleted.
+	OlderThan            int64    `protobuf:"varint,2,opt,name=olderThan,proto3" json:"olderThan,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DeleteBlocksRequest) Reset()         { *m = DeleteBlocksRequest{} }
+func (m *DeleteBlocksRequest) String() string { return proto.CompactTextString(m) }
+func (*DeleteBlocksRequest) ProtoMessage()    {}
+func (*DeleteBlocksRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a0b84a42fa06f626, []int{12}
+}
+
+func (m *DeleteBlocksRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DeleteBlocksRequest.Unmarshal(m, b)
+}
+func (m *DeleteBlocksRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DeleteBlocksRequest.Marshal(b, m, deterministic)
+}
+func (m *DeleteBlocksRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DeleteBlocksRequest.Merge(m, src)
+}
+func (m *DeleteBlocksRequest) XXX_Size() int {
+	return xxx_messageInfo_DeleteBlocksRequest.Size(m)
+}
+func (m *DeleteBlocksRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_DeleteBlocksRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DeleteBlocksRequest proto.InternalMessageInfo
+
+func (m *DeleteBlocksRequest) GetHashes() []string {
+	if m != nil {
+		return m.Hashes
+	}
+	return nil
+}
+
+func (m *DeleteBlocksRequest) GetOlderThan() int64 {
+	if m != nil {
+		return m.OlderThan
+	}
+	return 0
+}
+
+type DeleteBlocksResponse struct {
+	// The hashes of the blocks that were deleted.
+	Deleted              []string `protobuf:"bytes,1,rep,name=deleted,proto3" json:"deleted,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DeleteBlocksResponse) Reset()         { *m = DeleteBlocksResponse{} }
+func (m *DeleteBlocksResponse) String() string { return proto.CompactTextString(m) }
+func (*DeleteBlocksResponse) ProtoMessage()    {}
+func (*DeleteBlocksResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a0b84a42fa06f626, []int{13}
+}
+
+func (m *DeleteBlocksResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DeleteBlocksResponse.Unmarshal(m, b)
+}
+func (m *DeleteBlocksResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DeleteBlocksResponse.Marshal(b, m, deterministic)
+}
+func (m *DeleteBlocksResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DeleteBlocksResponse.Merge(m, src)
+}
+func (m *DeleteBlocksResponse) XXX_Size() int {
+	return xxx_messageInfo_DeleteBlocksResponse.Size(m)
+}
+func (m *DeleteBlocksResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_DeleteBlocksResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DeleteBlocksResponse proto.InternalMessageInfo
+
+func (m *DeleteBlocksResponse) GetDeleted() []string {
+	if m != nil {
+		return m.Deleted
+	}
+	return nil
+}
+
+type GetStatsRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetStatsRequest) Reset()         { *m = GetStatsRequest{} }
+func (m *GetStatsRequest) String() string { return proto.CompactTextString(m) }
+func (*GetStatsRequest) ProtoMessage()    {}
+func (*GetStatsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a0b84a42fa06f626, []int{14}
+}
+
+func (m *GetStatsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetStatsRequest.Unmarshal(m, b)
+}
+func (m *GetStatsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetStatsRequest.Marshal(b, m, deterministic)
+}
+func (m *GetStatsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetStatsRequest.Merge(m, src)
+}
+func (m *GetStatsRequest) XXX_Size() int {
+	return xxx_messageInfo_GetStatsRequest.Size(m)
+}
+func (m *GetStatsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetStatsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetStatsRequest proto.InternalMessageInfo
+
+type GetStatsResponse struct {
+	Blocks uint64 `protobuf:"varint,1,opt,name=blocks,proto3" json:"blocks,omitempty"`
+	// The number of blocks stored compressed.
+	CompressedBlocks uint64 `protobuf:"varint,2,opt,name=compressedBlocks,proto3" json:"compressedBlocks,omitempty"`
+	// The total size of the blocks, and the space they take up in the backend.
+	LogicalBytes         uint64   `protobuf:"varint,3,opt,name=logicalBytes,proto3" json:"logicalBytes,omitempty"`
+	PhysicalBytes        uint64   `protobuf:"varint,4,opt,name=physicalBytes,proto3" json:"physicalBytes,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetStatsResponse) Reset()         { *m = GetStatsResponse{} }
+func (m *GetStatsResponse) String() string { return proto.CompactTextString(m) }
+func (*GetStatsResponse) ProtoMessage()    {}
+func (*GetStatsResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a0b84a42fa06f626, []int{15}
+}
+
+func (m *GetStatsResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetStatsResponse.Unmarshal(m, b)
+}
+func (m *GetStatsResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetStatsResponse.Marshal(b, m, deterministic)
+}
+func (m *GetStatsResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetStatsResponse.Merge(m, src)
+}
+func (m *GetStatsResponse) XXX_Size() int {
+	return xxx_messageInfo_GetStatsResponse.Size(m)
+}
+func (m *GetStatsResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetStatsResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetStatsResponse proto.InternalMessageInfo
+
+func (m *GetStatsResponse) GetBlocks() uint64 {
+	if m != nil {
+		return m.Blocks
+	}
+	return 0
+}
+
+func (m *GetStatsResponse) GetCompressedBlocks() uint64 {
+	if m != nil {
+		return m.CompressedBlocks
+	}
+	return 0
+}
+
+func (m *GetStatsResponse) GetLogicalBytes() uint64 {
+	if m != nil {
+		return m.LogicalBytes
+	}
+	return 0
+}
+
+func (m *GetStatsResponse) GetPhysicalBytes() uint64 {
+	if m != nil {
+		return m.PhysicalBytes
+	}
+	return 0
+}
+
+type GetLimitsRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetLimitsRequest) Reset()         { *m = GetLimitsRequest{} }
+func (m *GetLimitsRequest) String() string { return proto.CompactTextString(m) }
+func (*GetLimitsRequest) ProtoMessage()    {}
+func (*GetLimitsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a0b84a42fa06f626, []int{16}
+}
+
+func (m *GetLimitsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetLimitsRequest.Unmarshal(m, b)
+}
+func (m *GetLimitsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetLimitsRequest.Marshal(b, m, deterministic)
+}
+func (m *GetLimitsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetLimitsRequest.Merge(m, src)
+}
+func (m *GetLimitsRequest) XXX_Size() int {
+	return xxx_messageInfo_GetLimitsRequest.Size(m)
+}
+func (m *GetLimitsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetLimitsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetLimitsRequest proto.InternalMessageInfo
+
+type GetLimitsResponse struct {
+	// The size of the largest block the store accepts, in bytes.
+	MaxBlockSize         uint64   `protobuf:"varint,1,opt,name=maxBlockSize,proto3" json:"maxBlockSize,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetLimitsResponse) Reset()         { *m = GetLimitsResponse{} }
+func (m *GetLimitsResponse) String() string { return proto.CompactTextString(m) }
+func (*GetLimitsResponse) ProtoMessage()    {}
+func (*GetLimitsResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a0b84a42fa06f626, []int{17}
+}
+
+func (m *GetLimitsResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetLimitsResponse.Unmarshal(m, b)
+}
+func (m *GetLimitsResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetLimitsResponse.Marshal(b, m, deterministic)
+}
+func (m *GetLimitsResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetLimitsResponse.Merge(m, src)
+}
+func (m *GetLimitsResponse) XXX_Size() int {
+	return xxx_messageInfo_GetLimitsResponse.Size(m)
+}
+func (m *GetLimitsResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetLimitsResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetLimitsResponse proto.InternalMessageInfo
+
+func (m *GetLimitsResponse) GetMaxBlockSize() uint64 {
+	if m != nil {
+		return m.MaxBlockSize
+	}
+	return 0
+}
+
 func init() {
 	proto.RegisterType((*StoreBlockRequest)(nil), "block.StoreBlockRequest")
 	proto.RegisterType((*StoreBlockResponse)(nil), "block.StoreBlockResponse")
 	proto.RegisterType((*HasBlockRequest)(nil), "block.HasBlockRequest")
 	proto.RegisterType((*HasBlockResponse)(nil), "block.HasBlockResponse")
+	proto.RegisterType((*HasBlocksRequest)(nil), "block.HasBlocksRequest")
+	proto.RegisterType((*HasBlocksResponse)(nil), "block.HasBlocksResponse")
 	proto.RegisterType((*GetBlockRequest)(nil), "block.GetBlockRequest")
 	proto.RegisterType((*GetBlockResponse)(nil), "block.GetBlockResponse")
+	proto.RegisterType((*PutBlocksResponse)(nil), "block.PutBlocksResponse")
+	proto.RegisterType((*GetBlocksRequest)(nil), "block.GetBlocksRequest")
+	proto.RegisterType((*ListBlocksRequest)(nil), "block.ListBlocksRequest")
+	proto.RegisterType((*ListBlocksResponse)(nil), "block.ListBlocksResponse")
+	proto.RegisterType((*DeleteBlocksRequest)(nil), "block.DeleteBlocksRequest")
+	proto.RegisterType((*DeleteBlocksResponse)(nil), "block.DeleteBlocksResponse")
+	proto.RegisterType((*GetStatsRequest)(nil), "block.GetStatsRequest")
+	proto.RegisterType((*GetStatsResponse)(nil), "block.GetStatsResponse")
+	proto.RegisterType((*GetLimitsRequest)(nil), "block.GetLimitsRequest")
+	proto.RegisterType((*GetLimitsResponse)(nil), "block.GetLimitsResponse")
 }
 
 func init() { proto.RegisterFile("service.proto", fileDescriptor_a0b84a42fa06f626) }
 
 var fileDescriptor_a0b84a42fa06f626 = []byte{
-	// 228 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe2, 0xe2, 0x2d, 0x4e, 0x2d, 0x2a,
-	0xcb, 0x4c, 0x4e, 0xd5, 0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x17, 0x62, 0x4d, 0xca, 0xc9, 0x4f, 0xce,
-	0x56, 0xb2, 0xe5, 0x12, 0x0c, 0x2e, 0xc9, 0x2f, 0x4a, 0x75, 0x02, 0xf1, 0x82, 0x52, 0x0b, 0x4b,
-	0x53, 0x8b, 0x4b, 0x84, 0x44, 0xb8, 0x20, 0xb2, 0x12, 0x8c, 0x0a, 0x8c, 0x1a, 0x3c, 0x41, 0x10,
-	0x8e, 0x90, 0x10, 0x17, 0x4b, 0x46, 0x62, 0x71, 0x86, 0x04, 0x93, 0x02, 0xa3, 0x06, 0x67, 0x10,
-	0x98, 0xad, 0xa4, 0xc7, 0x25, 0x84, 0xac, 0xbd, 0xb8, 0x20, 0x3f, 0xaf, 0x38, 0x55, 0x48, 0x82,
-	0x8b, 0xbd, 0xb8, 0x34, 0x39, 0x39, 0xb5, 0xb8, 0x18, 0x6c, 0x02, 0x47, 0x10, 0x8c, 0xab, 0xa4,
-	0xca, 0xc5, 0xef, 0x91, 0x58, 0x8c, 0x62, 0x19, 0xcc, 0x58, 0x46, 0x24, 0x63, 0x75, 0xb8, 0x04,
-	0x10, 0xca, 0x88, 0x31, 0xd4, 0x3d, 0xb5, 0x84, 0xa0, 0xa1, 0x4e, 0x5c, 0x02, 0x08, 0x65, 0x84,
-	0x0c, 0x45, 0x84, 0x01, 0x13, 0x52, 0x18, 0x18, 0x1d, 0x61, 0xe4, 0x62, 0x05, 0x7b, 0x58, 0xc8,
-	0x91, 0x8b, 0x0b, 0xe1, 0x73, 0x21, 0x09, 0x3d, 0xb0, 0xbc, 0x1e, 0x46, 0x58, 0x4a, 0x49, 0x62,
-	0x91, 0x81, 0x5a, 0x6e, 0xcd, 0xc5, 0x01, 0xf3, 0xa5, 0x90, 0x18, 0x54, 0x19, 0x5a, 0xe8, 0x48,
-	0x89, 0x63, 0x88, 0x23, 0x34, 0xc3, 0x7c, 0x03, 0xd7, 0x8c, 0x16, 0x0a, 0x70, 0xcd, 0xe8, 0xde,
-	0x4e, 0x62, 0x03, 0xa7, 0x01, 0x63, 0x40, 0x00, 0x00, 0x00, 0xff, 0xff, 0x67, 0x9e, 0x51, 0x18,
-	0x14, 0x02, 0x00, 0x00,
+	// 553 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x55, 0xdb, 0x6e, 0xd3, 0x40,
+	0x10, 0x95, 0x73, 0xa3, 0x1e, 0xa5, 0x22, 0x1e, 0xaa, 0xc4, 0x35, 0x3c, 0x54, 0x2b, 0x90, 0xa2,
+	0x50, 0xa2, 0x02, 0x0f, 0x3c, 0x20, 0x40, 0x0d, 0x48, 0x45, 0xa2, 0x0f, 0xc8, 0xe1, 0x07, 0x5c,
+	0x67, 0xd5, 0x58, 0x38, 0x71, 0xf0, 0x38, 0x88, 0xf2, 0x1b, 0xfc, 0x00, 0x9f, 0x8a, 0xbc, 0xde,
+	0xf5, 0xae, 0x2f, 0x10, 0xde, 0x32, 0x67, 0xce, 0xce, 0x39, 0x3b, 0xde, 0xa3, 0xc0, 0x31, 0xf1,
+	0xf4, 0x7b, 0x14, 0xf2, 0xf9, 0x2e, 0x4d, 0xb2, 0x04, 0xfb, 0x37, 0x71, 0x12, 0x7e, 0x65, 0x6f,
+	0xc0, 0x59, 0x66, 0x49, 0xca, 0x17, 0x79, 0xe5, 0xf3, 0x6f, 0x7b, 0x4e, 0x19, 0x9e, 0x40, 0xd1,
+	0x75, 0xad, 0x33, 0x6b, 0x3a, 0xf4, 0x8b, 0x02, 0x11, 0x7a, 0xeb, 0x80, 0xd6, 0x6e, 0xe7, 0xcc,
+	0x9a, 0xda, 0xbe, 0xf8, 0xcd, 0x16, 0x80, 0xe6, 0x71, 0xda, 0x25, 0x5b, 0xe2, 0xe8, 0xc2, 0x3d,
+	0xda, 0x87, 0x21, 0x27, 0x12, 0x13, 0x8e, 0x7c, 0x55, 0xb6, 0xce, 0x78, 0x02, 0xf7, 0x3f, 0x06,
+	0x54, 0x31, 0xa0, 0x68, 0x96, 0x41, 0x3b, 0x87, 0x91, 0xa6, 0x1d, 0x12, 0x62, 0x33, 0xcd, 0x26,
+	0x35, 0x75, 0x0c, 0x83, 0x7c, 0x12, 0xcf, 0xc9, 0xdd, 0xa9, 0xed, 0xcb, 0x8a, 0x3d, 0x03, 0xc7,
+	0xe0, 0xea, 0xd1, 0x9b, 0x88, 0x28, 0xda, 0xde, 0x4a, 0xb6, 0x2a, 0x73, 0xbf, 0x57, 0x3c, 0x3b,
+	0xe8, 0x77, 0x01, 0x23, 0x4d, 0x3b, 0xb8, 0x98, 0x72, 0xe5, 0x1d, 0x63, 0xe5, 0xec, 0x29, 0x38,
+	0x9f, 0xf7, 0x59, 0xcd, 0xd9, 0x18, 0x06, 0x94, 0xef, 0x7c, 0x25, 0x66, 0xf4, 0x7c, 0x59, 0xb1,
+	0x99, 0x16, 0x3c, 0x78, 0xe5, 0xe7, 0xe0, 0x5c, 0x47, 0x54, 0x23, 0x3f, 0x02, 0x3b, 0x89, 0x57,
+	0x3c, 0xfd, 0xb2, 0x0e, 0xb6, 0x62, 0x76, 0xd7, 0xd7, 0x00, 0x3b, 0x07, 0x34, 0x8f, 0x68, 0x33,
+	0xad, 0x02, 0x9f, 0xe0, 0xc1, 0x07, 0x1e, 0xf3, 0x8c, 0xff, 0x97, 0x9f, 0xaa, 0x74, 0xa7, 0x2e,
+	0x7d, 0x01, 0x27, 0xd5, 0x61, 0x7a, 0x9d, 0x2b, 0x81, 0xaf, 0xd4, 0x37, 0x92, 0x25, 0x73, 0xc4,
+	0x37, 0x5a, 0x66, 0x41, 0xa6, 0xa4, 0xd9, 0x6f, 0x0b, 0x46, 0x1a, 0xd3, 0xf6, 0xc5, 0xa6, 0x49,
+	0xed, 0xb2, 0xa8, 0x70, 0x06, 0xa3, 0x30, 0xd9, 0xec, 0x52, 0x4e, 0xc4, 0x57, 0x85, 0xaa, 0xb0,
+	0xd5, 0xf3, 0x1b, 0x38, 0x32, 0x18, 0xc6, 0xc9, 0x6d, 0x14, 0x06, 0xf1, 0xe2, 0x2e, 0xe3, 0xe4,
+	0x76, 0x05, 0xaf, 0x82, 0xe1, 0x63, 0x38, 0xde, 0xad, 0xef, 0x48, 0x93, 0x7a, 0x82, 0x54, 0x05,
+	0x19, 0x0a, 0x87, 0xd7, 0xd1, 0x26, 0xd2, 0xb6, 0x5f, 0x81, 0x63, 0x60, 0xd2, 0x36, 0x83, 0xe1,
+	0x26, 0xf8, 0x21, 0xf4, 0x97, 0xd1, 0x4f, 0x2e, 0xcd, 0x57, 0xb0, 0x17, 0xbf, 0xfa, 0xd0, 0x17,
+	0xd9, 0xc4, 0x4b, 0x00, 0x1d, 0x52, 0x74, 0xe7, 0xe2, 0x8e, 0xf3, 0x46, 0xec, 0xbd, 0xd3, 0x96,
+	0x8e, 0x14, 0x7c, 0x0d, 0x47, 0x2a, 0x22, 0x38, 0x96, 0xb4, 0x5a, 0x68, 0xbd, 0x49, 0x03, 0x97,
+	0x87, 0xdf, 0x82, 0xad, 0x30, 0xc2, 0x3a, 0x4b, 0x5d, 0xd4, 0x73, 0x9b, 0x0d, 0x2d, 0xae, 0x1e,
+	0x76, 0x29, 0x5e, 0x4b, 0xa0, 0x37, 0x69, 0xe0, 0xf2, 0xf0, 0x25, 0xd8, 0x65, 0x84, 0xfe, 0x71,
+	0x77, 0xd5, 0x69, 0xc4, 0x6d, 0x6a, 0xe1, 0x3b, 0xb0, 0xd5, 0x58, 0xed, 0xbf, 0x1e, 0xb5, 0xbf,
+	0x3a, 0xb8, 0xb0, 0xf0, 0x3d, 0x80, 0x8e, 0x4e, 0x69, 0xa2, 0x11, 0x40, 0xef, 0xb4, 0xa5, 0x53,
+	0x0e, 0xb9, 0x82, 0xa1, 0x19, 0x02, 0xf4, 0x24, 0xb9, 0x25, 0x66, 0xde, 0xc3, 0xd6, 0x5e, 0x65,
+	0x9d, 0x22, 0x07, 0xe6, 0x3a, 0xcd, 0xb0, 0x78, 0x93, 0x06, 0xae, 0xbf, 0x65, 0xf9, 0x1c, 0xcd,
+	0x5d, 0x54, 0x1e, 0xad, 0xe7, 0x36, 0x1b, 0xc5, 0xf9, 0x9b, 0x81, 0xf8, 0xf7, 0x79, 0xf9, 0x67,
+	0x00, 0x2e, 0xaf, 0x2d, 0x19, 0x8e, 0x06, 0x00, 0x00,
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -318,7 +855,24 @@ const _ = grpc.SupportPackageIsVersion4
 type StoreClient interface {
 	StoreBlock(ctx context.Context, in *StoreBlockRequest, opts ...grpc.CallOption) (*StoreBlockResponse, error)
 	HasBlock(ctx context.Context, in *HasBlockRequest, opts ...grpc.CallOption) (*HasBlockResponse, error)
+	// Checks for many blocks at once, returning the hashes of the blocks that are missing.
+	HasBlocks(ctx context.Context, in *HasBlocksRequest, opts ...grpc.CallOption) (*HasBlocksResponse, error)
 	GetBlock(ctx context.Context, in *GetBlockRequest, opts ...grpc.CallOption) (*GetBlockResponse, error)
+	// Stores a stream of blocks. The stream is aborted at the first block that fails to be stored.
+	PutBlocks(ctx context.Context, opts ...grpc.CallOption) (Store_PutBlocksClient, error)
+	// Retrieves a stream of blocks, in the order of the requested hashes.
+	GetBlocks(ctx context.Context, in *GetBlocksRequest, opts ...grpc.CallOption) (Store_GetBlocksClient, error)
+	// Lists, in batches, the blocks that have not been stored or checked for since the specified time.
+	// Used by the garbage collector to find candidates for deletion.
+	ListBlocks(ctx context.Context, in *ListBlocksRequest, opts ...grpc.CallOption) (Store_ListBlocksClient, error)
+	// Deletes the specified blocks, except those that have been stored or checked for since the
+	// specified time, which may be about to be referenced by a file.
+	DeleteBlocks(ctx context.Context, in *DeleteBlocksRequest, opts ...grpc.CallOption) (*DeleteBlocksResponse, error)
+	// Reports how many blocks the store holds, and how much space they take up before and after
+	// compression. The header of every block is read, so it is slow for large stores.
+	GetStats(ctx context.Context, in *GetStatsRequest, opts ...grpc.CallOption) (*GetStatsResponse, error)
+	// Reports the limits the store places on the blocks it accepts.
+	GetLimits(ctx context.Context, in *GetLimitsRequest, opts ...grpc.CallOption) (*GetLimitsResponse, error)
 }
 
 type storeClient struct {
@@ -347,6 +901,15 @@ func (c *storeClient) HasBlock(ctx context.Context, in *HasBlockRequest, opts ..
 	return out, nil
 }
 
+func (c *storeClient) HasBlocks(ctx context.Context, in *HasBlocksRequest, opts ...grpc.CallOption) (*HasBlocksResponse, error) {
+	out := new(HasBlocksResponse)
+	err := c.cc.Invoke(ctx, "/block.Store/HasBlocks", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *storeClient) GetBlock(ctx context.Context, in *GetBlockRequest, opts ...grpc.CallOption) (*GetBlockResponse, error) {
 	out := new(GetBlockResponse)
 	err := c.cc.Invoke(ctx, "/block.Store/GetBlock", in, out, opts...)
@@ -356,11 +919,153 @@ func (c *storeClient) GetBlock(ctx context.Context, in *GetBlockRequest, opts ..
 	return out, nil
 }
 
+func (c *storeClient) PutBlocks(ctx context.Context, opts ...grpc.CallOption) (Store_PutBlocksClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Store_serviceDesc.Streams[0], "/block.Store/PutBlocks", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &storePutBlocksClient{stream}
+	return x, nil
+}
+
+type Store_PutBlocksClient interface {
+	Send(*StoreBlockRequest) error
+	CloseAndRecv() (*PutBlocksResponse, error)
+	grpc.ClientStream
+}
+
+type storePutBlocksClient struct {
+	grpc.ClientStream
+}
+
+func (x *storePutBlocksClient) Send(m *StoreBlockRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *storePutBlocksClient) CloseAndRecv() (*PutBlocksResponse, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(PutBlocksResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *storeClient) GetBlocks(ctx context.Context, in *GetBlocksRequest, opts ...grpc.CallOption) (Store_GetBlocksClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Store_serviceDesc.Streams[1], "/block.Store/GetBlocks", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &storeGetBlocksClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Store_GetBlocksClient interface {
+	Recv() (*GetBlockResponse, error)
+	grpc.ClientStream
+}
+
+type storeGetBlocksClient struct {
+	grpc.ClientStream
+}
+
+func (x *storeGetBlocksClient) Recv() (*GetBlockResponse, error) {
+	m := new(GetBlockResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *storeClient) ListBlocks(ctx context.Context, in *ListBlocksRequest, opts ...grpc.CallOption) (Store_ListBlocksClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Store_serviceDesc.Streams[2], "/block.Store/ListBlocks", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &storeListBlocksClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Store_ListBlocksClient interface {
+	Recv() (*ListBlocksResponse, error)
+	grpc.ClientStream
+}
+
+type storeListBlocksClient struct {
+	grpc.ClientStream
+}
+
+func (x *storeListBlocksClient) Recv() (*ListBlocksResponse, error) {
+	m := new(ListBlocksResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *storeClient) DeleteBlocks(ctx context.Context, in *DeleteBlocksRequest, opts ...grpc.CallOption) (*DeleteBlocksResponse, error) {
+	out := new(DeleteBlocksResponse)
+	err := c.cc.Invoke(ctx, "/block.Store/DeleteBlocks", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storeClient) GetStats(ctx context.Context, in *GetStatsRequest, opts ...grpc.CallOption) (*GetStatsResponse, error) {
+	out := new(GetStatsResponse)
+	err := c.cc.Invoke(ctx, "/block.Store/GetStats", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storeClient) GetLimits(ctx context.Context, in *GetLimitsRequest, opts ...grpc.CallOption) (*GetLimitsResponse, error) {
+	out := new(GetLimitsResponse)
+	err := c.cc.Invoke(ctx, "/block.Store/GetLimits", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // StoreServer is the server API for Store service.
 type StoreServer interface {
 	StoreBlock(context.Context, *StoreBlockRequest) (*StoreBlockResponse, error)
 	HasBlock(context.Context, *HasBlockRequest) (*HasBlockResponse, error)
+	// Checks for many blocks at once, returning the hashes of the blocks that are missing.
+	HasBlocks(context.Context, *HasBlocksRequest) (*HasBlocksResponse, error)
 	GetBlock(context.Context, *GetBlockRequest) (*GetBlockResponse, error)
+	// Stores a stream of blocks. The stream is aborted at the first block that fails to be stored.
+	PutBlocks(Store_PutBlocksServer) error
+	// Retrieves a stream of blocks, in the order of the requested hashes.
+	GetBlocks(*GetBlocksRequest, Store_GetBlocksServer) error
+	// Lists, in batches, the blocks that have not been stored or checked for since the specified time.
+	// Used by the garbage collector to find candidates for deletion.
+	ListBlocks(*ListBlocksRequest, Store_ListBlocksServer) error
+	// Deletes the specified blocks, except those that have been stored or checked for since the
+	// specified time, which may be about to be referenced by a file.
+	DeleteBlocks(context.Context, *DeleteBlocksRequest) (*DeleteBlocksResponse, error)
+	// Reports how many blocks the store holds, and how much space they take up before and after
+	// compression. The header of every block is read, so it is slow for large stores.
+	GetStats(context.Context, *GetStatsRequest) (*GetStatsResponse, error)
+	// Reports the limits the store places on the blocks it accepts.
+	GetLimits(context.Context, *GetLimitsRequest) (*GetLimitsResponse, error)
 }
 
 // UnimplementedStoreServer can be embedded to have forward compatible implementations.
@@ -373,9 +1078,30 @@ func (*UnimplementedStoreServer) StoreBlock(ctx context.Context, req *StoreBlock
 func (*UnimplementedStoreServer) HasBlock(ctx context.Context, req *HasBlockRequest) (*HasBlockResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method HasBlock not implemented")
 }
+func (*UnimplementedStoreServer) HasBlocks(ctx context.Context, req *HasBlocksRequest) (*HasBlocksResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method HasBlocks not implemented")
+}
 func (*UnimplementedStoreServer) GetBlock(ctx context.Context, req *GetBlockRequest) (*GetBlockResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetBlock not implemented")
 }
+func (*UnimplementedStoreServer) PutBlocks(srv Store_PutBlocksServer) error {
+	return status.Errorf(codes.Unimplemented, "method PutBlocks not implemented")
+}
+func (*UnimplementedStoreServer) GetBlocks(req *GetBlocksRequest, srv Store_GetBlocksServer) error {
+	return status.Errorf(codes.Unimplemented, "method GetBlocks not implemented")
+}
+func (*UnimplementedStoreServer) ListBlocks(req *ListBlocksRequest, srv Store_ListBlocksServer) error {
+	return status.Errorf(codes.Unimplemented, "method ListBlocks not implemented")
+}
+func (*UnimplementedStoreServer) DeleteBlocks(ctx context.Context, req *DeleteBlocksRequest) (*DeleteBlocksResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteBlocks not implemented")
+}
+func (*UnimplementedStoreServer) GetStats(ctx context.Context, req *GetStatsRequest) (*GetStatsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetStats not implemented")
+}
+func (*UnimplementedStoreServer) GetLimits(ctx context.Context, req *GetLimitsRequest) (*GetLimitsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetLimits not implemented")
+}
 
 func RegisterStoreServer(s *grpc.Server, srv StoreServer) {
 	s.RegisterService(&_Store_serviceDesc, srv)
@@ -417,6 +1143,24 @@ func _Store_HasBlock_Handler(srv interface{}, ctx context.Context, dec func(inte
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Store_HasBlocks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HasBlocksRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StoreServer).HasBlocks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/block.Store/HasBlocks",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StoreServer).HasBlocks(ctx, req.(*HasBlocksRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _Store_GetBlock_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(GetBlockRequest)
 	if err := dec(in); err != nil {
@@ -435,6 +1179,128 @@ func _Store_GetBlock_Handler(srv interface{}, ctx context.Context, dec func(inte
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Store_PutBlocks_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(StoreServer).PutBlocks(&storePutBlocksServer{stream})
+}
+
+type Store_PutBlocksServer interface {
+	SendAndClose(*PutBlocksResponse) error
+	Recv() (*StoreBlockRequest, error)
+	grpc.ServerStream
+}
+
+type storePutBlocksServer struct {
+	grpc.ServerStream
+}
+
+func (x *storePutBlocksServer) SendAndClose(m *PutBlocksResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *storePutBlocksServer) Recv() (*StoreBlockRequest, error) {
+	m := new(StoreBlockRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _Store_GetBlocks_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(GetBlocksRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(StoreServer).GetBlocks(m, &storeGetBlocksServer{stream})
+}
+
+type Store_GetBlocksServer interface {
+	Send(*GetBlockResponse) error
+	grpc.ServerStream
+}
+
+type storeGetBlocksServer struct {
+	grpc.ServerStream
+}
+
+func (x *storeGetBlocksServer) Send(m *GetBlockResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Store_ListBlocks_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ListBlocksRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(StoreServer).ListBlocks(m, &storeListBlocksServer{stream})
+}
+
+type Store_ListBlocksServer interface {
+	Send(*ListBlocksResponse) error
+	grpc.ServerStream
+}
+
+type storeListBlocksServer struct {
+	grpc.ServerStream
+}
+
+func (x *storeListBlocksServer) Send(m *ListBlocksResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Store_DeleteBlocks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteBlocksRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StoreServer).DeleteBlocks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/block.Store/DeleteBlocks",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StoreServer).DeleteBlocks(ctx, req.(*DeleteBlocksRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Store_GetStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetStatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StoreServer).GetStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/block.Store/GetStats",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StoreServer).GetStats(ctx, req.(*GetStatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Store_GetLimits_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetLimitsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StoreServer).GetLimits(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/block.Store/GetLimits",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StoreServer).GetLimits(ctx, req.(*GetLimitsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _Store_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "block.Store",
 	HandlerType: (*StoreServer)(nil),
@@ -447,11 +1313,43 @@ var _Store_serviceDesc = grpc.ServiceDesc{
 			MethodName: "HasBlock",
 			Handler:    _Store_HasBlock_Handler,
 		},
+		{
+			MethodName: "HasBlocks",
+			Handler:    _Store_HasBlocks_Handler,
+		},
 		{
 			MethodName: "GetBlock",
 			Handler:    _Store_GetBlock_Handler,
 		},
+		{
+			MethodName: "DeleteBlocks",
+			Handler:    _Store_DeleteBlocks_Handler,
+		},
+		{
+			MethodName: "GetStats",
+			Handler:    _Store_GetStats_Handler,
+		},
+		{
+			MethodName: "GetLimits",
+			Handler:    _Store_GetLimits_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "PutBlocks",
+			Handler:       _Store_PutBlocks_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "GetBlocks",
+			Handler:       _Store_GetBlocks_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "ListBlocks",
+			Handler:       _Store_ListBlocks_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "service.proto",
 }
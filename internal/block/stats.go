@@ -0,0 +1,47 @@
+package block
+
+import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Reports the number of blocks and their sizes before and after compression. Only the header of each
+// block is read, so the cost depends on the number of blocks rather than on their size.
+func (s *Store) GetStats(ctx context.Context, req *GetStatsRequest) (*GetStatsResponse, error) {
+	res := &GetStatsResponse{}
+	err := s.backend.List(func(hash string, info BlockInfo) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		header, err := s.backend.GetPrefix(hash, maxBlockHeaderSize)
+		if err == ErrBlockNotFound {
+			// The block was deleted since it was listed.
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		c, size, _, err := parseBlockHeader(header)
+		if err != nil {
+			return status.Errorf(codes.DataLoss, "block %s could not be decoded: %v", hash, err)
+		}
+
+		res.Blocks++
+		res.PhysicalBytes += uint64(info.Size)
+		res.LogicalBytes += size
+
+		if c != NoCompression {
+			res.CompressedBlocks++
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
@@ -2,111 +2,295 @@ package block
 
 import (
 	"context"
-	"fmt"
-	"math/rand"
-	"os"
-	"path/filepath"
-	"time"
+	"io"
+	"sync"
+	"sync/atomic"
 
 	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
-const FilePrefix = "blk_"
-
-func init() {
-	rand.Seed(time.Now().UnixNano())
-}
+// Maximum number of blocks HasBlocks checks for at the same time.
+const hasBlocksConcurrency = 16
 
 type Store struct {
-	// Mapping of filename to file blocks.
-	blocks map[string]datafile
+	// The largest block the store accepts. Accessed atomically, since it may be changed while blocks
+	// are being stored, and kept first so that it is 64-bit aligned.
+	maxBlockSize uint64
 
-	// Key-value engine for storing file metadata.
-	engine engine
+	// The codec new blocks are compressed with. Accessed atomically.
+	compression int32
 
-	// The data directory for this instance of the metadata store.
-	dataDir string
+	// Holds the contents of the blocks.
+	backend Backend
 
-	// Assigns sequential IDs to blocks in the block store.
-	counter uint64
+	// Held for reading while blocks are stored or checked for, and for writing while blocks are
+	// deleted, so that a block is never deleted between being reported present and being touched.
+	gcMtx sync.RWMutex
 }
 
+// Creates a new block store service that keeps blocks in the specified data directory. Blocks stored by
+// a previous instance of the block store in the same directory remain available.
 func NewStore(dataDir string) (*Store, error) {
-
-	engine, err := openKeychainEngine("block.keychain")
+	backend, err := NewLocalBackend(dataDir)
 	if err != nil {
 		return nil, err
 	}
 
+	return NewBackendStore(backend), nil
+}
+
+// Creates a new block store service that keeps blocks in the specified backend.
+func NewBackendStore(backend Backend) *Store {
 	return &Store{
-		blocks:  make(map[string]datafile),
-		engine:  engine,
-		dataDir: dataDir,
-		counter: 0,
+		backend:      backend,
+		maxBlockSize: DefaultMaxBlockSize,
+	}
+}
+
+// Sets the largest block the store accepts. Larger blocks are rejected with an InvalidArgument status.
+func (s *Store) SetMaxBlockSize(size uint64) {
+	atomic.StoreUint64(&s.maxBlockSize, size)
+}
+
+// Sets the codec new blocks are compressed with. Blocks already stored are left as they are, and
+// remain readable whatever the codec.
+func (s *Store) SetCompression(c Compression) {
+	atomic.StoreInt32(&s.compression, int32(c))
+}
+
+// Reports the limits the store places on the blocks it accepts.
+func (s *Store) GetLimits(ctx context.Context, req *GetLimitsRequest) (*GetLimitsResponse, error) {
+	return &GetLimitsResponse{
+		MaxBlockSize: atomic.LoadUint64(&s.maxBlockSize),
 	}, nil
 }
 
-func (s *Store) StoreBlock(ctx context.Context, req *StoreBlockRequest) (*StoreBlockResponse, error) {
+// Closes the store and its backend.
+func (s *Store) Close() error {
+	return s.backend.Close()
+}
 
-	// This could totally cause collisions, need to fix this later.
-	base := fmt.Sprintf("%s%d", FilePrefix, rand.Uint64())
-	path := filepath.Join(s.dataDir, base)
+func (s *Store) StoreBlock(ctx context.Context, req *StoreBlockRequest) (*StoreBlockResponse, error) {
+	if _, err := blockName(req.Hash); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid block hash %q", req.Hash)
+	}
 
 	log.WithFields(log.Fields{
 		"hash": req.Hash,
-		"path": path,
 		"size": len(req.Block),
 	}).Debug("Storing block...")
 
-	f, err := os.Create(path)
-	if err != nil {
-		return nil, err
+	if maxBlockSize := atomic.LoadUint64(&s.maxBlockSize); uint64(len(req.Block)) > maxBlockSize {
+		log.WithFields(log.Fields{
+			"hash":         req.Hash,
+			"size":         len(req.Block),
+			"maxBlockSize": maxBlockSize,
+		}).Debug("Did not store block; block too large.")
+
+		return nil, status.Errorf(codes.InvalidArgument, "block of %d bytes exceeds the maximum block size of %d bytes", len(req.Block), maxBlockSize)
 	}
 
-	defer f.Close()
+	// Verify that the block actually hashes to the hash it is being stored under. Otherwise, a faulty
+	// client could store arbitrary data under a hash referenced by other files.
+	hash := blockHash(req.Block)
+	if hash != req.Hash {
+		log.WithFields(log.Fields{
+			"hash":     req.Hash,
+			"computed": hash,
+		}).Debug("Did not store block; hash mismatch.")
 
-	if _, err := f.Write(req.Block); err != nil {
-		return nil, err
+		return nil, hashMismatchError(req.Hash, hash)
 	}
 
-	if err := f.Sync(); err != nil {
-		return nil, err
-	}
+	s.gcMtx.RLock()
+	defer s.gcMtx.RUnlock()
 
-	s.blocks[req.Hash] = datafile{
-		path: path,
+	stored := Compression(atomic.LoadInt32(&s.compression)).encode(req.Block)
+	if err := s.backend.Put(hash, stored); err != nil {
+		return nil, err
 	}
 
 	return &StoreBlockResponse{
 		Success: true,
+		Hash:    hash,
 	}, nil
 }
 
+// Stores a stream of blocks. Each block is stored as if by StoreBlock, and the stream is aborted at the
+// first block that fails to be stored.
+func (s *Store) PutBlocks(stream Store_PutBlocksServer) error {
+	var stored uint64
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			log.Debugf("Stored %d block(s) from stream.", stored)
+			return stream.SendAndClose(&PutBlocksResponse{Stored: stored})
+		} else if err != nil {
+			return err
+		}
+
+		if _, err := s.StoreBlock(stream.Context(), req); err != nil {
+			return err
+		}
+
+		stored++
+	}
+}
+
+// Creates the error returned by StoreBlock when a block does not match its hash. The error has an
+// INVALID_ARGUMENT status with a StoreBlockResponse detail holding the computed hash.
+func hashMismatchError(expected string, computed string) error {
+	st := status.Newf(codes.InvalidArgument, "block hash mismatch: expected %s, computed %s", expected, computed)
+	st, err := st.WithDetails(&StoreBlockResponse{
+		Success: false,
+		Hash:    computed,
+	})
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to attach status details, %v", err)
+	}
+
+	return st.Err()
+}
+
+// Checks whether an error returned by StoreBlock indicates that the block did not match its hash,
+// i.e. that it was corrupted before reaching the block store. If so, the hash computed by the block
+// store is returned.
+func IsHashMismatch(err error) (string, bool) {
+	st, ok := status.FromError(err)
+	if !ok || st.Code() != codes.InvalidArgument {
+		return "", false
+	}
+
+	for _, detail := range st.Details() {
+		if res, ok := detail.(*StoreBlockResponse); ok {
+			return res.Hash, true
+		}
+	}
+
+	return "", false
+}
+
+// Checks whether the block store has a block. A block that is found is marked as recently used, since
+// the caller may be about to reference it from a file, so that it is not garbage collected in between.
 func (s *Store) HasBlock(ctx context.Context, req *HasBlockRequest) (*HasBlockResponse, error) {
-	_, ok := s.blocks[req.Hash]
+	if _, err := blockName(req.Hash); err != nil {
+		return &HasBlockResponse{Success: false}, nil
+	}
+
+	s.gcMtx.RLock()
+	defer s.gcMtx.RUnlock()
+
+	ok, err := s.backend.Has(req.Hash)
+	if err != nil {
+		return nil, err
+	}
+
 	return &HasBlockResponse{
 		Success: ok,
 	}, nil
 }
 
-func (s *Store) GetBlock(ctx context.Context, req *GetBlockRequest) (*GetBlockResponse, error) {
-	df, ok := s.blocks[req.Hash]
+// Checks for many blocks at once. This saves a round trip per block compared to HasBlock, and the
+// blocks are checked for concurrently, since backends such as S3 take a request per block.
+func (s *Store) HasBlocks(ctx context.Context, req *HasBlocksRequest) (*HasBlocksResponse, error) {
+	s.gcMtx.RLock()
+	defer s.gcMtx.RUnlock()
+
+	found := make([]bool, len(req.Hashes))
+	sem := make(chan struct{}, hasBlocksConcurrency)
+
+	var wg sync.WaitGroup
+	var errOnce sync.Once
+	var firstErr error
 
-	if !ok {
-		return &GetBlockResponse{
-			Success: ok,
-			Block:   nil,
-		}, nil
+	for i, hash := range req.Hashes {
+		if _, err := blockName(hash); err != nil {
+			continue
+		}
+
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(i int, hash string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			ok, err := s.backend.Has(hash)
+			if err != nil {
+				errOnce.Do(func() { firstErr = err })
+				return
+			}
+
+			found[i] = ok
+		}(i, hash)
 	}
 
-	b, err := df.readAll()
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	missing := make([]string, 0, len(req.Hashes))
+	for i, hash := range req.Hashes {
+		if !found[i] {
+			missing = append(missing, hash)
+		}
+	}
+
+	return &HasBlocksResponse{
+		Missing: missing,
+	}, nil
+}
+
+func (s *Store) GetBlock(ctx context.Context, req *GetBlockRequest) (*GetBlockResponse, error) {
+	if _, err := blockName(req.Hash); err != nil {
+		return &GetBlockResponse{Success: false}, nil
+	}
+
+	b, err := s.backend.Get(req.Hash)
 	if err != nil {
+		if err == ErrBlockNotFound {
+			return &GetBlockResponse{
+				Success: false,
+				Block:   nil,
+			}, nil
+		}
+
 		return nil, err
 	}
 
+	if b, err = decodeBlock(b); err != nil {
+		log.WithFields(log.Fields{
+			"hash": req.Hash,
+		}).Errorf("Could not decode block, %v", err)
+
+		return nil, status.Errorf(codes.DataLoss, "block %s could not be decoded: %v", req.Hash, err)
+	}
+
 	return &GetBlockResponse{
 		Success: true,
 		Block:   b,
 	}, nil
+}
+
+// Retrieves a stream of blocks in the order of the requested hashes. Each block is retrieved as if by
+// GetBlock, so a missing block is sent as an unsuccessful response rather than ending the stream.
+func (s *Store) GetBlocks(req *GetBlocksRequest, stream Store_GetBlocksServer) error {
+	for _, hash := range req.Hashes {
+		res, err := s.GetBlock(stream.Context(), &GetBlockRequest{Hash: hash})
+		if err != nil {
+			return err
+		}
+
+		// Send waits while the client's flow control window is full, so only one block is held in
+		// memory at a time.
+		if err := stream.Send(res); err != nil {
+			return err
+		}
+	}
 
+	return nil
 }
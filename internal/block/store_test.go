@@ -2,11 +2,21 @@ package block
 
 import (
 	"context"
+	"fmt"
+	"io"
 	"io/ioutil"
+	"net"
 	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
 )
 
 func tempBlock(dir string, pattern string, content []byte) (string, error) {
@@ -25,24 +35,32 @@ func tempBlock(dir string, pattern string, content []byte) (string, error) {
 	return f.Name(), nil
 }
 
-func TestStore_HasBlock(t *testing.T) {
-	fp, err := tempBlock(os.TempDir(), "surfs", []byte("block1"))
+// Creates a block store in a temporary directory containing the specified blocks.
+func tempStore(t *testing.T, blocks ...[]byte) (*Store, func()) {
+	dir, err := ioutil.TempDir("", "surfs")
 	assert.Nil(t, err)
 
-	defer os.Remove(fp)
+	s, err := NewStore(dir)
+	assert.Nil(t, err)
 
-	s := &Store{
-		blocks: map[string]datafile{
-			"hash1": {
-				path: fp,
-			},
-		},
-		dataDir: "",
-		counter: 0,
+	for _, b := range blocks {
+		df, err := datafileFor(dir, blockHash(b))
+		assert.Nil(t, err)
+		assert.Nil(t, df.writeAll(NoCompression.encode(b)))
+	}
+
+	return s, func() {
+		s.Close()
+		os.RemoveAll(dir)
 	}
+}
+
+func TestStore_HasBlock(t *testing.T) {
+	s, cleanup := tempStore(t, []byte("block1"))
+	defer cleanup()
 
 	req := &HasBlockRequest{
-		Hash: "hash1",
+		Hash: blockHash([]byte("block1")),
 	}
 
 	res, err := s.HasBlock(context.Background(), req)
@@ -50,32 +68,28 @@ func TestStore_HasBlock(t *testing.T) {
 	assert.True(t, res.Success)
 
 	req = &HasBlockRequest{
-		Hash: "hash2",
+		Hash: blockHash([]byte("block2")),
 	}
 
 	res, err = s.HasBlock(context.Background(), req)
 	assert.Nil(t, err)
 	assert.False(t, res.Success)
-}
 
-func TestStore_GetBlock(t *testing.T) {
-	fp, err := tempBlock(os.TempDir(), "surfs", []byte("block1"))
-	assert.Nil(t, err)
+	req = &HasBlockRequest{
+		Hash: "hash1",
+	}
 
-	defer os.Remove(fp)
+	res, err = s.HasBlock(context.Background(), req)
+	assert.Nil(t, err)
+	assert.False(t, res.Success)
+}
 
-	s := &Store{
-		blocks: map[string]datafile{
-			"hash1": {
-				path: fp,
-			},
-		},
-		dataDir: "",
-		counter: 0,
-	}
+func TestStore_GetBlock(t *testing.T) {
+	s, cleanup := tempStore(t, []byte("block1"))
+	defer cleanup()
 
 	req := &GetBlockRequest{
-		Hash: "hash1",
+		Hash: blockHash([]byte("block1")),
 	}
 
 	res, err := s.GetBlock(context.Background(), req)
@@ -84,7 +98,7 @@ func TestStore_GetBlock(t *testing.T) {
 	assert.Equal(t, []byte("block1"), res.Block)
 
 	req = &GetBlockRequest{
-		Hash: "hash2",
+		Hash: blockHash([]byte("block2")),
 	}
 
 	res, err = s.GetBlock(context.Background(), req)
@@ -92,3 +106,326 @@ func TestStore_GetBlock(t *testing.T) {
 	assert.False(t, res.Success)
 	assert.Equal(t, []byte(nil), res.Block)
 }
+
+func TestStore_StoreBlock(t *testing.T) {
+	s, cleanup := tempStore(t)
+	defer cleanup()
+
+	hash := blockHash([]byte("block1"))
+	req := &StoreBlockRequest{
+		Block: []byte("block1"),
+		Hash:  hash,
+	}
+
+	res, err := s.StoreBlock(context.Background(), req)
+	assert.Nil(t, err)
+	assert.True(t, res.Success)
+	assert.Equal(t, hash, res.Hash)
+
+	// The block should be stored at the path derived from its hash.
+	df, err := datafileFor(s.backend.(*localBackend).dir, hash)
+	assert.Nil(t, err)
+
+	b, err := df.readAll()
+	assert.Nil(t, err)
+	assert.Equal(t, NoCompression.encode([]byte("block1")), b)
+
+	// Storing the same block again should not create any new files.
+	info, err := os.Stat(df.path)
+	assert.Nil(t, err)
+
+	res, err = s.StoreBlock(context.Background(), req)
+	assert.Nil(t, err)
+	assert.True(t, res.Success)
+
+	infos, err := ioutil.ReadDir(filepath.Dir(df.path))
+	assert.Nil(t, err)
+	assert.Len(t, infos, 1)
+	assert.True(t, os.SameFile(info, infos[0]))
+
+	// Hashes that cannot be mapped to a path are rejected.
+	_, err = s.StoreBlock(context.Background(), &StoreBlockRequest{
+		Block: []byte("block1"),
+		Hash:  "../hash1",
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, ok := IsHashMismatch(err)
+	assert.False(t, ok)
+}
+
+func TestStore_StoreBlockHashMismatch(t *testing.T) {
+	s, cleanup := tempStore(t)
+	defer cleanup()
+
+	hash := blockHash([]byte("block1"))
+	res, err := s.StoreBlock(context.Background(), &StoreBlockRequest{
+		Block: []byte("block2"),
+		Hash:  hash,
+	})
+	assert.Nil(t, res)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	computed, ok := IsHashMismatch(err)
+	assert.True(t, ok)
+	assert.Equal(t, blockHash([]byte("block2")), computed)
+
+	// The corrupted block must not have been stored under either hash.
+	hasRes, err := s.HasBlock(context.Background(), &HasBlockRequest{Hash: hash})
+	assert.Nil(t, err)
+	assert.False(t, hasRes.Success)
+
+	hasRes, err = s.HasBlock(context.Background(), &HasBlockRequest{Hash: computed})
+	assert.Nil(t, err)
+	assert.False(t, hasRes.Success)
+}
+
+func TestStore_StoreBlockMaxSize(t *testing.T) {
+	s, cleanup := tempStore(t)
+	defer cleanup()
+
+	s.SetMaxBlockSize(4)
+
+	_, err := s.StoreBlock(context.Background(), &StoreBlockRequest{
+		Block: []byte("block"),
+		Hash:  blockHash([]byte("block")),
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	res, err := s.HasBlock(context.Background(), &HasBlockRequest{Hash: blockHash([]byte("block"))})
+	assert.Nil(t, err)
+	assert.False(t, res.Success)
+
+	// Blocks no larger than the limit are accepted.
+	res2, err := s.StoreBlock(context.Background(), &StoreBlockRequest{
+		Block: []byte("blk"),
+		Hash:  blockHash([]byte("blk")),
+	})
+	assert.Nil(t, err)
+	assert.True(t, res2.Success)
+}
+
+func TestStore_Reopen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "surfs")
+	assert.Nil(t, err)
+
+	defer os.RemoveAll(dir)
+
+	s, err := NewStore(dir)
+	assert.Nil(t, err)
+
+	hash := blockHash([]byte("block1"))
+	res, err := s.StoreBlock(context.Background(), &StoreBlockRequest{
+		Block: []byte("block1"),
+		Hash:  hash,
+	})
+	assert.Nil(t, err)
+	assert.True(t, res.Success)
+	assert.Nil(t, s.Close())
+
+	// The reopened store should still serve the block stored by the previous instance.
+	s, err = NewStore(dir)
+	assert.Nil(t, err)
+
+	defer s.Close()
+
+	hasRes, err := s.HasBlock(context.Background(), &HasBlockRequest{Hash: hash})
+	assert.Nil(t, err)
+	assert.True(t, hasRes.Success)
+
+	getRes, err := s.GetBlock(context.Background(), &GetBlockRequest{Hash: hash})
+	assert.Nil(t, err)
+	assert.True(t, getRes.Success)
+	assert.Equal(t, []byte("block1"), getRes.Block)
+}
+
+func TestStore_Migrate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "surfs")
+	assert.Nil(t, err)
+
+	defer os.RemoveAll(dir)
+
+	// Datafiles written by older versions of the block store should be moved into the
+	// content-addressed layout when the store is opened.
+	legacy, err := tempBlock(dir, FilePrefix, []byte("block1"))
+	assert.Nil(t, err)
+
+	s, err := NewStore(dir)
+	assert.Nil(t, err)
+
+	defer s.Close()
+
+	res, err := s.GetBlock(context.Background(), &GetBlockRequest{Hash: blockHash([]byte("block1"))})
+	assert.Nil(t, err)
+	assert.True(t, res.Success)
+	assert.Equal(t, []byte("block1"), res.Block)
+
+	_, err = os.Stat(legacy)
+	assert.True(t, os.IsNotExist(err))
+}
+
+// Serves a block store over an in-memory connection, returning a client connected to it.
+func serveStore(t *testing.T, s *Store) (StoreClient, func()) {
+	lis := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	RegisterStoreServer(server, s)
+
+	go server.Serve(lis)
+
+	conn, err := grpc.Dial("bufconn", grpc.WithInsecure(), grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+		return lis.Dial()
+	}))
+	assert.Nil(t, err)
+
+	return NewStoreClient(conn), func() {
+		conn.Close()
+		server.Stop()
+	}
+}
+
+func TestStore_PutBlocks(t *testing.T) {
+	s, cleanup := tempStore(t)
+	defer cleanup()
+
+	client, stop := serveStore(t, s)
+	defer stop()
+
+	stream, err := client.PutBlocks(context.Background())
+	assert.Nil(t, err)
+
+	blocks := [][]byte{[]byte("block1"), []byte("block2"), []byte("block3")}
+	for _, b := range blocks {
+		assert.Nil(t, stream.Send(&StoreBlockRequest{Block: b, Hash: blockHash(b)}))
+	}
+
+	res, err := stream.CloseAndRecv()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(3), res.Stored)
+
+	for _, b := range blocks {
+		hasRes, err := s.HasBlock(context.Background(), &HasBlockRequest{Hash: blockHash(b)})
+		assert.Nil(t, err)
+		assert.True(t, hasRes.Success)
+	}
+
+	// A corrupted block aborts the stream.
+	stream, err = client.PutBlocks(context.Background())
+	assert.Nil(t, err)
+	assert.Nil(t, stream.Send(&StoreBlockRequest{Block: []byte("block4"), Hash: blockHash([]byte("block5"))}))
+
+	_, err = stream.CloseAndRecv()
+	computed, ok := IsHashMismatch(err)
+	assert.True(t, ok)
+	assert.Equal(t, blockHash([]byte("block4")), computed)
+}
+
+func TestStore_GetBlocks(t *testing.T) {
+	s, cleanup := tempStore(t, []byte("block1"), []byte("block2"))
+	defer cleanup()
+
+	client, stop := serveStore(t, s)
+	defer stop()
+
+	hashes := []string{
+		blockHash([]byte("block2")),
+		blockHash([]byte("block3")),
+		blockHash([]byte("block1")),
+	}
+
+	stream, err := client.GetBlocks(context.Background(), &GetBlocksRequest{Hashes: hashes})
+	assert.Nil(t, err)
+
+	expected := []*GetBlockResponse{
+		{Success: true, Block: []byte("block2")},
+		{Success: false},
+		{Success: true, Block: []byte("block1")},
+	}
+
+	for _, e := range expected {
+		res, err := stream.Recv()
+		assert.Nil(t, err)
+		assert.Equal(t, e.Success, res.Success)
+		assert.Equal(t, e.Block, res.Block)
+	}
+
+	_, err = stream.Recv()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestStore_HasBlocks(t *testing.T) {
+	s, cleanup := tempStore(t, []byte("block1"), []byte("block3"))
+	defer cleanup()
+
+	req := &HasBlocksRequest{
+		Hashes: []string{
+			blockHash([]byte("block1")),
+			blockHash([]byte("block2")),
+			blockHash([]byte("block3")),
+			"hash4",
+		},
+	}
+
+	res, err := s.HasBlocks(context.Background(), req)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{blockHash([]byte("block2")), "hash4"}, res.Missing)
+}
+
+func TestStore_ConcurrentStoreBlock(t *testing.T) {
+	s, cleanup := tempStore(t)
+	defer cleanup()
+
+	blocks := make([][]byte, 32)
+	for i := range blocks {
+		blocks[i] = []byte(fmt.Sprintf("block%d", i))
+	}
+
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	for w := 0; w < 16; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+
+			// Changing the maximum block size while blocks are stored must be safe.
+			s.SetMaxBlockSize(DefaultMaxBlockSize)
+
+			// Every goroutine stores every block, starting at a different one, and reads each back.
+			for i := range blocks {
+				b := blocks[(i+w)%len(blocks)]
+				hash := blockHash(b)
+
+				res, err := s.StoreBlock(ctx, &StoreBlockRequest{Hash: hash, Block: b})
+				if !assert.Nil(t, err) {
+					return
+				}
+				assert.True(t, res.Success)
+
+				getRes, err := s.GetBlock(ctx, &GetBlockRequest{Hash: hash})
+				assert.Nil(t, err)
+				assert.Equal(t, b, getRes.Block)
+			}
+		}(w)
+	}
+
+	wg.Wait()
+
+	hashes := make([]string, 0, len(blocks))
+	for _, b := range blocks {
+		hashes = append(hashes, blockHash(b))
+	}
+
+	res, err := s.HasBlocks(ctx, &HasBlocksRequest{Hashes: hashes})
+	assert.Nil(t, err)
+	assert.Empty(t, res.Missing)
+
+	// No temporary files are left behind by writes that lost the race to store a block.
+	err = filepath.Walk(s.backend.(*localBackend).dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		assert.False(t, strings.HasPrefix(info.Name(), tempPrefix), path)
+		return nil
+	})
+	assert.Nil(t, err)
+}
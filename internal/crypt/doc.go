@@ -0,0 +1,4 @@
+// Package crypt encrypts the blocks of files on the client with convergent encryption, so that the
+// block store only ever holds ciphertext while identical blocks of the same tenant are still stored
+// once.
+package crypt
@@ -0,0 +1,141 @@
+package crypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Shortest secret a keyring accepts.
+const MinSecretSize = 16
+
+// Size of the keys blocks are encrypted with.
+const KeySize = 32
+
+// Number of bytes encryption adds to a block.
+const Overhead = 16
+
+var (
+	ErrWrongSecret  = errors.New("block key was not encrypted with this secret")
+	ErrCorruptBlock = errors.New("block could not be decrypted")
+)
+
+// Labels that derive independent keys from a tenant's secret.
+const (
+	blockKeyLabel = "surfs block key"
+	wrapKeyLabel  = "surfs key wrap"
+)
+
+// Keyring holds the keys derived from a tenant's secret. The key of each block is derived from the
+// hash of its contents and the secret, so a block always encrypts to the same ciphertext for the same
+// tenant and is deduplicated by the block store, while other tenants cannot even tell that they store
+// the same block. Block keys are themselves encrypted with the secret before they are stored in the
+// metadata of files.
+type Keyring struct {
+	// The secret block keys are derived from.
+	blockSecret []byte
+
+	// Encrypts block keys.
+	wrap cipher.AEAD
+}
+
+// Creates a keyring from a tenant's secret, which must be at least MinSecretSize bytes.
+func NewKeyring(secret []byte) (*Keyring, error) {
+	if len(secret) < MinSecretSize {
+		return nil, fmt.Errorf("encryption secret must be at least %d bytes", MinSecretSize)
+	}
+
+	wrap, err := newAEAD(derive(secret, wrapKeyLabel))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Keyring{
+		blockSecret: derive(secret, blockKeyLabel),
+		wrap:        wrap,
+	}, nil
+}
+
+// Encrypts a block, and returns the encrypted block along with the key it was encrypted with.
+func (k *Keyring) Seal(b []byte) ([]byte, []byte, error) {
+	sum := sha256.Sum256(b)
+	key := derive(k.blockSecret, string(sum[:]))
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Each key only ever encrypts the one block it was derived from, so a fixed nonce is never reused
+	// with different plaintext.
+	nonce := make([]byte, aead.NonceSize())
+	return aead.Seal(nil, nonce, b, nil), key, nil
+}
+
+// Decrypts a block encrypted by Seal with the specified key.
+func Open(key []byte, sealed []byte) ([]byte, error) {
+	if len(key) != KeySize {
+		return nil, ErrCorruptBlock
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := aead.Open(nil, make([]byte, aead.NonceSize()), sealed, nil)
+	if err != nil {
+		return nil, ErrCorruptBlock
+	}
+
+	return b, nil
+}
+
+// Encrypts the key of the block with the specified hash, for storage in the metadata of a file. The
+// encrypted key is bound to the hash, so it cannot be used for another block.
+func (k *Keyring) WrapKey(key []byte, hash string) ([]byte, error) {
+	nonce := make([]byte, k.wrap.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	return k.wrap.Seal(nonce, nonce, key, []byte(hash)), nil
+}
+
+// Decrypts the key of the block with the specified hash, which was encrypted by WrapKey. Returns
+// ErrWrongSecret if the key was encrypted with another secret or for another block.
+func (k *Keyring) UnwrapKey(wrapped []byte, hash string) ([]byte, error) {
+	n := k.wrap.NonceSize()
+	if len(wrapped) < n {
+		return nil, ErrWrongSecret
+	}
+
+	key, err := k.wrap.Open(nil, wrapped[:n], wrapped[n:], []byte(hash))
+	if err != nil {
+		return nil, ErrWrongSecret
+	}
+
+	return key, nil
+}
+
+// Derives a key for the specified purpose from a secret.
+func derive(secret []byte, label string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(label))
+	return mac.Sum(nil)
+}
+
+// Creates an AES-256-GCM cipher with the specified key.
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	c, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(c)
+}
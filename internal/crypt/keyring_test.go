@@ -0,0 +1,88 @@
+package crypt
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyring(t *testing.T) {
+	_, err := NewKeyring([]byte("short"))
+	assert.NotNil(t, err)
+
+	k, err := NewKeyring([]byte("tenant-one-secret"))
+	assert.Nil(t, err)
+
+	other, err := NewKeyring([]byte("tenant-two-secret"))
+	assert.Nil(t, err)
+
+	block := []byte("the contents of a block")
+
+	sealed, key, err := k.Seal(block)
+	assert.Nil(t, err)
+	assert.Len(t, key, KeySize)
+	assert.Len(t, sealed, len(block)+Overhead)
+	assert.False(t, bytes.Contains(sealed, []byte("contents")))
+
+	b, err := Open(key, sealed)
+	assert.Nil(t, err)
+	assert.Equal(t, block, b)
+
+	// The same block always encrypts the same way for the same tenant, so it is deduplicated.
+	again, againKey, err := k.Seal(block)
+	assert.Nil(t, err)
+	assert.Equal(t, sealed, again)
+	assert.Equal(t, key, againKey)
+
+	// Other tenants encrypt it differently, and other blocks have other keys.
+	otherSealed, otherKey, err := other.Seal(block)
+	assert.Nil(t, err)
+	assert.NotEqual(t, sealed, otherSealed)
+	assert.NotEqual(t, key, otherKey)
+
+	_, nextKey, err := k.Seal([]byte("the contents of another block"))
+	assert.Nil(t, err)
+	assert.NotEqual(t, key, nextKey)
+
+	// Tampering is detected.
+	tampered := append([]byte(nil), sealed...)
+	tampered[0] ^= 1
+	_, err = Open(key, tampered)
+	assert.Equal(t, ErrCorruptBlock, err)
+
+	_, err = Open(otherKey, sealed)
+	assert.Equal(t, ErrCorruptBlock, err)
+
+	_, err = Open(key[:8], sealed)
+	assert.Equal(t, ErrCorruptBlock, err)
+}
+
+func TestKeyring_WrapKey(t *testing.T) {
+	k, err := NewKeyring([]byte("tenant-one-secret"))
+	assert.Nil(t, err)
+
+	_, key, err := k.Seal([]byte("block"))
+	assert.Nil(t, err)
+
+	wrapped, err := k.WrapKey(key, "hash1")
+	assert.Nil(t, err)
+	assert.False(t, bytes.Contains(wrapped, key))
+
+	unwrapped, err := k.UnwrapKey(wrapped, "hash1")
+	assert.Nil(t, err)
+	assert.Equal(t, key, unwrapped)
+
+	// Keys are bound to their block and to the secret.
+	_, err = k.UnwrapKey(wrapped, "hash2")
+	assert.Equal(t, ErrWrongSecret, err)
+
+	other, err := NewKeyring([]byte("tenant-two-secret"))
+	assert.Nil(t, err)
+
+	_, err = other.UnwrapKey(wrapped, "hash1")
+	assert.Equal(t, ErrWrongSecret, err)
+
+	_, err = k.UnwrapKey(wrapped[:4], "hash1")
+	assert.Equal(t, ErrWrongSecret, err)
+}
@@ -0,0 +1,108 @@
+package meta
+
+import (
+	"context"
+
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Attributes of a file's contents, which are stored as reported by clients. See FileAttributes.
+type attributes struct {
+	size    uint64
+	mode    uint32
+	mtime   int64
+	creator string
+	digest  string
+
+	// The encrypted keys of the blocks, or nil if they are not encrypted.
+	blockKeys [][]byte
+}
+
+// Converts attributes received from a client. A nil message has zero attributes.
+func attributesFromProto(a *FileAttributes) attributes {
+	if a == nil {
+		return attributes{}
+	}
+
+	return attributes{
+		size:      a.Size,
+		mode:      a.Mode,
+		mtime:     a.Mtime,
+		creator:   a.Creator,
+		digest:    a.Digest,
+		blockKeys: a.BlockKeys,
+	}
+}
+
+// Converts attributes to send to a client, returning nil if no attributes were reported.
+func (a attributes) proto() *FileAttributes {
+	if a.size == 0 && a.mode == 0 && a.mtime == 0 && a.creator == "" && a.digest == "" && a.blockKeys == nil {
+		return nil
+	}
+
+	return &FileAttributes{
+		Size:      a.size,
+		Mode:      a.mode,
+		Mtime:     a.mtime,
+		Creator:   a.creator,
+		Digest:    a.digest,
+		BlockKeys: a.blockKeys,
+	}
+}
+
+// Checks that attributes received from a client can describe a file with the specified hash list.
+func validateAttributes(a *FileAttributes, hashList []string) error {
+	if a != nil && a.BlockKeys != nil && len(a.BlockKeys) != len(hashList) {
+		return status.Errorf(codes.InvalidArgument, "got %d block keys for %d blocks", len(a.BlockKeys), len(hashList))
+	}
+
+	return nil
+}
+
+// Describes a version of a file, by default the current one, without reading its blocks.
+func (s *MetadataStore) StatFile(ctx context.Context, req *StatFileRequest) (*StatFileResponse, error) {
+	log.WithFields(log.Fields{
+		"filename": req.Filename,
+		"version":  req.Version,
+	}).Debug("Describing file...")
+
+	if err := s.checkLeader(ctx); err != nil {
+		return nil, err
+	}
+
+	filename, err := cleanPath(req.Filename)
+	if err != nil {
+		return nil, err
+	}
+
+	st, ok, err := s.getFileMetadata(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "no such file %q", filename)
+	}
+
+	version := req.Version
+	if version == 0 {
+		version = st.version
+	}
+
+	v, ok := st.findVersion(version)
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "version %d of %q is not retained", version, filename)
+	}
+
+	return &StatFileResponse{
+		Version:    v.version,
+		ModTime:    v.modTime,
+		Deleted:    v.hashList == nil,
+		BlockSize:  v.blockSize,
+		Chunking:   v.chunking,
+		NumBlocks:  uint64(len(v.hashList)),
+		Attributes: v.attrs.proto(),
+	}, nil
+}
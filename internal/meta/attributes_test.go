@@ -0,0 +1,143 @@
+package meta
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestMetadataStore_StatFile(t *testing.T) {
+	mock := &mockClient{blocks: map[string][]byte{
+		"hash1": []byte("block1"),
+		"hash2": []byte("block2"),
+	}}
+
+	dir, err := ioutil.TempDir("", "surfs")
+	assert.Nil(t, err)
+
+	defer os.RemoveAll(dir)
+
+	engine, err := openKeychainEngine(filepath.Join(dir, MetadataFilename))
+	assert.Nil(t, err)
+
+	defer engine.close()
+
+	store := &MetadataStore{
+		client: mock,
+		engine: engine,
+	}
+	store.SetRetention(DefaultRetainVersions, 0)
+
+	_, err = store.StatFile(context.Background(), &StatFileRequest{Filename: "file1"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	attrs := &FileAttributes{
+		Size:    12,
+		Mode:    0640,
+		Mtime:   1571000000000000000,
+		Creator: "alice@host",
+		Digest:  "r7Ia+Pf1PTO7ve1Gb2RMsfFFVpJGiTzEY1bQL8BzD3k=",
+	}
+
+	expectModifyFile(store, &ModifyFileRequest{
+		Filename:   "file1",
+		Version:    1,
+		HashList:   []string{"hash1", "hash2"},
+		BlockSize:  4096,
+		Chunking:   Chunking_CONTENT_DEFINED,
+		Attributes: attrs,
+	}, &ModifyFileResponse{Success: true}, t)
+
+	// Clients that do not report attributes leave them unset.
+	expectModifyFile(store, &ModifyFileRequest{
+		Filename: "file1",
+		Version:  2,
+		HashList: []string{"hash2"},
+	}, &ModifyFileResponse{Success: true}, t)
+
+	delRes, err := store.DeleteFile(context.Background(), &DeleteFileRequest{Filename: "file1", Version: 3})
+	assert.Nil(t, err)
+	assert.True(t, delRes.Success)
+
+	res, err := store.StatFile(context.Background(), &StatFileRequest{Filename: "/file1"})
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(3), res.Version)
+	assert.True(t, res.Deleted)
+	assert.NotZero(t, res.ModTime)
+	assert.Nil(t, res.Attributes)
+
+	res, err = store.StatFile(context.Background(), &StatFileRequest{Filename: "file1", Version: 2})
+	assert.Nil(t, err)
+	assert.False(t, res.Deleted)
+	assert.Equal(t, uint64(1), res.NumBlocks)
+	assert.Nil(t, res.Attributes)
+
+	// Attributes of retained versions survive in the persistent engine.
+	res, err = store.StatFile(context.Background(), &StatFileRequest{Filename: "file1", Version: 1})
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1), res.Version)
+	assert.Equal(t, uint64(4096), res.BlockSize)
+	assert.Equal(t, Chunking_CONTENT_DEFINED, res.Chunking)
+	assert.Equal(t, uint64(2), res.NumBlocks)
+	assert.Equal(t, attrs, res.Attributes)
+
+	_, err = store.StatFile(context.Background(), &StatFileRequest{Filename: "file1", Version: 4})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestMetadataStore_BlockKeys(t *testing.T) {
+	mock := &mockClient{blocks: map[string][]byte{
+		"hash1": []byte("block1"),
+		"hash2": []byte("block2"),
+	}}
+
+	store := &MetadataStore{
+		client: mock,
+		engine: newMapEngine(),
+	}
+	store.SetRetention(DefaultRetainVersions, 0)
+
+	keys := [][]byte{[]byte("key1"), []byte("key2")}
+	expectModifyFile(store, &ModifyFileRequest{
+		Filename:   "file1",
+		Version:    1,
+		HashList:   []string{"hash1", "hash2"},
+		BlockSize:  4096,
+		Attributes: &FileAttributes{Size: 12, BlockKeys: keys},
+	}, &ModifyFileResponse{Success: true}, t)
+
+	expectReadFile(store, "file1", &ReadFileResponse{Version: 1, HashList: []string{"hash1", "hash2"}, BlockSize: 4096, BlockKeys: keys}, t)
+
+	expectModifyFile(store, &ModifyFileRequest{
+		Filename: "file1",
+		Version:  2,
+		HashList: []string{"hash2"},
+	}, &ModifyFileResponse{Success: true}, t)
+
+	expectReadFile(store, "file1", &ReadFileResponse{Version: 2, HashList: []string{"hash2"}, BlockSize: 64}, t)
+
+	// The keys of retained versions are kept with them.
+	readRes, err := store.ReadFileVersion(context.Background(), &ReadFileVersionRequest{Filename: "file1", Version: 1})
+	assert.Nil(t, err)
+	assert.Equal(t, keys, readRes.BlockKeys)
+
+	// Every block must have a key.
+	_, err = store.ModifyFile(context.Background(), &ModifyFileRequest{
+		Filename:   "file1",
+		Version:    3,
+		HashList:   []string{"hash1", "hash2"},
+		Attributes: &FileAttributes{BlockKeys: keys[:1]},
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = store.CommitBatch(context.Background(), &CommitBatchRequest{Operations: []*Operation{
+		{Type: Operation_MODIFY, Filename: "file1", Version: 3, HashList: []string{"hash1"}, Attributes: &FileAttributes{BlockKeys: keys}},
+	}})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
@@ -0,0 +1,116 @@
+package meta
+
+import (
+	"strings"
+	"surfs/internal/auth"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// A path and the access a request needs to it.
+type access struct {
+	path   string
+	access auth.Access
+}
+
+// Returns an authorization function that checks the requests to a metadata store against an access
+// control list. Requests that affect the whole store, including the Raft RPCs sent between the nodes
+// of a cluster, need write access to the root directory.
+func Authorize(acl auth.ACL) auth.AuthorizeFunc {
+	return func(principal string, req interface{}) error {
+		accesses, err := requiredAccess(req)
+		if err != nil {
+			return err
+		}
+
+		for _, a := range accesses {
+			if !acl.Allowed(principal, a.path, a.access) {
+				return status.Errorf(codes.PermissionDenied, "%s may not %s %q", principal, a.access, "/"+a.path)
+			}
+		}
+
+		return nil
+	}
+}
+
+// Returns the accesses a request needs, with normalized paths.
+func requiredAccess(req interface{}) ([]access, error) {
+	var accesses []access
+	add := func(p string, a auth.Access) error {
+		segments, err := splitPath(p)
+		if err != nil {
+			return err
+		}
+
+		accesses = append(accesses, access{path: strings.Join(segments, "/"), access: a})
+		return nil
+	}
+
+	var err error
+	switch req := req.(type) {
+	case *ReadFileRequest:
+		err = add(req.Filename, auth.Read)
+	case *GetVersionRequest:
+		err = add(req.Filename, auth.Read)
+	case *ReadFileVersionRequest:
+		err = add(req.Filename, auth.Read)
+	case *ListVersionsRequest:
+		err = add(req.Filename, auth.Read)
+	case *StatFileRequest:
+		err = add(req.Filename, auth.Read)
+	case *ListFilesRequest:
+		err = add(req.Path, auth.Read)
+	case *WatchRequest:
+		err = add(req.Prefix, auth.Read)
+	case *ModifyFileRequest:
+		err = add(req.Filename, auth.Write)
+	case *DeleteFileRequest:
+		err = add(req.Filename, auth.Write)
+	case *RenameFileRequest:
+		if err = add(req.Source, auth.Write); err == nil {
+			err = add(req.Destination, auth.Write)
+		}
+	case *CopyFileRequest:
+		if err = add(req.Source, auth.Read); err == nil {
+			err = add(req.Destination, auth.Write)
+		}
+	case *CommitBatchRequest:
+		for _, op := range req.Operations {
+			if err = addOperationAccess(op, add); err != nil {
+				break
+			}
+		}
+	case *IsLeaderRequest, *IsCrashedRequest:
+	default:
+		err = add("", auth.Write)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return accesses, nil
+}
+
+// Adds the accesses needed by an operation in a batch.
+func addOperationAccess(op *Operation, add func(string, auth.Access) error) error {
+	if op == nil {
+		return nil
+	}
+
+	switch op.Type {
+	case Operation_RENAME:
+		if err := add(op.Filename, auth.Write); err != nil {
+			return err
+		}
+		return add(op.Destination, auth.Write)
+	case Operation_COPY:
+		if err := add(op.Filename, auth.Read); err != nil {
+			return err
+		}
+		return add(op.Destination, auth.Write)
+	default:
+		return add(op.Filename, auth.Write)
+	}
+}
@@ -0,0 +1,107 @@
+package meta
+
+import (
+	"context"
+	"surfs/internal/auth"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestRequiredAccess(t *testing.T) {
+	cases := []struct {
+		req      interface{}
+		expected []access
+	}{
+		{&ReadFileRequest{Filename: "/a//b/"}, []access{{"a/b", auth.Read}}},
+		{&ListFilesRequest{Path: "/"}, []access{{"", auth.Read}}},
+		{&WatchRequest{Prefix: "a/./b"}, []access{{"a/b", auth.Read}}},
+		{&ModifyFileRequest{Filename: "a/b"}, []access{{"a/b", auth.Write}}},
+		{&DeleteFileRequest{Filename: "a/../b"}, []access{{"b", auth.Write}}},
+		{&RenameFileRequest{Source: "a", Destination: "b"}, []access{{"a", auth.Write}, {"b", auth.Write}}},
+		{&CopyFileRequest{Source: "a", Destination: "b"}, []access{{"a", auth.Read}, {"b", auth.Write}}},
+		{&CommitBatchRequest{Operations: []*Operation{
+			{Type: Operation_MODIFY, Filename: "a"},
+			{Type: Operation_COPY, Filename: "b", Destination: "c"},
+		}}, []access{{"a", auth.Write}, {"b", auth.Read}, {"c", auth.Write}}},
+		{&CollectGarbageRequest{}, []access{{"", auth.Write}}},
+		{&AppendEntriesRequest{}, []access{{"", auth.Write}}},
+		{&InstallSnapshotRequest{}, []access{{"", auth.Write}}},
+		{&IsLeaderRequest{}, nil},
+	}
+
+	for _, c := range cases {
+		accesses, err := requiredAccess(c.req)
+		assert.Nil(t, err)
+		assert.Equal(t, c.expected, accesses, "%T", c.req)
+	}
+
+	_, err := requiredAccess(&ReadFileRequest{Filename: "../a"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestMetadataStore_Authorization(t *testing.T) {
+	authn := auth.NewAuthenticator(map[string]string{
+		"alice-key": "alice",
+		"bob-key":   "bob",
+	}, nil)
+
+	acl := auth.ACL{
+		{Principal: "alice", Prefix: "", Access: auth.Write},
+		{Principal: "bob", Prefix: "shared", Access: auth.Read},
+		{Principal: "bob", Prefix: "bob", Access: auth.Write},
+	}
+
+	mock := &mockClient{blocks: map[string][]byte{"hash1": []byte("block1")}}
+	store := &MetadataStore{client: mock, engine: newMapEngine()}
+	opts := auth.ServerOptions(authn, Authorize(acl))
+
+	alice, cleanupAlice := serveTestStoreWith(t, store, opts, grpc.WithInsecure(), auth.WithToken("alice-key"))
+	defer cleanupAlice()
+
+	bob, cleanupBob := serveTestStoreWith(t, store, opts, grpc.WithInsecure(), auth.WithToken("bob-key"))
+	defer cleanupBob()
+
+	anonymous, cleanupAnonymous := serveTestStoreWith(t, store, opts, grpc.WithInsecure())
+	defer cleanupAnonymous()
+
+	ctx := context.Background()
+	modify := func(client MetadataStoreClient, filename string) error {
+		_, err := client.ModifyFile(ctx, &ModifyFileRequest{Filename: filename, Version: 1, HashList: []string{"hash1"}})
+		return err
+	}
+
+	assert.Nil(t, modify(alice, "shared/file1"))
+	assert.Nil(t, modify(bob, "/bob/file1"))
+	assert.Equal(t, codes.PermissionDenied, status.Code(modify(bob, "shared/file2")))
+	assert.Equal(t, codes.PermissionDenied, status.Code(modify(bob, "bobby/file1")))
+	assert.Equal(t, codes.Unauthenticated, status.Code(modify(anonymous, "file1")))
+
+	_, err := bob.ReadFile(ctx, &ReadFileRequest{Filename: "shared/file1"})
+	assert.Nil(t, err)
+
+	_, err = bob.ReadFile(ctx, &ReadFileRequest{Filename: "file1"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = bob.DeleteFile(ctx, &DeleteFileRequest{Filename: "shared/file1", Version: 2})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = bob.CopyFile(ctx, &CopyFileRequest{Source: "shared/file1", SourceVersion: 1, Destination: "bob/file2", DestinationVersion: 1})
+	assert.Nil(t, err)
+
+	_, err = bob.CollectGarbage(ctx, &CollectGarbageRequest{DryRun: true})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = bob.IsLeader(ctx, &IsLeaderRequest{})
+	assert.Nil(t, err)
+
+	// Streams are authorized when the request is received.
+	stream, err := bob.Watch(ctx, &WatchRequest{Prefix: "private"})
+	assert.Nil(t, err)
+
+	_, err = stream.Recv()
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
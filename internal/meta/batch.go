@@ -0,0 +1,316 @@
+package meta
+
+import (
+	"context"
+	"sort"
+	"surfs/internal/block"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// The maximum number of operations in a batch.
+const maxBatchOperations = 1000
+
+// A set of changes to file metadata that are written to the engine together. Operations are staged
+// one at a time, and each sees the changes staged before it.
+type batch struct {
+	store *MetadataStore
+
+	// The time the changes are committed, in nanoseconds since the Unix epoch.
+	now int64
+
+	// The new metadata of the changed files.
+	staged map[string]Stat
+
+	// The file that the last operation that was not staged would have conflicted with, if that is why
+	// it was not staged. See pathConflict.
+	conflict string
+}
+
+// Creates an empty batch committed at the specified time. The caller must hold the lock for as long
+// as the batch is used.
+func (s *MetadataStore) newBatch(now int64) *batch {
+	return &batch{
+		store:  s,
+		now:    now,
+		staged: make(map[string]Stat),
+	}
+}
+
+// Gets the metadata of a file as it would be once the staged changes are written.
+func (b *batch) get(filename string) (Stat, error) {
+	if st, ok := b.staged[filename]; ok {
+		return st, nil
+	}
+
+	st, _, err := b.store.engine.getFileMetadata(filename)
+	return st, err
+}
+
+// Stages an operation. Returns false if a version of the operation does not match, in which case the
+// batch must not be written.
+func (b *batch) stage(op *Operation) (bool, error) {
+	switch op.Type {
+	case Operation_MODIFY, Operation_DELETE:
+	case Operation_RENAME, Operation_COPY:
+		return b.stageTransfer(op)
+	default:
+		return false, status.Errorf(codes.InvalidArgument, "unknown operation type %v", op.Type)
+	}
+
+	st, err := b.get(op.Filename)
+	if err != nil {
+		return false, err
+	}
+
+	if op.Version != st.version+1 {
+		return false, nil
+	}
+
+	if op.Type == Operation_MODIFY && st.hashList == nil {
+		if ok, err := b.checkCreate(op.Filename); err != nil || !ok {
+			return false, err
+		}
+	}
+
+	next := Stat{
+		version: op.Version,
+		modTime: b.now,
+		history: b.store.retainedHistory(st, b.now),
+	}
+
+	// Deleting the file simply consists of setting its hash list to a nil slice, which is automatically
+	// set by the zero value of stat. An empty file has an empty hash list instead, which arrives as nil
+	// from the wire.
+	if op.Type == Operation_MODIFY {
+		next.hashList = op.HashList
+		if next.hashList == nil {
+			next.hashList = []string{}
+		}
+
+		next.blockSize = op.BlockSize
+		next.chunking = op.Chunking
+		next.attrs = attributesFromProto(op.Attributes)
+	}
+
+	b.staged[op.Filename] = next
+	return true, nil
+}
+
+// Checks that a file can be created without conflicting with another file or directory. Returns false
+// and records the conflict if not.
+func (b *batch) checkCreate(filename string) (bool, error) {
+	conflict, err := b.pathConflict(filename)
+	if err != nil {
+		return false, err
+	}
+
+	b.conflict = conflict
+	return conflict == "", nil
+}
+
+// Returns the changes the staged metadata makes to files, ordered by filename, for watches.
+func (b *batch) events() ([]*WatchEvent, error) {
+	filenames := make([]string, 0, len(b.staged))
+	for filename := range b.staged {
+		filenames = append(filenames, filename)
+	}
+	sort.Strings(filenames)
+
+	events := make([]*WatchEvent, 0, len(filenames))
+	for _, filename := range filenames {
+		prev, _, err := b.store.engine.getFileMetadata(filename)
+		if err != nil {
+			return nil, err
+		}
+
+		next := b.staged[filename]
+		event := &WatchEvent{
+			Type:     WatchEvent_MODIFY,
+			Filename: filename,
+			Version:  next.version,
+			ModTime:  next.modTime,
+		}
+
+		if next.hashList == nil {
+			event.Type = WatchEvent_DELETE
+		} else if prev.hashList == nil {
+			event.Type = WatchEvent_CREATE
+		}
+
+		events = append(events, event)
+	}
+
+	return events, nil
+}
+
+// Validates an operation received in a batch, and returns a copy with its paths normalized and
+// defaults filled in.
+func batchOperation(op *Operation) (*Operation, error) {
+	if op == nil {
+		return nil, status.Error(codes.InvalidArgument, "missing operation")
+	}
+
+	filename, err := cleanPath(op.Filename)
+	if err != nil {
+		return nil, err
+	}
+
+	normalized := &Operation{
+		Type:     op.Type,
+		Filename: filename,
+		Version:  op.Version,
+	}
+
+	switch op.Type {
+	case Operation_MODIFY:
+		if _, ok := Chunking_name[int32(op.Chunking)]; !ok {
+			return nil, status.Errorf(codes.InvalidArgument, "unknown chunking method %v", op.Chunking)
+		}
+
+		if err := validateAttributes(op.Attributes, op.HashList); err != nil {
+			return nil, err
+		}
+
+		normalized.HashList = op.HashList
+		normalized.BlockSize = op.BlockSize
+		normalized.Chunking = op.Chunking
+		normalized.Attributes = op.Attributes
+
+		// As with ModifyFile, a missing block size is the legacy block size.
+		if normalized.BlockSize == 0 {
+			normalized.BlockSize = block.LegacyBlockSize
+		}
+	case Operation_DELETE:
+	case Operation_RENAME, Operation_COPY:
+		_, destination, err := transferPaths(filename, op.Destination)
+		if err != nil {
+			return nil, err
+		}
+
+		normalized.Destination = destination
+		normalized.DestinationVersion = op.DestinationVersion
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "operation type %v cannot be batched", op.Type)
+	}
+
+	return normalized, nil
+}
+
+// Checks the operations of a batch against the current metadata. Returns false if a version does not
+// match, a NOT_FOUND status if the source of a rename or copy does not exist, and a
+// FAILED_PRECONDITION status if a file would be created in the place of a directory or the reverse.
+func (s *MetadataStore) checkBatch(ops []*Operation) (bool, error) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	b := s.newBatch(0)
+	for _, op := range ops {
+		if op.Type == Operation_RENAME || op.Type == Operation_COPY {
+			src, err := b.get(op.Filename)
+			if err != nil {
+				return false, err
+			}
+
+			if src.hashList == nil {
+				return false, status.Errorf(codes.NotFound, "no such file %q", op.Filename)
+			}
+		}
+
+		if ok, err := b.stage(op); err != nil || !ok {
+			if err == nil && b.conflict != "" {
+				target := op.Filename
+				if op.Type == Operation_RENAME || op.Type == Operation_COPY {
+					target = op.Destination
+				}
+
+				return false, pathConflictError(target, b.conflict)
+			}
+
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+// Commits several operations atomically. Either every operation succeeds, or none do.
+func (s *MetadataStore) CommitBatch(ctx context.Context, req *CommitBatchRequest) (*CommitBatchResponse, error) {
+	log.WithFields(log.Fields{
+		"operations": len(req.Operations),
+	}).Debug("Committing batch...")
+
+	if err := s.checkLeader(ctx); err != nil {
+		return nil, err
+	}
+
+	if len(req.Operations) == 0 || len(req.Operations) > maxBatchOperations {
+		return nil, status.Errorf(codes.InvalidArgument, "batch must have between 1 and %d operations", maxBatchOperations)
+	}
+
+	ops := make([]*Operation, 0, len(req.Operations))
+	for _, op := range req.Operations {
+		normalized, err := batchOperation(op)
+		if err != nil {
+			return nil, err
+		}
+
+		ops = append(ops, normalized)
+	}
+
+	ok, err := s.checkBatch(ops)
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		log.Debug("Did not commit batch; invalid file version.")
+		return &CommitBatchResponse{Success: false}, nil
+	}
+
+	// Every block of every modification must be present before any of them is committed. Blocks
+	// shared between modifications are only checked once.
+	seen := make(map[string]struct{})
+	var hashes []string
+	for _, op := range ops {
+		for _, hash := range op.HashList {
+			if _, ok := seen[hash]; !ok {
+				seen[hash] = struct{}{}
+				hashes = append(hashes, hash)
+			}
+		}
+	}
+
+	missing, err := s.missingBlocks(ctx, hashes)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(missing) > 0 {
+		log.Debugf("Did not commit batch; missing %d block(s).", len(missing))
+		return &CommitBatchResponse{Success: false, MissingHashList: missing}, nil
+	}
+
+	ok, err = s.commit(ctx, &Operation{
+		Type:      Operation_BATCH,
+		Batch:     ops,
+		Timestamp: time.Now().UnixNano(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		log.Debug("Did not commit batch; files were modified concurrently.")
+		return &CommitBatchResponse{Success: false}, nil
+	}
+
+	log.WithFields(log.Fields{
+		"operations": len(ops),
+	}).Debug("Committed batch successfully.")
+
+	return &CommitBatchResponse{Success: true}, nil
+}
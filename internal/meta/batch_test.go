@@ -0,0 +1,175 @@
+package meta
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestMetadataStore_CommitBatch(t *testing.T) {
+	mock := &mockClient{blocks: map[string][]byte{
+		"hash1": []byte("block1"),
+		"hash2": []byte("block2"),
+	}}
+
+	store := &MetadataStore{
+		client: mock,
+		engine: newMapEngine(),
+	}
+
+	expectModifyFile(store, &ModifyFileRequest{
+		Filename: "old",
+		Version:  1,
+		HashList: []string{"hash1"},
+	}, &ModifyFileResponse{Success: true}, t)
+
+	// Missing blocks of every modification are reported, once each.
+	res, err := store.CommitBatch(context.Background(), &CommitBatchRequest{Operations: []*Operation{
+		{Type: Operation_MODIFY, Filename: "manifest", Version: 1, HashList: []string{"hash3", "hash1"}},
+		{Type: Operation_MODIFY, Filename: "data", Version: 1, HashList: []string{"hash4", "hash3"}},
+	}})
+	assert.Nil(t, err)
+	assert.False(t, res.Success)
+	assert.Equal(t, []string{"hash3", "hash4"}, res.MissingHashList)
+
+	// If any version does not match, nothing is committed.
+	res, err = store.CommitBatch(context.Background(), &CommitBatchRequest{Operations: []*Operation{
+		{Type: Operation_MODIFY, Filename: "manifest", Version: 1, HashList: []string{"hash1"}},
+		{Type: Operation_DELETE, Filename: "old", Version: 1},
+	}})
+	assert.Nil(t, err)
+	assert.False(t, res.Success)
+	assert.Empty(t, res.MissingHashList)
+	expectReadFile(store, "manifest", &ReadFileResponse{}, t)
+
+	// Operations see the effects of those before them.
+	res, err = store.CommitBatch(context.Background(), &CommitBatchRequest{Operations: []*Operation{
+		{Type: Operation_MODIFY, Filename: "/tmp/manifest", Version: 1, HashList: []string{"hash1"}, BlockSize: 4096},
+		{Type: Operation_RENAME, Filename: "tmp/manifest", Version: 2, Destination: "manifest", DestinationVersion: 1},
+		{Type: Operation_MODIFY, Filename: "data", Version: 1, HashList: []string{"hash2"}},
+		{Type: Operation_COPY, Filename: "data", Version: 1, Destination: "backup", DestinationVersion: 1},
+		{Type: Operation_DELETE, Filename: "old", Version: 2},
+	}})
+	assert.Nil(t, err)
+	assert.True(t, res.Success)
+
+	expectReadFile(store, "tmp/manifest", &ReadFileResponse{Version: 2}, t)
+	expectReadFile(store, "manifest", &ReadFileResponse{Version: 1, HashList: []string{"hash1"}, BlockSize: 4096}, t)
+	expectReadFile(store, "data", &ReadFileResponse{Version: 1, HashList: []string{"hash2"}, BlockSize: 64}, t)
+	expectReadFile(store, "backup", &ReadFileResponse{Version: 1, HashList: []string{"hash2"}, BlockSize: 64}, t)
+	expectReadFile(store, "old", &ReadFileResponse{Version: 2}, t)
+
+	_, err = store.CommitBatch(context.Background(), &CommitBatchRequest{Operations: []*Operation{
+		{Type: Operation_RENAME, Filename: "missing", Version: 1, Destination: "file", DestinationVersion: 1},
+	}})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	invalid := [][]*Operation{
+		nil,
+		{{Type: Operation_NOOP, Filename: "file"}},
+		{{Type: Operation_BATCH, Filename: "file"}},
+		{{Type: Operation_MODIFY, Filename: "../file", Version: 1}},
+		{{Type: Operation_COPY, Filename: "data", Version: 1, Destination: "/data", DestinationVersion: 2}},
+		{nil},
+	}
+
+	for _, ops := range invalid {
+		_, err = store.CommitBatch(context.Background(), &CommitBatchRequest{Operations: ops})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	}
+}
+
+func TestKeychainEngine_Batch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "surfs")
+	assert.Nil(t, err)
+
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, MetadataFilename)
+	engine, err := openKeychainEngine(path)
+	assert.Nil(t, err)
+
+	assert.Nil(t, engine.setFilesMetadata(map[string]Stat{
+		"file1": {version: 1, hashList: []string{"hash1"}},
+		"file2": {version: 1, hashList: []string{"hash2"}},
+	}, logPosition{index: 3, term: 1}))
+
+	applied, err := engine.getAppliedPosition()
+	assert.Nil(t, err)
+	assert.Equal(t, logPosition{index: 3, term: 1}, applied)
+
+	// Simulate a crash after the batch key was written, but before any file or the applied index was
+	// set.
+	assert.Nil(t, engine.inner.Set([]byte(batchKey), marshalBatch(map[string]Stat{
+		"file1": {version: 2, hashList: nil},
+		"file3": {version: 1, hashList: []string{"hash3"}},
+	}, logPosition{index: 4, term: 2})))
+	assert.Nil(t, engine.close())
+
+	// The interrupted batch is written when the engine is reopened.
+	engine, err = openKeychainEngine(path)
+	assert.Nil(t, err)
+
+	defer engine.close()
+
+	expected := map[string]Stat{
+		"file1": {version: 2, hashList: nil},
+		"file2": {version: 1, hashList: []string{"hash2"}},
+		"file3": {version: 1, hashList: []string{"hash3"}},
+	}
+
+	for filename, stat := range expected {
+		got, ok, err := engine.getFileMetadata(filename)
+		assert.Nil(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, stat, got)
+	}
+
+	applied, err = engine.getAppliedPosition()
+	assert.Nil(t, err)
+	assert.Equal(t, logPosition{index: 4, term: 2}, applied)
+
+	b, err := engine.inner.Get([]byte(batchKey))
+	assert.Nil(t, err)
+	assert.Nil(t, b)
+
+	filenames, err := engine.listFilenames()
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []string{"file1", "file2", "file3"}, filenames)
+
+	_, _, err = unmarshalBatch([]byte{0, 0, 2, 1, 'a'})
+	assert.Equal(t, errMalformedBatch, err)
+}
+
+func TestRaft_CommitBatch(t *testing.T) {
+	mock := &mockClient{blocks: map[string][]byte{
+		"hash1": []byte("block1"),
+		"hash2": []byte("block2"),
+	}}
+
+	stores := newTestCluster(t, 3, mock)
+	defer closeTestCluster(stores)
+
+	leader := waitForLeader(t, stores)
+
+	res, err := leader.CommitBatch(context.Background(), &CommitBatchRequest{Operations: []*Operation{
+		{Type: Operation_MODIFY, Filename: "file1", Version: 1, HashList: []string{"hash1"}},
+		{Type: Operation_MODIFY, Filename: "file2", Version: 1, HashList: []string{"hash2"}},
+	}})
+	assert.Nil(t, err)
+	assert.True(t, res.Success)
+
+	for _, store := range stores {
+		st := waitForVersion(t, store, "file1", 1)
+		assert.Equal(t, []string{"hash1"}, st.hashList)
+
+		st = waitForVersion(t, store, "file2", 1)
+		assert.Equal(t, []string{"hash2"}, st.hashList)
+	}
+}
@@ -0,0 +1,135 @@
+package meta
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// The number of goroutines that commit to the same files at once.
+const stressWorkers = 16
+
+// A commit that succeeded, and the worker that made it.
+type win struct {
+	worker  int
+	deleted bool
+}
+
+// Has many goroutines race to commit each version of a few files, and checks that exactly one commit
+// of every version succeeded, and that the retained versions are those of the winners.
+func testConcurrentCommits(t *testing.T, store *MetadataStore, versions uint64) {
+	store.SetRetention(uint(versions), 0)
+
+	filenames := []string{"file1", "dir/file2"}
+
+	var mtx sync.Mutex
+	wins := make(map[string]map[uint64][]win, len(filenames))
+	for _, filename := range filenames {
+		wins[filename] = make(map[uint64][]win)
+	}
+
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	for w := 0; w < stressWorkers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+
+			filename := filenames[w%len(filenames)]
+			for {
+				res, err := store.GetVersion(ctx, &GetVersionRequest{Filename: filename})
+				if !assert.Nil(t, err) || res.Version >= versions {
+					return
+				}
+
+				next := res.Version + 1
+				deleted := next%4 == 0
+
+				var success bool
+				if deleted {
+					delRes, err := store.DeleteFile(ctx, &DeleteFileRequest{Filename: filename, Version: next})
+					if !assert.Nil(t, err) {
+						return
+					}
+					success = delRes.Success
+				} else {
+					modRes, err := store.ModifyFile(ctx, &ModifyFileRequest{
+						Filename: filename,
+						Version:  next,
+						HashList: []string{stressHash(w)},
+					})
+					if !assert.Nil(t, err) {
+						return
+					}
+					success = modRes.Success
+				}
+
+				if success {
+					mtx.Lock()
+					wins[filename][next] = append(wins[filename][next], win{worker: w, deleted: deleted})
+					mtx.Unlock()
+				}
+			}
+		}(w)
+	}
+
+	wg.Wait()
+
+	for _, filename := range filenames {
+		for version := uint64(1); version <= versions; version++ {
+			if !assert.Len(t, wins[filename][version], 1, "%s version %d", filename, version) {
+				continue
+			}
+
+			winner := wins[filename][version][0]
+			if winner.deleted {
+				continue
+			}
+
+			res, err := store.ReadFileVersion(ctx, &ReadFileVersionRequest{Filename: filename, Version: version})
+			assert.Nil(t, err)
+			assert.Equal(t, []string{stressHash(winner.worker)}, res.HashList, "%s version %d", filename, version)
+		}
+
+		assert.Len(t, listVersionNumbers(t, store, filename), int(versions))
+	}
+}
+
+// Returns the hash of the block that a worker's commits reference.
+func stressHash(worker int) string {
+	return fmt.Sprintf("hash%d", worker)
+}
+
+func TestMetadataStore_ConcurrentCommits(t *testing.T) {
+	blocks := make(map[string][]byte, stressWorkers)
+	for w := 0; w < stressWorkers; w++ {
+		blocks[stressHash(w)] = []byte(stressHash(w))
+	}
+
+	mock := &mockClient{blocks: blocks}
+
+	t.Run("map", func(t *testing.T) {
+		testConcurrentCommits(t, &MetadataStore{client: mock, engine: newMapEngine()}, 200)
+	})
+
+	t.Run("keychain", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "surfs")
+		assert.Nil(t, err)
+
+		defer os.RemoveAll(dir)
+
+		engine, err := openKeychainEngine(filepath.Join(dir, MetadataFilename))
+		assert.Nil(t, err)
+
+		defer engine.close()
+
+		// Every write to the keychain is synced, so fewer versions are committed.
+		testConcurrentCommits(t, &MetadataStore{client: mock, engine: engine}, 40)
+	})
+}
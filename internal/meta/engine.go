@@ -3,6 +3,7 @@ package meta
 import (
 	"bytes"
 	"encoding/binary"
+	"errors"
 
 	"github.com/maybetheresloop/keychain"
 )
@@ -13,40 +14,264 @@ type engine interface {
 	// Sets the metadata for the specified file.
 	setFileMetadata(filename string, stat Stat) error
 
+	// Sets the metadata for several files atomically, so that even after a crash, either all or none
+	// of them are set. Unless the applied index is zero, the applied position is recorded along with the
+	// metadata as the position of the last Raft log entry the metadata reflects.
+	setFilesMetadata(stats map[string]Stat, applied logPosition) error
+
+	// Returns the position of the last Raft log entry the metadata reflects, or the zero position if
+	// none was recorded.
+	getAppliedPosition() (logPosition, error)
+
 	// Gets the metadata associated with the specified file.
 	getFileMetadata(filename string) (Stat, bool, error)
+
+	// Lists the names of all files with metadata, including deleted files, in no particular order.
+	listFilenames() ([]string, error)
+
+	// Closes the engine, releasing any underlying resources.
+	close() error
 }
 
+// The key under which a batch of metadata is stored while it is being written, and the key under which
+// the applied position is stored as its index and term, both big-endian uint64s. No file can have these names, since paths cannot
+// contain NUL bytes.
+const (
+	batchKey   = "\x00batch"
+	appliedKey = "\x00applied"
+)
+
+var errMalformedBatch = errors.New("malformed metadata batch")
+
 // Implementation of the Engine interface backed by a Keychain key-value
 // store.
 type keychainEngine struct {
 	inner *keychain.Keychain
+
+	// The names of the files in the store. Keychain cannot list its keys, so they are recorded in a
+	// filename log next to the store file, and kept in memory as files are added.
+	names     *filenameLog
+	filenames map[string]struct{}
 }
 
-// Creates a Keychain engine by opening the specified Keychain store file.
+// Creates a Keychain engine by opening the specified Keychain store file and its filename log.
 func openKeychainEngine(name string) (keychainEngine, error) {
-	kc, err := keychain.Open(name)
+	kc, err := keychain.OpenConf(name, &keychain.Conf{Sync: true})
+	if err != nil {
+		return keychainEngine{}, err
+	}
+
+	names, filenames, err := openFilenameLog(name)
 	if err != nil {
-		return keychainEngine{}, nil
+		kc.Close()
+		return keychainEngine{}, err
+	}
+
+	k := keychainEngine{inner: kc, names: names, filenames: filenames}
+
+	// A crash can leave names in the log whose metadata was never set.
+	for filename := range filenames {
+		if b, err := kc.Get([]byte(filename)); err != nil {
+			k.close()
+			return keychainEngine{}, err
+		} else if b == nil {
+			delete(filenames, filename)
+		}
+	}
+
+	// Finish writing a batch that was interrupted by a crash.
+	if err := k.recoverBatch(); err != nil {
+		k.close()
+		return keychainEngine{}, err
+	}
+
+	return k, nil
+}
+
+// Records the names of the specified files in the filename log, unless they are already recorded.
+// Names must be recorded before their metadata is set.
+func (k keychainEngine) recordFilenames(filenames []string) error {
+	var added []string
+	for _, filename := range filenames {
+		if _, ok := k.filenames[filename]; !ok {
+			added = append(added, filename)
+		}
 	}
 
-	return keychainEngine{inner: kc}, nil
+	return k.names.append(added)
 }
 
 // Sets the metadata for the specified file.
-// TODO: research on how the hash list can be stored more compactly instead of wasting space
 func (k keychainEngine) setFileMetadata(filename string, stat Stat) error {
+	if err := k.recordFilenames([]string{filename}); err != nil {
+		return err
+	}
+
+	if err := k.inner.Set([]byte(filename), stat.marshal()); err != nil {
+		return err
+	}
+
+	k.filenames[filename] = struct{}{}
+	return nil
+}
+
+// Sets the metadata for several files atomically. Keychain has no transactions, so the whole batch and
+// the applied position are first written under a single key, which is removed once every file and the
+// applied position are set. If the batch key is present when the store is opened, the batch is written
+// again.
+func (k keychainEngine) setFilesMetadata(stats map[string]Stat, applied logPosition) error {
+	if len(stats) == 0 && applied.index == 0 {
+		return nil
+	}
+
+	// A single key is set atomically, so there is no need for a batch.
+	if len(stats) == 0 {
+		return k.setAppliedPosition(applied)
+	}
+
+	if len(stats) == 1 && applied.index == 0 {
+		for filename, stat := range stats {
+			return k.setFileMetadata(filename, stat)
+		}
+	}
+
+	filenames := make([]string, 0, len(stats))
+	for filename := range stats {
+		filenames = append(filenames, filename)
+	}
+
+	if err := k.recordFilenames(filenames); err != nil {
+		return err
+	}
+
+	if err := k.inner.Set([]byte(batchKey), marshalBatch(stats, applied)); err != nil {
+		return err
+	}
+
+	return k.writeBatch(stats, applied)
+}
+
+// Sets the metadata of each file in a batch and the applied position, unless its index is zero, then
+// removes the batch key.
+func (k keychainEngine) writeBatch(stats map[string]Stat, applied logPosition) error {
+	for filename, stat := range stats {
+		if err := k.setFileMetadata(filename, stat); err != nil {
+			return err
+		}
+	}
+
+	if applied.index != 0 {
+		if err := k.setAppliedPosition(applied); err != nil {
+			return err
+		}
+	}
+
+	_, err := k.inner.Remove([]byte(batchKey))
+	return err
+}
+
+func (k keychainEngine) setAppliedPosition(applied logPosition) error {
+	var b [16]byte
+	binary.BigEndian.PutUint64(b[:8], applied.index)
+	binary.BigEndian.PutUint64(b[8:], applied.term)
+	return k.inner.Set([]byte(appliedKey), b[:])
+}
+
+func (k keychainEngine) getAppliedPosition() (logPosition, error) {
+	b, err := k.inner.Get([]byte(appliedKey))
+	if err != nil || b == nil {
+		return logPosition{}, err
+	}
+
+	if len(b) != 16 {
+		return logPosition{}, errMalformedBatch
+	}
+
+	return logPosition{index: binary.BigEndian.Uint64(b[:8]), term: binary.BigEndian.Uint64(b[8:])}, nil
+}
+
+// Writes the batch left under the batch key, if any.
+func (k keychainEngine) recoverBatch() error {
+	b, err := k.inner.Get([]byte(batchKey))
+	if err != nil || b == nil {
+		return err
+	}
+
+	stats, applied, err := unmarshalBatch(b)
+	if err != nil {
+		return err
+	}
+
+	return k.writeBatch(stats, applied)
+}
+
+// Encodes a batch of metadata as the index and term of the applied position and the number of files as
+// uvarints, followed by each filename and its encoded Stat, both prefixed by their length as a uvarint.
+func marshalBatch(stats map[string]Stat, applied logPosition) []byte {
 	var buf bytes.Buffer
+	var scratch [binary.MaxVarintLen64]byte
+
+	for _, v := range []uint64{applied.index, applied.term, uint64(len(stats))} {
+		n := binary.PutUvarint(scratch[:], v)
+		buf.Write(scratch[:n])
+	}
+
+	for filename, stat := range stats {
+		for _, b := range [][]byte{[]byte(filename), stat.marshal()} {
+			n := binary.PutUvarint(scratch[:], uint64(len(b)))
+			buf.Write(scratch[:n])
+			buf.Write(b)
+		}
+	}
+
+	return buf.Bytes()
+}
+
+// Decodes a batch of metadata previously encoded with marshalBatch, returning the metadata and the
+// applied position.
+func unmarshalBatch(b []byte) (map[string]Stat, logPosition, error) {
+	r := bytes.NewReader(b)
+
+	var applied logPosition
+	var err error
+	if applied.index, err = binary.ReadUvarint(r); err != nil {
+		return nil, logPosition{}, errMalformedBatch
+	}
+
+	if applied.term, err = binary.ReadUvarint(r); err != nil {
+		return nil, logPosition{}, errMalformedBatch
+	}
+
+	count, err := binary.ReadUvarint(r)
+	if err != nil || count > uint64(r.Len()) {
+		return nil, logPosition{}, errMalformedBatch
+	}
+
+	stats := make(map[string]Stat, count)
+	for i := uint64(0); i < count; i++ {
+		filename, err := readString(r)
+		if err != nil {
+			return nil, logPosition{}, errMalformedBatch
+		}
+
+		encoded, err := readString(r)
+		if err != nil {
+			return nil, logPosition{}, errMalformedBatch
+		}
+
+		stat, err := unmarshalStat([]byte(encoded))
+		if err != nil {
+			return nil, logPosition{}, err
+		}
 
-	var versionBytes [8]byte
-	binary.BigEndian.PutUint64(versionBytes[:], stat.version)
-	buf.Write(versionBytes[:])
+		stats[filename] = stat
+	}
 
-	for _, hash := range stat.hashList {
-		buf.WriteString(hash)
+	if r.Len() != 0 {
+		return nil, logPosition{}, errMalformedBatch
 	}
 
-	return k.inner.Set([]byte(filename), buf.Bytes())
+	return stats, applied, nil
 }
 
 func (k keychainEngine) getFileMetadata(filename string) (Stat, bool, error) {
@@ -59,34 +284,77 @@ func (k keychainEngine) getFileMetadata(filename string) (Stat, bool, error) {
 		return Stat{}, false, nil
 	}
 
-	stat := Stat{}
+	stat, err := unmarshalStat(b)
+	if err != nil {
+		return Stat{}, false, err
+	}
 
-	// Each hash is 32 bytes long, and the header is 8 bytes.
-	numHashes := (len(b) - 8) / 32
-	stat.version = binary.BigEndian.Uint64(b[:8])
-	stat.hashList = make([]string, 0, numHashes)
+	return stat, true, nil
+}
 
-	b = b[8:]
-	for i := 0; i < numHashes; i++ {
-		stat.hashList = append(stat.hashList, string(b[i*8:i*8+8]))
+func (k keychainEngine) listFilenames() ([]string, error) {
+	filenames := make([]string, 0, len(k.filenames))
+	for filename := range k.filenames {
+		filenames = append(filenames, filename)
 	}
 
-	return Stat{}, true, nil
+	return filenames, nil
+}
+
+func (k keychainEngine) close() error {
+	err := k.inner.Close()
+	if closeErr := k.names.close(); err == nil {
+		err = closeErr
+	}
+
+	return err
 }
 
 // Implementation of the Engine interface backed by a regular Go map.
-type mapEngine map[string]Stat
+type mapEngine struct {
+	files   map[string]Stat
+	applied logPosition
+}
 
-func newMapEngine() mapEngine {
-	return make(map[string]Stat)
+func newMapEngine() *mapEngine {
+	return &mapEngine{files: make(map[string]Stat)}
+}
+
+func (m *mapEngine) setFileMetadata(filename string, stat Stat) error {
+	m.files[filename] = stat
+	return nil
 }
 
-func (m mapEngine) setFileMetadata(filename string, stat Stat) error {
-	m[filename] = stat
+func (m *mapEngine) setFilesMetadata(stats map[string]Stat, applied logPosition) error {
+	for filename, stat := range stats {
+		m.files[filename] = stat
+	}
+
+	if applied.index != 0 {
+		m.applied = applied
+	}
+
 	return nil
 }
 
-func (m mapEngine) getFileMetadata(filename string) (Stat, bool, error) {
-	stat, ok := m[filename]
+func (m *mapEngine) getAppliedPosition() (logPosition, error) {
+	return m.applied, nil
+}
+
+func (m *mapEngine) getFileMetadata(filename string) (Stat, bool, error) {
+	stat, ok := m.files[filename]
 	return stat, ok, nil
 }
+
+func (m *mapEngine) listFilenames() ([]string, error) {
+	filenames := make([]string, 0, len(m.files))
+	for filename := range m.files {
+		filenames = append(filenames, filename)
+	}
+
+	return filenames, nil
+}
+
+func (m *mapEngine) close() error {
+	return nil
+}
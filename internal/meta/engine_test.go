@@ -0,0 +1,200 @@
+package meta
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStat_Marshal(t *testing.T) {
+	stats := []Stat{
+		{version: 1, hashList: []string{"hash1", "hash2"}},
+		{version: 2, hashList: []string{}},
+		{version: 3, hashList: nil},
+		{version: 1 << 40, hashList: []string{"r7Ia+Pf1PTO7ve1Gb2RMsfFFVpJGiTzEY1bQL8BzD3k="}},
+		{version: 4, hashList: []string{"hash1"}, blockSize: 1 << 20},
+		{version: 5, hashList: []string{"hash1"}, blockSize: 4096, chunking: Chunking_CONTENT_DEFINED},
+		{version: 6, hashList: []string{"hash2"}, blockSize: 4096, modTime: 1571000000000000000, history: []Stat{
+			{version: 5, hashList: nil, modTime: 1570000000000000000},
+			{version: 4, hashList: []string{"hash1"}, blockSize: 64, modTime: -1},
+		}},
+		{version: 7, hashList: []string{"hash1"}, blockSize: 4096, modTime: 1572000000000000000, attrs: attributes{
+			size:    4000,
+			mode:    0644,
+			mtime:   1571500000000000000,
+			creator: "alice@host",
+			digest:  "r7Ia+Pf1PTO7ve1Gb2RMsfFFVpJGiTzEY1bQL8BzD3k=",
+		}},
+		{version: 8, hashList: []string{"hash1", "hash2"}, blockSize: 4096, modTime: 1573000000000000000, attrs: attributes{
+			size:      5000,
+			blockKeys: [][]byte{[]byte("key1"), []byte("key2")},
+		}},
+	}
+
+	for _, stat := range stats {
+		got, err := unmarshalStat(stat.marshal())
+		assert.Nil(t, err)
+		assert.Equal(t, stat, got)
+	}
+
+	_, err := unmarshalStat([]byte{0, 0, 0})
+	assert.Equal(t, errMalformedStat, err)
+
+	// Metadata in an unknown encoding should be rejected.
+	b := Stat{version: 1, hashList: []string{"hash1"}}.marshal()
+	b[0] = statEncodingVersion + 1
+	_, err = unmarshalStat(b)
+	assert.NotNil(t, err)
+
+	// Truncating the metadata anywhere, or appending to it, should be detected.
+	b = stats[len(stats)-1].marshal()
+	for i := range b {
+		_, err = unmarshalStat(b[:i])
+		assert.NotNil(t, err, i)
+	}
+
+	_, err = unmarshalStat(append(b, 0))
+	assert.Equal(t, errMalformedStat, err)
+
+	// Truncating a previous version should be detected.
+	b = stats[6].marshal()
+	for i := range b {
+		_, err = unmarshalStat(b[:i])
+		assert.NotNil(t, err, i)
+	}
+}
+
+func TestKeychainEngine(t *testing.T) {
+	dir, err := ioutil.TempDir("", "surfs")
+	assert.Nil(t, err)
+
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, MetadataFilename)
+	engine, err := openKeychainEngine(path)
+	assert.Nil(t, err)
+
+	assert.Nil(t, engine.setFileMetadata("file1", Stat{version: 1, hashList: []string{"hash1", "hash2"}}))
+	assert.Nil(t, engine.setFileMetadata("file1", Stat{version: 2, hashList: []string{"hash3"}}))
+	assert.Nil(t, engine.setFileMetadata("file2", Stat{version: 1, hashList: []string{"hash1"}}))
+	assert.Nil(t, engine.setFileMetadata("file2", Stat{version: 2, hashList: nil}))
+
+	expect := func(engine keychainEngine) {
+		stat, ok, err := engine.getFileMetadata("file1")
+		assert.Nil(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, Stat{version: 2, hashList: []string{"hash3"}}, stat)
+
+		// Deleted files are kept as tombstones.
+		stat, ok, err = engine.getFileMetadata("file2")
+		assert.Nil(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, Stat{version: 2, hashList: nil}, stat)
+
+		stat, ok, err = engine.getFileMetadata("file3")
+		assert.Nil(t, err)
+		assert.False(t, ok)
+		assert.Equal(t, Stat{}, stat)
+
+		filenames, err := engine.listFilenames()
+		assert.Nil(t, err)
+		assert.ElementsMatch(t, []string{"file1", "file2"}, filenames)
+	}
+
+	expect(engine)
+	assert.Nil(t, engine.close())
+
+	// The metadata should survive reopening the engine.
+	engine, err = openKeychainEngine(path)
+	assert.Nil(t, err)
+
+	defer engine.close()
+
+	expect(engine)
+}
+
+func TestKeychainEngine_TruncatedFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "surfs")
+	assert.Nil(t, err)
+
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, MetadataFilename)
+	engine, err := openKeychainEngine(path)
+	assert.Nil(t, err)
+
+	assert.Nil(t, engine.setFileMetadata("file1", Stat{version: 1, hashList: []string{"hash1"}}))
+	assert.Nil(t, engine.setFileMetadata("file2", Stat{version: 1, hashList: []string{"hash2"}}))
+	assert.Nil(t, engine.close())
+
+	// A write interrupted by a crash leaves an incomplete item at the end of the file, which is
+	// ignored.
+	info, err := os.Stat(path)
+	assert.Nil(t, err)
+	assert.Nil(t, os.Truncate(path, info.Size()-1))
+
+	engine, err = openKeychainEngine(path)
+	assert.Nil(t, err)
+
+	defer engine.close()
+
+	filenames, err := engine.listFilenames()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"file1"}, filenames)
+}
+
+func TestKeychainEngine_FilenameLog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "surfs")
+	assert.Nil(t, err)
+
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, MetadataFilename)
+	engine, err := openKeychainEngine(path)
+	assert.Nil(t, err)
+
+	assert.Nil(t, engine.setFileMetadata("file1", Stat{version: 1, hashList: []string{"hash1"}}))
+	assert.Nil(t, engine.setFilesMetadata(map[string]Stat{
+		"file2": {version: 1, hashList: []string{"hash2"}},
+		"file3": {version: 1, hashList: []string{"hash3"}},
+	}, logPosition{}))
+
+	// A name recorded before a crash prevented its metadata from being set is not listed.
+	assert.Nil(t, engine.recordFilenames([]string{"file4"}))
+	assert.Nil(t, engine.close())
+
+	// A record torn by a crash is discarded.
+	f, err := os.OpenFile(path+filenameLogSuffix, os.O_WRONLY|os.O_APPEND, 0644)
+	assert.Nil(t, err)
+	_, err = f.Write([]byte{5, 'f', 'i'})
+	assert.Nil(t, err)
+	assert.Nil(t, f.Close())
+
+	engine, err = openKeychainEngine(path)
+	assert.Nil(t, err)
+
+	filenames, err := engine.listFilenames()
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []string{"file1", "file2", "file3"}, filenames)
+
+	// Names recorded after the torn record are read back.
+	assert.Nil(t, engine.setFileMetadata("file4", Stat{version: 1, hashList: []string{"hash4"}}))
+	assert.Nil(t, engine.close())
+
+	engine, err = openKeychainEngine(path)
+	assert.Nil(t, err)
+
+	defer engine.close()
+
+	filenames, err = engine.listFilenames()
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []string{"file1", "file2", "file3", "file4"}, filenames)
+}
+
+func TestOpenKeychainEngine_Error(t *testing.T) {
+	_, err := openKeychainEngine(filepath.Join(os.DevNull, "meta.keychain"))
+	assert.NotNil(t, err)
+}
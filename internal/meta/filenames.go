@@ -0,0 +1,153 @@
+package meta
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Suffix of the file, next to a Keychain store file, that records the names of the files in the
+// store. Keychain cannot list its keys, so the engine keeps its own record of them.
+const filenameLogSuffix = ".names"
+
+// Longest filename read back from the filename log, which protects against allocating huge buffers for
+// a corrupted length.
+const maxFilenameSize = 1 << 20
+
+var errMalformedFilenameLog = errors.New("malformed filename log")
+
+// An append-only log of the names of the files in a Keychain store. Each record is the length of the
+// name as a uvarint, the name, and the CRC-32 of the name. A name is appended and synced before its
+// metadata is first set, so the log holds every name in the store, and possibly names whose metadata
+// was never set because of a crash. A record torn by a crash is discarded when the log is opened.
+type filenameLog struct {
+	f    *os.File
+	size int64
+}
+
+// Opens the filename log of the specified Keychain store file, creating it if it does not exist, and
+// returns the names it holds.
+func openFilenameLog(name string) (*filenameLog, map[string]struct{}, error) {
+	f, err := os.OpenFile(name+filenameLogSuffix, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// A log created after the store file must not be lost by a crash while the store file survives.
+	if err := syncDir(filepath.Dir(name)); err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+
+	l := &filenameLog{f: f}
+	filenames, err := l.load()
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+
+	return l, filenames, nil
+}
+
+// Reads the names in the log, truncating it after the last complete record.
+func (l *filenameLog) load() (map[string]struct{}, error) {
+	filenames := make(map[string]struct{})
+	r := bufio.NewReader(l.f)
+
+	for {
+		filename, n, err := readFilenameRecord(r)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			log.WithFields(log.Fields{
+				"path":   l.f.Name(),
+				"offset": l.size,
+			}).Warnf("Discarding the end of the filename log, %v", err)
+
+			if err := l.f.Truncate(l.size); err != nil {
+				return nil, err
+			}
+
+			if err := l.f.Sync(); err != nil {
+				return nil, err
+			}
+
+			break
+		}
+
+		filenames[filename] = struct{}{}
+		l.size += n
+	}
+
+	return filenames, nil
+}
+
+// Appends the specified names to the log and syncs it.
+func (l *filenameLog) append(filenames []string) error {
+	if len(filenames) == 0 {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	for _, filename := range filenames {
+		writeFilenameRecord(&buf, filename)
+	}
+
+	if _, err := l.f.WriteAt(buf.Bytes(), l.size); err != nil {
+		return err
+	}
+
+	if err := l.f.Sync(); err != nil {
+		return err
+	}
+
+	l.size += int64(buf.Len())
+	return nil
+}
+
+func (l *filenameLog) close() error {
+	return l.f.Close()
+}
+
+// Writes a filename as a record of the filename log.
+func writeFilenameRecord(buf *bytes.Buffer, filename string) {
+	var scratch [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(scratch[:], uint64(len(filename)))
+	buf.Write(scratch[:n])
+	buf.WriteString(filename)
+
+	binary.BigEndian.PutUint32(scratch[:4], crc32.ChecksumIEEE([]byte(filename)))
+	buf.Write(scratch[:4])
+}
+
+// Reads a record of the filename log, and returns the filename and the size of the record. Returns
+// io.EOF at the end of the file, and another error if the record is incomplete or corrupted.
+func readFilenameRecord(r *bufio.Reader) (string, int64, error) {
+	if _, err := r.Peek(1); err == io.EOF {
+		return "", 0, io.EOF
+	}
+
+	size, err := binary.ReadUvarint(r)
+	if err != nil || size > maxFilenameSize {
+		return "", 0, errMalformedFilenameLog
+	}
+
+	b := make([]byte, size+4)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return "", 0, errMalformedFilenameLog
+	}
+
+	if crc32.ChecksumIEEE(b[:size]) != binary.BigEndian.Uint32(b[size:]) {
+		return "", 0, errMalformedFilenameLog
+	}
+
+	var scratch [binary.MaxVarintLen64]byte
+	return string(b[:size]), int64(binary.PutUvarint(scratch[:], size)) + int64(size) + 4, nil
+}
@@ -0,0 +1,147 @@
+package meta
+
+import (
+	"context"
+	"io"
+	"surfs/internal/block"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Maximum number of hashes sent to the block store in a single DeleteBlocks request.
+const deleteBlocksBatchSize = 4096
+
+// How long blocks are kept after they were last stored or checked for, unless another grace period is
+// requested. Uploading the blocks of a file and committing it must take less time than this.
+const DefaultGCGracePeriod = time.Hour
+
+// Returns the set of hashes referenced by any file, including by retained previous versions that have
+// not expired.
+func (s *MetadataStore) liveHashes() (map[string]struct{}, error) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	now := time.Now().UnixNano()
+
+	filenames, err := s.engine.listFilenames()
+	if err != nil {
+		return nil, err
+	}
+
+	live := make(map[string]struct{})
+	for _, filename := range filenames {
+		st, _, err := s.engine.getFileMetadata(filename)
+		if err != nil {
+			return nil, err
+		}
+
+		st = s.unexpired(st, now)
+		for _, hash := range st.hashList {
+			live[hash] = struct{}{}
+		}
+
+		for _, prev := range st.history {
+			for _, hash := range prev.hashList {
+				live[hash] = struct{}{}
+			}
+		}
+	}
+
+	return live, nil
+}
+
+// Returns the hashes of the blocks in the block store that have not been stored or checked for since
+// the specified time.
+func (s *MetadataStore) listOldBlocks(ctx context.Context, olderThan time.Time) ([]string, error) {
+	stream, err := s.client.ListBlocks(ctx, &block.ListBlocksRequest{OlderThan: olderThan.UnixNano()})
+	if err != nil {
+		return nil, err
+	}
+
+	var hashes []string
+	for {
+		res, err := stream.Recv()
+		if err == io.EOF {
+			return hashes, nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		hashes = append(hashes, res.Hashes...)
+	}
+}
+
+// Deletes the blocks in the block store that are not referenced by any file. This is a mark-and-sweep
+// collection: the block store lists the blocks older than the grace period, the blocks referenced by
+// files are marked, and the block store deletes the rest. A block that a client checks for or stores
+// while the collection runs is marked as recently used by the block store, which then refuses to
+// delete it, so blocks are never collected out from under a file that is about to reference them.
+func (s *MetadataStore) CollectGarbage(ctx context.Context, req *CollectGarbageRequest) (*CollectGarbageResponse, error) {
+	if err := s.checkLeader(ctx); err != nil {
+		return nil, err
+	}
+
+	gracePeriod := time.Duration(req.GracePeriod)
+	if gracePeriod <= 0 {
+		gracePeriod = DefaultGCGracePeriod
+	}
+
+	olderThan := time.Now().Add(-gracePeriod)
+
+	log.WithFields(log.Fields{
+		"olderThan": olderThan,
+		"dryRun":    req.DryRun,
+	}).Debug("Collecting garbage...")
+
+	candidates, err := s.listOldBlocks(ctx, olderThan)
+	if err != nil {
+		return nil, err
+	}
+
+	live, err := s.liveHashes()
+	if err != nil {
+		return nil, err
+	}
+
+	garbage := make([]string, 0, len(candidates))
+	for _, hash := range candidates {
+		if _, ok := live[hash]; !ok {
+			garbage = append(garbage, hash)
+		}
+	}
+
+	res := &CollectGarbageResponse{
+		Candidates:   uint64(len(candidates)),
+		Unreferenced: uint64(len(garbage)),
+	}
+
+	if req.DryRun {
+		return res, nil
+	}
+
+	for start := 0; start < len(garbage); start += deleteBlocksBatchSize {
+		end := start + deleteBlocksBatchSize
+		if end > len(garbage) {
+			end = len(garbage)
+		}
+
+		delRes, err := s.client.DeleteBlocks(ctx, &block.DeleteBlocksRequest{
+			Hashes:    garbage[start:end],
+			OlderThan: olderThan.UnixNano(),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		res.Deleted += uint64(len(delRes.Deleted))
+	}
+
+	log.WithFields(log.Fields{
+		"candidates":   res.Candidates,
+		"unreferenced": res.Unreferenced,
+		"deleted":      res.Deleted,
+	}).Debug("Collected garbage.")
+
+	return res, nil
+}
@@ -0,0 +1,134 @@
+package meta
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"io/ioutil"
+	"net"
+	"os"
+	"surfs/internal/block"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// Creates a metadata store backed by a real block store in a temporary directory, served over an
+// in-memory connection.
+func newGCTestStore(t *testing.T) (*MetadataStore, block.StoreClient, func()) {
+	dir, err := ioutil.TempDir("", "surfs")
+	assert.Nil(t, err)
+
+	bs, err := block.NewStore(dir)
+	assert.Nil(t, err)
+
+	lis := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	block.RegisterStoreServer(server, bs)
+
+	go server.Serve(lis)
+
+	conn, err := grpc.Dial("bufconn", grpc.WithInsecure(), grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+		return lis.Dial()
+	}))
+	assert.Nil(t, err)
+
+	client := block.NewStoreClient(conn)
+	store := &MetadataStore{
+		client: client,
+		engine: newMapEngine(),
+	}
+
+	return store, client, func() {
+		conn.Close()
+		server.Stop()
+		bs.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+// Stores a block, returning its hash.
+func storeTestBlock(t *testing.T, client block.StoreClient, b []byte) string {
+	sha := sha256.Sum256(b)
+	hash := base64.StdEncoding.EncodeToString(sha[:])
+
+	_, err := client.StoreBlock(context.Background(), &block.StoreBlockRequest{Block: b, Hash: hash})
+	assert.Nil(t, err)
+
+	return hash
+}
+
+func TestMetadataStore_CollectGarbage(t *testing.T) {
+	store, client, cleanup := newGCTestStore(t)
+	defer cleanup()
+
+	shared := storeTestBlock(t, client, []byte("shared"))
+	old := storeTestBlock(t, client, []byte("old"))
+	deleted := storeTestBlock(t, client, []byte("deleted"))
+	orphan := storeTestBlock(t, client, []byte("orphan"))
+
+	modify := func(filename string, version uint64, hashList []string) {
+		res, err := store.ModifyFile(context.Background(), &ModifyFileRequest{Filename: filename, Version: version, HashList: hashList})
+		assert.Nil(t, err)
+		assert.True(t, res.Success)
+	}
+
+	modify("file1", 1, []string{shared, old})
+	modify("file1", 2, []string{shared})
+	modify("file2", 1, []string{deleted})
+
+	delRes, err := store.DeleteFile(context.Background(), &DeleteFileRequest{Filename: "file2", Version: 2})
+	assert.Nil(t, err)
+	assert.True(t, delRes.Success)
+
+	// Every block was used within the default grace period, so nothing is collected.
+	res, err := store.CollectGarbage(context.Background(), &CollectGarbageRequest{})
+	assert.Nil(t, err)
+	assert.Equal(t, &CollectGarbageResponse{}, res)
+
+	// Wait until the blocks are older than a short grace period.
+	time.Sleep(20 * time.Millisecond)
+	gracePeriod := int64(10 * time.Millisecond)
+
+	res, err = store.CollectGarbage(context.Background(), &CollectGarbageRequest{GracePeriod: gracePeriod, DryRun: true})
+	assert.Nil(t, err)
+	assert.Equal(t, &CollectGarbageResponse{Candidates: 4, Unreferenced: 3}, res)
+
+	res, err = store.CollectGarbage(context.Background(), &CollectGarbageRequest{GracePeriod: gracePeriod})
+	assert.Nil(t, err)
+	assert.Equal(t, &CollectGarbageResponse{Candidates: 4, Unreferenced: 3, Deleted: 3}, res)
+
+	has, err := client.HasBlocks(context.Background(), &block.HasBlocksRequest{Hashes: []string{shared, old, deleted, orphan}})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{old, deleted, orphan}, has.Missing)
+
+	// The file still referencing the shared block can be read back in full.
+	getRes, err := client.GetBlock(context.Background(), &block.GetBlockRequest{Hash: shared})
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("shared"), getRes.Block)
+}
+
+func TestMetadataStore_CollectGarbageSparesRecentlyChecked(t *testing.T) {
+	store, client, cleanup := newGCTestStore(t)
+	defer cleanup()
+
+	orphan := storeTestBlock(t, client, []byte("orphan"))
+
+	time.Sleep(20 * time.Millisecond)
+
+	// A client about to commit a file referencing the block checks for it, which protects it
+	// from collection until the grace period has passed again.
+	_, err := client.HasBlocks(context.Background(), &block.HasBlocksRequest{Hashes: []string{orphan}})
+	assert.Nil(t, err)
+
+	gcRes, err := store.CollectGarbage(context.Background(), &CollectGarbageRequest{GracePeriod: int64(10 * time.Millisecond)})
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(0), gcRes.Deleted)
+
+	modRes, err := store.ModifyFile(context.Background(), &ModifyFileRequest{Filename: "file1", Version: 1, HashList: []string{orphan}})
+	assert.Nil(t, err)
+	assert.True(t, modRes.Success)
+}
@@ -0,0 +1,97 @@
+package meta
+
+import (
+	"context"
+
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Returns the version of a file with the specified version number, which is either the current version
+// or a retained previous version.
+func (st Stat) findVersion(version uint64) (Stat, bool) {
+	if st.version == version {
+		return st, true
+	}
+
+	for _, prev := range st.history {
+		if prev.version == version {
+			return prev, true
+		}
+	}
+
+	return Stat{}, false
+}
+
+// Reads a retained version of a file. The response is the same as ReadFile's for that version.
+func (s *MetadataStore) ReadFileVersion(ctx context.Context, req *ReadFileVersionRequest) (*ReadFileResponse, error) {
+	log.WithFields(log.Fields{
+		"filename": req.Filename,
+		"version":  req.Version,
+	}).Debug("Reading file version...")
+
+	if err := s.checkLeader(ctx); err != nil {
+		return nil, err
+	}
+
+	filename, err := cleanPath(req.Filename)
+	if err != nil {
+		return nil, err
+	}
+
+	st, _, err := s.getFileMetadata(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	// Version 0 is never a real version, even though the zero value of a missing file's Stat has it.
+	v, ok := st.findVersion(req.Version)
+	if !ok || req.Version == 0 {
+		return nil, status.Errorf(codes.NotFound, "version %d of %q is not retained", req.Version, filename)
+	}
+
+	return &ReadFileResponse{
+		Version:   v.version,
+		HashList:  v.hashList,
+		BlockSize: v.blockSize,
+		Chunking:  v.chunking,
+		BlockKeys: v.attrs.blockKeys,
+		Exists:    v.hashList != nil,
+	}, nil
+}
+
+// Lists the current version of a file followed by its retained previous versions, newest first. A file
+// that has never existed has no versions.
+func (s *MetadataStore) ListVersions(ctx context.Context, req *ListVersionsRequest) (*ListVersionsResponse, error) {
+	if err := s.checkLeader(ctx); err != nil {
+		return nil, err
+	}
+
+	filename, err := cleanPath(req.Filename)
+	if err != nil {
+		return nil, err
+	}
+
+	st, ok, err := s.getFileMetadata(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &ListVersionsResponse{}
+	if !ok {
+		return res, nil
+	}
+
+	res.Versions = make([]*FileVersion, 0, len(st.history)+1)
+	for _, v := range append([]Stat{st}, st.history...) {
+		res.Versions = append(res.Versions, &FileVersion{
+			Version:   v.version,
+			ModTime:   v.modTime,
+			Deleted:   v.hashList == nil,
+			NumBlocks: uint64(len(v.hashList)),
+		})
+	}
+
+	return res, nil
+}
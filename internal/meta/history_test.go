@@ -0,0 +1,185 @@
+package meta
+
+import (
+	"context"
+	"surfs/internal/block"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Returns the version numbers listed for a file.
+func listVersionNumbers(t *testing.T, store *MetadataStore, filename string) []uint64 {
+	res, err := store.ListVersions(context.Background(), &ListVersionsRequest{Filename: filename})
+	assert.Nil(t, err)
+
+	versions := make([]uint64, 0, len(res.Versions))
+	for _, v := range res.Versions {
+		versions = append(versions, v.Version)
+	}
+
+	return versions
+}
+
+func TestMetadataStore_ListVersions(t *testing.T) {
+	mock := &mockClient{blocks: map[string][]byte{
+		"hash1": []byte("block1"),
+		"hash2": []byte("block2"),
+	}}
+
+	store := &MetadataStore{
+		client: mock,
+		engine: newMapEngine(),
+	}
+	store.SetRetention(3, 0)
+
+	assert.Empty(t, listVersionNumbers(t, store, "file1"))
+
+	for version := uint64(1); version <= 4; version++ {
+		expectModifyFile(store, &ModifyFileRequest{
+			Filename:  "file1",
+			Version:   version,
+			HashList:  []string{"hash1"},
+			BlockSize: version,
+		}, &ModifyFileResponse{Success: true}, t)
+	}
+
+	expectModifyFile(store, &ModifyFileRequest{
+		Filename: "file1",
+		Version:  5,
+		HashList: []string{"hash2", "hash1"},
+	}, &ModifyFileResponse{Success: true}, t)
+
+	delRes, err := store.DeleteFile(context.Background(), &DeleteFileRequest{Filename: "file1", Version: 6})
+	assert.Nil(t, err)
+	assert.True(t, delRes.Success)
+
+	// Only the three most recent previous versions are retained.
+	res, err := store.ListVersions(context.Background(), &ListVersionsRequest{Filename: "file1"})
+	assert.Nil(t, err)
+	assert.Len(t, res.Versions, 4)
+
+	assert.Equal(t, uint64(6), res.Versions[0].Version)
+	assert.True(t, res.Versions[0].Deleted)
+	assert.Equal(t, uint64(5), res.Versions[1].Version)
+	assert.False(t, res.Versions[1].Deleted)
+	assert.Equal(t, uint64(2), res.Versions[1].NumBlocks)
+	assert.Equal(t, []uint64{6, 5, 4, 3}, listVersionNumbers(t, store, "file1"))
+
+	for _, v := range res.Versions {
+		assert.NotZero(t, v.ModTime)
+	}
+
+	// Retained versions can be read back with their layout.
+	readRes, err := store.ReadFileVersion(context.Background(), &ReadFileVersionRequest{Filename: "file1", Version: 3})
+	assert.Nil(t, err)
+	assert.Equal(t, &ReadFileResponse{Version: 3, HashList: []string{"hash1"}, BlockSize: 3, Exists: true}, readRes)
+
+	for _, version := range []uint64{0, 2, 7} {
+		_, err = store.ReadFileVersion(context.Background(), &ReadFileVersionRequest{Filename: "file1", Version: version})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	}
+
+	// Restoring a version is just modifying the file to point at its hash list again.
+	expectModifyFile(store, &ModifyFileRequest{
+		Filename:  "file1",
+		Version:   7,
+		HashList:  readRes.HashList,
+		BlockSize: readRes.BlockSize,
+	}, &ModifyFileResponse{Success: true}, t)
+
+	expectReadFile(store, "file1", &ReadFileResponse{Version: 7, HashList: []string{"hash1"}, BlockSize: 3}, t)
+	assert.Equal(t, []uint64{7, 6, 5, 4}, listVersionNumbers(t, store, "file1"))
+}
+
+func TestMetadataStore_RetentionAge(t *testing.T) {
+	store := &MetadataStore{engine: newMapEngine()}
+	store.SetRetention(DefaultRetainVersions, time.Hour)
+
+	start := time.Now()
+	apply := func(version uint64, at time.Duration) {
+		ok, err := store.apply(logPosition{}, &Operation{
+			Type:      Operation_MODIFY,
+			Filename:  "file1",
+			Version:   version,
+			HashList:  []string{"hash1"},
+			Timestamp: start.Add(at).UnixNano(),
+		})
+		assert.Nil(t, err)
+		assert.True(t, ok)
+	}
+
+	apply(1, 0)
+	apply(2, 10*time.Minute)
+	apply(3, 2*time.Hour)
+	apply(4, 2*time.Hour+time.Minute)
+
+	// Version 1 was superseded more than an hour before version 4 was committed, while versions 2 and
+	// 3 were superseded less than an hour before.
+	assert.Equal(t, []uint64{4, 3, 2}, listVersionNumbers(t, store, "file1"))
+
+	// With no retention, no previous versions are kept.
+	store.SetRetention(0, 0)
+	apply(5, 3*time.Hour)
+	assert.Equal(t, []uint64{5}, listVersionNumbers(t, store, "file1"))
+}
+
+func TestMetadataStore_RetentionAgeWithoutChanges(t *testing.T) {
+	store := &MetadataStore{engine: newMapEngine()}
+	store.SetRetention(DefaultRetainVersions, time.Hour)
+
+	start := time.Now()
+	for version, hash := range []string{"hash1", "hash2", "hash3"} {
+		ok, err := store.apply(logPosition{}, &Operation{
+			Type:      Operation_MODIFY,
+			Filename:  "file1",
+			Version:   uint64(version + 1),
+			HashList:  []string{hash},
+			Timestamp: start.Add(time.Duration(version-3) * 40 * time.Minute).UnixNano(),
+		})
+		assert.Nil(t, err)
+		assert.True(t, ok)
+	}
+
+	// Version 1 was superseded 80 minutes ago, so it has expired even though the file has not changed
+	// since, while version 2 was superseded 40 minutes ago.
+	assert.Equal(t, []uint64{3, 2}, listVersionNumbers(t, store, "file1"))
+
+	_, err := store.ReadFileVersion(context.Background(), &ReadFileVersionRequest{Filename: "file1", Version: 1})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	live, err := store.liveHashes()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]struct{}{"hash2": {}, "hash3": {}}, live)
+}
+
+func TestMetadataStore_CollectGarbageRetainsVersions(t *testing.T) {
+	store, client, cleanup := newGCTestStore(t)
+	defer cleanup()
+
+	store.SetRetention(1, 0)
+
+	first := storeTestBlock(t, client, []byte("first"))
+	second := storeTestBlock(t, client, []byte("second"))
+	third := storeTestBlock(t, client, []byte("third"))
+
+	for version, hash := range []string{first, second, third} {
+		res, err := store.ModifyFile(context.Background(), &ModifyFileRequest{Filename: "file1", Version: uint64(version + 1), HashList: []string{hash}})
+		assert.Nil(t, err)
+		assert.True(t, res.Success)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+
+	// The blocks of the retained previous version are still referenced.
+	res, err := store.CollectGarbage(context.Background(), &CollectGarbageRequest{GracePeriod: int64(10 * time.Millisecond)})
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1), res.Deleted)
+
+	has, err := client.HasBlocks(context.Background(), &block.HasBlocksRequest{Hashes: []string{first, second, third}})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{first}, has.Missing)
+}
@@ -0,0 +1,339 @@
+package meta
+
+import (
+	"context"
+	"encoding/base64"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// The number of entries ListFiles returns per page, unless fewer are requested.
+const maxListPageSize = 1000
+
+// Splits a slash-separated path into its segments. Paths are relative to the root of the namespace,
+// so leading, trailing and repeated slashes and "." segments are ignored, and ".." segments are
+// resolved. Paths that would leave the root, or that contain NUL bytes, are rejected.
+func splitPath(p string) ([]string, error) {
+	// NUL bytes are reserved for keys that are not files.
+	if strings.IndexByte(p, 0) >= 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "path %q contains a NUL byte", p)
+	}
+
+	segments := make([]string, 0, strings.Count(p, "/")+1)
+	for _, segment := range strings.Split(p, "/") {
+		switch segment {
+		case "", ".":
+		case "..":
+			if len(segments) == 0 {
+				return nil, status.Errorf(codes.InvalidArgument, "path %q is outside the root directory", p)
+			}
+
+			segments = segments[:len(segments)-1]
+		default:
+			segments = append(segments, segment)
+		}
+	}
+
+	return segments, nil
+}
+
+// Normalizes the path of a file, so that different spellings of a path, such as "/a/./b" and "a//b",
+// name the same file.
+func cleanPath(p string) (string, error) {
+	segments, err := splitPath(p)
+	if err != nil {
+		return "", err
+	}
+
+	if len(segments) == 0 {
+		return "", status.Errorf(codes.InvalidArgument, "path %q does not name a file", p)
+	}
+
+	return strings.Join(segments, "/"), nil
+}
+
+// Returns the sort key of a listing entry. Directories sort as if their names had a trailing slash, so
+// that a file and a directory with the same name have distinct keys.
+func listKey(info *FileInfo) string {
+	if info.Directory {
+		return info.Name + "/"
+	}
+
+	return info.Name
+}
+
+// The sorted names of the files that exist, so that a page of a directory listing can be found without
+// reading the metadata of every file. Deleted files are left out.
+type nameIndex struct {
+	names []string
+}
+
+// Builds the index of the files in an engine.
+func buildNameIndex(e engine) (*nameIndex, error) {
+	filenames, err := e.listFilenames()
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(filenames))
+	for _, filename := range filenames {
+		st, _, err := e.getFileMetadata(filename)
+		if err != nil {
+			return nil, err
+		}
+
+		if st.hashList != nil {
+			names = append(names, filename)
+		}
+	}
+
+	sort.Strings(names)
+	return &nameIndex{names: names}, nil
+}
+
+// Returns the position of the first name at or after the specified key.
+func (x *nameIndex) seek(key string) int {
+	return sort.SearchStrings(x.names, key)
+}
+
+// Records the new metadata of a file.
+func (x *nameIndex) update(filename string, st Stat) {
+	i := x.seek(filename)
+	found := i < len(x.names) && x.names[i] == filename
+
+	if st.hashList != nil && !found {
+		x.names = append(x.names, "")
+		copy(x.names[i+1:], x.names[i:])
+		x.names[i] = filename
+	} else if st.hashList == nil && found {
+		x.names = append(x.names[:i], x.names[i+1:]...)
+	}
+}
+
+// Returns the index of the files that exist, building it on first use. The caller must hold the lock,
+// for reading or writing.
+func (s *MetadataStore) nameIndex() (*nameIndex, error) {
+	s.namesOnce.Do(func() {
+		s.names, s.namesErr = buildNameIndex(s.engine)
+	})
+
+	return s.names, s.namesErr
+}
+
+// Returns the key that sorts after every name below a directory, which is the directory's name followed
+// by the character after the slash.
+func skipDir(dir string) string {
+	return dir + string('/'+1)
+}
+
+// Lists the files and implicit directories in a directory, in pages. Directories are not stored;
+// a directory exists while any file below it exists, and deleted files are not listed.
+func (s *MetadataStore) ListFiles(ctx context.Context, req *ListFilesRequest) (*ListFilesResponse, error) {
+	log.WithFields(log.Fields{
+		"path":      req.Path,
+		"recursive": req.Recursive,
+	}).Debug("Listing files...")
+
+	if err := s.checkLeader(ctx); err != nil {
+		return nil, err
+	}
+
+	segments, err := splitPath(req.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := strings.Join(segments, "/")
+
+	var after string
+	if req.PageToken != "" {
+		b, err := base64.RawURLEncoding.DecodeString(req.PageToken)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid page token %q", req.PageToken)
+		}
+
+		after = string(b)
+	}
+
+	pageSize := int(req.PageSize)
+	if pageSize == 0 || pageSize > maxListPageSize {
+		pageSize = maxListPageSize
+	}
+
+	entries, more, err := s.listDir(dir, req.Recursive, after, pageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &ListFilesResponse{Files: entries}
+	if more {
+		res.NextPageToken = base64.RawURLEncoding.EncodeToString([]byte(listKey(entries[len(entries)-1])))
+	}
+
+	return res, nil
+}
+
+// Returns a page of the entries in a directory: at most the specified number of entries whose keys
+// sort after the specified key, in order, and whether more follow. If the directory is empty but the
+// path names a file, the file is returned instead. Returns a NOT_FOUND status if the path names
+// neither. Only the entries on the page are read, by seeking in the index of names, and the files below
+// a subdirectory are skipped over rather than read.
+func (s *MetadataStore) listDir(dir string, recursive bool, after string, pageSize int) ([]*FileInfo, bool, error) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	index, err := s.nameIndex()
+	if err != nil {
+		return nil, false, err
+	}
+
+	names := index.names
+
+	prefix := ""
+	if dir != "" {
+		prefix = dir + "/"
+	}
+
+	i := index.seek(prefix)
+	if i == len(names) || !strings.HasPrefix(names[i], prefix) {
+		return s.listFile(dir)
+	}
+
+	// Names below a subdirectory sort after its key, so the page after a subdirectory starts after all
+	// of them. Names cannot contain NUL bytes, so the first name after any other key is found by seeking
+	// to the key followed by one.
+	if after != "" {
+		start := after + "\x00"
+		if strings.HasSuffix(after, "/") {
+			start = skipDir(strings.TrimSuffix(after, "/"))
+		}
+
+		if j := index.seek(start); j > i {
+			i = j
+		}
+	}
+
+	entries := make([]*FileInfo, 0, 16)
+	for i < len(names) && strings.HasPrefix(names[i], prefix) {
+		if len(entries) == pageSize {
+			return entries, true, nil
+		}
+
+		filename := names[i]
+
+		// Unless listing recursively, files below a subdirectory are represented by the subdirectory.
+		rel := filename[len(prefix):]
+		if j := strings.IndexByte(rel, '/'); j >= 0 && !recursive {
+			sub := prefix + rel[:j]
+			entries = append(entries, &FileInfo{Name: sub, Directory: true})
+			i = index.seek(skipDir(sub))
+			continue
+		}
+
+		st, _, err := s.engine.getFileMetadata(filename)
+		if err != nil {
+			return nil, false, err
+		}
+
+		entries = append(entries, &FileInfo{
+			Name:    filename,
+			Version: st.version,
+			ModTime: st.modTime,
+		})
+		i++
+	}
+
+	return entries, false, nil
+}
+
+// Lists a path that is not a directory: the file it names, or nothing if it names the root, which is
+// empty. Returns a NOT_FOUND status if the path names no file. The caller must hold the lock.
+func (s *MetadataStore) listFile(filename string) ([]*FileInfo, bool, error) {
+	if filename == "" {
+		return nil, false, nil
+	}
+
+	st, _, err := s.engine.getFileMetadata(filename)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if st.hashList == nil {
+		return nil, false, status.Errorf(codes.NotFound, "no such file or directory %q", filename)
+	}
+
+	return []*FileInfo{{Name: filename, Version: st.version, ModTime: st.modTime}}, false, nil
+}
+
+// Returns the path of a file that creating the specified file would put in the place of a directory,
+// or the reverse: a file at the path of one of its parent directories, or a file below it. Returns ""
+// if there is none. The staged changes are taken into account.
+func (b *batch) pathConflict(filename string) (string, error) {
+	for i := 0; i < len(filename); i++ {
+		if filename[i] != '/' {
+			continue
+		}
+
+		st, err := b.get(filename[:i])
+		if err != nil {
+			return "", err
+		}
+
+		if st.hashList != nil {
+			return filename[:i], nil
+		}
+	}
+
+	index, err := b.store.nameIndex()
+	if err != nil {
+		return "", err
+	}
+
+	prefix := filename + "/"
+	for i := index.seek(prefix); i < len(index.names) && strings.HasPrefix(index.names[i], prefix); i++ {
+		if st, ok := b.staged[index.names[i]]; ok && st.hashList == nil {
+			continue
+		}
+
+		return index.names[i], nil
+	}
+
+	for name, st := range b.staged {
+		if strings.HasPrefix(name, prefix) && st.hashList != nil {
+			return name, nil
+		}
+	}
+
+	return "", nil
+}
+
+// Creates the error returned when creating a file would conflict with another file or directory.
+func pathConflictError(filename string, conflict string) error {
+	if strings.HasPrefix(conflict, filename+"/") {
+		return status.Errorf(codes.FailedPrecondition, "%q is a directory containing %q", filename, conflict)
+	}
+
+	return status.Errorf(codes.FailedPrecondition, "cannot create %q, since %q is a file", filename, conflict)
+}
+
+// Returns a FAILED_PRECONDITION status if creating the specified file would conflict with another file
+// or directory. See pathConflict.
+func (s *MetadataStore) checkCreate(filename string) error {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	conflict, err := s.newBatch(0).pathConflict(filename)
+	if err != nil {
+		return err
+	}
+
+	if conflict != "" {
+		return pathConflictError(filename, conflict)
+	}
+
+	return nil
+}
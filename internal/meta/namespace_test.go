@@ -0,0 +1,228 @@
+package meta
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestCleanPath(t *testing.T) {
+	paths := map[string]string{
+		"file1":          "file1",
+		"/file1":         "file1",
+		"a//b/./c/":      "a/b/c",
+		"a/b/../c":       "a/c",
+		"./a/../b/../c":  "c",
+		"a/b/c/../../..": "",
+		"/../a":          "",
+		"":               "",
+		"/":              "",
+		"a/\x00b":        "",
+	}
+
+	for p, expected := range paths {
+		cleaned, err := cleanPath(p)
+		if expected == "" {
+			assert.Equal(t, codes.InvalidArgument, status.Code(err), p)
+			continue
+		}
+
+		assert.Nil(t, err, p)
+		assert.Equal(t, expected, cleaned, p)
+	}
+}
+
+// Lists a directory, returning the names of the entries, with a trailing slash for directories.
+func listNames(t *testing.T, store *MetadataStore, req *ListFilesRequest) []string {
+	res, err := store.ListFiles(context.Background(), req)
+	assert.Nil(t, err)
+
+	names := make([]string, 0, len(res.Files))
+	for _, info := range res.Files {
+		names = append(names, listKey(info))
+	}
+
+	return names
+}
+
+func testListFiles(t *testing.T, store *MetadataStore) {
+	for _, filename := range []string{"a.txt", "docs/b.txt", "docs/c.txt", "docs/sub/d.txt", "docs/sub/e.txt", "deleted/f.txt", "docs.txt"} {
+		expectModifyFile(store, &ModifyFileRequest{
+			Filename: filename,
+			Version:  1,
+			HashList: []string{"hash1"},
+		}, &ModifyFileResponse{Success: true}, t)
+	}
+
+	delRes, err := store.DeleteFile(context.Background(), &DeleteFileRequest{Filename: "deleted/f.txt", Version: 2})
+	assert.Nil(t, err)
+	assert.True(t, delRes.Success)
+
+	// Different spellings of a path name the same file.
+	modRes, err := store.ModifyFile(context.Background(), &ModifyFileRequest{
+		Filename: "/docs/./sub//d.txt",
+		Version:  2,
+		HashList: []string{"hash1"},
+	})
+	assert.Nil(t, err)
+	assert.True(t, modRes.Success)
+	expectReadFile(store, "docs/sub/d.txt", &ReadFileResponse{Version: 2, HashList: []string{"hash1"}, BlockSize: 64}, t)
+
+	// Directories exist implicitly while any file below them exists.
+	assert.Equal(t, []string{"a.txt", "docs.txt", "docs/"}, listNames(t, store, &ListFilesRequest{}))
+	assert.Equal(t, []string{"a.txt", "docs.txt", "docs/"}, listNames(t, store, &ListFilesRequest{Path: "/"}))
+	assert.Equal(t, []string{"docs/b.txt", "docs/c.txt", "docs/sub/"}, listNames(t, store, &ListFilesRequest{Path: "docs"}))
+	assert.Equal(t, []string{"docs/b.txt", "docs/c.txt", "docs/sub/d.txt", "docs/sub/e.txt"}, listNames(t, store, &ListFilesRequest{Path: "/docs/", Recursive: true}))
+	assert.Equal(t, []string{"a.txt", "docs.txt", "docs/b.txt", "docs/c.txt", "docs/sub/d.txt", "docs/sub/e.txt"}, listNames(t, store, &ListFilesRequest{Recursive: true}))
+
+	// Listing a file lists just that file.
+	assert.Equal(t, []string{"docs/sub/d.txt"}, listNames(t, store, &ListFilesRequest{Path: "docs/sub/d.txt"}))
+
+	res, err := store.ListFiles(context.Background(), &ListFilesRequest{Path: "a.txt"})
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1), res.Files[0].Version)
+	assert.NotZero(t, res.Files[0].ModTime)
+
+	for _, p := range []string{"deleted", "missing", "docs/sub/missing"} {
+		_, err = store.ListFiles(context.Background(), &ListFilesRequest{Path: p})
+		assert.Equal(t, codes.NotFound, status.Code(err), p)
+	}
+
+	_, err = store.ListFiles(context.Background(), &ListFilesRequest{Path: "../docs"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestMetadataStore_ListFiles(t *testing.T) {
+	mock := &mockClient{blocks: map[string][]byte{"hash1": []byte("block1")}}
+
+	t.Run("map", func(t *testing.T) {
+		testListFiles(t, &MetadataStore{client: mock, engine: newMapEngine()})
+	})
+
+	t.Run("keychain", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "surfs")
+		assert.Nil(t, err)
+
+		defer os.RemoveAll(dir)
+
+		engine, err := openKeychainEngine(filepath.Join(dir, MetadataFilename))
+		assert.Nil(t, err)
+
+		defer engine.close()
+
+		testListFiles(t, &MetadataStore{client: mock, engine: engine})
+	})
+}
+
+func TestMetadataStore_ListFilesPages(t *testing.T) {
+	mock := &mockClient{blocks: map[string][]byte{"hash1": []byte("block1")}}
+	store := &MetadataStore{client: mock, engine: newMapEngine()}
+
+	var expected []string
+	for i := 0; i < 25; i++ {
+		filename := fmt.Sprintf("dir/file%02d", i)
+		expected = append(expected, filename)
+
+		expectModifyFile(store, &ModifyFileRequest{
+			Filename: filename,
+			Version:  1,
+			HashList: []string{"hash1"},
+		}, &ModifyFileResponse{Success: true}, t)
+	}
+
+	var names []string
+	req := &ListFilesRequest{Path: "dir", PageSize: 10}
+	for pages := 1; ; pages++ {
+		res, err := store.ListFiles(context.Background(), req)
+		assert.Nil(t, err)
+		assert.True(t, len(res.Files) <= 10)
+
+		for _, info := range res.Files {
+			names = append(names, info.Name)
+		}
+
+		if res.NextPageToken == "" {
+			assert.Equal(t, 3, pages)
+			break
+		}
+
+		req.PageToken = res.NextPageToken
+	}
+
+	assert.Equal(t, expected, names)
+
+	_, err := store.ListFiles(context.Background(), &ListFilesRequest{Path: "dir", PageToken: "!"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestMetadataStore_ListFilesPagesSubdirectories(t *testing.T) {
+	mock := &mockClient{blocks: map[string][]byte{"hash1": []byte("block1")}}
+	store := &MetadataStore{client: mock, engine: newMapEngine()}
+
+	// "a.b" and "a0" sort just before and after the files below "a/".
+	for _, filename := range []string{"a.b", "a/x", "a/y", "a/z/w", "a0", "b"} {
+		expectModifyFile(store, &ModifyFileRequest{Filename: filename, Version: 1, HashList: []string{"hash1"}}, &ModifyFileResponse{Success: true}, t)
+	}
+
+	var names []string
+	req := &ListFilesRequest{PageSize: 1}
+	for {
+		res, err := store.ListFiles(context.Background(), req)
+		assert.Nil(t, err)
+		assert.Len(t, res.Files, 1)
+
+		names = append(names, listKey(res.Files[0]))
+		if res.NextPageToken == "" {
+			break
+		}
+
+		req.PageToken = res.NextPageToken
+	}
+
+	assert.Equal(t, []string{"a.b", "a/", "a0", "b"}, names)
+	assert.Equal(t, []string{"a/x", "a/y", "a/z/"}, listNames(t, store, &ListFilesRequest{Path: "a"}))
+}
+
+func TestMetadataStore_PathConflicts(t *testing.T) {
+	mock := &mockClient{blocks: map[string][]byte{"hash1": []byte("block1")}}
+	store := &MetadataStore{client: mock, engine: newMapEngine()}
+
+	expectModifyFile(store, &ModifyFileRequest{Filename: "a", Version: 1, HashList: []string{"hash1"}}, &ModifyFileResponse{Success: true}, t)
+	expectModifyFile(store, &ModifyFileRequest{Filename: "d/f", Version: 1, HashList: []string{"hash1"}}, &ModifyFileResponse{Success: true}, t)
+
+	// A file cannot be created below a file, or in the place of a directory.
+	for _, filename := range []string{"a/b", "a/b/c", "d"} {
+		_, err := store.ModifyFile(context.Background(), &ModifyFileRequest{Filename: filename, Version: 1, HashList: []string{"hash1"}})
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err), filename)
+	}
+
+	_, err := store.CopyFile(context.Background(), &CopyFileRequest{Source: "a", SourceVersion: 1, Destination: "d", DestinationVersion: 1})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	_, err = store.CommitBatch(context.Background(), &CommitBatchRequest{Operations: []*Operation{
+		{Type: Operation_MODIFY, Filename: "d", Version: 1, HashList: []string{"hash1"}},
+	}})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	// A file can take the place of a directory that the same batch empties, and a file can be renamed
+	// below its own path.
+	batchRes, err := store.CommitBatch(context.Background(), &CommitBatchRequest{Operations: []*Operation{
+		{Type: Operation_DELETE, Filename: "d/f", Version: 2},
+		{Type: Operation_MODIFY, Filename: "d", Version: 1, HashList: []string{"hash1"}},
+	}})
+	assert.Nil(t, err)
+	assert.True(t, batchRes.Success)
+
+	renameRes, err := store.RenameFile(context.Background(), &RenameFileRequest{Source: "a", SourceVersion: 2, Destination: "a/b", DestinationVersion: 1})
+	assert.Nil(t, err)
+	assert.True(t, renameRes.Success)
+
+	assert.Equal(t, []string{"a/", "d"}, listNames(t, store, &ListFilesRequest{}))
+}
@@ -0,0 +1,1307 @@
+package meta
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// Default minimum election timeout. The actual timeout is chosen randomly between this and
+	// twice this value, so that nodes rarely start elections at the same time.
+	DefaultElectionTimeout = 300 * time.Millisecond
+
+	// Default interval at which the leader sends heartbeats to its followers.
+	DefaultHeartbeatInterval = 50 * time.Millisecond
+
+	// Maximum time to wait for a proposed operation to be committed and applied.
+	proposeTimeout = 5 * time.Second
+
+	// Longest wait between attempts to apply an operation that failed to apply.
+	maxApplyBackoff = 5 * time.Second
+
+	// Default limits on the entries sent in a single AppendEntries request: their number, and their
+	// total encoded size. A single entry larger than the size limit is still sent on its own.
+	defaultMaxAppendEntries = 1000
+	defaultMaxAppendBytes   = 1 << 20
+
+	// Default number of applied entries kept in the log. Once twice as many are, the earlier ones are
+	// discarded, and followers that still need them are sent a snapshot instead.
+	defaultLogRetain = 10000
+
+	// Size of the chunks in which a snapshot is sent to a follower.
+	snapshotChunkSize = 512 << 10
+)
+
+var (
+	errCrashed        = status.Error(codes.Unavailable, "metadata store is crashed")
+	errLeadershipLost = status.Error(codes.Unavailable, "leadership lost before operation was committed")
+	errProposeTimeout = status.Error(codes.Unavailable, "timed out waiting for operation to be committed")
+	errNotReady       = status.Error(codes.Unavailable, "leader has not yet applied entries from previous terms")
+)
+
+// Peer is an interface to the Raft RPCs of another node in the cluster. It is satisfied by
+// MetadataStoreClient.
+type raftPeer interface {
+	AppendEntries(ctx context.Context, in *AppendEntriesRequest, opts ...grpc.CallOption) (*AppendEntriesResponse, error)
+	RequestVote(ctx context.Context, in *RequestVoteRequest, opts ...grpc.CallOption) (*RequestVoteResponse, error)
+	InstallSnapshot(ctx context.Context, in *InstallSnapshotRequest, opts ...grpc.CallOption) (*InstallSnapshotResponse, error)
+}
+
+type raftState int
+
+const (
+	follower raftState = iota
+	candidate
+	leader
+)
+
+func (s raftState) String() string {
+	switch s {
+	case follower:
+		return "follower"
+	case candidate:
+		return "candidate"
+	default:
+		return "leader"
+	}
+}
+
+// The index and term of an entry in the log.
+type logPosition struct {
+	index uint64
+	term  uint64
+}
+
+// The state machine replicated by Raft, which is the metadata of the files.
+type raftStateMachine interface {
+	// Applies the operation committed at the specified log position. Returns false if the operation
+	// was rejected, which must depend only on the operations applied before it.
+	apply(pos logPosition, op *Operation) (bool, error)
+
+	// Returns the position of the last log entry whose changes have been persisted. The position is
+	// persisted atomically with the changes, so a restarted node applies exactly the entries after it.
+	appliedPosition() (logPosition, error)
+
+	// Returns the encoded state of the state machine, reflecting every entry applied so far.
+	snapshot() ([]byte, error)
+
+	// Replaces the state with a snapshot reflecting every entry up to the specified position, which is
+	// persisted as the applied position.
+	restoreSnapshot(pos logPosition, data []byte) error
+}
+
+// A snapshot of the state machine, reflecting every entry up to and including a log position.
+type raftSnapshot struct {
+	pos  logPosition
+	data []byte
+}
+
+// A snapshot received from the leader, waiting to be restored by the applier, which delivers the
+// outcome on the channel.
+type snapshotRestore struct {
+	snap *raftSnapshot
+	done chan error
+}
+
+// An error applying an operation that would recur however many times the operation was applied, such
+// as an operation of an unknown type or metadata that cannot be decoded. Skipping the operation would
+// leave the node's metadata different from the other nodes', so the node stops applying operations
+// instead of retrying.
+type permanentApplyError struct {
+	err error
+}
+
+func (e permanentApplyError) Error() string {
+	return e.err.Error()
+}
+
+// The outcome of applying a committed operation, delivered to the proposer of the operation.
+type applyResult struct {
+	success bool
+	err     error
+}
+
+// A proposer waiting for the operation at a log index to be applied.
+type waiter struct {
+	term uint64
+	ch   chan applyResult
+}
+
+// Implementation of the Raft consensus algorithm, used to replicate metadata operations across the
+// nodes of the metadata store. The log, term and vote are kept in memory, and are also saved to the
+// node's storage before it acts on them, so that a restarted node neither votes twice in a term nor
+// loses entries it acknowledged.
+type raft struct {
+	mtx sync.Mutex
+
+	// ID of this node, which is the address other nodes and clients use to reach it.
+	id string
+
+	// Other nodes in the cluster, keyed by ID.
+	peers map[string]raftPeer
+
+	electionTimeout   time.Duration
+	heartbeatInterval time.Duration
+	maxAppendEntries  int
+	maxAppendBytes    int
+	logRetain         uint64
+
+	state       raftState
+	currentTerm uint64
+	votedFor    string
+	leaderID    string
+	crashed     bool
+
+	// Saves the term, vote and log.
+	storage raftStorage
+
+	// The log, starting at the base index. The entry at the base index is a sentinel holding the term
+	// of the last entry discarded by compaction, or of no entry if none was, so real entries start
+	// after it.
+	log         []*LogEntry
+	baseIndex   uint64
+	commitIndex uint64
+	lastApplied uint64
+
+	// Leader state: for each peer, the index of the next entry to send and of the highest entry
+	// known to be replicated.
+	nextIndex  map[string]uint64
+	matchIndex map[string]uint64
+
+	// Leader state: the latest snapshot taken for followers that need discarded entries, whether the
+	// applier should take a new one, and the peers a snapshot is being sent to.
+	snapshot          *raftSnapshot
+	snapshotRequested bool
+	sendingSnapshot   map[string]bool
+
+	// Follower state: the snapshot being received from the leader, and the one waiting to be restored.
+	incoming       *raftSnapshot
+	pendingRestore *snapshotRestore
+
+	electionDeadline time.Time
+	nextHeartbeat    time.Time
+
+	// Index of the no-op entry appended when this node became leader. The leader only serves clients
+	// once it has applied this entry, and with it every entry committed by previous leaders.
+	readyIndex uint64
+
+	// Applies committed operations.
+	sm      raftStateMachine
+	waiters map[uint64]waiter
+
+	// The error returned to clients once an operation failed to apply with a permanent error, after
+	// which the node applies nothing more and does not stand for election.
+	applyErr error
+
+	// Signalled when the commit index advances, when a snapshot is requested or received, or when the
+	// node is stopped.
+	commitCond *sync.Cond
+
+	stopped bool
+	done    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// Creates a Raft node with the specified ID and peers, restoring the term, vote and log saved in the
+// storage. Committed operations are applied to the state machine in log order, starting after the last
+// entry whose changes the state machine has persisted. Applying the earlier entries again would not be
+// safe: an operation rejected because of a path conflict may succeed against later metadata. The node
+// does not participate in the cluster until it is started.
+func newRaft(id string, peers map[string]raftPeer, storage raftStorage, sm raftStateMachine) (*raft, error) {
+	saved, err := storage.load()
+	if err != nil {
+		return nil, err
+	}
+
+	applied, err := sm.appliedPosition()
+	if err != nil {
+		return nil, err
+	}
+
+	r := &raft{
+		id:                id,
+		peers:             peers,
+		electionTimeout:   DefaultElectionTimeout,
+		heartbeatInterval: DefaultHeartbeatInterval,
+		maxAppendEntries:  defaultMaxAppendEntries,
+		maxAppendBytes:    defaultMaxAppendBytes,
+		logRetain:         defaultLogRetain,
+		state:             follower,
+		currentTerm:       saved.term,
+		votedFor:          saved.votedFor,
+		storage:           storage,
+		log:               append([]*LogEntry{{Term: saved.base.term}}, saved.entries...),
+		baseIndex:         saved.base.index,
+		nextIndex:         make(map[string]uint64),
+		matchIndex:        make(map[string]uint64),
+		sendingSnapshot:   make(map[string]bool),
+		sm:                sm,
+		waiters:           make(map[uint64]waiter),
+		done:              make(chan struct{}),
+	}
+	r.commitCond = sync.NewCond(&r.mtx)
+
+	// Entries are saved before they are committed, and are only discarded once applied, so every
+	// applied entry is in the log unless the node restored a snapshot and crashed before compacting
+	// its log. The log is then compacted up to the snapshot now.
+	if applied.index > r.baseIndex && (applied.index > r.lastLogIndex() || r.entry(applied.index).Term != applied.term) {
+		if err := r.compactLog(applied); err != nil {
+			return nil, err
+		}
+	}
+
+	// Entries that were applied without changing the metadata, such as rejected operations, do not
+	// update the applied position, so the log may have been compacted past it. Those entries would
+	// change nothing if applied again.
+	r.lastApplied = applied.index
+	if r.lastApplied < r.baseIndex {
+		r.lastApplied = r.baseIndex
+	}
+	r.commitIndex = r.lastApplied
+
+	if len(saved.entries) > 0 || saved.term > 0 {
+		log.WithFields(log.Fields{
+			"id":      id,
+			"term":    saved.term,
+			"base":    r.baseIndex,
+			"entries": len(saved.entries),
+			"applied": r.lastApplied,
+		}).Debug("Restored Raft state.")
+	}
+
+	return r, nil
+}
+
+// Starts the election timer and the goroutine applying committed operations.
+func (r *raft) start() {
+	r.mtx.Lock()
+	r.resetElectionDeadline()
+	r.mtx.Unlock()
+
+	r.wg.Add(2)
+	go r.runTicker()
+	go r.runApplier()
+}
+
+// Stops the node and waits for its goroutines to exit.
+func (r *raft) stop() {
+	r.mtx.Lock()
+	if r.stopped {
+		r.mtx.Unlock()
+		return
+	}
+
+	r.stopped = true
+	close(r.done)
+	r.failWaiters(0, errLeadershipLost)
+	r.commitCond.Broadcast()
+	r.mtx.Unlock()
+
+	r.wg.Wait()
+
+	if err := r.storage.close(); err != nil {
+		log.WithField("id", r.id).Errorf("Failed to close Raft storage, %v", err)
+	}
+}
+
+// Returns the entry at the specified index, which must be at or after the base index.
+func (r *raft) entry(index uint64) *LogEntry {
+	return r.log[index-r.baseIndex]
+}
+
+func (r *raft) lastLogIndex() uint64 {
+	return r.baseIndex + uint64(len(r.log)-1)
+}
+
+func (r *raft) lastLogTerm() uint64 {
+	return r.log[len(r.log)-1].Term
+}
+
+// Must be called with the lock held.
+func (r *raft) resetElectionDeadline() {
+	timeout := r.electionTimeout + time.Duration(rand.Int63n(int64(r.electionTimeout)))
+	r.electionDeadline = time.Now().Add(timeout)
+}
+
+// Fails all proposers waiting on entries at or after the specified index. Must be called with the
+// lock held.
+func (r *raft) failWaiters(from uint64, err error) {
+	for index, w := range r.waiters {
+		if index >= from {
+			w.ch <- applyResult{err: err}
+			delete(r.waiters, index)
+		}
+	}
+}
+
+// Saves the current term and vote. Must be called with the lock held, before replying to or sending
+// any RPC that depends on them.
+func (r *raft) saveState() error {
+	if err := r.storage.setState(r.currentTerm, r.votedFor); err != nil {
+		log.WithFields(log.Fields{
+			"id":   r.id,
+			"term": r.currentTerm,
+		}).Errorf("Failed to save Raft state, %v", err)
+
+		return status.Errorf(codes.Unavailable, "failed to save raft state, %v", err)
+	}
+
+	return nil
+}
+
+// Appends entries to the log at the specified index, discarding any entries at or after it, once they
+// are saved. Must be called with the lock held.
+func (r *raft) appendLog(index uint64, entries ...*LogEntry) error {
+	if err := r.storage.appendEntries(index, entries); err != nil {
+		log.WithFields(log.Fields{
+			"id":    r.id,
+			"index": index,
+		}).Errorf("Failed to save Raft log, %v", err)
+
+		return status.Errorf(codes.Unavailable, "failed to save raft log, %v", err)
+	}
+
+	r.log = append(r.log[:index-r.baseIndex], entries...)
+	return nil
+}
+
+// Discards the entries up to and including the specified position once the storage has. The entries
+// after it are kept if the entry at the position has the same term, which means they match the log
+// the position was taken from. Otherwise, every entry is discarded. Must be called with the lock held.
+func (r *raft) compactLog(base logPosition) error {
+	keep := base.index <= r.lastLogIndex() && r.entry(base.index).Term == base.term
+
+	err := r.storage.compact(base)
+	if err == nil && !keep {
+		err = r.storage.appendEntries(base.index+1, nil)
+	}
+
+	if err != nil {
+		log.WithFields(log.Fields{
+			"id":    r.id,
+			"index": base.index,
+		}).Errorf("Failed to compact Raft log, %v", err)
+
+		return status.Errorf(codes.Unavailable, "failed to compact raft log, %v", err)
+	}
+
+	entries := []*LogEntry{{Term: base.term}}
+	if keep {
+		entries = append(entries, r.log[base.index-r.baseIndex+1:]...)
+	}
+
+	r.log = entries
+	r.baseIndex = base.index
+	return nil
+}
+
+// Steps down to follower in the specified term. Must be called with the lock held. Returns an error
+// if the new term could not be saved, in which case the node must not reply in the new term.
+func (r *raft) becomeFollower(term uint64) error {
+	var err error
+	if term > r.currentTerm {
+		r.currentTerm = term
+		r.votedFor = ""
+		err = r.saveState()
+	}
+
+	if r.state != follower {
+		log.WithFields(log.Fields{
+			"id":   r.id,
+			"term": r.currentTerm,
+		}).Debug("Stepping down to follower.")
+	}
+
+	r.state = follower
+	return err
+}
+
+// Becomes the leader of the current term. Must be called with the lock held.
+func (r *raft) becomeLeader() {
+	// Entries from earlier terms can only be committed once an entry from the current term is, so
+	// a no-op is appended to commit them without waiting for a client request. A leader that cannot
+	// save it cannot save client requests either, so it waits for another node to take over.
+	noop := &LogEntry{Term: r.currentTerm, Operation: &Operation{Type: Operation_NOOP}}
+	if err := r.appendLog(r.lastLogIndex()+1, noop); err != nil {
+		r.state = follower
+		r.resetElectionDeadline()
+		return
+	}
+
+	log.WithFields(log.Fields{
+		"id":   r.id,
+		"term": r.currentTerm,
+	}).Debug("Became leader.")
+
+	r.state = leader
+	r.leaderID = r.id
+
+	for id := range r.peers {
+		r.nextIndex[id] = r.lastLogIndex()
+		r.matchIndex[id] = 0
+	}
+
+	r.readyIndex = r.lastLogIndex()
+	r.advanceCommitIndex()
+	r.broadcastAppendEntries()
+}
+
+// Periodically starts elections or sends heartbeats, depending on the state of the node.
+func (r *raft) runTicker() {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(r.heartbeatInterval / 5)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.done:
+			return
+		case now := <-ticker.C:
+			r.mtx.Lock()
+			if !r.crashed {
+				if r.state == leader {
+					if now.After(r.nextHeartbeat) {
+						r.broadcastAppendEntries()
+					}
+				} else if now.After(r.electionDeadline) && r.applyErr == nil {
+					r.startElection()
+				}
+			}
+			r.mtx.Unlock()
+		}
+	}
+}
+
+// Applies committed entries to the state machine in log order. The applier is the only goroutine that
+// touches the state machine, so it also takes and restores snapshots, and compacts the log once enough
+// entries are applied.
+func (r *raft) runApplier() {
+	defer r.wg.Done()
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	backoff := r.heartbeatInterval
+	for {
+		for !r.stopped && r.lastApplied >= r.commitIndex && !r.snapshotRequested && r.pendingRestore == nil {
+			r.commitCond.Wait()
+		}
+
+		if r.stopped {
+			return
+		}
+
+		if r.pendingRestore != nil {
+			if err := r.restoreSnapshot(); err != nil {
+				return
+			}
+
+			continue
+		}
+
+		if r.snapshotRequested {
+			r.takeSnapshot()
+			continue
+		}
+
+		index := r.lastApplied + 1
+		entry := r.entry(index)
+
+		// The state machine is applied without holding the lock, since applying may be slow. Entries
+		// up to the commit index are never truncated, so the entry is safe to use.
+		r.mtx.Unlock()
+		success, err := r.sm.apply(logPosition{index: index, term: entry.Term}, entry.Operation)
+		r.mtx.Lock()
+
+		// Skipping an entry would leave this node's metadata different from the other nodes'. A
+		// permanent failure stops the node from applying entries, and is reported to clients. Other
+		// failures, such as a failure to write the storage engine, are retried until the entry applies
+		// or the node is stopped. Operations that are merely rejected do not fail.
+		if perr, ok := err.(permanentApplyError); ok {
+			log.WithFields(log.Fields{
+				"id":    r.id,
+				"index": index,
+			}).Errorf("Failed to apply operation, no longer applying operations, %v", perr.err)
+
+			r.stopApplying(status.Errorf(codes.Internal, "failed to apply log entry %d, %v", index, perr.err))
+			return
+		} else if err != nil {
+			log.WithFields(log.Fields{
+				"id":    r.id,
+				"index": index,
+			}).Errorf("Failed to apply operation, retrying in %s, %v", backoff, err)
+
+			r.mtx.Unlock()
+			select {
+			case <-r.done:
+			case <-time.After(backoff):
+			}
+			r.mtx.Lock()
+
+			if backoff *= 2; backoff > maxApplyBackoff {
+				backoff = maxApplyBackoff
+			}
+
+			continue
+		}
+
+		backoff = r.heartbeatInterval
+		r.lastApplied = index
+
+		if w, ok := r.waiters[index]; ok {
+			if w.term == entry.Term {
+				w.ch <- applyResult{success: success}
+			} else {
+				w.ch <- applyResult{err: errLeadershipLost}
+			}
+			delete(r.waiters, index)
+		}
+
+		if r.lastApplied-r.baseIndex >= 2*r.logRetain {
+			index := r.lastApplied - r.logRetain
+			r.compactLog(logPosition{index: index, term: r.entry(index).Term})
+		}
+	}
+}
+
+// Stops applying entries after an error that would recur, failing proposers and pending restores.
+// Must be called with the lock held.
+func (r *raft) stopApplying(err error) {
+	r.applyErr = err
+	r.failWaiters(0, err)
+
+	if r.pendingRestore != nil {
+		r.pendingRestore.done <- err
+		r.pendingRestore = nil
+	}
+
+	// Another node, which may have applied the entry, must take over as leader.
+	if r.state != follower {
+		r.becomeFollower(r.currentTerm)
+	}
+}
+
+// Takes a snapshot of the state machine for followers that need entries discarded from the log. Must
+// be called by the applier with the lock held.
+func (r *raft) takeSnapshot() {
+	pos := logPosition{index: r.lastApplied, term: r.entry(r.lastApplied).Term}
+
+	r.mtx.Unlock()
+	data, err := r.sm.snapshot()
+	r.mtx.Lock()
+
+	r.snapshotRequested = false
+	if err != nil {
+		log.WithFields(log.Fields{
+			"id":    r.id,
+			"index": pos.index,
+		}).Errorf("Failed to take snapshot, %v", err)
+
+		return
+	}
+
+	log.WithFields(log.Fields{
+		"id":    r.id,
+		"index": pos.index,
+		"size":  len(data),
+	}).Debug("Took snapshot.")
+
+	r.snapshot = &raftSnapshot{pos: pos, data: data}
+}
+
+// Restores the snapshot received from the leader, unless the state machine has already applied the
+// entries it reflects, and compacts the log up to it. Must be called by the applier with the lock held.
+// Returns an error if the node must stop applying entries, since the state machine reflects the
+// snapshot but the log could not be compacted to match.
+func (r *raft) restoreSnapshot() error {
+	restore := r.pendingRestore
+	r.pendingRestore = nil
+
+	pos := restore.snap.pos
+	if pos.index <= r.lastApplied {
+		restore.done <- nil
+		return nil
+	}
+
+	r.mtx.Unlock()
+	err := r.sm.restoreSnapshot(pos, restore.snap.data)
+	r.mtx.Lock()
+
+	if err != nil {
+		log.WithFields(log.Fields{
+			"id":    r.id,
+			"index": pos.index,
+		}).Errorf("Failed to restore snapshot, %v", err)
+
+		restore.done <- status.Errorf(codes.Unavailable, "failed to restore snapshot, %v", err)
+		return nil
+	}
+
+	if err := r.compactLog(pos); err != nil {
+		restore.done <- err
+		r.stopApplying(err)
+		return err
+	}
+
+	log.WithFields(log.Fields{
+		"id":    r.id,
+		"index": pos.index,
+	}).Debug("Restored snapshot.")
+
+	r.lastApplied = pos.index
+	if r.commitIndex < pos.index {
+		r.commitIndex = pos.index
+	}
+
+	r.failWaiters(0, errLeadershipLost)
+	restore.done <- nil
+	return nil
+}
+
+// Starts an election for a new term. Must be called with the lock held.
+func (r *raft) startElection() {
+	r.state = candidate
+	r.currentTerm++
+	r.votedFor = r.id
+	r.leaderID = ""
+	r.resetElectionDeadline()
+
+	// The vote for ourselves must be saved before asking for others, so that we never vote for another
+	// candidate in this term after a restart. If it cannot be saved, we try again at the next timeout.
+	if err := r.saveState(); err != nil {
+		r.state = follower
+		return
+	}
+
+	log.WithFields(log.Fields{
+		"id":   r.id,
+		"term": r.currentTerm,
+	}).Debug("Starting election...")
+
+	if len(r.peers) == 0 {
+		r.becomeLeader()
+		return
+	}
+
+	term := r.currentTerm
+	req := &RequestVoteRequest{
+		Term:         term,
+		CandidateId:  r.id,
+		LastLogIndex: r.lastLogIndex(),
+		LastLogTerm:  r.lastLogTerm(),
+	}
+
+	votes := 1
+	for id, peer := range r.peers {
+		go func(id string, peer raftPeer) {
+			ctx, cancel := context.WithTimeout(context.Background(), r.electionTimeout)
+			defer cancel()
+
+			res, err := peer.RequestVote(ctx, req)
+			if err != nil {
+				return
+			}
+
+			r.mtx.Lock()
+			defer r.mtx.Unlock()
+
+			if res.Term > r.currentTerm {
+				r.becomeFollower(res.Term)
+				return
+			}
+
+			if r.crashed || r.state != candidate || r.currentTerm != term || !res.VoteGranted {
+				return
+			}
+
+			votes++
+			if votes > (len(r.peers)+1)/2 {
+				r.becomeLeader()
+			}
+		}(id, peer)
+	}
+}
+
+// Sends AppendEntries to all peers. Must be called with the lock held.
+func (r *raft) broadcastAppendEntries() {
+	r.nextHeartbeat = time.Now().Add(r.heartbeatInterval)
+	for id, peer := range r.peers {
+		go r.sendAppendEntries(id, peer)
+	}
+}
+
+// Sends the entries a peer is missing, or a heartbeat if it is up to date. A peer that needs entries
+// discarded from the log is sent a snapshot instead.
+func (r *raft) sendAppendEntries(id string, peer raftPeer) {
+	r.mtx.Lock()
+	if r.crashed || r.state != leader {
+		r.mtx.Unlock()
+		return
+	}
+
+	term := r.currentTerm
+	next := r.nextIndex[id]
+	if next <= r.baseIndex {
+		r.mtx.Unlock()
+		r.sendSnapshot(id, peer)
+		return
+	}
+
+	prev := next - 1
+	req := &AppendEntriesRequest{
+		Term:         term,
+		LeaderId:     r.id,
+		PrevLogIndex: prev,
+		PrevLogTerm:  r.entry(prev).Term,
+		Entries:      r.entriesFrom(next),
+		LeaderCommit: r.commitIndex,
+	}
+	r.mtx.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), r.electionTimeout)
+	defer cancel()
+
+	res, err := peer.AppendEntries(ctx, req)
+	if err != nil {
+		return
+	}
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if res.Term > r.currentTerm {
+		r.becomeFollower(res.Term)
+		r.resetElectionDeadline()
+		return
+	}
+
+	if r.crashed || r.state != leader || r.currentTerm != term {
+		return
+	}
+
+	if res.Success {
+		match := prev + uint64(len(req.Entries))
+		if match > r.matchIndex[id] {
+			r.matchIndex[id] = match
+		}
+		if match+1 > r.nextIndex[id] {
+			r.nextIndex[id] = match + 1
+		}
+
+		r.advanceCommitIndex()
+
+		// A peer catching up is sent the rest of its entries without waiting for the next heartbeat.
+		if len(req.Entries) > 0 && r.nextIndex[id] <= r.lastLogIndex() {
+			go r.sendAppendEntries(id, peer)
+		}
+
+		return
+	}
+
+	// The logs diverge before next, so back up and retry on the next heartbeat. The follower's last
+	// log index lets us skip over entries it is missing entirely.
+	next = prev
+	if res.LastLogIndex+1 < next {
+		next = res.LastLogIndex + 1
+	}
+	if next < 1 {
+		next = 1
+	}
+	r.nextIndex[id] = next
+}
+
+// Returns the entries starting at the specified index that fit in a single AppendEntries request. At
+// least one entry is returned unless the index is past the end of the log. Must be called with the lock
+// held.
+func (r *raft) entriesFrom(next uint64) []*LogEntry {
+	var entries []*LogEntry
+	size := 0
+	for index := next; index <= r.lastLogIndex() && len(entries) < r.maxAppendEntries; index++ {
+		entry := r.entry(index)
+		if size += proto.Size(entry); len(entries) > 0 && size > r.maxAppendBytes {
+			break
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries
+}
+
+// Sends a snapshot to a peer that needs entries discarded from the log, in chunks. If there is no
+// snapshot recent enough, the applier is asked to take one, and it is sent on a later heartbeat.
+func (r *raft) sendSnapshot(id string, peer raftPeer) {
+	r.mtx.Lock()
+	if r.crashed || r.state != leader || r.sendingSnapshot[id] {
+		r.mtx.Unlock()
+		return
+	}
+
+	// The peer needs the entries after the snapshot, so it must not have been discarded as well.
+	snap := r.snapshot
+	if snap == nil || snap.pos.index < r.baseIndex {
+		r.snapshotRequested = true
+		r.commitCond.Broadcast()
+		r.mtx.Unlock()
+		return
+	}
+
+	term := r.currentTerm
+	r.sendingSnapshot[id] = true
+	r.mtx.Unlock()
+
+	defer func() {
+		r.mtx.Lock()
+		delete(r.sendingSnapshot, id)
+		r.mtx.Unlock()
+	}()
+
+	log.WithFields(log.Fields{
+		"id":    r.id,
+		"peer":  id,
+		"index": snap.pos.index,
+	}).Debug("Sending snapshot...")
+
+	ctx, cancel := context.WithTimeout(context.Background(), proposeTimeout)
+	defer cancel()
+
+	for offset := 0; ; {
+		end := offset + snapshotChunkSize
+		if end > len(snap.data) {
+			end = len(snap.data)
+		}
+
+		req := &InstallSnapshotRequest{
+			Term:              term,
+			LeaderId:          r.id,
+			LastIncludedIndex: snap.pos.index,
+			LastIncludedTerm:  snap.pos.term,
+			Offset:            uint64(offset),
+			Data:              snap.data[offset:end],
+			Done:              end == len(snap.data),
+		}
+
+		res, err := peer.InstallSnapshot(ctx, req)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"id":   r.id,
+				"peer": id,
+			}).Debugf("Failed to send snapshot, %v", err)
+
+			return
+		}
+
+		if !r.snapshotSent(id, term, req, res) {
+			return
+		}
+
+		offset = end
+	}
+}
+
+// Handles the response to a chunk of a snapshot. Returns true if the next chunk should be sent.
+func (r *raft) snapshotSent(id string, term uint64, req *InstallSnapshotRequest, res *InstallSnapshotResponse) bool {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if res.Term > r.currentTerm {
+		r.becomeFollower(res.Term)
+		r.resetElectionDeadline()
+		return false
+	}
+
+	if r.crashed || r.state != leader || r.currentTerm != term {
+		return false
+	}
+
+	if !req.Done {
+		return true
+	}
+
+	if req.LastIncludedIndex > r.matchIndex[id] {
+		r.matchIndex[id] = req.LastIncludedIndex
+	}
+	if req.LastIncludedIndex+1 > r.nextIndex[id] {
+		r.nextIndex[id] = req.LastIncludedIndex + 1
+	}
+
+	r.advanceCommitIndex()
+	return false
+}
+
+// Advances the commit index to the highest entry of the current term stored on a majority of nodes.
+// Must be called with the lock held.
+func (r *raft) advanceCommitIndex() {
+	for n := r.lastLogIndex(); n > r.commitIndex; n-- {
+		if r.entry(n).Term != r.currentTerm {
+			break
+		}
+
+		count := 1
+		for id := range r.peers {
+			if r.matchIndex[id] >= n {
+				count++
+			}
+		}
+
+		if count > (len(r.peers)+1)/2 {
+			r.commitIndex = n
+			r.commitCond.Broadcast()
+			return
+		}
+	}
+}
+
+// Handles an AppendEntries RPC from the leader.
+func (r *raft) appendEntries(req *AppendEntriesRequest) (*AppendEntriesResponse, error) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if r.crashed {
+		return nil, errCrashed
+	}
+
+	if req.Term < r.currentTerm {
+		return &AppendEntriesResponse{Term: r.currentTerm, Success: false, LastLogIndex: r.lastLogIndex()}, nil
+	}
+
+	if err := r.becomeFollower(req.Term); err != nil {
+		return nil, err
+	}
+
+	r.leaderID = req.LeaderId
+	r.resetElectionDeadline()
+
+	// Entries up to the base index are committed, so they match the leader's and can be skipped.
+	prev, prevTerm, entries := req.PrevLogIndex, req.PrevLogTerm, req.Entries
+	if prev < r.baseIndex {
+		skip := r.baseIndex - prev
+		if skip > uint64(len(entries)) {
+			skip = uint64(len(entries))
+		}
+
+		prev, prevTerm, entries = r.baseIndex, r.entry(r.baseIndex).Term, entries[skip:]
+	}
+
+	if prev > r.lastLogIndex() || r.entry(prev).Term != prevTerm {
+		last := r.lastLogIndex()
+		if prev > 0 && prev-1 < last {
+			last = prev - 1
+		}
+
+		return &AppendEntriesResponse{Term: r.currentTerm, Success: false, LastLogIndex: last}, nil
+	}
+
+	// Skip the entries we already have. Conflicting entries are never committed, so they can be
+	// discarded along with everything after them. The new entries are saved before we acknowledge
+	// them.
+	for i, entry := range entries {
+		index := prev + 1 + uint64(i)
+		if index <= r.lastLogIndex() && r.entry(index).Term == entry.Term {
+			continue
+		}
+
+		if err := r.appendLog(index, entries[i:]...); err != nil {
+			return nil, err
+		}
+
+		r.failWaiters(index, errLeadershipLost)
+		break
+	}
+
+	if req.LeaderCommit > r.commitIndex {
+		last := prev + uint64(len(entries))
+		if req.LeaderCommit < last {
+			last = req.LeaderCommit
+		}
+
+		if last > r.commitIndex {
+			r.commitIndex = last
+			r.commitCond.Broadcast()
+		}
+	}
+
+	return &AppendEntriesResponse{Term: r.currentTerm, Success: true, LastLogIndex: r.lastLogIndex()}, nil
+}
+
+// Handles a RequestVote RPC from a candidate.
+func (r *raft) requestVote(req *RequestVoteRequest) (*RequestVoteResponse, error) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if r.crashed {
+		return nil, errCrashed
+	}
+
+	if req.Term < r.currentTerm {
+		return &RequestVoteResponse{Term: r.currentTerm, VoteGranted: false}, nil
+	}
+
+	if req.Term > r.currentTerm {
+		if err := r.becomeFollower(req.Term); err != nil {
+			return nil, err
+		}
+	}
+
+	// Only vote for candidates whose logs are at least as up to date as ours, so that the new leader
+	// holds every committed entry.
+	upToDate := req.LastLogTerm > r.lastLogTerm() ||
+		(req.LastLogTerm == r.lastLogTerm() && req.LastLogIndex >= r.lastLogIndex())
+
+	if (r.votedFor == "" || r.votedFor == req.CandidateId) && upToDate {
+		r.votedFor = req.CandidateId
+		if err := r.saveState(); err != nil {
+			r.votedFor = ""
+			return nil, err
+		}
+
+		r.resetElectionDeadline()
+
+		return &RequestVoteResponse{Term: r.currentTerm, VoteGranted: true}, nil
+	}
+
+	return &RequestVoteResponse{Term: r.currentTerm, VoteGranted: false}, nil
+}
+
+// Handles an InstallSnapshot RPC from the leader. The chunks are collected until the last one, and the
+// snapshot is then restored by the applier before replying.
+func (r *raft) installSnapshot(req *InstallSnapshotRequest) (*InstallSnapshotResponse, error) {
+	r.mtx.Lock()
+
+	if r.crashed {
+		r.mtx.Unlock()
+		return nil, errCrashed
+	}
+
+	if req.Term < r.currentTerm {
+		res := &InstallSnapshotResponse{Term: r.currentTerm}
+		r.mtx.Unlock()
+		return res, nil
+	}
+
+	if err := r.becomeFollower(req.Term); err != nil {
+		r.mtx.Unlock()
+		return nil, err
+	}
+
+	r.leaderID = req.LeaderId
+	r.resetElectionDeadline()
+
+	if r.applyErr != nil {
+		err := r.applyErr
+		r.mtx.Unlock()
+		return nil, err
+	}
+
+	pos := logPosition{index: req.LastIncludedIndex, term: req.LastIncludedTerm}
+	if req.Offset == 0 {
+		r.incoming = &raftSnapshot{pos: pos}
+	}
+
+	if r.incoming == nil || r.incoming.pos != pos || uint64(len(r.incoming.data)) != req.Offset {
+		r.incoming = nil
+		r.mtx.Unlock()
+		return nil, status.Errorf(codes.FailedPrecondition, "unexpected snapshot chunk at offset %d", req.Offset)
+	}
+
+	r.incoming.data = append(r.incoming.data, req.Data...)
+	res := &InstallSnapshotResponse{Term: r.currentTerm}
+	if !req.Done {
+		r.mtx.Unlock()
+		return res, nil
+	}
+
+	restore := &snapshotRestore{snap: r.incoming, done: make(chan error, 1)}
+	r.incoming = nil
+
+	if r.pendingRestore != nil {
+		r.pendingRestore.done <- status.Error(codes.Aborted, "superseded by a later snapshot")
+	}
+
+	r.pendingRestore = restore
+	r.commitCond.Broadcast()
+	r.mtx.Unlock()
+
+	select {
+	case err := <-restore.done:
+		if err != nil {
+			return nil, err
+		}
+
+		return res, nil
+	case <-r.done:
+		return nil, errLeadershipLost
+	}
+}
+
+// Proposes an operation to the cluster, and waits for it to be committed and applied. Only the leader
+// accepts proposals.
+func (r *raft) propose(ctx context.Context, op *Operation) (bool, error) {
+	r.mtx.Lock()
+
+	if r.crashed {
+		r.mtx.Unlock()
+		return false, errCrashed
+	}
+
+	if r.applyErr != nil {
+		err := r.applyErr
+		r.mtx.Unlock()
+		return false, err
+	}
+
+	if r.state != leader {
+		err := notLeaderError(r.leaderID)
+		r.mtx.Unlock()
+		return false, err
+	}
+
+	entry := &LogEntry{Term: r.currentTerm, Operation: op}
+	index := r.lastLogIndex() + 1
+	if err := r.appendLog(index, entry); err != nil {
+		r.mtx.Unlock()
+		return false, err
+	}
+
+	ch := make(chan applyResult, 1)
+	r.waiters[index] = waiter{term: entry.Term, ch: ch}
+
+	r.advanceCommitIndex()
+	r.broadcastAppendEntries()
+	r.mtx.Unlock()
+
+	timer := time.NewTimer(proposeTimeout)
+	defer timer.Stop()
+
+	select {
+	case res := <-ch:
+		return res.success, res.err
+	case <-timer.C:
+		return false, errProposeTimeout
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+}
+
+// Checks whether the node may serve client requests, i.e. whether it is the leader and not crashed.
+// A new leader waits until it has applied the entries committed by previous leaders, so that it never
+// serves stale metadata.
+func (r *raft) checkLeader(ctx context.Context) error {
+	deadline := time.Now().Add(proposeTimeout)
+
+	for {
+		r.mtx.Lock()
+		if r.crashed {
+			r.mtx.Unlock()
+			return errCrashed
+		}
+
+		if r.applyErr != nil {
+			err := r.applyErr
+			r.mtx.Unlock()
+			return err
+		}
+
+		if r.state != leader {
+			err := notLeaderError(r.leaderID)
+			r.mtx.Unlock()
+			return err
+		}
+
+		ready := r.lastApplied >= r.readyIndex
+		r.mtx.Unlock()
+
+		if ready {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return errNotReady
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(r.heartbeatInterval / 5):
+		}
+	}
+}
+
+// Simulates a crash. A crashed node rejects all RPCs and takes no part in elections or replication
+// until it is restored.
+func (r *raft) crash() {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	log.WithField("id", r.id).Debug("Crashing...")
+
+	r.crashed = true
+	r.failWaiters(0, errCrashed)
+}
+
+// Restores a crashed node. It rejoins the cluster as a follower.
+func (r *raft) restore() {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	log.WithField("id", r.id).Debug("Restoring...")
+
+	r.crashed = false
+	r.state = follower
+	r.resetElectionDeadline()
+}
+
+func (r *raft) isLeader() (bool, string) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	return !r.crashed && r.state == leader, r.leaderID
+}
+
+func (r *raft) isCrashed() bool {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	return r.crashed
+}
+
+// Creates the error returned to clients that send requests to a node other than the leader. The
+// error has a FAILED_PRECONDITION status with an IsLeaderResponse detail naming the leader, if known.
+func notLeaderError(leaderID string) error {
+	st := status.New(codes.FailedPrecondition, "metadata store is not the leader")
+	st, err := st.WithDetails(&IsLeaderResponse{
+		Leader:   false,
+		LeaderId: leaderID,
+	})
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to attach status details, %v", err)
+	}
+
+	return st.Err()
+}
+
+// Checks whether an error returned by the metadata store indicates that the request was sent to a
+// node other than the leader. If so, the ID of the leader is returned if it is known.
+func IsNotLeader(err error) (string, bool) {
+	st, ok := status.FromError(err)
+	if !ok || st.Code() != codes.FailedPrecondition {
+		return "", false
+	}
+
+	for _, detail := range st.Details() {
+		if res, ok := detail.(*IsLeaderResponse); ok {
+			return res.LeaderId, true
+		}
+	}
+
+	return "", false
+}
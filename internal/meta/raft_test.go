@@ -0,0 +1,753 @@
+package meta
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"surfs/internal/block"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Implementation of raftPeer that calls another in-process metadata store directly.
+type localPeer struct {
+	store *MetadataStore
+}
+
+func (p localPeer) AppendEntries(ctx context.Context, in *AppendEntriesRequest, opts ...grpc.CallOption) (*AppendEntriesResponse, error) {
+	return p.store.AppendEntries(ctx, in)
+}
+
+func (p localPeer) RequestVote(ctx context.Context, in *RequestVoteRequest, opts ...grpc.CallOption) (*RequestVoteResponse, error) {
+	return p.store.RequestVote(ctx, in)
+}
+
+func (p localPeer) InstallSnapshot(ctx context.Context, in *InstallSnapshotRequest, opts ...grpc.CallOption) (*InstallSnapshotResponse, error) {
+	return p.store.InstallSnapshot(ctx, in)
+}
+
+// Implementation of raftStateMachine that applies operations with a function and persists nothing.
+type funcStateMachine func(op *Operation) (bool, error)
+
+func (f funcStateMachine) apply(pos logPosition, op *Operation) (bool, error) {
+	return f(op)
+}
+
+func (f funcStateMachine) appliedPosition() (logPosition, error) {
+	return logPosition{}, nil
+}
+
+func (f funcStateMachine) snapshot() ([]byte, error) {
+	return nil, errors.New("snapshots are not supported")
+}
+
+func (f funcStateMachine) restoreSnapshot(pos logPosition, data []byte) error {
+	return errors.New("snapshots are not supported")
+}
+
+// Creates a cluster of in-process metadata stores sharing the specified block store.
+func newTestCluster(t *testing.T, n int, mock *mockClient) []*MetadataStore {
+	stores := make([]*MetadataStore, n)
+	storages := make([]raftStorage, n)
+	for i := range stores {
+		stores[i] = &MetadataStore{
+			client: mock,
+			engine: newMapEngine(),
+		}
+		storages[i] = memoryRaftStorage{}
+	}
+
+	startTestCluster(t, stores, storages)
+	return stores
+}
+
+// Joins the specified stores into a cluster, in which each node saves its Raft state to the
+// corresponding storage, and starts them.
+func startTestCluster(t *testing.T, stores []*MetadataStore, storages []raftStorage) {
+	for i, store := range stores {
+		peers := make(map[string]raftPeer)
+		for j, other := range stores {
+			if i != j {
+				peers[fmt.Sprintf("node%d", j)] = localPeer{store: other}
+			}
+		}
+
+		r, err := newRaft(fmt.Sprintf("node%d", i), peers, storages[i], store)
+		if err != nil {
+			t.Fatalf("failed to create raft node, %v", err)
+		}
+
+		r.electionTimeout = 50 * time.Millisecond
+		r.heartbeatInterval = 10 * time.Millisecond
+		store.raft = r
+	}
+
+	for _, store := range stores {
+		store.raft.start()
+	}
+}
+
+func closeTestCluster(stores []*MetadataStore) {
+	for _, store := range stores {
+		store.Close()
+	}
+}
+
+// Waits for exactly one of the stores that are not crashed to become leader, and returns it.
+func waitForLeader(t *testing.T, stores []*MetadataStore) *MetadataStore {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		var leaders []*MetadataStore
+		for _, store := range stores {
+			res, err := store.IsLeader(context.Background(), &IsLeaderRequest{})
+			assert.Nil(t, err)
+
+			if res.Leader {
+				leaders = append(leaders, store)
+			}
+		}
+
+		if len(leaders) == 1 {
+			return leaders[0]
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatalf("no leader elected")
+	return nil
+}
+
+// Waits for the specified store to have applied the specified version of a file.
+func waitForVersion(t *testing.T, store *MetadataStore, filename string, version uint64) Stat {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		st, _, err := store.getFileMetadata(filename)
+		assert.Nil(t, err)
+
+		if st.version == version {
+			return st
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatalf("version %d of %s was not applied", version, filename)
+	return Stat{}
+}
+
+func TestRaft_Replication(t *testing.T) {
+	mock := &mockClient{blocks: map[string][]byte{
+		"hash1": []byte("block1"),
+		"hash2": []byte("block2"),
+	}}
+
+	stores := newTestCluster(t, 3, mock)
+	defer closeTestCluster(stores)
+
+	leader := waitForLeader(t, stores)
+
+	expectModifyFile(leader, &ModifyFileRequest{
+		Filename: "file1",
+		Version:  1,
+		HashList: []string{"hash1", "hash2"},
+	}, &ModifyFileResponse{Success: true}, t)
+
+	expectDeleteFile(leader, &DeleteFileRequest{Filename: "file1", Version: 2}, &DeleteFileResponse{Success: true}, t)
+	expectModifyFile(leader, &ModifyFileRequest{
+		Filename: "file1",
+		Version:  3,
+		HashList: []string{"hash2"},
+	}, &ModifyFileResponse{Success: true}, t)
+
+	// Stale versions are rejected.
+	expectModifyFile(leader, &ModifyFileRequest{
+		Filename: "file1",
+		Version:  3,
+		HashList: []string{"hash1"},
+	}, &ModifyFileResponse{Success: false}, t)
+
+	for _, store := range stores {
+		st := waitForVersion(t, store, "file1", 3)
+		assert.Equal(t, []string{"hash2"}, st.hashList)
+	}
+}
+
+func TestRaft_FollowersRejectRequests(t *testing.T) {
+	mock := &mockClient{blocks: map[string][]byte{"hash1": []byte("block1")}}
+
+	stores := newTestCluster(t, 3, mock)
+	defer closeTestCluster(stores)
+
+	leader := waitForLeader(t, stores)
+	leaderID := leader.raft.id
+
+	for _, store := range stores {
+		if store == leader {
+			continue
+		}
+
+		// Wait for the follower to learn who the leader is.
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			if _, id := store.raft.isLeader(); id == leaderID {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		_, err := store.ModifyFile(context.Background(), &ModifyFileRequest{
+			Filename: "file1",
+			Version:  1,
+			HashList: []string{"hash1"},
+		})
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+		id, ok := IsNotLeader(err)
+		assert.True(t, ok)
+		assert.Equal(t, leaderID, id)
+
+		_, err = store.ReadFile(context.Background(), &ReadFileRequest{Filename: "file1"})
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	}
+}
+
+func TestRaft_Failover(t *testing.T) {
+	mock := &mockClient{blocks: map[string][]byte{
+		"hash1": []byte("block1"),
+		"hash2": []byte("block2"),
+	}}
+
+	stores := newTestCluster(t, 5, mock)
+	defer closeTestCluster(stores)
+
+	leader := waitForLeader(t, stores)
+	expectModifyFile(leader, &ModifyFileRequest{
+		Filename: "file1",
+		Version:  1,
+		HashList: []string{"hash1"},
+	}, &ModifyFileResponse{Success: true}, t)
+
+	// Crash the leader. A new leader should be elected, and it must hold the committed modification.
+	_, err := leader.Crash(context.Background(), &CrashRequest{})
+	assert.Nil(t, err)
+
+	res, err := leader.IsCrashed(context.Background(), &IsCrashedRequest{})
+	assert.Nil(t, err)
+	assert.True(t, res.Crashed)
+
+	_, err = leader.ReadFile(context.Background(), &ReadFileRequest{Filename: "file1"})
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+
+	oldLeader := leader
+	leader = waitForLeader(t, stores)
+	assert.NotEqual(t, oldLeader, leader)
+
+	expectReadFile(leader, "file1", &ReadFileResponse{Version: 1, HashList: []string{"hash1"}, BlockSize: block.LegacyBlockSize}, t)
+	expectModifyFile(leader, &ModifyFileRequest{
+		Filename: "file1",
+		Version:  2,
+		HashList: []string{"hash2"},
+	}, &ModifyFileResponse{Success: true}, t)
+
+	// The old leader catches up once it is restored.
+	_, err = oldLeader.Restore(context.Background(), &RestoreRequest{})
+	assert.Nil(t, err)
+
+	st := waitForVersion(t, oldLeader, "file1", 2)
+	assert.Equal(t, []string{"hash2"}, st.hashList)
+}
+
+func TestRaft_NoQuorum(t *testing.T) {
+	mock := &mockClient{blocks: map[string][]byte{"hash1": []byte("block1")}}
+
+	stores := newTestCluster(t, 3, mock)
+	defer closeTestCluster(stores)
+
+	leader := waitForLeader(t, stores)
+
+	// Without a majority, the leader cannot commit, so the modification must not be applied.
+	for _, store := range stores {
+		if store != leader {
+			_, err := store.Crash(context.Background(), &CrashRequest{})
+			assert.Nil(t, err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	_, err := leader.ModifyFile(ctx, &ModifyFileRequest{
+		Filename: "file1",
+		Version:  1,
+		HashList: []string{"hash1"},
+	})
+	assert.NotNil(t, err)
+
+	st, _, err := leader.getFileMetadata("file1")
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(0), st.version)
+
+	// Once the followers are restored, the cluster makes progress again.
+	for _, store := range stores {
+		if store != leader {
+			_, err := store.Restore(context.Background(), &RestoreRequest{})
+			assert.Nil(t, err)
+		}
+	}
+
+	// The uncommitted modification may or may not survive, depending on which node wins the next
+	// election, so the client retries with the current version until the modification succeeds.
+	for attempt := 0; attempt < 10; attempt++ {
+		leader = waitForLeader(t, stores)
+
+		readRes, err := leader.ReadFile(context.Background(), &ReadFileRequest{Filename: "file1"})
+		if err != nil {
+			continue
+		}
+
+		res, err := leader.ModifyFile(context.Background(), &ModifyFileRequest{
+			Filename: "file1",
+			Version:  readRes.Version + 1,
+			HashList: []string{"hash1"},
+		})
+		if err == nil && res.Success {
+			return
+		}
+	}
+
+	t.Fatalf("failed to modify file after restoring quorum")
+}
+
+func TestRaft_VoteSurvivesRestart(t *testing.T) {
+	dir, err := ioutil.TempDir("", "surfs")
+	assert.Nil(t, err)
+
+	defer os.RemoveAll(dir)
+
+	open := func() *raft {
+		storage, err := openFileRaftStorage(dir)
+		assert.Nil(t, err)
+
+		r, err := newRaft("node0", nil, storage, funcStateMachine(func(op *Operation) (bool, error) { return true, nil }))
+		assert.Nil(t, err)
+
+		return r
+	}
+
+	r := open()
+	res, err := r.requestVote(&RequestVoteRequest{Term: 5, CandidateId: "node1"})
+	assert.Nil(t, err)
+	assert.True(t, res.VoteGranted)
+	r.stop()
+
+	// After a restart, the node must not vote for another candidate in the same term.
+	r = open()
+	defer r.stop()
+
+	res, err = r.requestVote(&RequestVoteRequest{Term: 5, CandidateId: "node2"})
+	assert.Nil(t, err)
+	assert.False(t, res.VoteGranted)
+	assert.Equal(t, uint64(5), res.Term)
+
+	res, err = r.requestVote(&RequestVoteRequest{Term: 5, CandidateId: "node1"})
+	assert.Nil(t, err)
+	assert.True(t, res.VoteGranted)
+}
+
+func TestRaft_Restart(t *testing.T) {
+	mock := &mockClient{blocks: map[string][]byte{
+		"hash1": []byte("block1"),
+		"hash2": []byte("block2"),
+	}}
+
+	dir, err := ioutil.TempDir("", "surfs")
+	assert.Nil(t, err)
+
+	defer os.RemoveAll(dir)
+
+	// Opens a cluster whose nodes persist their metadata and Raft state in subdirectories.
+	open := func() []*MetadataStore {
+		stores := make([]*MetadataStore, 3)
+		storages := make([]raftStorage, 3)
+		for i := range stores {
+			nodeDir := filepath.Join(dir, fmt.Sprintf("node%d", i))
+			assert.Nil(t, os.MkdirAll(nodeDir, 0755))
+
+			engine, err := openKeychainEngine(filepath.Join(nodeDir, MetadataFilename))
+			assert.Nil(t, err)
+
+			storages[i], err = openFileRaftStorage(nodeDir)
+			assert.Nil(t, err)
+
+			stores[i] = &MetadataStore{client: mock, engine: engine}
+			stores[i].SetRetention(DefaultRetainVersions, 0)
+		}
+
+		startTestCluster(t, stores, storages)
+		return stores
+	}
+
+	stores := open()
+	leader := waitForLeader(t, stores)
+
+	for version, hash := range []string{"hash1", "hash2"} {
+		expectModifyFile(leader, &ModifyFileRequest{
+			Filename: "file1",
+			Version:  uint64(version + 1),
+			HashList: []string{hash},
+		}, &ModifyFileResponse{Success: true}, t)
+	}
+
+	terms := make([]uint64, len(stores))
+	for i, store := range stores {
+		waitForVersion(t, store, "file1", 2)
+
+		store.raft.mtx.Lock()
+		terms[i] = store.raft.currentTerm
+		store.raft.mtx.Unlock()
+	}
+
+	closeTestCluster(stores)
+
+	// The restarted nodes remember their terms and logs. Applying the log again leaves the metadata
+	// as it was, and the cluster carries on from there.
+	stores = open()
+	defer closeTestCluster(stores)
+
+	for i, store := range stores {
+		store.raft.mtx.Lock()
+		assert.True(t, store.raft.currentTerm >= terms[i])
+		assert.True(t, store.raft.lastLogIndex() >= 3)
+		store.raft.mtx.Unlock()
+	}
+
+	leader = waitForLeader(t, stores)
+	expectReadFile(leader, "file1", &ReadFileResponse{Version: 2, HashList: []string{"hash2"}, BlockSize: block.LegacyBlockSize}, t)
+
+	expectModifyFile(leader, &ModifyFileRequest{
+		Filename: "file1",
+		Version:  3,
+		HashList: []string{"hash1"},
+	}, &ModifyFileResponse{Success: true}, t)
+
+	for _, store := range stores {
+		st := waitForVersion(t, store, "file1", 3)
+		assert.Equal(t, []string{"hash1"}, st.hashList)
+		assert.Len(t, st.history, 2)
+	}
+}
+
+func TestRaft_RestartSkipsAppliedEntries(t *testing.T) {
+	dir, err := ioutil.TempDir("", "surfs")
+	assert.Nil(t, err)
+
+	defer os.RemoveAll(dir)
+
+	open := func() *MetadataStore {
+		engine, err := openKeychainEngine(filepath.Join(dir, MetadataFilename))
+		assert.Nil(t, err)
+
+		storage, err := openFileRaftStorage(dir)
+		assert.Nil(t, err)
+
+		store := &MetadataStore{client: &mockClient{}, engine: engine}
+		startTestCluster(t, []*MetadataStore{store}, []raftStorage{storage})
+		waitForLeader(t, []*MetadataStore{store})
+
+		return store
+	}
+
+	store := open()
+
+	ok, err := store.commit(context.Background(), &Operation{Type: Operation_MODIFY, Filename: "a/b", Version: 1, HashList: []string{}})
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	// Creating a file at the path of a directory is rejected.
+	ok, err = store.commit(context.Background(), &Operation{Type: Operation_MODIFY, Filename: "a", Version: 1, HashList: []string{}})
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	ok, err = store.commit(context.Background(), &Operation{Type: Operation_DELETE, Filename: "a/b", Version: 2})
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	store.Close()
+
+	// Applying the log again would create the file, since the directory no longer exists. The
+	// restarted node only applies the entries after those it has persisted.
+	store = open()
+	defer store.Close()
+
+	store.raft.mtx.Lock()
+	assert.True(t, store.raft.lastApplied >= 4)
+	store.raft.mtx.Unlock()
+
+	_, ok, err = store.getFileMetadata("a")
+	assert.Nil(t, err)
+	assert.False(t, ok)
+}
+
+func TestRaft_ApplyRetries(t *testing.T) {
+	var mtx sync.Mutex
+	var applied []string
+	failures := 2
+
+	apply := func(op *Operation) (bool, error) {
+		mtx.Lock()
+		defer mtx.Unlock()
+
+		if op.Type == Operation_NOOP {
+			return true, nil
+		}
+
+		applied = append(applied, op.Filename)
+		if failures > 0 {
+			failures--
+			return false, errors.New("disk full")
+		}
+
+		return true, nil
+	}
+
+	r, err := newRaft("node0", nil, memoryRaftStorage{}, funcStateMachine(apply))
+	assert.Nil(t, err)
+
+	r.electionTimeout = 10 * time.Millisecond
+	r.heartbeatInterval = 5 * time.Millisecond
+	r.start()
+	defer r.stop()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if leader, _ := r.isLeader(); leader {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// An operation that fails to apply is retried rather than skipped, and the operations after it
+	// wait for it.
+	ok, err := r.propose(context.Background(), &Operation{Type: Operation_MODIFY, Filename: "file1"})
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	ok, err = r.propose(context.Background(), &Operation{Type: Operation_MODIFY, Filename: "file2"})
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	mtx.Lock()
+	defer mtx.Unlock()
+	assert.Equal(t, []string{"file1", "file1", "file1", "file2"}, applied)
+}
+
+func TestRaft_ApplyStopsOnPermanentError(t *testing.T) {
+	var mtx sync.Mutex
+	var applied []string
+
+	apply := func(op *Operation) (bool, error) {
+		mtx.Lock()
+		defer mtx.Unlock()
+
+		if op.Type == Operation_NOOP {
+			return true, nil
+		}
+
+		applied = append(applied, op.Filename)
+		if op.Filename == "file1" {
+			return false, permanentApplyError{err: errMalformedStat}
+		}
+
+		return true, nil
+	}
+
+	r, err := newRaft("node0", nil, memoryRaftStorage{}, funcStateMachine(apply))
+	assert.Nil(t, err)
+
+	r.electionTimeout = 10 * time.Millisecond
+	r.heartbeatInterval = 5 * time.Millisecond
+	r.start()
+	defer r.stop()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if leader, _ := r.isLeader(); leader {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// An operation that fails permanently is not retried. The node reports the failure instead of
+	// applying further operations, and steps down for good.
+	_, err = r.propose(context.Background(), &Operation{Type: Operation_MODIFY, Filename: "file1"})
+	assert.Equal(t, codes.Internal, status.Code(err))
+
+	_, err = r.propose(context.Background(), &Operation{Type: Operation_MODIFY, Filename: "file2"})
+	assert.Equal(t, codes.Internal, status.Code(err))
+	assert.Equal(t, codes.Internal, status.Code(r.checkLeader(context.Background())))
+
+	time.Sleep(10 * r.electionTimeout)
+	leader, _ := r.isLeader()
+	assert.False(t, leader)
+
+	mtx.Lock()
+	defer mtx.Unlock()
+	assert.Equal(t, []string{"file1"}, applied)
+}
+
+func TestMetadataStore_ApplyErrors(t *testing.T) {
+	store := &MetadataStore{client: &mockClient{}, engine: newMapEngine()}
+
+	// An operation of an unknown type fails however many times it is applied.
+	_, err := store.apply(logPosition{index: 1, term: 1}, &Operation{Type: Operation_Type(100), Filename: "file1", Version: 1})
+	_, ok := err.(permanentApplyError)
+	assert.True(t, ok)
+
+	// Failing to read the engine may not recur.
+	ioErr := errors.New("input/output error")
+	assert.Equal(t, ioErr, applyError(ioErr))
+	assert.Nil(t, applyError(nil))
+}
+
+func TestRaft_AppendEntriesLimits(t *testing.T) {
+	r, err := newRaft("node0", nil, memoryRaftStorage{}, funcStateMachine(nil))
+	assert.Nil(t, err)
+
+	for i := 0; i < 5; i++ {
+		assert.Nil(t, r.appendLog(r.lastLogIndex()+1, testLogEntry(1, fmt.Sprintf("file%d", i))))
+	}
+
+	r.maxAppendEntries = 3
+	r.maxAppendBytes = 2 * proto.Size(testLogEntry(1, "file0"))
+
+	assert.Len(t, r.entriesFrom(1), 2)
+	assert.Len(t, r.entriesFrom(5), 1)
+	assert.Empty(t, r.entriesFrom(6))
+
+	r.maxAppendBytes = 1 << 20
+	assert.Len(t, r.entriesFrom(1), 3)
+
+	// An entry larger than the size limit is still sent on its own.
+	r.maxAppendBytes = 1
+	assert.Len(t, r.entriesFrom(1), 1)
+}
+
+func TestRaft_SnapshotCatchUp(t *testing.T) {
+	mock := &mockClient{blocks: map[string][]byte{"hash1": []byte("block1")}}
+
+	stores := newTestCluster(t, 3, mock)
+	defer closeTestCluster(stores)
+
+	for _, store := range stores {
+		store.raft.mtx.Lock()
+		store.raft.logRetain = 2
+		store.raft.maxAppendEntries = 2
+		store.raft.mtx.Unlock()
+	}
+
+	leader := waitForLeader(t, stores)
+
+	var lagging *MetadataStore
+	for _, store := range stores {
+		if store != leader {
+			lagging = store
+			break
+		}
+	}
+
+	_, err := lagging.Crash(context.Background(), &CrashRequest{})
+	assert.Nil(t, err)
+
+	for version := uint64(1); version <= 10; version++ {
+		expectModifyFile(leader, &ModifyFileRequest{
+			Filename: "file1",
+			Version:  version,
+			HashList: []string{"hash1"},
+		}, &ModifyFileResponse{Success: true}, t)
+	}
+
+	// The leader discards applied entries, so the lagging follower must be sent a snapshot.
+	leader.raft.mtx.Lock()
+	assert.True(t, leader.raft.baseIndex > 0)
+	leader.raft.mtx.Unlock()
+
+	_, err = lagging.Restore(context.Background(), &RestoreRequest{})
+	assert.Nil(t, err)
+
+	waitForVersion(t, lagging, "file1", 10)
+
+	leader.raft.mtx.Lock()
+	assert.NotNil(t, leader.raft.snapshot)
+	leader.raft.mtx.Unlock()
+
+	// Entries after the snapshot are replicated as usual.
+	expectModifyFile(leader, &ModifyFileRequest{
+		Filename: "file2",
+		Version:  1,
+		HashList: []string{"hash1"},
+	}, &ModifyFileResponse{Success: true}, t)
+
+	waitForVersion(t, lagging, "file2", 1)
+}
+
+func TestRaft_RestartAfterSnapshot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "surfs")
+	assert.Nil(t, err)
+
+	defer os.RemoveAll(dir)
+
+	source := &MetadataStore{client: &mockClient{}, engine: newMapEngine()}
+	ok, err := source.apply(logPosition{index: 1, term: 1}, &Operation{Type: Operation_MODIFY, Filename: "file1", Version: 1, HashList: []string{}})
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	data, err := source.snapshot()
+	assert.Nil(t, err)
+
+	engine, err := openKeychainEngine(filepath.Join(dir, MetadataFilename))
+	assert.Nil(t, err)
+
+	storage, err := openFileRaftStorage(dir)
+	assert.Nil(t, err)
+
+	_, err = storage.load()
+	assert.Nil(t, err)
+	assert.Nil(t, storage.appendEntries(1, []*LogEntry{testLogEntry(1, "file2"), testLogEntry(1, "file3")}))
+
+	// Simulate a crash after a snapshot was restored, but before the log was compacted.
+	store := &MetadataStore{client: &mockClient{}, engine: engine}
+	assert.Nil(t, store.restoreSnapshot(logPosition{index: 5, term: 2}, data))
+
+	r, err := newRaft("node0", nil, storage, store)
+	assert.Nil(t, err)
+
+	defer store.Close()
+
+	assert.Equal(t, uint64(5), r.baseIndex)
+	assert.Equal(t, uint64(5), r.lastApplied)
+	assert.Equal(t, uint64(5), r.lastLogIndex())
+	assert.Equal(t, uint64(2), r.lastLogTerm())
+
+	store.raft = r
+	r.start()
+	waitForLeader(t, []*MetadataStore{store})
+
+	ok, err = store.commit(context.Background(), &Operation{Type: Operation_MODIFY, Filename: "file1", Version: 2, HashList: []string{}})
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	st, _, err := store.getFileMetadata("file1")
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(2), st.version)
+}
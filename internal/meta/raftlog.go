@@ -0,0 +1,459 @@
+package meta
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/golang/protobuf/proto"
+	log "github.com/sirupsen/logrus"
+)
+
+// Names of the files, relative to the data directory, that hold the Raft state of a replicated store:
+// the current term and vote, and the log.
+const (
+	RaftStateFilename = "raft.state"
+	RaftLogFilename   = "raft.log"
+)
+
+// Largest log entry read back from the log file, which protects against allocating huge buffers for
+// a corrupted length.
+const maxLogEntrySize = 1 << 30
+
+var errMalformedRaftState = errors.New("malformed raft state")
+
+// Size of the header at the start of the log file: the index and term of the last entry discarded from
+// the start of the log, as big-endian uint64s, and the CRC-32 of both.
+const logHeaderSize = 20
+
+// The state a Raft node restores when it restarts.
+type savedRaftState struct {
+	term     uint64
+	votedFor string
+
+	// The last entry discarded from the start of the log, or the zero position if none was, and the
+	// entries after it.
+	base    logPosition
+	entries []*LogEntry
+}
+
+// Storage for the state a Raft node must not forget when it restarts: its current term, the candidate
+// it voted for in that term, and its log. Changes must be durable before the methods return, since the
+// node replies to RPCs as soon as they do.
+type raftStorage interface {
+	// Returns the stored term, vote and log.
+	load() (savedRaftState, error)
+
+	// Stores the current term and vote.
+	setState(term uint64, votedFor string) error
+
+	// Stores entries starting at the specified log index, discarding any stored entries at or after it.
+	appendEntries(index uint64, entries []*LogEntry) error
+
+	// Discards the entries up to and including the specified position, keeping those after it. The
+	// position may be past the end of the log, in which case every entry is discarded.
+	compact(base logPosition) error
+
+	// Closes the storage, releasing any underlying resources.
+	close() error
+}
+
+// Implementation of raftStorage that forgets everything, for stores that keep their metadata in
+// memory only and so lose it when they restart anyway.
+type memoryRaftStorage struct{}
+
+func (memoryRaftStorage) load() (savedRaftState, error) {
+	return savedRaftState{}, nil
+}
+
+func (memoryRaftStorage) setState(term uint64, votedFor string) error {
+	return nil
+}
+
+func (memoryRaftStorage) appendEntries(index uint64, entries []*LogEntry) error {
+	return nil
+}
+
+func (memoryRaftStorage) compact(base logPosition) error {
+	return nil
+}
+
+func (memoryRaftStorage) close() error {
+	return nil
+}
+
+// Implementation of raftStorage backed by files in the data directory. The term and vote are
+// replaced atomically in the state file. The log file starts with a header holding the position of
+// the last entry discarded by compaction, followed by one record per entry: the length of the encoded
+// entry as a uvarint, the entry, and the CRC-32 of the entry. Records are appended and synced before
+// returning, and a record torn by a crash is discarded when the log is loaded. Compaction writes the
+// entries it keeps to a new log file, which replaces the old one.
+type fileRaftStorage struct {
+	dir string
+	f   *os.File
+
+	// The last entry discarded from the start of the log.
+	base logPosition
+
+	// The offset of each record in the log file, by log index minus the base index minus one, and the
+	// size of the file.
+	offsets []int64
+	size    int64
+}
+
+// Opens the Raft state in the specified directory, creating it if it does not exist.
+func openFileRaftStorage(dir string) (*fileRaftStorage, error) {
+	f, err := os.OpenFile(filepath.Join(dir, RaftLogFilename), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	return &fileRaftStorage{dir: dir, f: f}, nil
+}
+
+func (s *fileRaftStorage) load() (savedRaftState, error) {
+	term, votedFor, err := s.loadState()
+	if err != nil {
+		return savedRaftState{}, err
+	}
+
+	if s.base, err = s.loadHeader(); err != nil {
+		return savedRaftState{}, err
+	}
+
+	r := bufio.NewReader(s.f)
+
+	var entries []*LogEntry
+	s.offsets = s.offsets[:0]
+	s.size = logHeaderSize
+
+	for {
+		entry, n, err := readLogRecord(r)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			log.WithFields(log.Fields{
+				"index":  s.base.index + uint64(len(entries)) + 1,
+				"offset": s.size,
+			}).Warnf("Discarding the end of the Raft log, %v", err)
+
+			if err := s.truncate(s.size); err != nil {
+				return savedRaftState{}, err
+			}
+
+			break
+		}
+
+		entries = append(entries, entry)
+		s.offsets = append(s.offsets, s.size)
+		s.size += n
+	}
+
+	return savedRaftState{term: term, votedFor: votedFor, base: s.base, entries: entries}, nil
+}
+
+// Reads the header of the log file, leaving the file positioned after it. The header of an empty log
+// file, which has just been created, is written first.
+func (s *fileRaftStorage) loadHeader() (logPosition, error) {
+	info, err := s.f.Stat()
+	if err != nil {
+		return logPosition{}, err
+	}
+
+	if info.Size() == 0 {
+		if _, err := s.f.WriteAt(marshalLogHeader(logPosition{}), 0); err != nil {
+			return logPosition{}, err
+		}
+
+		if err := s.f.Sync(); err != nil {
+			return logPosition{}, err
+		}
+	}
+
+	var header [logHeaderSize]byte
+	if _, err := s.f.ReadAt(header[:], 0); err != nil {
+		return logPosition{}, errMalformedRaftState
+	}
+
+	if crc32.ChecksumIEEE(header[:16]) != binary.BigEndian.Uint32(header[16:]) {
+		return logPosition{}, errMalformedRaftState
+	}
+
+	if _, err := s.f.Seek(logHeaderSize, io.SeekStart); err != nil {
+		return logPosition{}, err
+	}
+
+	return logPosition{
+		index: binary.BigEndian.Uint64(header[:8]),
+		term:  binary.BigEndian.Uint64(header[8:16]),
+	}, nil
+}
+
+// Encodes the header of a log file whose last discarded entry is at the specified position.
+func marshalLogHeader(base logPosition) []byte {
+	header := make([]byte, logHeaderSize)
+	binary.BigEndian.PutUint64(header[:8], base.index)
+	binary.BigEndian.PutUint64(header[8:16], base.term)
+	binary.BigEndian.PutUint32(header[16:], crc32.ChecksumIEEE(header[:16]))
+	return header
+}
+
+// Reads the state file, which holds the term as a big-endian uint64, the vote prefixed by its length
+// as a uvarint, and the CRC-32 of the preceding bytes. A missing state file is the zero state.
+func (s *fileRaftStorage) loadState() (uint64, string, error) {
+	b, err := ioutil.ReadFile(filepath.Join(s.dir, RaftStateFilename))
+	if os.IsNotExist(err) {
+		return 0, "", nil
+	} else if err != nil {
+		return 0, "", err
+	}
+
+	if len(b) < 12 {
+		return 0, "", errMalformedRaftState
+	}
+
+	body, sum := b[:len(b)-4], binary.BigEndian.Uint32(b[len(b)-4:])
+	if crc32.ChecksumIEEE(body) != sum {
+		return 0, "", errMalformedRaftState
+	}
+
+	r := bytes.NewReader(body[8:])
+	votedFor, err := readString(r)
+	if err != nil || r.Len() != 0 {
+		return 0, "", errMalformedRaftState
+	}
+
+	return binary.BigEndian.Uint64(body[:8]), votedFor, nil
+}
+
+// Replaces the state file by writing a temporary file and renaming it into place, so a crash leaves
+// either the old or the new state.
+func (s *fileRaftStorage) setState(term uint64, votedFor string) error {
+	var buf bytes.Buffer
+	var scratch [binary.MaxVarintLen64]byte
+
+	binary.BigEndian.PutUint64(scratch[:8], term)
+	buf.Write(scratch[:8])
+
+	n := binary.PutUvarint(scratch[:], uint64(len(votedFor)))
+	buf.Write(scratch[:n])
+	buf.WriteString(votedFor)
+
+	binary.BigEndian.PutUint32(scratch[:4], crc32.ChecksumIEEE(buf.Bytes()))
+	buf.Write(scratch[:4])
+
+	f, err := ioutil.TempFile(s.dir, RaftStateFilename+".tmp")
+	if err != nil {
+		return err
+	}
+
+	tmp := f.Name()
+	defer os.Remove(tmp)
+
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp, filepath.Join(s.dir, RaftStateFilename)); err != nil {
+		return err
+	}
+
+	return syncDir(s.dir)
+}
+
+func (s *fileRaftStorage) appendEntries(index uint64, entries []*LogEntry) error {
+	if index <= s.base.index || index > s.base.index+uint64(len(s.offsets))+1 {
+		return errMalformedRaftState
+	}
+
+	if i := index - s.base.index - 1; i < uint64(len(s.offsets)) {
+		if err := s.truncate(s.offsets[i]); err != nil {
+			return err
+		}
+
+		s.offsets = s.offsets[:i]
+	}
+
+	if len(entries) == 0 {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	offsets := make([]int64, 0, len(entries))
+	for _, entry := range entries {
+		offsets = append(offsets, s.size+int64(buf.Len()))
+		if err := writeLogRecord(&buf, entry); err != nil {
+			return err
+		}
+	}
+
+	if _, err := s.f.WriteAt(buf.Bytes(), s.size); err != nil {
+		return err
+	}
+
+	if err := s.f.Sync(); err != nil {
+		return err
+	}
+
+	s.offsets = append(s.offsets, offsets...)
+	s.size += int64(buf.Len())
+	return nil
+}
+
+// Writes the records after the new base to a temporary file, which is then renamed over the log
+// file, so a crash leaves either the old or the new log.
+func (s *fileRaftStorage) compact(base logPosition) error {
+	if base.index <= s.base.index {
+		return nil
+	}
+
+	// The offset of the first record kept, which is the end of the file if none is.
+	start := s.size
+	kept := base.index - s.base.index
+	if kept < uint64(len(s.offsets)) {
+		start = s.offsets[kept]
+	} else {
+		kept = uint64(len(s.offsets))
+	}
+
+	path := filepath.Join(s.dir, RaftLogFilename)
+	f, err := ioutil.TempFile(s.dir, RaftLogFilename+".tmp")
+	if err != nil {
+		return err
+	}
+
+	tmp := f.Name()
+	defer os.Remove(tmp)
+
+	if _, err := f.Write(marshalLogHeader(base)); err != nil {
+		f.Close()
+		return err
+	}
+
+	if _, err := io.Copy(f, io.NewSectionReader(s.f, start, s.size-start)); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		f.Close()
+		return err
+	}
+
+	s.f.Close()
+	s.f = f
+
+	shift := start - logHeaderSize
+	offsets := make([]int64, 0, uint64(len(s.offsets))-kept)
+	for _, offset := range s.offsets[kept:] {
+		offsets = append(offsets, offset-shift)
+	}
+
+	s.base = base
+	s.offsets = offsets
+	s.size -= shift
+	return syncDir(s.dir)
+}
+
+// Truncates the log file to the specified size and syncs it.
+func (s *fileRaftStorage) truncate(size int64) error {
+	if err := s.f.Truncate(size); err != nil {
+		return err
+	}
+
+	if err := s.f.Sync(); err != nil {
+		return err
+	}
+
+	s.size = size
+	return nil
+}
+
+func (s *fileRaftStorage) close() error {
+	return s.f.Close()
+}
+
+// Writes a log entry as a record of the log file.
+func writeLogRecord(w io.Writer, entry *LogEntry) error {
+	b, err := proto.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	var scratch [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(scratch[:], uint64(len(b)))
+	if _, err := w.Write(scratch[:n]); err != nil {
+		return err
+	}
+
+	if _, err := w.Write(b); err != nil {
+		return err
+	}
+
+	binary.BigEndian.PutUint32(scratch[:4], crc32.ChecksumIEEE(b))
+	_, err = w.Write(scratch[:4])
+	return err
+}
+
+// Reads a record of the log file, and returns the entry and the size of the record. Returns io.EOF at
+// the end of the file, and another error if the record is incomplete or corrupted.
+func readLogRecord(r *bufio.Reader) (*LogEntry, int64, error) {
+	if _, err := r.Peek(1); err == io.EOF {
+		return nil, 0, io.EOF
+	}
+
+	size, err := binary.ReadUvarint(r)
+	if err != nil || size > maxLogEntrySize {
+		return nil, 0, errMalformedRaftState
+	}
+
+	b := make([]byte, size+4)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, 0, errMalformedRaftState
+	}
+
+	body := b[:size]
+	if crc32.ChecksumIEEE(body) != binary.BigEndian.Uint32(b[size:]) {
+		return nil, 0, errMalformedRaftState
+	}
+
+	entry := &LogEntry{}
+	if err := proto.Unmarshal(body, entry); err != nil {
+		return nil, 0, errMalformedRaftState
+	}
+
+	var scratch [binary.MaxVarintLen64]byte
+	return entry, int64(binary.PutUvarint(scratch[:], size)) + int64(size) + 4, nil
+}
+
+// Syncs a directory, so that files renamed into it survive a crash.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+
+	defer d.Close()
+	return d.Sync()
+}
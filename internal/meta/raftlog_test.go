@@ -0,0 +1,156 @@
+package meta
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+func testLogEntry(term uint64, filename string) *LogEntry {
+	return &LogEntry{Term: term, Operation: &Operation{Type: Operation_MODIFY, Filename: filename, Version: 1}}
+}
+
+func assertLogEntries(t *testing.T, expected []*LogEntry, actual []*LogEntry) {
+	assert.Equal(t, len(expected), len(actual))
+	for i := range expected {
+		if i < len(actual) {
+			assert.True(t, proto.Equal(expected[i], actual[i]), "entry %d", i+1)
+		}
+	}
+}
+
+func TestFileRaftStorage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "surfs")
+	assert.Nil(t, err)
+
+	defer os.RemoveAll(dir)
+
+	s, err := openFileRaftStorage(dir)
+	assert.Nil(t, err)
+
+	saved, err := s.load()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(0), saved.term)
+	assert.Equal(t, "", saved.votedFor)
+	assert.Equal(t, logPosition{}, saved.base)
+	assert.Empty(t, saved.entries)
+
+	log := []*LogEntry{testLogEntry(1, "a"), testLogEntry(1, "b"), testLogEntry(2, "c")}
+	assert.Nil(t, s.setState(2, "node1"))
+	assert.Nil(t, s.appendEntries(1, log[:2]))
+	assert.Nil(t, s.appendEntries(3, log[2:]))
+
+	// Conflicting entries are replaced.
+	replaced := testLogEntry(3, "d")
+	assert.Nil(t, s.appendEntries(2, []*LogEntry{replaced}))
+	log = []*LogEntry{log[0], replaced}
+
+	assert.NotNil(t, s.appendEntries(5, log))
+	assert.Nil(t, s.close())
+
+	s, err = openFileRaftStorage(dir)
+	assert.Nil(t, err)
+
+	saved, err = s.load()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(2), saved.term)
+	assert.Equal(t, "node1", saved.votedFor)
+	assertLogEntries(t, log, saved.entries)
+
+	// Entries can be appended after reloading.
+	log = append(log, testLogEntry(3, "e"))
+	assert.Nil(t, s.appendEntries(3, log[2:]))
+	assert.Nil(t, s.close())
+
+	// A record torn by a crash is discarded, and later entries are appended in its place.
+	path := filepath.Join(dir, RaftLogFilename)
+	info, err := os.Stat(path)
+	assert.Nil(t, err)
+	assert.Nil(t, os.Truncate(path, info.Size()-2))
+
+	s, err = openFileRaftStorage(dir)
+	assert.Nil(t, err)
+
+	saved, err = s.load()
+	assert.Nil(t, err)
+	assertLogEntries(t, log[:2], saved.entries)
+
+	log = append(log[:2], testLogEntry(4, "f"))
+	assert.Nil(t, s.appendEntries(3, log[2:]))
+
+	saved, err = s.load()
+	assert.Nil(t, err)
+	assertLogEntries(t, log, saved.entries)
+	assert.Nil(t, s.close())
+
+	// A corrupted state file is an error rather than a forgotten vote.
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, RaftStateFilename), []byte("garbage in the state"), 0644))
+
+	s, err = openFileRaftStorage(dir)
+	assert.Nil(t, err)
+
+	_, err = s.load()
+	assert.Equal(t, errMalformedRaftState, err)
+	assert.Nil(t, s.close())
+}
+
+func TestFileRaftStorage_Compact(t *testing.T) {
+	dir, err := ioutil.TempDir("", "surfs")
+	assert.Nil(t, err)
+
+	defer os.RemoveAll(dir)
+
+	s, err := openFileRaftStorage(dir)
+	assert.Nil(t, err)
+
+	_, err = s.load()
+	assert.Nil(t, err)
+
+	log := []*LogEntry{testLogEntry(1, "a"), testLogEntry(1, "b"), testLogEntry(2, "c"), testLogEntry(2, "d")}
+	assert.Nil(t, s.appendEntries(1, log))
+
+	// The entries after the base are kept, and entries are appended after them.
+	assert.Nil(t, s.compact(logPosition{index: 2, term: 1}))
+	assert.Nil(t, s.appendEntries(5, []*LogEntry{testLogEntry(3, "e")}))
+	log = append(log[2:], testLogEntry(3, "e"))
+
+	// Entries at or before the base cannot be replaced.
+	assert.NotNil(t, s.appendEntries(2, log))
+	assert.Nil(t, s.close())
+
+	s, err = openFileRaftStorage(dir)
+	assert.Nil(t, err)
+
+	saved, err := s.load()
+	assert.Nil(t, err)
+	assert.Equal(t, logPosition{index: 2, term: 1}, saved.base)
+	assertLogEntries(t, log, saved.entries)
+
+	// Entries after the base can still be replaced.
+	log = append(log[:1], testLogEntry(4, "f"))
+	assert.Nil(t, s.appendEntries(4, log[1:]))
+
+	// Compacting past the end of the log discards every entry.
+	assert.Nil(t, s.compact(logPosition{index: 10, term: 5}))
+	assert.Nil(t, s.close())
+
+	s, err = openFileRaftStorage(dir)
+	assert.Nil(t, err)
+
+	defer s.close()
+
+	saved, err = s.load()
+	assert.Nil(t, err)
+	assert.Equal(t, logPosition{index: 10, term: 5}, saved.base)
+	assert.Empty(t, saved.entries)
+
+	assert.Nil(t, s.appendEntries(11, []*LogEntry{testLogEntry(5, "g")}))
+
+	saved, err = s.load()
+	assert.Nil(t, err)
+	assertLogEntries(t, []*LogEntry{testLogEntry(5, "g")}, saved.entries)
+}
@@ -0,0 +1,196 @@
+package meta
+
+import (
+	"context"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Normalizes the source and destination of a rename or copy, which must be different files.
+func transferPaths(source string, destination string) (string, string, error) {
+	source, err := cleanPath(source)
+	if err != nil {
+		return "", "", err
+	}
+
+	destination, err = cleanPath(destination)
+	if err != nil {
+		return "", "", err
+	}
+
+	if source == destination {
+		return "", "", status.Errorf(codes.InvalidArgument, "source and destination are both %q", source)
+	}
+
+	return source, destination, nil
+}
+
+// Validates and commits a rename or copy operation. The source must exist, and both versions are
+// checked before the operation is committed, as well as when it is applied. Returns false if a
+// version does not match.
+func (s *MetadataStore) transfer(ctx context.Context, op *Operation) (bool, error) {
+	src, _, err := s.getFileMetadata(op.Filename)
+	if err != nil {
+		return false, err
+	}
+
+	if src.hashList == nil {
+		return false, status.Errorf(codes.NotFound, "no such file %q", op.Filename)
+	}
+
+	dest, _, err := s.getFileMetadata(op.Destination)
+	if err != nil {
+		return false, err
+	}
+
+	if !transferVersionsMatch(op, src, dest) {
+		log.WithFields(log.Fields{
+			"operation":             op.Type,
+			"source":                op.Filename,
+			"sourceVersion":         op.Version,
+			"oldSourceVersion":      src.version,
+			"destination":           op.Destination,
+			"destinationVersion":    op.DestinationVersion,
+			"oldDestinationVersion": dest.version,
+		}).Debug("Did not transfer file; invalid file version.")
+
+		return false, nil
+	}
+
+	if dest.hashList == nil {
+		if _, err := s.checkBatch([]*Operation{op}); err != nil {
+			return false, err
+		}
+	}
+
+	op.Timestamp = time.Now().UnixNano()
+	return s.commit(ctx, op)
+}
+
+// Reports whether the versions of a rename or copy operation match the current versions of its source
+// and destination. A renamed source gets a new version, while a copied one must be unchanged.
+func transferVersionsMatch(op *Operation, src Stat, dest Stat) bool {
+	if op.DestinationVersion != dest.version+1 {
+		return false
+	}
+
+	if op.Type == Operation_RENAME {
+		return op.Version == src.version+1
+	}
+
+	return op.Version == src.version
+}
+
+// Stages a rename or copy operation. The destination becomes a new version with the contents and
+// attributes of the source, and a renamed source is deleted.
+func (b *batch) stageTransfer(op *Operation) (bool, error) {
+	src, err := b.get(op.Filename)
+	if err != nil {
+		return false, err
+	}
+
+	dest, err := b.get(op.Destination)
+	if err != nil {
+		return false, err
+	}
+
+	if src.hashList == nil || !transferVersionsMatch(op, src, dest) {
+		return false, nil
+	}
+
+	// A renamed source no longer exists once the destination is created.
+	if dest.hashList == nil {
+		if op.Type == Operation_RENAME {
+			b.staged[op.Filename] = Stat{version: op.Version}
+		}
+
+		ok, err := b.checkCreate(op.Destination)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+
+	b.staged[op.Destination] = Stat{
+		version:   op.DestinationVersion,
+		hashList:  src.hashList,
+		blockSize: src.blockSize,
+		chunking:  src.chunking,
+		modTime:   b.now,
+		history:   b.store.retainedHistory(dest, b.now),
+		attrs:     src.attrs,
+	}
+
+	if op.Type == Operation_RENAME {
+		b.staged[op.Filename] = Stat{
+			version: op.Version,
+			modTime: b.now,
+			history: b.store.retainedHistory(src, b.now),
+		}
+	}
+
+	return true, nil
+}
+
+// Moves a file to a new name. The source is deleted and the destination gets its contents, in a single
+// operation.
+func (s *MetadataStore) RenameFile(ctx context.Context, req *RenameFileRequest) (*RenameFileResponse, error) {
+	log.WithFields(log.Fields{
+		"source":      req.Source,
+		"destination": req.Destination,
+	}).Debug("Renaming file...")
+
+	if err := s.checkLeader(ctx); err != nil {
+		return nil, err
+	}
+
+	source, destination, err := transferPaths(req.Source, req.Destination)
+	if err != nil {
+		return nil, err
+	}
+
+	ok, err := s.transfer(ctx, &Operation{
+		Type:               Operation_RENAME,
+		Filename:           source,
+		Version:            req.SourceVersion,
+		Destination:        destination,
+		DestinationVersion: req.DestinationVersion,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &RenameFileResponse{Success: ok}, nil
+}
+
+// Copies a file to a new name. The copy shares the blocks of the source.
+func (s *MetadataStore) CopyFile(ctx context.Context, req *CopyFileRequest) (*CopyFileResponse, error) {
+	log.WithFields(log.Fields{
+		"source":      req.Source,
+		"destination": req.Destination,
+	}).Debug("Copying file...")
+
+	if err := s.checkLeader(ctx); err != nil {
+		return nil, err
+	}
+
+	source, destination, err := transferPaths(req.Source, req.Destination)
+	if err != nil {
+		return nil, err
+	}
+
+	ok, err := s.transfer(ctx, &Operation{
+		Type:               Operation_COPY,
+		Filename:           source,
+		Version:            req.SourceVersion,
+		Destination:        destination,
+		DestinationVersion: req.DestinationVersion,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &CopyFileResponse{Success: ok}, nil
+}
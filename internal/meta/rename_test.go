@@ -0,0 +1,167 @@
+package meta
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestMetadataStore_RenameFile(t *testing.T) {
+	mock := &mockClient{blocks: map[string][]byte{
+		"hash1": []byte("block1"),
+		"hash2": []byte("block2"),
+	}}
+
+	store := &MetadataStore{
+		client: mock,
+		engine: newMapEngine(),
+	}
+	store.SetRetention(DefaultRetainVersions, 0)
+
+	attrs := &FileAttributes{Size: 12, Mode: 0644, Creator: "alice@host"}
+	expectModifyFile(store, &ModifyFileRequest{
+		Filename:   "a/file1",
+		Version:    1,
+		HashList:   []string{"hash1", "hash2"},
+		BlockSize:  4096,
+		Attributes: attrs,
+	}, &ModifyFileResponse{Success: true}, t)
+
+	// The source must get a new version, and the destination must be one more than its current one.
+	for _, req := range []*RenameFileRequest{
+		{Source: "a/file1", SourceVersion: 1, Destination: "b/file2", DestinationVersion: 1},
+		{Source: "a/file1", SourceVersion: 2, Destination: "b/file2", DestinationVersion: 2},
+	} {
+		res, err := store.RenameFile(context.Background(), req)
+		assert.Nil(t, err)
+		assert.False(t, res.Success)
+	}
+
+	res, err := store.RenameFile(context.Background(), &RenameFileRequest{
+		Source:             "/a/file1",
+		SourceVersion:      2,
+		Destination:        "b//file2",
+		DestinationVersion: 1,
+	})
+	assert.Nil(t, err)
+	assert.True(t, res.Success)
+
+	expectReadFile(store, "a/file1", &ReadFileResponse{Version: 2, HashList: nil}, t)
+	expectReadFile(store, "b/file2", &ReadFileResponse{Version: 1, HashList: []string{"hash1", "hash2"}, BlockSize: 4096}, t)
+
+	statRes, err := store.StatFile(context.Background(), &StatFileRequest{Filename: "b/file2"})
+	assert.Nil(t, err)
+	assert.Equal(t, attrs, statRes.Attributes)
+
+	// The renamed file's contents are retained in its history.
+	readRes, err := store.ReadFileVersion(context.Background(), &ReadFileVersionRequest{Filename: "a/file1", Version: 1})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"hash1", "hash2"}, readRes.HashList)
+
+	// A deleted or missing source cannot be renamed.
+	for _, source := range []string{"a/file1", "missing"} {
+		_, err = store.RenameFile(context.Background(), &RenameFileRequest{
+			Source:             source,
+			SourceVersion:      3,
+			Destination:        "file3",
+			DestinationVersion: 1,
+		})
+		assert.Equal(t, codes.NotFound, status.Code(err), source)
+	}
+
+	_, err = store.RenameFile(context.Background(), &RenameFileRequest{
+		Source:             "b/file2",
+		SourceVersion:      2,
+		Destination:        "/b/./file2",
+		DestinationVersion: 2,
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestMetadataStore_CopyFile(t *testing.T) {
+	mock := &mockClient{blocks: map[string][]byte{
+		"hash1": []byte("block1"),
+		"hash2": []byte("block2"),
+	}}
+
+	store := &MetadataStore{
+		client: mock,
+		engine: newMapEngine(),
+	}
+
+	expectModifyFile(store, &ModifyFileRequest{
+		Filename: "file1",
+		Version:  1,
+		HashList: []string{"hash1"},
+	}, &ModifyFileResponse{Success: true}, t)
+
+	expectModifyFile(store, &ModifyFileRequest{
+		Filename: "file2",
+		Version:  1,
+		HashList: []string{"hash2"},
+	}, &ModifyFileResponse{Success: true}, t)
+
+	// The source version must be the current one, and the copy overwrites the destination as a new
+	// version.
+	for _, req := range []*CopyFileRequest{
+		{Source: "file1", SourceVersion: 2, Destination: "file2", DestinationVersion: 2},
+		{Source: "file1", SourceVersion: 1, Destination: "file2", DestinationVersion: 1},
+	} {
+		res, err := store.CopyFile(context.Background(), req)
+		assert.Nil(t, err)
+		assert.False(t, res.Success)
+	}
+
+	res, err := store.CopyFile(context.Background(), &CopyFileRequest{
+		Source:             "file1",
+		SourceVersion:      1,
+		Destination:        "file2",
+		DestinationVersion: 2,
+	})
+	assert.Nil(t, err)
+	assert.True(t, res.Success)
+
+	expectReadFile(store, "file1", &ReadFileResponse{Version: 1, HashList: []string{"hash1"}, BlockSize: 64}, t)
+	expectReadFile(store, "file2", &ReadFileResponse{Version: 2, HashList: []string{"hash1"}, BlockSize: 64}, t)
+
+	_, err = store.CopyFile(context.Background(), &CopyFileRequest{
+		Source:             "missing",
+		Destination:        "file3",
+		DestinationVersion: 1,
+	})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestRaft_RenameFile(t *testing.T) {
+	mock := &mockClient{blocks: map[string][]byte{"hash1": []byte("block1")}}
+
+	stores := newTestCluster(t, 3, mock)
+	defer closeTestCluster(stores)
+
+	leader := waitForLeader(t, stores)
+	expectModifyFile(leader, &ModifyFileRequest{
+		Filename: "file1",
+		Version:  1,
+		HashList: []string{"hash1"},
+	}, &ModifyFileResponse{Success: true}, t)
+
+	res, err := leader.RenameFile(context.Background(), &RenameFileRequest{
+		Source:             "file1",
+		SourceVersion:      2,
+		Destination:        "file2",
+		DestinationVersion: 1,
+	})
+	assert.Nil(t, err)
+	assert.True(t, res.Success)
+
+	for _, store := range stores {
+		st := waitForVersion(t, store, "file2", 1)
+		assert.Equal(t, []string{"hash1"}, st.hashList)
+
+		st = waitForVersion(t, store, "file1", 2)
+		assert.Nil(t, st.hashList)
+	}
+}
@@ -24,6 +24,102 @@ var _ = math.Inf
 // proto package needs to be updated.
 const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
 
+// The method used to divide a file into blocks. The values match block.Chunking.
+type Chunking int32
+
+const (
+	Chunking_FIXED           Chunking = 0
+	Chunking_CONTENT_DEFINED Chunking = 1
+)
+
+var Chunking_name = map[int32]string{
+	0: "FIXED",
+	1: "CONTENT_DEFINED",
+}
+
+var Chunking_value = map[string]int32{
+	"FIXED":           0,
+	"CONTENT_DEFINED": 1,
+}
+
+func (x Chunking) String() string {
+	return proto.EnumName(Chunking_name, int32(x))
+}
+
+func (Chunking) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_a0b84a42fa06f626, []int{0}
+}
+
+type WatchEvent_Type int32
+
+const (
+	WatchEvent_CREATE WatchEvent_Type = 0
+	WatchEvent_MODIFY WatchEvent_Type = 1
+	WatchEvent_DELETE WatchEvent_Type = 2
+	// Sent first on every watch, with the store version the watch starts after and no file. A
+	// client can list the files after receiving it without missing any change made meanwhile.
+	WatchEvent_START WatchEvent_Type = 3
+)
+
+var WatchEvent_Type_name = map[int32]string{
+	0: "CREATE",
+	1: "MODIFY",
+	2: "DELETE",
+	3: "START",
+}
+
+var WatchEvent_Type_value = map[string]int32{
+	"CREATE": 0,
+	"MODIFY": 1,
+	"DELETE": 2,
+	"START":  3,
+}
+
+func (x WatchEvent_Type) String() string {
+	return proto.EnumName(WatchEvent_Type_name, int32(x))
+}
+
+func (WatchEvent_Type) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_a0b84a42fa06f626, []int{13, 0}
+}
+
+type Operation_Type int32
+
+const (
+	Operation_NOOP   Operation_Type = 0
+	Operation_MODIFY Operation_Type = 1
+	Operation_DELETE Operation_Type = 2
+	Operation_RENAME Operation_Type = 3
+	Operation_COPY   Operation_Type = 4
+	Operation_BATCH  Operation_Type = 5
+)
+
+var Operation_Type_name = map[int32]string{
+	0: "NOOP",
+	1: "MODIFY",
+	2: "DELETE",
+	3: "RENAME",
+	4: "COPY",
+	5: "BATCH",
+}
+
+var Operation_Type_value = map[string]int32{
+	"NOOP":   0,
+	"MODIFY": 1,
+	"DELETE": 2,
+	"RENAME": 3,
+	"COPY":   4,
+	"BATCH":  5,
+}
+
+func (x Operation_Type) String() string {
+	return proto.EnumName(Operation_Type_name, int32(x))
+}
+
+func (Operation_Type) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_a0b84a42fa06f626, []int{36, 0}
+}
+
 type ReadFileRequest struct {
 	Filename             string   `protobuf:"bytes,1,opt,name=filename,proto3" json:"filename,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
@@ -64,8 +160,18 @@ func (m *ReadFileRequest) GetFilename() string {
 }
 
 type ReadFileResponse struct {
-	Version              uint64   `protobuf:"varint,1,opt,name=version,proto3" json:"version,omitempty"`
-	HashList             []string `protobuf:"bytes,2,rep,name=hashList,proto3" json:"hashList,omitempty"`
+	Version  uint64   `protobuf:"varint,1,opt,name=version,proto3" json:"version,omitempty"`
+	HashList []string `protobuf:"bytes,2,rep,name=hashList,proto3" json:"hashList,omitempty"`
+	// The size of the blocks the file was divided into.
+	BlockSize uint64   `protobuf:"varint,3,opt,name=blockSize,proto3" json:"blockSize,omitempty"`
+	Chunking  Chunking `protobuf:"varint,4,opt,name=chunking,proto3,enum=meta.Chunking" json:"chunking,omitempty"`
+	// The encrypted keys of the blocks, in the order of the hash list, if the client encrypted them.
+	// See FileAttributes.
+	BlockKeys [][]byte `protobuf:"bytes,5,rep,name=blockKeys,proto3" json:"blockKeys,omitempty"`
+	// Set if the version holds the file's contents rather than deleting it. This tells an empty file,
+	// whose hash list is empty, from a deleted or missing one, whose hash list is also empty on the
+	// wire.
+	Exists               bool     `protobuf:"varint,6,opt,name=exists,proto3" json:"exists,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -110,13 +216,46 @@ func (m *ReadFileResponse) GetHashList() []string {
 	return nil
 }
 
+func (m *ReadFileResponse) GetBlockSize() uint64 {
+	if m != nil {
+		return m.BlockSize
+	}
+	return 0
+}
+
+func (m *ReadFileResponse) GetChunking() Chunking {
+	if m != nil {
+		return m.Chunking
+	}
+	return Chunking_FIXED
+}
+
+func (m *ReadFileResponse) GetBlockKeys() [][]byte {
+	if m != nil {
+		return m.BlockKeys
+	}
+	return nil
+}
+
+func (m *ReadFileResponse) GetExists() bool {
+	if m != nil {
+		return m.Exists
+	}
+	return false
+}
+
 type ModifyFileRequest struct {
-	Filename             string   `protobuf:"bytes,1,opt,name=filename,proto3" json:"filename,omitempty"`
-	Version              uint64   `protobuf:"varint,2,opt,name=version,proto3" json:"version,omitempty"`
-	HashList             []string `protobuf:"bytes,3,rep,name=hashList,proto3" json:"hashList,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+	Filename string   `protobuf:"bytes,1,opt,name=filename,proto3" json:"filename,omitempty"`
+	Version  uint64   `protobuf:"varint,2,opt,name=version,proto3" json:"version,omitempty"`
+	HashList []string `protobuf:"bytes,3,rep,name=hashList,proto3" json:"hashList,omitempty"`
+	// The size of the blocks the file was divided into. If zero, the legacy block size is assumed.
+	BlockSize uint64   `protobuf:"varint,4,opt,name=blockSize,proto3" json:"blockSize,omitempty"`
+	Chunking  Chunking `protobuf:"varint,5,opt,name=chunking,proto3,enum=meta.Chunking" json:"chunking,omitempty"`
+	// Attributes of the file's contents, reported by the client and returned as is by StatFile.
+	Attributes           *FileAttributes `protobuf:"bytes,6,opt,name=attributes,proto3" json:"attributes,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
+	XXX_unrecognized     []byte          `json:"-"`
+	XXX_sizecache        int32           `json:"-"`
 }
 
 func (m *ModifyFileRequest) Reset()         { *m = ModifyFileRequest{} }
@@ -165,6 +304,27 @@ func (m *ModifyFileRequest) GetHashList() []string {
 	return nil
 }
 
+func (m *ModifyFileRequest) GetBlockSize() uint64 {
+	if m != nil {
+		return m.BlockSize
+	}
+	return 0
+}
+
+func (m *ModifyFileRequest) GetChunking() Chunking {
+	if m != nil {
+		return m.Chunking
+	}
+	return Chunking_FIXED
+}
+
+func (m *ModifyFileRequest) GetAttributes() *FileAttributes {
+	if m != nil {
+		return m.Attributes
+	}
+	return nil
+}
+
 type ModifyFileResponse struct {
 	Success              bool     `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
 	MissingHashList      []string `protobuf:"bytes,2,rep,name=missingHashList,proto3" json:"missingHashList,omitempty"`
@@ -298,228 +458,2633 @@ func (m *DeleteFileResponse) GetSuccess() bool {
 	return false
 }
 
-type GetVersionRequest struct {
-	Filename             string   `protobuf:"bytes,1,opt,name=filename,proto3" json:"filename,omitempty"`
+type RenameFileRequest struct {
+	Source string `protobuf:"bytes,1,opt,name=source,proto3" json:"source,omitempty"`
+	// The new version of the source, which marks it as deleted. It must be exactly one more than the
+	// current version of the source.
+	SourceVersion uint64 `protobuf:"varint,2,opt,name=sourceVersion,proto3" json:"sourceVersion,omitempty"`
+	Destination   string `protobuf:"bytes,3,opt,name=destination,proto3" json:"destination,omitempty"`
+	// The new version of the destination, which must be exactly one more than its current version.
+	DestinationVersion   uint64   `protobuf:"varint,4,opt,name=destinationVersion,proto3" json:"destinationVersion,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
 }
 
-func (m *GetVersionRequest) Reset()         { *m = GetVersionRequest{} }
-func (m *GetVersionRequest) String() string { return proto.CompactTextString(m) }
-func (*GetVersionRequest) ProtoMessage()    {}
-func (*GetVersionRequest) Descriptor() ([]byte, []int) {
+func (m *RenameFileRequest) Reset()         { *m = RenameFileRequest{} }
+func (m *RenameFileRequest) String() string { return proto.CompactTextString(m) }
+func (*RenameFileRequest) ProtoMessage()    {}
+func (*RenameFileRequest) Descriptor() ([]byte, []int) {
 	return fileDescriptor_a0b84a42fa06f626, []int{6}
 }
 
-func (m *GetVersionRequest) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_GetVersionRequest.Unmarshal(m, b)
+func (m *RenameFileRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RenameFileRequest.Unmarshal(m, b)
 }
-func (m *GetVersionRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_GetVersionRequest.Marshal(b, m, deterministic)
+func (m *RenameFileRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RenameFileRequest.Marshal(b, m, deterministic)
 }
-func (m *GetVersionRequest) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_GetVersionRequest.Merge(m, src)
+func (m *RenameFileRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RenameFileRequest.Merge(m, src)
 }
-func (m *GetVersionRequest) XXX_Size() int {
-	return xxx_messageInfo_GetVersionRequest.Size(m)
+func (m *RenameFileRequest) XXX_Size() int {
+	return xxx_messageInfo_RenameFileRequest.Size(m)
 }
-func (m *GetVersionRequest) XXX_DiscardUnknown() {
-	xxx_messageInfo_GetVersionRequest.DiscardUnknown(m)
+func (m *RenameFileRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_RenameFileRequest.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_GetVersionRequest proto.InternalMessageInfo
+var xxx_messageInfo_RenameFileRequest proto.InternalMessageInfo
 
-func (m *GetVersionRequest) GetFilename() string {
+func (m *RenameFileRequest) GetSource() string {
 	if m != nil {
-		return m.Filename
+		return m.Source
 	}
 	return ""
 }
 
-type GetVersionResponse struct {
-	Version              uint64   `protobuf:"varint,1,opt,name=version,proto3" json:"version,omitempty"`
+func (m *RenameFileRequest) GetSourceVersion() uint64 {
+	if m != nil {
+		return m.SourceVersion
+	}
+	return 0
+}
+
+func (m *RenameFileRequest) GetDestination() string {
+	if m != nil {
+		return m.Destination
+	}
+	return ""
+}
+
+func (m *RenameFileRequest) GetDestinationVersion() uint64 {
+	if m != nil {
+		return m.DestinationVersion
+	}
+	return 0
+}
+
+type RenameFileResponse struct {
+	Success              bool     `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
 }
 
-func (m *GetVersionResponse) Reset()         { *m = GetVersionResponse{} }
-func (m *GetVersionResponse) String() string { return proto.CompactTextString(m) }
-func (*GetVersionResponse) ProtoMessage()    {}
-func (*GetVersionResponse) Descriptor() ([]byte, []int) {
+func (m *RenameFileResponse) Reset()         { *m = RenameFileResponse{} }
+func (m *RenameFileResponse) String() string { return proto.CompactTextString(m) }
+func (*RenameFileResponse) ProtoMessage()    {}
+func (*RenameFileResponse) Descriptor() ([]byte, []int) {
 	return fileDescriptor_a0b84a42fa06f626, []int{7}
 }
 
-func (m *GetVersionResponse) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_GetVersionResponse.Unmarshal(m, b)
+func (m *RenameFileResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RenameFileResponse.Unmarshal(m, b)
 }
-func (m *GetVersionResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_GetVersionResponse.Marshal(b, m, deterministic)
+func (m *RenameFileResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RenameFileResponse.Marshal(b, m, deterministic)
 }
-func (m *GetVersionResponse) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_GetVersionResponse.Merge(m, src)
+func (m *RenameFileResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RenameFileResponse.Merge(m, src)
 }
-func (m *GetVersionResponse) XXX_Size() int {
-	return xxx_messageInfo_GetVersionResponse.Size(m)
+func (m *RenameFileResponse) XXX_Size() int {
+	return xxx_messageInfo_RenameFileResponse.Size(m)
 }
-func (m *GetVersionResponse) XXX_DiscardUnknown() {
-	xxx_messageInfo_GetVersionResponse.DiscardUnknown(m)
+func (m *RenameFileResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_RenameFileResponse.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_GetVersionResponse proto.InternalMessageInfo
+var xxx_messageInfo_RenameFileResponse proto.InternalMessageInfo
 
-func (m *GetVersionResponse) GetVersion() uint64 {
+func (m *RenameFileResponse) GetSuccess() bool {
 	if m != nil {
-		return m.Version
+		return m.Success
 	}
-	return 0
+	return false
 }
 
-func init() {
-	proto.RegisterType((*ReadFileRequest)(nil), "meta.ReadFileRequest")
-	proto.RegisterType((*ReadFileResponse)(nil), "meta.ReadFileResponse")
-	proto.RegisterType((*ModifyFileRequest)(nil), "meta.ModifyFileRequest")
-	proto.RegisterType((*ModifyFileResponse)(nil), "meta.ModifyFileResponse")
-	proto.RegisterType((*DeleteFileRequest)(nil), "meta.DeleteFileRequest")
-	proto.RegisterType((*DeleteFileResponse)(nil), "meta.DeleteFileResponse")
-	proto.RegisterType((*GetVersionRequest)(nil), "meta.GetVersionRequest")
-	proto.RegisterType((*GetVersionResponse)(nil), "meta.GetVersionResponse")
+type CopyFileRequest struct {
+	Source string `protobuf:"bytes,1,opt,name=source,proto3" json:"source,omitempty"`
+	// The version of the source to copy, which must be its current version.
+	SourceVersion uint64 `protobuf:"varint,2,opt,name=sourceVersion,proto3" json:"sourceVersion,omitempty"`
+	Destination   string `protobuf:"bytes,3,opt,name=destination,proto3" json:"destination,omitempty"`
+	// The new version of the destination, which must be exactly one more than its current version.
+	DestinationVersion   uint64   `protobuf:"varint,4,opt,name=destinationVersion,proto3" json:"destinationVersion,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
-func init() { proto.RegisterFile("service.proto", fileDescriptor_a0b84a42fa06f626) }
-
-var fileDescriptor_a0b84a42fa06f626 = []byte{
-	// 309 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xa4, 0x93, 0x41, 0x4b, 0xc3, 0x40,
-	0x10, 0x85, 0x69, 0x5a, 0x34, 0x0e, 0x94, 0x9a, 0x05, 0x35, 0xe4, 0x54, 0xf6, 0xd4, 0x8b, 0x11,
-	0xf4, 0xe4, 0xa9, 0x17, 0xd1, 0x0a, 0xf6, 0x12, 0x41, 0xbc, 0xae, 0xc9, 0xd4, 0x2e, 0x24, 0xd9,
-	0x9a, 0xd9, 0x16, 0xfc, 0x11, 0xfe, 0x67, 0x49, 0xea, 0xba, 0x31, 0xab, 0xb4, 0xe0, 0x71, 0x36,
-	0xb3, 0xdf, 0x9b, 0x7d, 0x6f, 0x02, 0x43, 0xc2, 0x6a, 0x23, 0x53, 0x8c, 0x57, 0x95, 0xd2, 0x8a,
-	0x0d, 0x0a, 0xd4, 0x82, 0x9f, 0xc3, 0x28, 0x41, 0x91, 0xdd, 0xca, 0x1c, 0x13, 0x7c, 0x5b, 0x23,
-	0x69, 0x16, 0x81, 0xbf, 0x90, 0x39, 0x96, 0xa2, 0xc0, 0xb0, 0x37, 0xee, 0x4d, 0x8e, 0x92, 0xef,
-	0x9a, 0xcf, 0xe0, 0xd8, 0xb6, 0xd3, 0x4a, 0x95, 0x84, 0x2c, 0x84, 0xc3, 0x0d, 0x56, 0x24, 0x55,
-	0xd9, 0xb4, 0x0f, 0x12, 0x53, 0xd6, 0xa4, 0xa5, 0xa0, 0xe5, 0x83, 0x24, 0x1d, 0x7a, 0xe3, 0x7e,
-	0x4d, 0x32, 0x35, 0x47, 0x08, 0xe6, 0x2a, 0x93, 0x8b, 0xf7, 0x3d, 0xa5, 0xdb, 0x32, 0xde, 0xdf,
-	0x32, 0xfd, 0x8e, 0xcc, 0x33, 0xb0, 0xb6, 0x8c, 0x1d, 0x99, 0xd6, 0x69, 0x8a, 0x44, 0x8d, 0x8c,
-	0x9f, 0x98, 0x92, 0x4d, 0x60, 0x54, 0x48, 0x22, 0x59, 0xbe, 0xce, 0x7e, 0x4e, 0xde, 0x3d, 0xe6,
-	0xf7, 0x10, 0xdc, 0x60, 0x8e, 0x1a, 0xff, 0xfd, 0x00, 0x1e, 0x03, 0x6b, 0xa3, 0x76, 0x0d, 0xc9,
-	0x2f, 0x20, 0xb8, 0x43, 0xfd, 0xb4, 0xbd, 0xbd, 0x4f, 0x6c, 0x31, 0xb0, 0xf6, 0x85, 0x5d, 0xc1,
-	0x5d, 0x7e, 0x78, 0x30, 0x9c, 0xa3, 0x16, 0x99, 0xd0, 0xe2, 0x51, 0xab, 0x0a, 0xd9, 0x35, 0xf8,
-	0x26, 0x78, 0x76, 0x12, 0xd7, 0xab, 0x13, 0x77, 0xf6, 0x26, 0x3a, 0xed, 0x1e, 0x7f, 0xc9, 0x4c,
-	0x01, 0x6c, 0x04, 0xec, 0x6c, 0xdb, 0xe5, 0x64, 0x1f, 0x85, 0xee, 0x07, 0x0b, 0xb0, 0xf6, 0x18,
-	0x80, 0xe3, 0xbd, 0x01, 0xfc, 0xe2, 0xe4, 0x14, 0xc0, 0x3e, 0xdf, 0x00, 0x1c, 0x07, 0x0d, 0xc0,
-	0x75, 0xea, 0xe5, 0xa0, 0xf9, 0x65, 0xae, 0x3e, 0x03, 0x00, 0x00, 0xff, 0xff, 0xd2, 0x59, 0x78,
-	0x6d, 0x43, 0x03, 0x00, 0x00,
+func (m *CopyFileRequest) Reset()         { *m = CopyFileRequest{} }
+func (m *CopyFileRequest) String() string { return proto.CompactTextString(m) }
+func (*CopyFileRequest) ProtoMessage()    {}
+func (*CopyFileRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a0b84a42fa06f626, []int{8}
 }
 
-// Reference imports to suppress errors if they are not otherwise used.
-var _ context.Context
-var _ grpc.ClientConn
+func (m *CopyFileRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CopyFileRequest.Unmarshal(m, b)
+}
+func (m *CopyFileRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CopyFileRequest.Marshal(b, m, deterministic)
+}
+func (m *CopyFileRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CopyFileRequest.Merge(m, src)
+}
+func (m *CopyFileRequest) XXX_Size() int {
+	return xxx_messageInfo_CopyFileRequest.Size(m)
+}
+func (m *CopyFileRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_CopyFileRequest.DiscardUnknown(m)
+}
 
-// This is a compile-time assertion to ensure that this generated file
-// is compatible with the grpc package it is being compiled against.
-const _ = grpc.SupportPackageIsVersion4
+var xxx_messageInfo_CopyFileRequest proto.InternalMessageInfo
 
-// MetadataStoreClient is the client API for MetadataStore service.
-//
-// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
-type MetadataStoreClient interface {
-	ReadFile(ctx context.Context, in *ReadFileRequest, opts ...grpc.CallOption) (*ReadFileResponse, error)
-	ModifyFile(ctx context.Context, in *ModifyFileRequest, opts ...grpc.CallOption) (*ModifyFileResponse, error)
-	DeleteFile(ctx context.Context, in *DeleteFileRequest, opts ...grpc.CallOption) (*DeleteFileResponse, error)
-	GetVersion(ctx context.Context, in *GetVersionRequest, opts ...grpc.CallOption) (*GetVersionResponse, error)
+func (m *CopyFileRequest) GetSource() string {
+	if m != nil {
+		return m.Source
+	}
+	return ""
 }
 
-type metadataStoreClient struct {
-	cc *grpc.ClientConn
+func (m *CopyFileRequest) GetSourceVersion() uint64 {
+	if m != nil {
+		return m.SourceVersion
+	}
+	return 0
 }
 
-func NewMetadataStoreClient(cc *grpc.ClientConn) MetadataStoreClient {
-	return &metadataStoreClient{cc}
+func (m *CopyFileRequest) GetDestination() string {
+	if m != nil {
+		return m.Destination
+	}
+	return ""
 }
 
-func (c *metadataStoreClient) ReadFile(ctx context.Context, in *ReadFileRequest, opts ...grpc.CallOption) (*ReadFileResponse, error) {
-	out := new(ReadFileResponse)
-	err := c.cc.Invoke(ctx, "/meta.MetadataStore/ReadFile", in, out, opts...)
-	if err != nil {
-		return nil, err
+func (m *CopyFileRequest) GetDestinationVersion() uint64 {
+	if m != nil {
+		return m.DestinationVersion
 	}
-	return out, nil
+	return 0
 }
 
-func (c *metadataStoreClient) ModifyFile(ctx context.Context, in *ModifyFileRequest, opts ...grpc.CallOption) (*ModifyFileResponse, error) {
-	out := new(ModifyFileResponse)
-	err := c.cc.Invoke(ctx, "/meta.MetadataStore/ModifyFile", in, out, opts...)
-	if err != nil {
-		return nil, err
-	}
-	return out, nil
+type CopyFileResponse struct {
+	Success              bool     `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
-func (c *metadataStoreClient) DeleteFile(ctx context.Context, in *DeleteFileRequest, opts ...grpc.CallOption) (*DeleteFileResponse, error) {
-	out := new(DeleteFileResponse)
-	err := c.cc.Invoke(ctx, "/meta.MetadataStore/DeleteFile", in, out, opts...)
-	if err != nil {
-		return nil, err
-	}
-	return out, nil
+func (m *CopyFileResponse) Reset()         { *m = CopyFileResponse{} }
+func (m *CopyFileResponse) String() string { return proto.CompactTextString(m) }
+func (*CopyFileResponse) ProtoMessage()    {}
+func (*CopyFileResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a0b84a42fa06f626, []int{9}
 }
 
-func (c *metadataStoreClient) GetVersion(ctx context.Context, in *GetVersionRequest, opts ...grpc.CallOption) (*GetVersionResponse, error) {
-	out := new(GetVersionResponse)
-	err := c.cc.Invoke(ctx, "/meta.MetadataStore/GetVersion", in, out, opts...)
-	if err != nil {
-		return nil, err
+func (m *CopyFileResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CopyFileResponse.Unmarshal(m, b)
+}
+func (m *CopyFileResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CopyFileResponse.Marshal(b, m, deterministic)
+}
+func (m *CopyFileResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CopyFileResponse.Merge(m, src)
+}
+func (m *CopyFileResponse) XXX_Size() int {
+	return xxx_messageInfo_CopyFileResponse.Size(m)
+}
+func (m *CopyFileResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_CopyFileResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CopyFileResponse proto.InternalMessageInfo
+
+func (m *CopyFileResponse) GetSuccess() bool {
+	if m != nil {
+		return m.Success
 	}
-	return out, nil
+	return false
 }
 
-// MetadataStoreServer is the server API for MetadataStore service.
-type MetadataStoreServer interface {
-	ReadFile(context.Context, *ReadFileRequest) (*ReadFileResponse, error)
-	ModifyFile(context.Context, *ModifyFileRequest) (*ModifyFileResponse, error)
-	DeleteFile(context.Context, *DeleteFileRequest) (*DeleteFileResponse, error)
-	GetVersion(context.Context, *GetVersionRequest) (*GetVersionResponse, error)
+type CommitBatchRequest struct {
+	// The operations to commit, in order. Each is a MODIFY, DELETE, RENAME or COPY operation with the
+	// same fields and version semantics as the corresponding RPC, and sees the effects of the
+	// operations before it. The timestamp is ignored.
+	Operations           []*Operation `protobuf:"bytes,1,rep,name=operations,proto3" json:"operations,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
+	XXX_unrecognized     []byte       `json:"-"`
+	XXX_sizecache        int32        `json:"-"`
 }
 
-// UnimplementedMetadataStoreServer can be embedded to have forward compatible implementations.
-type UnimplementedMetadataStoreServer struct {
+func (m *CommitBatchRequest) Reset()         { *m = CommitBatchRequest{} }
+func (m *CommitBatchRequest) String() string { return proto.CompactTextString(m) }
+func (*CommitBatchRequest) ProtoMessage()    {}
+func (*CommitBatchRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a0b84a42fa06f626, []int{10}
 }
 
-func (*UnimplementedMetadataStoreServer) ReadFile(ctx context.Context, req *ReadFileRequest) (*ReadFileResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method ReadFile not implemented")
+func (m *CommitBatchRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CommitBatchRequest.Unmarshal(m, b)
 }
-func (*UnimplementedMetadataStoreServer) ModifyFile(ctx context.Context, req *ModifyFileRequest) (*ModifyFileResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method ModifyFile not implemented")
+func (m *CommitBatchRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CommitBatchRequest.Marshal(b, m, deterministic)
 }
-func (*UnimplementedMetadataStoreServer) DeleteFile(ctx context.Context, req *DeleteFileRequest) (*DeleteFileResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method DeleteFile not implemented")
+func (m *CommitBatchRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CommitBatchRequest.Merge(m, src)
 }
-func (*UnimplementedMetadataStoreServer) GetVersion(ctx context.Context, req *GetVersionRequest) (*GetVersionResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method GetVersion not implemented")
+func (m *CommitBatchRequest) XXX_Size() int {
+	return xxx_messageInfo_CommitBatchRequest.Size(m)
 }
-
-func RegisterMetadataStoreServer(s *grpc.Server, srv MetadataStoreServer) {
-	s.RegisterService(&_MetadataStore_serviceDesc, srv)
+func (m *CommitBatchRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_CommitBatchRequest.DiscardUnknown(m)
 }
 
-func _MetadataStore_ReadFile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(ReadFileRequest)
-	if err := dec(in); err != nil {
-		return nil, err
-	}
-	if interceptor == nil {
-		return srv.(MetadataStoreServer).ReadFile(ctx, in)
-	}
-	info := &grpc.UnaryServerInfo{
-		Server:     srv,
-		FullMethod: "/meta.MetadataStore/ReadFile",
-	}
-	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(MetadataStoreServer).ReadFile(ctx, req.(*ReadFileRequest))
+var xxx_messageInfo_CommitBatchRequest proto.InternalMessageInfo
+
+func (m *CommitBatchRequest) GetOperations() []*Operation {
+	if m != nil {
+		return m.Operations
 	}
-	return interceptor(ctx, in, info, handler)
+	return nil
+}
+
+type CommitBatchResponse struct {
+	Success bool `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	// The blocks missing from the block store for any of the modifications. If the batch was
+	// unsuccessful and this is empty, a version did not match.
+	MissingHashList      []string `protobuf:"bytes,2,rep,name=missingHashList,proto3" json:"missingHashList,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CommitBatchResponse) Reset()         { *m = CommitBatchResponse{} }
+func (m *CommitBatchResponse) String() string { return proto.CompactTextString(m) }
+func (*CommitBatchResponse) ProtoMessage()    {}
+func (*CommitBatchResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a0b84a42fa06f626, []int{11}
+}
+
+func (m *CommitBatchResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CommitBatchResponse.Unmarshal(m, b)
+}
+func (m *CommitBatchResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CommitBatchResponse.Marshal(b, m, deterministic)
+}
+func (m *CommitBatchResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CommitBatchResponse.Merge(m, src)
+}
+func (m *CommitBatchResponse) XXX_Size() int {
+	return xxx_messageInfo_CommitBatchResponse.Size(m)
+}
+func (m *CommitBatchResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_CommitBatchResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CommitBatchResponse proto.InternalMessageInfo
+
+func (m *CommitBatchResponse) GetSuccess() bool {
+	if m != nil {
+		return m.Success
+	}
+	return false
+}
+
+func (m *CommitBatchResponse) GetMissingHashList() []string {
+	if m != nil {
+		return m.MissingHashList
+	}
+	return nil
+}
+
+type WatchRequest struct {
+	// Only changes to files at or below this path are sent. An empty path or "/" watches every file.
+	Prefix string `protobuf:"bytes,1,opt,name=prefix,proto3" json:"prefix,omitempty"`
+	// Only changes after this store version are sent, so that a client that reconnects does not miss
+	// any. If zero, only changes made after the watch starts are sent.
+	SinceVersion uint64 `protobuf:"varint,2,opt,name=sinceVersion,proto3" json:"sinceVersion,omitempty"`
+	// The incarnation of the store that sinceVersion was received from. Store versions of different
+	// incarnations are unrelated, so if it does not match the current incarnation, because the node
+	// restarted or another node became the leader, the watch fails with OUT_OF_RANGE. The client must
+	// then list the files again and start a new watch. Ignored if sinceVersion is zero.
+	Incarnation          string   `protobuf:"bytes,3,opt,name=incarnation,proto3" json:"incarnation,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *WatchRequest) Reset()         { *m = WatchRequest{} }
+func (m *WatchRequest) String() string { return proto.CompactTextString(m) }
+func (*WatchRequest) ProtoMessage()    {}
+func (*WatchRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a0b84a42fa06f626, []int{12}
+}
+
+func (m *WatchRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_WatchRequest.Unmarshal(m, b)
+}
+func (m *WatchRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_WatchRequest.Marshal(b, m, deterministic)
+}
+func (m *WatchRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_WatchRequest.Merge(m, src)
+}
+func (m *WatchRequest) XXX_Size() int {
+	return xxx_messageInfo_WatchRequest.Size(m)
+}
+func (m *WatchRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_WatchRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_WatchRequest proto.InternalMessageInfo
+
+func (m *WatchRequest) GetPrefix() string {
+	if m != nil {
+		return m.Prefix
+	}
+	return ""
+}
+
+func (m *WatchRequest) GetSinceVersion() uint64 {
+	if m != nil {
+		return m.SinceVersion
+	}
+	return 0
+}
+
+func (m *WatchRequest) GetIncarnation() string {
+	if m != nil {
+		return m.Incarnation
+	}
+	return ""
+}
+
+// A committed change to a file.
+type WatchEvent struct {
+	// The version of the store after the change. Every change committed to any file increments the
+	// store version. It is local to the node serving the watch, and starts from zero when the node
+	// starts.
+	StoreVersion uint64          `protobuf:"varint,1,opt,name=storeVersion,proto3" json:"storeVersion,omitempty"`
+	Type         WatchEvent_Type `protobuf:"varint,2,opt,name=type,proto3,enum=meta.WatchEvent_Type" json:"type,omitempty"`
+	Filename     string          `protobuf:"bytes,3,opt,name=filename,proto3" json:"filename,omitempty"`
+	// The new version of the file, and the time it was committed, in nanoseconds since the Unix
+	// epoch.
+	Version uint64 `protobuf:"varint,4,opt,name=version,proto3" json:"version,omitempty"`
+	ModTime int64  `protobuf:"varint,5,opt,name=modTime,proto3" json:"modTime,omitempty"`
+	// Identifies the run of the node serving the watch, which store versions are local to. It changes
+	// whenever the node restarts. See WatchRequest.
+	Incarnation          string   `protobuf:"bytes,6,opt,name=incarnation,proto3" json:"incarnation,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *WatchEvent) Reset()         { *m = WatchEvent{} }
+func (m *WatchEvent) String() string { return proto.CompactTextString(m) }
+func (*WatchEvent) ProtoMessage()    {}
+func (*WatchEvent) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a0b84a42fa06f626, []int{13}
+}
+
+func (m *WatchEvent) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_WatchEvent.Unmarshal(m, b)
+}
+func (m *WatchEvent) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_WatchEvent.Marshal(b, m, deterministic)
+}
+func (m *WatchEvent) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_WatchEvent.Merge(m, src)
+}
+func (m *WatchEvent) XXX_Size() int {
+	return xxx_messageInfo_WatchEvent.Size(m)
+}
+func (m *WatchEvent) XXX_DiscardUnknown() {
+	xxx_messageInfo_WatchEvent.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_WatchEvent proto.InternalMessageInfo
+
+func (m *WatchEvent) GetStoreVersion() uint64 {
+	if m != nil {
+		return m.StoreVersion
+	}
+	return 0
+}
+
+func (m *WatchEvent) GetType() WatchEvent_Type {
+	if m != nil {
+		return m.Type
+	}
+	return WatchEvent_CREATE
+}
+
+func (m *WatchEvent) GetFilename() string {
+	if m != nil {
+		return m.Filename
+	}
+	return ""
+}
+
+func (m *WatchEvent) GetVersion() uint64 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
+func (m *WatchEvent) GetModTime() int64 {
+	if m != nil {
+		return m.ModTime
+	}
+	return 0
+}
+
+func (m *WatchEvent) GetIncarnation() string {
+	if m != nil {
+		return m.Incarnation
+	}
+	return ""
+}
+
+type GetVersionRequest struct {
+	Filename             string   `protobuf:"bytes,1,opt,name=filename,proto3" json:"filename,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetVersionRequest) Reset()         { *m = GetVersionRequest{} }
+func (m *GetVersionRequest) String() string { return proto.CompactTextString(m) }
+func (*GetVersionRequest) ProtoMessage()    {}
+func (*GetVersionRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a0b84a42fa06f626, []int{14}
+}
+
+func (m *GetVersionRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetVersionRequest.Unmarshal(m, b)
+}
+func (m *GetVersionRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetVersionRequest.Marshal(b, m, deterministic)
+}
+func (m *GetVersionRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetVersionRequest.Merge(m, src)
+}
+func (m *GetVersionRequest) XXX_Size() int {
+	return xxx_messageInfo_GetVersionRequest.Size(m)
+}
+func (m *GetVersionRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetVersionRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetVersionRequest proto.InternalMessageInfo
+
+func (m *GetVersionRequest) GetFilename() string {
+	if m != nil {
+		return m.Filename
+	}
+	return ""
+}
+
+type GetVersionResponse struct {
+	Version              uint64   `protobuf:"varint,1,opt,name=version,proto3" json:"version,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetVersionResponse) Reset()         { *m = GetVersionResponse{} }
+func (m *GetVersionResponse) String() string { return proto.CompactTextString(m) }
+func (*GetVersionResponse) ProtoMessage()    {}
+func (*GetVersionResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a0b84a42fa06f626, []int{15}
+}
+
+func (m *GetVersionResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetVersionResponse.Unmarshal(m, b)
+}
+func (m *GetVersionResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetVersionResponse.Marshal(b, m, deterministic)
+}
+func (m *GetVersionResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetVersionResponse.Merge(m, src)
+}
+func (m *GetVersionResponse) XXX_Size() int {
+	return xxx_messageInfo_GetVersionResponse.Size(m)
+}
+func (m *GetVersionResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetVersionResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetVersionResponse proto.InternalMessageInfo
+
+func (m *GetVersionResponse) GetVersion() uint64 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
+// Attributes of a file's contents. They are reported by the client that uploads a version, and are
+// not checked by the metadata store.
+type FileAttributes struct {
+	// The size of the file in bytes.
+	Size uint64 `protobuf:"varint,1,opt,name=size,proto3" json:"size,omitempty"`
+	// The POSIX permission bits of the file.
+	Mode uint32 `protobuf:"varint,2,opt,name=mode,proto3" json:"mode,omitempty"`
+	// The time the file was last modified on the client, in nanoseconds since the Unix epoch. Unlike
+	// modTime elsewhere, this is not the time the version was committed.
+	Mtime int64 `protobuf:"varint,3,opt,name=mtime,proto3" json:"mtime,omitempty"`
+	// The user who uploaded the version.
+	Creator string `protobuf:"bytes,4,opt,name=creator,proto3" json:"creator,omitempty"`
+	// The Base64-encoded SHA-256 hash of the whole file.
+	Digest string `protobuf:"bytes,5,opt,name=digest,proto3" json:"digest,omitempty"`
+	// Set if the client encrypted the blocks of the file, in which case the hash list holds the hashes
+	// of the encrypted blocks. Holds the key each block was encrypted with, in the order of the hash
+	// list, itself encrypted with a secret known only to the client.
+	BlockKeys            [][]byte `protobuf:"bytes,6,rep,name=blockKeys,proto3" json:"blockKeys,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *FileAttributes) Reset()         { *m = FileAttributes{} }
+func (m *FileAttributes) String() string { return proto.CompactTextString(m) }
+func (*FileAttributes) ProtoMessage()    {}
+func (*FileAttributes) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a0b84a42fa06f626, []int{16}
+}
+
+func (m *FileAttributes) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_FileAttributes.Unmarshal(m, b)
+}
+func (m *FileAttributes) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_FileAttributes.Marshal(b, m, deterministic)
+}
+func (m *FileAttributes) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_FileAttributes.Merge(m, src)
+}
+func (m *FileAttributes) XXX_Size() int {
+	return xxx_messageInfo_FileAttributes.Size(m)
+}
+func (m *FileAttributes) XXX_DiscardUnknown() {
+	xxx_messageInfo_FileAttributes.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_FileAttributes proto.InternalMessageInfo
+
+func (m *FileAttributes) GetSize() uint64 {
+	if m != nil {
+		return m.Size
+	}
+	return 0
+}
+
+func (m *FileAttributes) GetMode() uint32 {
+	if m != nil {
+		return m.Mode
+	}
+	return 0
+}
+
+func (m *FileAttributes) GetMtime() int64 {
+	if m != nil {
+		return m.Mtime
+	}
+	return 0
+}
+
+func (m *FileAttributes) GetCreator() string {
+	if m != nil {
+		return m.Creator
+	}
+	return ""
+}
+
+func (m *FileAttributes) GetDigest() string {
+	if m != nil {
+		return m.Digest
+	}
+	return ""
+}
+
+func (m *FileAttributes) GetBlockKeys() [][]byte {
+	if m != nil {
+		return m.BlockKeys
+	}
+	return nil
+}
+
+type StatFileRequest struct {
+	Filename string `protobuf:"bytes,1,opt,name=filename,proto3" json:"filename,omitempty"`
+	// The version to describe, which must be the current or a retained version. If zero, the current
+	// version is described.
+	Version              uint64   `protobuf:"varint,2,opt,name=version,proto3" json:"version,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *StatFileRequest) Reset()         { *m = StatFileRequest{} }
+func (m *StatFileRequest) String() string { return proto.CompactTextString(m) }
+func (*StatFileRequest) ProtoMessage()    {}
+func (*StatFileRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a0b84a42fa06f626, []int{17}
+}
+
+func (m *StatFileRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_StatFileRequest.Unmarshal(m, b)
+}
+func (m *StatFileRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_StatFileRequest.Marshal(b, m, deterministic)
+}
+func (m *StatFileRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_StatFileRequest.Merge(m, src)
+}
+func (m *StatFileRequest) XXX_Size() int {
+	return xxx_messageInfo_StatFileRequest.Size(m)
+}
+func (m *StatFileRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_StatFileRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_StatFileRequest proto.InternalMessageInfo
+
+func (m *StatFileRequest) GetFilename() string {
+	if m != nil {
+		return m.Filename
+	}
+	return ""
+}
+
+func (m *StatFileRequest) GetVersion() uint64 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
+type StatFileResponse struct {
+	Version uint64 `protobuf:"varint,1,opt,name=version,proto3" json:"version,omitempty"`
+	// The time the version was committed, in nanoseconds since the Unix epoch, or zero if it was
+	// committed before times were recorded.
+	ModTime int64 `protobuf:"varint,2,opt,name=modTime,proto3" json:"modTime,omitempty"`
+	// Set if the version deleted the file, in which case it has no blocks or attributes.
+	Deleted   bool     `protobuf:"varint,3,opt,name=deleted,proto3" json:"deleted,omitempty"`
+	BlockSize uint64   `protobuf:"varint,4,opt,name=blockSize,proto3" json:"blockSize,omitempty"`
+	Chunking  Chunking `protobuf:"varint,5,opt,name=chunking,proto3,enum=meta.Chunking" json:"chunking,omitempty"`
+	NumBlocks uint64   `protobuf:"varint,6,opt,name=numBlocks,proto3" json:"numBlocks,omitempty"`
+	// The attributes reported when the version was uploaded. Unset for versions uploaded by clients
+	// that did not report them.
+	Attributes           *FileAttributes `protobuf:"bytes,7,opt,name=attributes,proto3" json:"attributes,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
+	XXX_unrecognized     []byte          `json:"-"`
+	XXX_sizecache        int32           `json:"-"`
+}
+
+func (m *StatFileResponse) Reset()         { *m = StatFileResponse{} }
+func (m *StatFileResponse) String() string { return proto.CompactTextString(m) }
+func (*StatFileResponse) ProtoMessage()    {}
+func (*StatFileResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a0b84a42fa06f626, []int{18}
+}
+
+func (m *StatFileResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_StatFileResponse.Unmarshal(m, b)
+}
+func (m *StatFileResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_StatFileResponse.Marshal(b, m, deterministic)
+}
+func (m *StatFileResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_StatFileResponse.Merge(m, src)
+}
+func (m *StatFileResponse) XXX_Size() int {
+	return xxx_messageInfo_StatFileResponse.Size(m)
+}
+func (m *StatFileResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_StatFileResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_StatFileResponse proto.InternalMessageInfo
+
+func (m *StatFileResponse) GetVersion() uint64 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
+func (m *StatFileResponse) GetModTime() int64 {
+	if m != nil {
+		return m.ModTime
+	}
+	return 0
+}
+
+func (m *StatFileResponse) GetDeleted() bool {
+	if m != nil {
+		return m.Deleted
+	}
+	return false
+}
+
+func (m *StatFileResponse) GetBlockSize() uint64 {
+	if m != nil {
+		return m.BlockSize
+	}
+	return 0
+}
+
+func (m *StatFileResponse) GetChunking() Chunking {
+	if m != nil {
+		return m.Chunking
+	}
+	return Chunking_FIXED
+}
+
+func (m *StatFileResponse) GetNumBlocks() uint64 {
+	if m != nil {
+		return m.NumBlocks
+	}
+	return 0
+}
+
+func (m *StatFileResponse) GetAttributes() *FileAttributes {
+	if m != nil {
+		return m.Attributes
+	}
+	return nil
+}
+
+type ReadFileVersionRequest struct {
+	Filename             string   `protobuf:"bytes,1,opt,name=filename,proto3" json:"filename,omitempty"`
+	Version              uint64   `protobuf:"varint,2,opt,name=version,proto3" json:"version,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ReadFileVersionRequest) Reset()         { *m = ReadFileVersionRequest{} }
+func (m *ReadFileVersionRequest) String() string { return proto.CompactTextString(m) }
+func (*ReadFileVersionRequest) ProtoMessage()    {}
+func (*ReadFileVersionRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a0b84a42fa06f626, []int{19}
+}
+
+func (m *ReadFileVersionRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ReadFileVersionRequest.Unmarshal(m, b)
+}
+func (m *ReadFileVersionRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ReadFileVersionRequest.Marshal(b, m, deterministic)
+}
+func (m *ReadFileVersionRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ReadFileVersionRequest.Merge(m, src)
+}
+func (m *ReadFileVersionRequest) XXX_Size() int {
+	return xxx_messageInfo_ReadFileVersionRequest.Size(m)
+}
+func (m *ReadFileVersionRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ReadFileVersionRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ReadFileVersionRequest proto.InternalMessageInfo
+
+func (m *ReadFileVersionRequest) GetFilename() string {
+	if m != nil {
+		return m.Filename
+	}
+	return ""
+}
+
+func (m *ReadFileVersionRequest) GetVersion() uint64 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
+type ListVersionsRequest struct {
+	Filename             string   `protobuf:"bytes,1,opt,name=filename,proto3" json:"filename,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListVersionsRequest) Reset()         { *m = ListVersionsRequest{} }
+func (m *ListVersionsRequest) String() string { return proto.CompactTextString(m) }
+func (*ListVersionsRequest) ProtoMessage()    {}
+func (*ListVersionsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a0b84a42fa06f626, []int{20}
+}
+
+func (m *ListVersionsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListVersionsRequest.Unmarshal(m, b)
+}
+func (m *ListVersionsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListVersionsRequest.Marshal(b, m, deterministic)
+}
+func (m *ListVersionsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListVersionsRequest.Merge(m, src)
+}
+func (m *ListVersionsRequest) XXX_Size() int {
+	return xxx_messageInfo_ListVersionsRequest.Size(m)
+}
+func (m *ListVersionsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListVersionsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListVersionsRequest proto.InternalMessageInfo
+
+func (m *ListVersionsRequest) GetFilename() string {
+	if m != nil {
+		return m.Filename
+	}
+	return ""
+}
+
+// A summary of a version of a file.
+type FileVersion struct {
+	Version uint64 `protobuf:"varint,1,opt,name=version,proto3" json:"version,omitempty"`
+	// The time the version was committed, in nanoseconds since the Unix epoch, or zero if it was
+	// committed before times were recorded.
+	ModTime int64 `protobuf:"varint,2,opt,name=modTime,proto3" json:"modTime,omitempty"`
+	// Set if the version deleted the file.
+	Deleted bool `protobuf:"varint,3,opt,name=deleted,proto3" json:"deleted,omitempty"`
+	// The number of blocks in the version's hash list.
+	NumBlocks            uint64   `protobuf:"varint,4,opt,name=numBlocks,proto3" json:"numBlocks,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *FileVersion) Reset()         { *m = FileVersion{} }
+func (m *FileVersion) String() string { return proto.CompactTextString(m) }
+func (*FileVersion) ProtoMessage()    {}
+func (*FileVersion) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a0b84a42fa06f626, []int{21}
+}
+
+func (m *FileVersion) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_FileVersion.Unmarshal(m, b)
+}
+func (m *FileVersion) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_FileVersion.Marshal(b, m, deterministic)
+}
+func (m *FileVersion) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_FileVersion.Merge(m, src)
+}
+func (m *FileVersion) XXX_Size() int {
+	return xxx_messageInfo_FileVersion.Size(m)
+}
+func (m *FileVersion) XXX_DiscardUnknown() {
+	xxx_messageInfo_FileVersion.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_FileVersion proto.InternalMessageInfo
+
+func (m *FileVersion) GetVersion() uint64 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
+func (m *FileVersion) GetModTime() int64 {
+	if m != nil {
+		return m.ModTime
+	}
+	return 0
+}
+
+func (m *FileVersion) GetDeleted() bool {
+	if m != nil {
+		return m.Deleted
+	}
+	return false
+}
+
+func (m *FileVersion) GetNumBlocks() uint64 {
+	if m != nil {
+		return m.NumBlocks
+	}
+	return 0
+}
+
+type ListVersionsResponse struct {
+	// The current version of the file followed by the retained previous versions, newest first.
+	Versions             []*FileVersion `protobuf:"bytes,1,rep,name=versions,proto3" json:"versions,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}       `json:"-"`
+	XXX_unrecognized     []byte         `json:"-"`
+	XXX_sizecache        int32          `json:"-"`
+}
+
+func (m *ListVersionsResponse) Reset()         { *m = ListVersionsResponse{} }
+func (m *ListVersionsResponse) String() string { return proto.CompactTextString(m) }
+func (*ListVersionsResponse) ProtoMessage()    {}
+func (*ListVersionsResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a0b84a42fa06f626, []int{22}
+}
+
+func (m *ListVersionsResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListVersionsResponse.Unmarshal(m, b)
+}
+func (m *ListVersionsResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListVersionsResponse.Marshal(b, m, deterministic)
+}
+func (m *ListVersionsResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListVersionsResponse.Merge(m, src)
+}
+func (m *ListVersionsResponse) XXX_Size() int {
+	return xxx_messageInfo_ListVersionsResponse.Size(m)
+}
+func (m *ListVersionsResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListVersionsResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListVersionsResponse proto.InternalMessageInfo
+
+func (m *ListVersionsResponse) GetVersions() []*FileVersion {
+	if m != nil {
+		return m.Versions
+	}
+	return nil
+}
+
+type ListFilesRequest struct {
+	// The directory to list. An empty path or "/" lists the root directory. If the path names a file
+	// rather than a directory, only that file is listed.
+	Path string `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	// If set, every file below the directory is listed, instead of its immediate children.
+	Recursive bool `protobuf:"varint,2,opt,name=recursive,proto3" json:"recursive,omitempty"`
+	// The nextPageToken of a previous response, to continue listing where it left off.
+	PageToken string `protobuf:"bytes,3,opt,name=pageToken,proto3" json:"pageToken,omitempty"`
+	// The maximum number of entries to return. If zero, or more than the limit, the limit is used.
+	PageSize             uint32   `protobuf:"varint,4,opt,name=pageSize,proto3" json:"pageSize,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListFilesRequest) Reset()         { *m = ListFilesRequest{} }
+func (m *ListFilesRequest) String() string { return proto.CompactTextString(m) }
+func (*ListFilesRequest) ProtoMessage()    {}
+func (*ListFilesRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a0b84a42fa06f626, []int{23}
+}
+
+func (m *ListFilesRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListFilesRequest.Unmarshal(m, b)
+}
+func (m *ListFilesRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListFilesRequest.Marshal(b, m, deterministic)
+}
+func (m *ListFilesRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListFilesRequest.Merge(m, src)
+}
+func (m *ListFilesRequest) XXX_Size() int {
+	return xxx_messageInfo_ListFilesRequest.Size(m)
+}
+func (m *ListFilesRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListFilesRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListFilesRequest proto.InternalMessageInfo
+
+func (m *ListFilesRequest) GetPath() string {
+	if m != nil {
+		return m.Path
+	}
+	return ""
+}
+
+func (m *ListFilesRequest) GetRecursive() bool {
+	if m != nil {
+		return m.Recursive
+	}
+	return false
+}
+
+func (m *ListFilesRequest) GetPageToken() string {
+	if m != nil {
+		return m.PageToken
+	}
+	return ""
+}
+
+func (m *ListFilesRequest) GetPageSize() uint32 {
+	if m != nil {
+		return m.PageSize
+	}
+	return 0
+}
+
+// An entry in a directory listing.
+type FileInfo struct {
+	// The full path of the file or directory.
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// Set for directories, which exist implicitly while any file below them exists.
+	Directory bool `protobuf:"varint,2,opt,name=directory,proto3" json:"directory,omitempty"`
+	// The current version of a file, and the time it was committed, in nanoseconds since the Unix
+	// epoch. Unset for directories.
+	Version              uint64   `protobuf:"varint,3,opt,name=version,proto3" json:"version,omitempty"`
+	ModTime              int64    `protobuf:"varint,4,opt,name=modTime,proto3" json:"modTime,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *FileInfo) Reset()         { *m = FileInfo{} }
+func (m *FileInfo) String() string { return proto.CompactTextString(m) }
+func (*FileInfo) ProtoMessage()    {}
+func (*FileInfo) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a0b84a42fa06f626, []int{24}
+}
+
+func (m *FileInfo) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_FileInfo.Unmarshal(m, b)
+}
+func (m *FileInfo) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_FileInfo.Marshal(b, m, deterministic)
+}
+func (m *FileInfo) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_FileInfo.Merge(m, src)
+}
+func (m *FileInfo) XXX_Size() int {
+	return xxx_messageInfo_FileInfo.Size(m)
+}
+func (m *FileInfo) XXX_DiscardUnknown() {
+	xxx_messageInfo_FileInfo.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_FileInfo proto.InternalMessageInfo
+
+func (m *FileInfo) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *FileInfo) GetDirectory() bool {
+	if m != nil {
+		return m.Directory
+	}
+	return false
+}
+
+func (m *FileInfo) GetVersion() uint64 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
+func (m *FileInfo) GetModTime() int64 {
+	if m != nil {
+		return m.ModTime
+	}
+	return 0
+}
+
+type ListFilesResponse struct {
+	// The entries, sorted by name.
+	Files []*FileInfo `protobuf:"bytes,1,rep,name=files,proto3" json:"files,omitempty"`
+	// Passed in a later request to list the next page of entries. Empty on the last page.
+	NextPageToken        string   `protobuf:"bytes,2,opt,name=nextPageToken,proto3" json:"nextPageToken,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListFilesResponse) Reset()         { *m = ListFilesResponse{} }
+func (m *ListFilesResponse) String() string { return proto.CompactTextString(m) }
+func (*ListFilesResponse) ProtoMessage()    {}
+func (*ListFilesResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a0b84a42fa06f626, []int{25}
+}
+
+func (m *ListFilesResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListFilesResponse.Unmarshal(m, b)
+}
+func (m *ListFilesResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListFilesResponse.Marshal(b, m, deterministic)
+}
+func (m *ListFilesResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListFilesResponse.Merge(m, src)
+}
+func (m *ListFilesResponse) XXX_Size() int {
+	return xxx_messageInfo_ListFilesResponse.Size(m)
+}
+func (m *ListFilesResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListFilesResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListFilesResponse proto.InternalMessageInfo
+
+func (m *ListFilesResponse) GetFiles() []*FileInfo {
+	if m != nil {
+		return m.Files
+	}
+	return nil
+}
+
+func (m *ListFilesResponse) GetNextPageToken() string {
+	if m != nil {
+		return m.NextPageToken
+	}
+	return ""
+}
+
+type CollectGarbageRequest struct {
+	// How long, in nanoseconds, blocks are kept after they were last stored or checked for, so that
+	// blocks being uploaded for a file that is not yet committed are not collected. If zero, the
+	// default grace period is used.
+	GracePeriod int64 `protobuf:"varint,1,opt,name=gracePeriod,proto3" json:"gracePeriod,omitempty"`
+	// If set, unreferenced blocks are counted but not deleted.
+	DryRun               bool     `protobuf:"varint,2,opt,name=dryRun,proto3" json:"dryRun,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CollectGarbageRequest) Reset()         { *m = CollectGarbageRequest{} }
+func (m *CollectGarbageRequest) String() string { return proto.CompactTextString(m) }
+func (*CollectGarbageRequest) ProtoMessage()    {}
+func (*CollectGarbageRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a0b84a42fa06f626, []int{26}
+}
+
+func (m *CollectGarbageRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CollectGarbageRequest.Unmarshal(m, b)
+}
+func (m *CollectGarbageRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CollectGarbageRequest.Marshal(b, m, deterministic)
+}
+func (m *CollectGarbageRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CollectGarbageRequest.Merge(m, src)
+}
+func (m *CollectGarbageRequest) XXX_Size() int {
+	return xxx_messageInfo_CollectGarbageRequest.Size(m)
+}
+func (m *CollectGarbageRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_CollectGarbageRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CollectGarbageRequest proto.InternalMessageInfo
+
+func (m *CollectGarbageRequest) GetGracePeriod() int64 {
+	if m != nil {
+		return m.GracePeriod
+	}
+	return 0
+}
+
+func (m *CollectGarbageRequest) GetDryRun() bool {
+	if m != nil {
+		return m.DryRun
+	}
+	return false
+}
+
+type CollectGarbageResponse struct {
+	// The number of blocks older than the grace period.
+	Candidates uint64 `protobuf:"varint,1,opt,name=candidates,proto3" json:"candidates,omitempty"`
+	// The number of those blocks not referenced by any file.
+	Unreferenced uint64 `protobuf:"varint,2,opt,name=unreferenced,proto3" json:"unreferenced,omitempty"`
+	// The number of blocks deleted.
+	Deleted              uint64   `protobuf:"varint,3,opt,name=deleted,proto3" json:"deleted,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CollectGarbageResponse) Reset()         { *m = CollectGarbageResponse{} }
+func (m *CollectGarbageResponse) String() string { return proto.CompactTextString(m) }
+func (*CollectGarbageResponse) ProtoMessage()    {}
+func (*CollectGarbageResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a0b84a42fa06f626, []int{27}
+}
+
+func (m *CollectGarbageResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CollectGarbageResponse.Unmarshal(m, b)
+}
+func (m *CollectGarbageResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CollectGarbageResponse.Marshal(b, m, deterministic)
+}
+func (m *CollectGarbageResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CollectGarbageResponse.Merge(m, src)
+}
+func (m *CollectGarbageResponse) XXX_Size() int {
+	return xxx_messageInfo_CollectGarbageResponse.Size(m)
+}
+func (m *CollectGarbageResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_CollectGarbageResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CollectGarbageResponse proto.InternalMessageInfo
+
+func (m *CollectGarbageResponse) GetCandidates() uint64 {
+	if m != nil {
+		return m.Candidates
+	}
+	return 0
+}
+
+func (m *CollectGarbageResponse) GetUnreferenced() uint64 {
+	if m != nil {
+		return m.Unreferenced
+	}
+	return 0
+}
+
+func (m *CollectGarbageResponse) GetDeleted() uint64 {
+	if m != nil {
+		return m.Deleted
+	}
+	return 0
+}
+
+type CrashRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CrashRequest) Reset()         { *m = CrashRequest{} }
+func (m *CrashRequest) String() string { return proto.CompactTextString(m) }
+func (*CrashRequest) ProtoMessage()    {}
+func (*CrashRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a0b84a42fa06f626, []int{28}
+}
+
+func (m *CrashRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CrashRequest.Unmarshal(m, b)
+}
+func (m *CrashRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CrashRequest.Marshal(b, m, deterministic)
+}
+func (m *CrashRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CrashRequest.Merge(m, src)
+}
+func (m *CrashRequest) XXX_Size() int {
+	return xxx_messageInfo_CrashRequest.Size(m)
+}
+func (m *CrashRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_CrashRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CrashRequest proto.InternalMessageInfo
+
+type CrashResponse struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CrashResponse) Reset()         { *m = CrashResponse{} }
+func (m *CrashResponse) String() string { return proto.CompactTextString(m) }
+func (*CrashResponse) ProtoMessage()    {}
+func (*CrashResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a0b84a42fa06f626, []int{29}
+}
+
+func (m *CrashResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CrashResponse.Unmarshal(m, b)
+}
+func (m *CrashResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CrashResponse.Marshal(b, m, deterministic)
+}
+func (m *CrashResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CrashResponse.Merge(m, src)
+}
+func (m *CrashResponse) XXX_Size() int {
+	return xxx_messageInfo_CrashResponse.Size(m)
+}
+func (m *CrashResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_CrashResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CrashResponse proto.InternalMessageInfo
+
+type RestoreRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RestoreRequest) Reset()         { *m = RestoreRequest{} }
+func (m *RestoreRequest) String() string { return proto.CompactTextString(m) }
+func (*RestoreRequest) ProtoMessage()    {}
+func (*RestoreRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a0b84a42fa06f626, []int{30}
+}
+
+func (m *RestoreRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RestoreRequest.Unmarshal(m, b)
+}
+func (m *RestoreRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RestoreRequest.Marshal(b, m, deterministic)
+}
+func (m *RestoreRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RestoreRequest.Merge(m, src)
+}
+func (m *RestoreRequest) XXX_Size() int {
+	return xxx_messageInfo_RestoreRequest.Size(m)
+}
+func (m *RestoreRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_RestoreRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RestoreRequest proto.InternalMessageInfo
+
+type RestoreResponse struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RestoreResponse) Reset()         { *m = RestoreResponse{} }
+func (m *RestoreResponse) String() string { return proto.CompactTextString(m) }
+func (*RestoreResponse) ProtoMessage()    {}
+func (*RestoreResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a0b84a42fa06f626, []int{31}
+}
+
+func (m *RestoreResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RestoreResponse.Unmarshal(m, b)
+}
+func (m *RestoreResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RestoreResponse.Marshal(b, m, deterministic)
+}
+func (m *RestoreResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RestoreResponse.Merge(m, src)
+}
+func (m *RestoreResponse) XXX_Size() int {
+	return xxx_messageInfo_RestoreResponse.Size(m)
+}
+func (m *RestoreResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_RestoreResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RestoreResponse proto.InternalMessageInfo
+
+type IsLeaderRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *IsLeaderRequest) Reset()         { *m = IsLeaderRequest{} }
+func (m *IsLeaderRequest) String() string { return proto.CompactTextString(m) }
+func (*IsLeaderRequest) ProtoMessage()    {}
+func (*IsLeaderRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a0b84a42fa06f626, []int{32}
+}
+
+func (m *IsLeaderRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_IsLeaderRequest.Unmarshal(m, b)
+}
+func (m *IsLeaderRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_IsLeaderRequest.Marshal(b, m, deterministic)
+}
+func (m *IsLeaderRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_IsLeaderRequest.Merge(m, src)
+}
+func (m *IsLeaderRequest) XXX_Size() int {
+	return xxx_messageInfo_IsLeaderRequest.Size(m)
+}
+func (m *IsLeaderRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_IsLeaderRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_IsLeaderRequest proto.InternalMessageInfo
+
+type IsLeaderResponse struct {
+	Leader bool `protobuf:"varint,1,opt,name=leader,proto3" json:"leader,omitempty"`
+	// The ID of the node believed to be the leader, or empty if it is unknown.
+	LeaderId             string   `protobuf:"bytes,2,opt,name=leaderId,proto3" json:"leaderId,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *IsLeaderResponse) Reset()         { *m = IsLeaderResponse{} }
+func (m *IsLeaderResponse) String() string { return proto.CompactTextString(m) }
+func (*IsLeaderResponse) ProtoMessage()    {}
+func (*IsLeaderResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a0b84a42fa06f626, []int{33}
+}
+
+func (m *IsLeaderResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_IsLeaderResponse.Unmarshal(m, b)
+}
+func (m *IsLeaderResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_IsLeaderResponse.Marshal(b, m, deterministic)
+}
+func (m *IsLeaderResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_IsLeaderResponse.Merge(m, src)
+}
+func (m *IsLeaderResponse) XXX_Size() int {
+	return xxx_messageInfo_IsLeaderResponse.Size(m)
+}
+func (m *IsLeaderResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_IsLeaderResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_IsLeaderResponse proto.InternalMessageInfo
+
+func (m *IsLeaderResponse) GetLeader() bool {
+	if m != nil {
+		return m.Leader
+	}
+	return false
+}
+
+func (m *IsLeaderResponse) GetLeaderId() string {
+	if m != nil {
+		return m.LeaderId
+	}
+	return ""
+}
+
+type IsCrashedRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *IsCrashedRequest) Reset()         { *m = IsCrashedRequest{} }
+func (m *IsCrashedRequest) String() string { return proto.CompactTextString(m) }
+func (*IsCrashedRequest) ProtoMessage()    {}
+func (*IsCrashedRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a0b84a42fa06f626, []int{34}
+}
+
+func (m *IsCrashedRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_IsCrashedRequest.Unmarshal(m, b)
+}
+func (m *IsCrashedRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_IsCrashedRequest.Marshal(b, m, deterministic)
+}
+func (m *IsCrashedRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_IsCrashedRequest.Merge(m, src)
+}
+func (m *IsCrashedRequest) XXX_Size() int {
+	return xxx_messageInfo_IsCrashedRequest.Size(m)
+}
+func (m *IsCrashedRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_IsCrashedRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_IsCrashedRequest proto.InternalMessageInfo
+
+type IsCrashedResponse struct {
+	Crashed              bool     `protobuf:"varint,1,opt,name=crashed,proto3" json:"crashed,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *IsCrashedResponse) Reset()         { *m = IsCrashedResponse{} }
+func (m *IsCrashedResponse) String() string { return proto.CompactTextString(m) }
+func (*IsCrashedResponse) ProtoMessage()    {}
+func (*IsCrashedResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a0b84a42fa06f626, []int{35}
+}
+
+func (m *IsCrashedResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_IsCrashedResponse.Unmarshal(m, b)
+}
+func (m *IsCrashedResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_IsCrashedResponse.Marshal(b, m, deterministic)
+}
+func (m *IsCrashedResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_IsCrashedResponse.Merge(m, src)
+}
+func (m *IsCrashedResponse) XXX_Size() int {
+	return xxx_messageInfo_IsCrashedResponse.Size(m)
+}
+func (m *IsCrashedResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_IsCrashedResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_IsCrashedResponse proto.InternalMessageInfo
+
+func (m *IsCrashedResponse) GetCrashed() bool {
+	if m != nil {
+		return m.Crashed
+	}
+	return false
+}
+
+// An operation on the file metadata, replicated through the Raft log.
+type Operation struct {
+	Type      Operation_Type `protobuf:"varint,1,opt,name=type,proto3,enum=meta.Operation_Type" json:"type,omitempty"`
+	Filename  string         `protobuf:"bytes,2,opt,name=filename,proto3" json:"filename,omitempty"`
+	Version   uint64         `protobuf:"varint,3,opt,name=version,proto3" json:"version,omitempty"`
+	HashList  []string       `protobuf:"bytes,4,rep,name=hashList,proto3" json:"hashList,omitempty"`
+	BlockSize uint64         `protobuf:"varint,5,opt,name=blockSize,proto3" json:"blockSize,omitempty"`
+	Chunking  Chunking       `protobuf:"varint,6,opt,name=chunking,proto3,enum=meta.Chunking" json:"chunking,omitempty"`
+	// The time the operation was proposed, in nanoseconds since the Unix epoch. It is chosen by the
+	// leader, so that every node records the same modification time and prunes the same versions.
+	Timestamp  int64           `protobuf:"varint,7,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Attributes *FileAttributes `protobuf:"bytes,8,opt,name=attributes,proto3" json:"attributes,omitempty"`
+	// The file a RENAME or COPY operation writes to, and its new version. For these operations,
+	// filename and version are those of the source.
+	Destination        string `protobuf:"bytes,9,opt,name=destination,proto3" json:"destination,omitempty"`
+	DestinationVersion uint64 `protobuf:"varint,10,opt,name=destinationVersion,proto3" json:"destinationVersion,omitempty"`
+	// The operations of a BATCH operation, which are applied together or not at all, with the
+	// timestamp of the batch.
+	Batch                []*Operation `protobuf:"bytes,11,rep,name=batch,proto3" json:"batch,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
+	XXX_unrecognized     []byte       `json:"-"`
+	XXX_sizecache        int32        `json:"-"`
+}
+
+func (m *Operation) Reset()         { *m = Operation{} }
+func (m *Operation) String() string { return proto.CompactTextString(m) }
+func (*Operation) ProtoMessage()    {}
+func (*Operation) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a0b84a42fa06f626, []int{36}
+}
+
+func (m *Operation) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Operation.Unmarshal(m, b)
+}
+func (m *Operation) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Operation.Marshal(b, m, deterministic)
+}
+func (m *Operation) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Operation.Merge(m, src)
+}
+func (m *Operation) XXX_Size() int {
+	return xxx_messageInfo_Operation.Size(m)
+}
+func (m *Operation) XXX_DiscardUnknown() {
+	xxx_messageInfo_Operation.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Operation proto.InternalMessageInfo
+
+func (m *Operation) GetType() Operation_Type {
+	if m != nil {
+		return m.Type
+	}
+	return Operation_NOOP
+}
+
+func (m *Operation) GetFilename() string {
+	if m != nil {
+		return m.Filename
+	}
+	return ""
+}
+
+func (m *Operation) GetVersion() uint64 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
+func (m *Operation) GetHashList() []string {
+	if m != nil {
+		return m.HashList
+	}
+	return nil
+}
+
+func (m *Operation) GetBlockSize() uint64 {
+	if m != nil {
+		return m.BlockSize
+	}
+	return 0
+}
+
+func (m *Operation) GetChunking() Chunking {
+	if m != nil {
+		return m.Chunking
+	}
+	return Chunking_FIXED
+}
+
+func (m *Operation) GetTimestamp() int64 {
+	if m != nil {
+		return m.Timestamp
+	}
+	return 0
+}
+
+func (m *Operation) GetAttributes() *FileAttributes {
+	if m != nil {
+		return m.Attributes
+	}
+	return nil
+}
+
+func (m *Operation) GetDestination() string {
+	if m != nil {
+		return m.Destination
+	}
+	return ""
+}
+
+func (m *Operation) GetDestinationVersion() uint64 {
+	if m != nil {
+		return m.DestinationVersion
+	}
+	return 0
+}
+
+func (m *Operation) GetBatch() []*Operation {
+	if m != nil {
+		return m.Batch
+	}
+	return nil
+}
+
+type LogEntry struct {
+	Term                 uint64     `protobuf:"varint,1,opt,name=term,proto3" json:"term,omitempty"`
+	Operation            *Operation `protobuf:"bytes,2,opt,name=operation,proto3" json:"operation,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
+	XXX_unrecognized     []byte     `json:"-"`
+	XXX_sizecache        int32      `json:"-"`
+}
+
+func (m *LogEntry) Reset()         { *m = LogEntry{} }
+func (m *LogEntry) String() string { return proto.CompactTextString(m) }
+func (*LogEntry) ProtoMessage()    {}
+func (*LogEntry) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a0b84a42fa06f626, []int{37}
+}
+
+func (m *LogEntry) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_LogEntry.Unmarshal(m, b)
+}
+func (m *LogEntry) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_LogEntry.Marshal(b, m, deterministic)
+}
+func (m *LogEntry) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_LogEntry.Merge(m, src)
+}
+func (m *LogEntry) XXX_Size() int {
+	return xxx_messageInfo_LogEntry.Size(m)
+}
+func (m *LogEntry) XXX_DiscardUnknown() {
+	xxx_messageInfo_LogEntry.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_LogEntry proto.InternalMessageInfo
+
+func (m *LogEntry) GetTerm() uint64 {
+	if m != nil {
+		return m.Term
+	}
+	return 0
+}
+
+func (m *LogEntry) GetOperation() *Operation {
+	if m != nil {
+		return m.Operation
+	}
+	return nil
+}
+
+type AppendEntriesRequest struct {
+	Term                 uint64      `protobuf:"varint,1,opt,name=term,proto3" json:"term,omitempty"`
+	LeaderId             string      `protobuf:"bytes,2,opt,name=leaderId,proto3" json:"leaderId,omitempty"`
+	PrevLogIndex         uint64      `protobuf:"varint,3,opt,name=prevLogIndex,proto3" json:"prevLogIndex,omitempty"`
+	PrevLogTerm          uint64      `protobuf:"varint,4,opt,name=prevLogTerm,proto3" json:"prevLogTerm,omitempty"`
+	Entries              []*LogEntry `protobuf:"bytes,5,rep,name=entries,proto3" json:"entries,omitempty"`
+	LeaderCommit         uint64      `protobuf:"varint,6,opt,name=leaderCommit,proto3" json:"leaderCommit,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
+	XXX_unrecognized     []byte      `json:"-"`
+	XXX_sizecache        int32       `json:"-"`
+}
+
+func (m *AppendEntriesRequest) Reset()         { *m = AppendEntriesRequest{} }
+func (m *AppendEntriesRequest) String() string { return proto.CompactTextString(m) }
+func (*AppendEntriesRequest) ProtoMessage()    {}
+func (*AppendEntriesRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a0b84a42fa06f626, []int{38}
+}
+
+func (m *AppendEntriesRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AppendEntriesRequest.Unmarshal(m, b)
+}
+func (m *AppendEntriesRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AppendEntriesRequest.Marshal(b, m, deterministic)
+}
+func (m *AppendEntriesRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AppendEntriesRequest.Merge(m, src)
+}
+func (m *AppendEntriesRequest) XXX_Size() int {
+	return xxx_messageInfo_AppendEntriesRequest.Size(m)
+}
+func (m *AppendEntriesRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_AppendEntriesRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AppendEntriesRequest proto.InternalMessageInfo
+
+func (m *AppendEntriesRequest) GetTerm() uint64 {
+	if m != nil {
+		return m.Term
+	}
+	return 0
+}
+
+func (m *AppendEntriesRequest) GetLeaderId() string {
+	if m != nil {
+		return m.LeaderId
+	}
+	return ""
+}
+
+func (m *AppendEntriesRequest) GetPrevLogIndex() uint64 {
+	if m != nil {
+		return m.PrevLogIndex
+	}
+	return 0
+}
+
+func (m *AppendEntriesRequest) GetPrevLogTerm() uint64 {
+	if m != nil {
+		return m.PrevLogTerm
+	}
+	return 0
+}
+
+func (m *AppendEntriesRequest) GetEntries() []*LogEntry {
+	if m != nil {
+		return m.Entries
+	}
+	return nil
+}
+
+func (m *AppendEntriesRequest) GetLeaderCommit() uint64 {
+	if m != nil {
+		return m.LeaderCommit
+	}
+	return 0
+}
+
+type AppendEntriesResponse struct {
+	Term    uint64 `protobuf:"varint,1,opt,name=term,proto3" json:"term,omitempty"`
+	Success bool   `protobuf:"varint,2,opt,name=success,proto3" json:"success,omitempty"`
+	// The index of the last entry in the follower's log, used by the leader to find where the
+	// logs diverge when the append fails.
+	LastLogIndex         uint64   `protobuf:"varint,3,opt,name=lastLogIndex,proto3" json:"lastLogIndex,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *AppendEntriesResponse) Reset()         { *m = AppendEntriesResponse{} }
+func (m *AppendEntriesResponse) String() string { return proto.CompactTextString(m) }
+func (*AppendEntriesResponse) ProtoMessage()    {}
+func (*AppendEntriesResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a0b84a42fa06f626, []int{39}
+}
+
+func (m *AppendEntriesResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AppendEntriesResponse.Unmarshal(m, b)
+}
+func (m *AppendEntriesResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AppendEntriesResponse.Marshal(b, m, deterministic)
+}
+func (m *AppendEntriesResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AppendEntriesResponse.Merge(m, src)
+}
+func (m *AppendEntriesResponse) XXX_Size() int {
+	return xxx_messageInfo_AppendEntriesResponse.Size(m)
+}
+func (m *AppendEntriesResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_AppendEntriesResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AppendEntriesResponse proto.InternalMessageInfo
+
+func (m *AppendEntriesResponse) GetTerm() uint64 {
+	if m != nil {
+		return m.Term
+	}
+	return 0
+}
+
+func (m *AppendEntriesResponse) GetSuccess() bool {
+	if m != nil {
+		return m.Success
+	}
+	return false
+}
+
+func (m *AppendEntriesResponse) GetLastLogIndex() uint64 {
+	if m != nil {
+		return m.LastLogIndex
+	}
+	return 0
+}
+
+type RequestVoteRequest struct {
+	Term                 uint64   `protobuf:"varint,1,opt,name=term,proto3" json:"term,omitempty"`
+	CandidateId          string   `protobuf:"bytes,2,opt,name=candidateId,proto3" json:"candidateId,omitempty"`
+	LastLogIndex         uint64   `protobuf:"varint,3,opt,name=lastLogIndex,proto3" json:"lastLogIndex,omitempty"`
+	LastLogTerm          uint64   `protobuf:"varint,4,opt,name=lastLogTerm,proto3" json:"lastLogTerm,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RequestVoteRequest) Reset()         { *m = RequestVoteRequest{} }
+func (m *RequestVoteRequest) String() string { return proto.CompactTextString(m) }
+func (*RequestVoteRequest) ProtoMessage()    {}
+func (*RequestVoteRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a0b84a42fa06f626, []int{40}
+}
+
+func (m *RequestVoteRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RequestVoteRequest.Unmarshal(m, b)
+}
+func (m *RequestVoteRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RequestVoteRequest.Marshal(b, m, deterministic)
+}
+func (m *RequestVoteRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RequestVoteRequest.Merge(m, src)
+}
+func (m *RequestVoteRequest) XXX_Size() int {
+	return xxx_messageInfo_RequestVoteRequest.Size(m)
+}
+func (m *RequestVoteRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_RequestVoteRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RequestVoteRequest proto.InternalMessageInfo
+
+func (m *RequestVoteRequest) GetTerm() uint64 {
+	if m != nil {
+		return m.Term
+	}
+	return 0
+}
+
+func (m *RequestVoteRequest) GetCandidateId() string {
+	if m != nil {
+		return m.CandidateId
+	}
+	return ""
+}
+
+func (m *RequestVoteRequest) GetLastLogIndex() uint64 {
+	if m != nil {
+		return m.LastLogIndex
+	}
+	return 0
+}
+
+func (m *RequestVoteRequest) GetLastLogTerm() uint64 {
+	if m != nil {
+		return m.LastLogTerm
+	}
+	return 0
+}
+
+type RequestVoteResponse struct {
+	Term                 uint64   `protobuf:"varint,1,opt,name=term,proto3" json:"term,omitempty"`
+	VoteGranted          bool     `protobuf:"varint,2,opt,name=voteGranted,proto3" json:"voteGranted,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RequestVoteResponse) Reset()         { *m = RequestVoteResponse{} }
+func (m *RequestVoteResponse) String() string { return proto.CompactTextString(m) }
+func (*RequestVoteResponse) ProtoMessage()    {}
+func (*RequestVoteResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a0b84a42fa06f626, []int{41}
+}
+
+func (m *RequestVoteResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RequestVoteResponse.Unmarshal(m, b)
+}
+func (m *RequestVoteResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RequestVoteResponse.Marshal(b, m, deterministic)
+}
+func (m *RequestVoteResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RequestVoteResponse.Merge(m, src)
+}
+func (m *RequestVoteResponse) XXX_Size() int {
+	return xxx_messageInfo_RequestVoteResponse.Size(m)
+}
+func (m *RequestVoteResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_RequestVoteResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RequestVoteResponse proto.InternalMessageInfo
+
+func (m *RequestVoteResponse) GetTerm() uint64 {
+	if m != nil {
+		return m.Term
+	}
+	return 0
+}
+
+func (m *RequestVoteResponse) GetVoteGranted() bool {
+	if m != nil {
+		return m.VoteGranted
+	}
+	return false
+}
+
+// A chunk of a snapshot of the metadata, sent by the leader to a follower that needs entries the
+// leader has discarded from its log. The snapshot reflects every entry up to and including the last
+// included entry.
+type InstallSnapshotRequest struct {
+	Term              uint64 `protobuf:"varint,1,opt,name=term,proto3" json:"term,omitempty"`
+	LeaderId          string `protobuf:"bytes,2,opt,name=leaderId,proto3" json:"leaderId,omitempty"`
+	LastIncludedIndex uint64 `protobuf:"varint,3,opt,name=lastIncludedIndex,proto3" json:"lastIncludedIndex,omitempty"`
+	LastIncludedTerm  uint64 `protobuf:"varint,4,opt,name=lastIncludedTerm,proto3" json:"lastIncludedTerm,omitempty"`
+	// The offset of the chunk in the snapshot, and whether it is the last chunk.
+	Offset               uint64   `protobuf:"varint,5,opt,name=offset,proto3" json:"offset,omitempty"`
+	Data                 []byte   `protobuf:"bytes,6,opt,name=data,proto3" json:"data,omitempty"`
+	Done                 bool     `protobuf:"varint,7,opt,name=done,proto3" json:"done,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *InstallSnapshotRequest) Reset()         { *m = InstallSnapshotRequest{} }
+func (m *InstallSnapshotRequest) String() string { return proto.CompactTextString(m) }
+func (*InstallSnapshotRequest) ProtoMessage()    {}
+func (*InstallSnapshotRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a0b84a42fa06f626, []int{42}
+}
+
+func (m *InstallSnapshotRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_InstallSnapshotRequest.Unmarshal(m, b)
+}
+func (m *InstallSnapshotRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_InstallSnapshotRequest.Marshal(b, m, deterministic)
+}
+func (m *InstallSnapshotRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_InstallSnapshotRequest.Merge(m, src)
+}
+func (m *InstallSnapshotRequest) XXX_Size() int {
+	return xxx_messageInfo_InstallSnapshotRequest.Size(m)
+}
+func (m *InstallSnapshotRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_InstallSnapshotRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_InstallSnapshotRequest proto.InternalMessageInfo
+
+func (m *InstallSnapshotRequest) GetTerm() uint64 {
+	if m != nil {
+		return m.Term
+	}
+	return 0
+}
+
+func (m *InstallSnapshotRequest) GetLeaderId() string {
+	if m != nil {
+		return m.LeaderId
+	}
+	return ""
+}
+
+func (m *InstallSnapshotRequest) GetLastIncludedIndex() uint64 {
+	if m != nil {
+		return m.LastIncludedIndex
+	}
+	return 0
+}
+
+func (m *InstallSnapshotRequest) GetLastIncludedTerm() uint64 {
+	if m != nil {
+		return m.LastIncludedTerm
+	}
+	return 0
+}
+
+func (m *InstallSnapshotRequest) GetOffset() uint64 {
+	if m != nil {
+		return m.Offset
+	}
+	return 0
+}
+
+func (m *InstallSnapshotRequest) GetData() []byte {
+	if m != nil {
+		return m.Data
+	}
+	return nil
+}
+
+func (m *InstallSnapshotRequest) GetDone() bool {
+	if m != nil {
+		return m.Done
+	}
+	return false
+}
+
+type InstallSnapshotResponse struct {
+	Term                 uint64   `protobuf:"varint,1,opt,name=term,proto3" json:"term,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *InstallSnapshotResponse) Reset()         { *m = InstallSnapshotResponse{} }
+func (m *InstallSnapshotResponse) String() string { return proto.CompactTextString(m) }
+func (*InstallSnapshotResponse) ProtoMessage()    {}
+func (*InstallSnapshotResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a0b84a42fa06f626, []int{43}
+}
+
+func (m *InstallSnapshotResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_InstallSnapshotResponse.Unmarshal(m, b)
+}
+func (m *InstallSnapshotResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_InstallSnapshotResponse.Marshal(b, m, deterministic)
+}
+func (m *InstallSnapshotResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_InstallSnapshotResponse.Merge(m, src)
+}
+func (m *InstallSnapshotResponse) XXX_Size() int {
+	return xxx_messageInfo_InstallSnapshotResponse.Size(m)
+}
+func (m *InstallSnapshotResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_InstallSnapshotResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_InstallSnapshotResponse proto.InternalMessageInfo
+
+func (m *InstallSnapshotResponse) GetTerm() uint64 {
+	if m != nil {
+		return m.Term
+	}
+	return 0
+}
+
+func init() {
+	proto.RegisterEnum("meta.Chunking", Chunking_name, Chunking_value)
+	proto.RegisterEnum("meta.WatchEvent_Type", WatchEvent_Type_name, WatchEvent_Type_value)
+	proto.RegisterEnum("meta.Operation_Type", Operation_Type_name, Operation_Type_value)
+	proto.RegisterType((*ReadFileRequest)(nil), "meta.ReadFileRequest")
+	proto.RegisterType((*ReadFileResponse)(nil), "meta.ReadFileResponse")
+	proto.RegisterType((*ModifyFileRequest)(nil), "meta.ModifyFileRequest")
+	proto.RegisterType((*ModifyFileResponse)(nil), "meta.ModifyFileResponse")
+	proto.RegisterType((*DeleteFileRequest)(nil), "meta.DeleteFileRequest")
+	proto.RegisterType((*DeleteFileResponse)(nil), "meta.DeleteFileResponse")
+	proto.RegisterType((*RenameFileRequest)(nil), "meta.RenameFileRequest")
+	proto.RegisterType((*RenameFileResponse)(nil), "meta.RenameFileResponse")
+	proto.RegisterType((*CopyFileRequest)(nil), "meta.CopyFileRequest")
+	proto.RegisterType((*CopyFileResponse)(nil), "meta.CopyFileResponse")
+	proto.RegisterType((*CommitBatchRequest)(nil), "meta.CommitBatchRequest")
+	proto.RegisterType((*CommitBatchResponse)(nil), "meta.CommitBatchResponse")
+	proto.RegisterType((*WatchRequest)(nil), "meta.WatchRequest")
+	proto.RegisterType((*WatchEvent)(nil), "meta.WatchEvent")
+	proto.RegisterType((*GetVersionRequest)(nil), "meta.GetVersionRequest")
+	proto.RegisterType((*GetVersionResponse)(nil), "meta.GetVersionResponse")
+	proto.RegisterType((*FileAttributes)(nil), "meta.FileAttributes")
+	proto.RegisterType((*StatFileRequest)(nil), "meta.StatFileRequest")
+	proto.RegisterType((*StatFileResponse)(nil), "meta.StatFileResponse")
+	proto.RegisterType((*ReadFileVersionRequest)(nil), "meta.ReadFileVersionRequest")
+	proto.RegisterType((*ListVersionsRequest)(nil), "meta.ListVersionsRequest")
+	proto.RegisterType((*FileVersion)(nil), "meta.FileVersion")
+	proto.RegisterType((*ListVersionsResponse)(nil), "meta.ListVersionsResponse")
+	proto.RegisterType((*ListFilesRequest)(nil), "meta.ListFilesRequest")
+	proto.RegisterType((*FileInfo)(nil), "meta.FileInfo")
+	proto.RegisterType((*ListFilesResponse)(nil), "meta.ListFilesResponse")
+	proto.RegisterType((*CollectGarbageRequest)(nil), "meta.CollectGarbageRequest")
+	proto.RegisterType((*CollectGarbageResponse)(nil), "meta.CollectGarbageResponse")
+	proto.RegisterType((*CrashRequest)(nil), "meta.CrashRequest")
+	proto.RegisterType((*CrashResponse)(nil), "meta.CrashResponse")
+	proto.RegisterType((*RestoreRequest)(nil), "meta.RestoreRequest")
+	proto.RegisterType((*RestoreResponse)(nil), "meta.RestoreResponse")
+	proto.RegisterType((*IsLeaderRequest)(nil), "meta.IsLeaderRequest")
+	proto.RegisterType((*IsLeaderResponse)(nil), "meta.IsLeaderResponse")
+	proto.RegisterType((*IsCrashedRequest)(nil), "meta.IsCrashedRequest")
+	proto.RegisterType((*IsCrashedResponse)(nil), "meta.IsCrashedResponse")
+	proto.RegisterType((*Operation)(nil), "meta.Operation")
+	proto.RegisterType((*LogEntry)(nil), "meta.LogEntry")
+	proto.RegisterType((*AppendEntriesRequest)(nil), "meta.AppendEntriesRequest")
+	proto.RegisterType((*AppendEntriesResponse)(nil), "meta.AppendEntriesResponse")
+	proto.RegisterType((*RequestVoteRequest)(nil), "meta.RequestVoteRequest")
+	proto.RegisterType((*RequestVoteResponse)(nil), "meta.RequestVoteResponse")
+	proto.RegisterType((*InstallSnapshotRequest)(nil), "meta.InstallSnapshotRequest")
+	proto.RegisterType((*InstallSnapshotResponse)(nil), "meta.InstallSnapshotResponse")
+}
+
+func init() { proto.RegisterFile("service.proto", fileDescriptor_a0b84a42fa06f626) }
+
+var fileDescriptor_a0b84a42fa06f626 = []byte{
+	// 1825 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xcc, 0x58, 0xcd, 0x6e, 0xe4, 0xb8,
+	0x11, 0x5e, 0xb9, 0x7f, 0xac, 0xae, 0xb6, 0xdd, 0x6a, 0xda, 0xd3, 0xa3, 0x68, 0x27, 0x41, 0x83,
+	0xd8, 0x00, 0xce, 0x60, 0xed, 0xd9, 0x38, 0x3f, 0xc0, 0x02, 0x01, 0x02, 0x4f, 0x5b, 0xf6, 0x34,
+	0xc6, 0x3f, 0x13, 0xba, 0xb1, 0xd9, 0x39, 0x2d, 0x64, 0x89, 0xb6, 0x85, 0xe9, 0x96, 0x3a, 0x12,
+	0xdb, 0x98, 0xce, 0x21, 0xa7, 0x3c, 0x40, 0x2e, 0xb9, 0x06, 0x08, 0xf2, 0x32, 0x79, 0x86, 0x1c,
+	0x03, 0xe4, 0x90, 0x97, 0x08, 0x02, 0x52, 0xa4, 0x44, 0xa9, 0x7f, 0x6c, 0x64, 0xe7, 0x90, 0x1b,
+	0xeb, 0x23, 0x55, 0xac, 0xfa, 0x58, 0xaa, 0x2a, 0x12, 0xb6, 0x53, 0x9a, 0x3c, 0x84, 0x3e, 0x3d,
+	0x9c, 0x26, 0x31, 0x8b, 0x51, 0x7d, 0x42, 0x99, 0x87, 0x0f, 0xa0, 0x43, 0xa8, 0x17, 0x9c, 0x86,
+	0x63, 0x4a, 0xe8, 0xef, 0x66, 0x34, 0x65, 0xc8, 0x01, 0xf3, 0x36, 0x1c, 0xd3, 0xc8, 0x9b, 0x50,
+	0xdb, 0xe8, 0x1b, 0xfb, 0x2d, 0x92, 0xcb, 0xf8, 0xef, 0x06, 0x58, 0xc5, 0xfa, 0x74, 0x1a, 0x47,
+	0x29, 0x45, 0x36, 0x6c, 0x3e, 0xd0, 0x24, 0x0d, 0xe3, 0x48, 0xac, 0xaf, 0x13, 0x25, 0x72, 0x55,
+	0xf7, 0x5e, 0x7a, 0x7f, 0x1e, 0xa6, 0xcc, 0xde, 0xe8, 0xd7, 0xb8, 0x2a, 0x25, 0xa3, 0x17, 0xd0,
+	0xba, 0x19, 0xc7, 0xfe, 0x87, 0xeb, 0xf0, 0xf7, 0xd4, 0xae, 0x89, 0xef, 0x0a, 0x00, 0xbd, 0x04,
+	0xd3, 0xbf, 0x9f, 0x45, 0x1f, 0xc2, 0xe8, 0xce, 0xae, 0xf7, 0x8d, 0xfd, 0x9d, 0xa3, 0x9d, 0x43,
+	0x6e, 0xf0, 0xe1, 0x40, 0xa2, 0x24, 0x9f, 0xcf, 0x35, 0xbd, 0xa5, 0xf3, 0xd4, 0x6e, 0xf4, 0x6b,
+	0xfb, 0x5b, 0xa4, 0x00, 0x50, 0x0f, 0x9a, 0xf4, 0x63, 0x98, 0xb2, 0xd4, 0x6e, 0xf6, 0x8d, 0x7d,
+	0x93, 0x48, 0x09, 0xff, 0xcb, 0x80, 0xee, 0x45, 0x1c, 0x84, 0xb7, 0xf3, 0x27, 0x3a, 0xaf, 0xfb,
+	0xb9, 0xb1, 0xda, 0xcf, 0xda, 0x3a, 0x3f, 0xeb, 0xeb, 0xfc, 0x6c, 0x3c, 0xe2, 0xe7, 0xcf, 0x01,
+	0x3c, 0xc6, 0x92, 0xf0, 0x66, 0xc6, 0x68, 0xe6, 0x4d, 0xfb, 0x68, 0x2f, 0x5b, 0xcd, 0x5d, 0x38,
+	0xce, 0xe7, 0x88, 0xb6, 0x0e, 0x7f, 0x0b, 0x48, 0x77, 0xb3, 0x38, 0xb3, 0x74, 0xe6, 0xfb, 0x34,
+	0x4d, 0x85, 0x9b, 0x26, 0x51, 0x22, 0xda, 0x87, 0xce, 0x24, 0x4c, 0xd3, 0x30, 0xba, 0x7b, 0x53,
+	0x3e, 0xba, 0x2a, 0x8c, 0x87, 0xd0, 0x3d, 0xa1, 0x63, 0xca, 0xe8, 0xf7, 0x26, 0x10, 0x1f, 0x02,
+	0xd2, 0x55, 0x3d, 0x66, 0x24, 0xfe, 0x9b, 0x01, 0x5d, 0x22, 0x94, 0xea, 0x7b, 0xf7, 0xa0, 0x99,
+	0xc6, 0xb3, 0xc4, 0x57, 0x3b, 0x4b, 0x09, 0x7d, 0x01, 0xdb, 0xd9, 0xe8, 0x9b, 0xd2, 0xee, 0x65,
+	0x10, 0xf5, 0xa1, 0x1d, 0xd0, 0x94, 0x85, 0x91, 0xc7, 0xf8, 0x9a, 0x9a, 0x50, 0xa1, 0x43, 0xe8,
+	0x10, 0x90, 0x26, 0x2a, 0x65, 0xd9, 0x99, 0x2e, 0x99, 0xe1, 0x5e, 0xe9, 0x46, 0x3e, 0xea, 0xd5,
+	0x5f, 0x0d, 0xe8, 0x0c, 0xe2, 0xe9, 0xfc, 0xff, 0xd9, 0xa7, 0x2f, 0xc1, 0x2a, 0x4c, 0x7c, 0xd4,
+	0x23, 0x17, 0xd0, 0x20, 0x9e, 0x4c, 0x42, 0xf6, 0xda, 0x63, 0xfe, 0xbd, 0xf2, 0xe9, 0x15, 0x40,
+	0x3c, 0xa5, 0x89, 0xd0, 0xcb, 0x3f, 0xa9, 0xed, 0xb7, 0x8f, 0x3a, 0x59, 0x20, 0x5f, 0x29, 0x9c,
+	0x68, 0x4b, 0xf0, 0x7b, 0xd8, 0x2d, 0xa9, 0xf9, 0x84, 0x41, 0x3c, 0x86, 0xad, 0xdf, 0xea, 0xb6,
+	0xf5, 0xa0, 0x39, 0x4d, 0xe8, 0x6d, 0xf8, 0x51, 0xf1, 0x9d, 0x49, 0x08, 0xc3, 0x56, 0x1a, 0x46,
+	0x55, 0xba, 0x4b, 0x18, 0x67, 0x3b, 0x8c, 0x7c, 0x2f, 0x29, 0xb3, 0xad, 0x41, 0xf8, 0x8f, 0x1b,
+	0x00, 0x62, 0x3b, 0xf7, 0x81, 0x46, 0x4c, 0x28, 0x65, 0x71, 0x92, 0x2b, 0x35, 0xa4, 0x52, 0x0d,
+	0x43, 0x3f, 0x81, 0x3a, 0x9b, 0x4f, 0xa9, 0xd8, 0x70, 0xe7, 0xe8, 0x59, 0x46, 0x53, 0xa1, 0xe3,
+	0x70, 0x34, 0x9f, 0x52, 0x22, 0x96, 0x94, 0xfe, 0xbd, 0xda, 0xea, 0x7f, 0xaf, 0x5e, 0x4e, 0x5e,
+	0x36, 0x6c, 0x4e, 0xe2, 0x60, 0x14, 0x4e, 0xa8, 0xc8, 0x40, 0x35, 0xa2, 0xc4, 0xaa, 0x3f, 0xcd,
+	0x45, 0x7f, 0x7e, 0x01, 0x75, 0xbe, 0x3f, 0x02, 0x68, 0x0e, 0x88, 0x7b, 0x3c, 0x72, 0xad, 0xcf,
+	0xf8, 0xf8, 0xe2, 0xea, 0x64, 0x78, 0xfa, 0xde, 0x32, 0xf8, 0xf8, 0xc4, 0x3d, 0x77, 0x47, 0xae,
+	0xb5, 0x81, 0x5a, 0xd0, 0xb8, 0x1e, 0x1d, 0x93, 0x91, 0x55, 0xc3, 0xaf, 0xa0, 0x7b, 0x46, 0x99,
+	0xf4, 0xf0, 0x29, 0x75, 0xe7, 0x10, 0x90, 0xfe, 0xc1, 0x63, 0x85, 0x07, 0xff, 0xc5, 0x80, 0x9d,
+	0x72, 0x4e, 0x44, 0x08, 0xea, 0x29, 0x4f, 0xc1, 0xd9, 0x4a, 0x31, 0xe6, 0xd8, 0x24, 0x0e, 0x32,
+	0x6e, 0xb7, 0x89, 0x18, 0xa3, 0x3d, 0x68, 0x4c, 0x58, 0x28, 0x19, 0xac, 0x91, 0x4c, 0xe0, 0x5b,
+	0xf9, 0x09, 0xf5, 0x58, 0x9c, 0x08, 0xfa, 0x5a, 0x44, 0x89, 0x3c, 0x60, 0x82, 0xf0, 0x8e, 0xa6,
+	0x4c, 0xb0, 0xd7, 0x22, 0x52, 0x2a, 0x57, 0xa5, 0x66, 0xa5, 0x2a, 0xe1, 0x33, 0xe8, 0x5c, 0x33,
+	0x8f, 0x7d, 0xff, 0xcc, 0xf9, 0x1f, 0x03, 0xac, 0x42, 0xd3, 0xa3, 0x15, 0x59, 0x3b, 0xec, 0x8d,
+	0xf2, 0x61, 0xdb, 0xb0, 0x19, 0x88, 0x14, 0x1c, 0x08, 0xcf, 0x4d, 0xa2, 0xc4, 0x4f, 0x58, 0xc1,
+	0x5e, 0x40, 0x2b, 0x9a, 0x4d, 0x5e, 0xf3, 0x6f, 0xb3, 0x02, 0x56, 0x27, 0x05, 0x50, 0xa9, 0x6f,
+	0x9b, 0x4f, 0xac, 0x6f, 0x97, 0xd0, 0x53, 0x1d, 0xc9, 0xd3, 0x03, 0x6a, 0x0d, 0xa1, 0x3f, 0x85,
+	0x5d, 0x9e, 0x18, 0xa4, 0xae, 0xf4, 0x29, 0xd1, 0x39, 0x87, 0xb6, 0xb6, 0xfd, 0xa7, 0x67, 0xbf,
+	0xe0, 0xac, 0x5e, 0xe1, 0x0c, 0xbb, 0xb0, 0x57, 0xb6, 0x56, 0x46, 0xc0, 0x01, 0x98, 0x72, 0x53,
+	0x95, 0x60, 0xbb, 0x05, 0x93, 0x8a, 0xa7, 0x7c, 0x09, 0xfe, 0x03, 0x58, 0x5c, 0x0d, 0x9f, 0xcc,
+	0x3d, 0x46, 0x50, 0x9f, 0x7a, 0xec, 0x5e, 0x7a, 0x2b, 0xc6, 0xdc, 0x98, 0x84, 0xfa, 0xb3, 0x24,
+	0x0d, 0x1f, 0x32, 0x17, 0x4c, 0x52, 0x00, 0x7c, 0x76, 0xea, 0xdd, 0xd1, 0x51, 0xfc, 0x81, 0xaa,
+	0xec, 0x57, 0x00, 0x9c, 0x41, 0x2e, 0xe4, 0x51, 0xb4, 0x4d, 0x72, 0x19, 0x4f, 0xc1, 0xe4, 0x7b,
+	0x0f, 0xa3, 0xdb, 0x98, 0xef, 0xab, 0xb1, 0x2c, 0xc6, 0x5c, 0x73, 0x10, 0x26, 0xd4, 0x67, 0x71,
+	0x32, 0x57, 0xfb, 0xe6, 0x80, 0x4e, 0x78, 0x6d, 0x25, 0xe1, 0xf5, 0x12, 0xe1, 0xf8, 0x3b, 0xe8,
+	0x6a, 0x1e, 0x4b, 0xd6, 0xbe, 0x80, 0x06, 0x3f, 0x54, 0x45, 0xd9, 0x4e, 0x41, 0x19, 0xb7, 0x8c,
+	0x64, 0x93, 0xbc, 0xf4, 0x46, 0xf4, 0x23, 0x7b, 0x97, 0xbb, 0xba, 0x21, 0x2c, 0x2d, 0x83, 0xf8,
+	0x37, 0xf0, 0x6c, 0x10, 0x8f, 0xc7, 0xd4, 0x67, 0x67, 0x5e, 0x72, 0xe3, 0xdd, 0xe5, 0xff, 0x79,
+	0x1f, 0xda, 0x77, 0x89, 0xe7, 0xd3, 0x77, 0x34, 0x09, 0xe3, 0x40, 0xb8, 0x59, 0x23, 0x3a, 0x24,
+	0x52, 0x4a, 0x32, 0x27, 0xb3, 0x48, 0xba, 0x2a, 0x25, 0xfc, 0x00, 0xbd, 0xaa, 0x4a, 0x69, 0xf8,
+	0x8f, 0x00, 0x7c, 0x2f, 0x0a, 0xc2, 0xc0, 0x63, 0x34, 0x95, 0x51, 0xa7, 0x21, 0xbc, 0xd0, 0xcc,
+	0xa2, 0x84, 0xde, 0xd2, 0x84, 0x46, 0x3e, 0x0d, 0x54, 0xf5, 0xd2, 0xb1, 0x6a, 0x08, 0xd6, 0xf3,
+	0x10, 0xc4, 0x3b, 0xb0, 0x35, 0x48, 0xbc, 0x54, 0xd5, 0x48, 0xdc, 0x81, 0x6d, 0x29, 0x67, 0xdb,
+	0x63, 0x0b, 0x76, 0x08, 0x15, 0x55, 0x4b, 0x2d, 0xe9, 0x42, 0x27, 0x47, 0xe4, 0xa2, 0x2e, 0x74,
+	0x86, 0xe9, 0x39, 0xf5, 0x02, 0x9a, 0xa8, 0x55, 0xa7, 0x60, 0x15, 0x90, 0x74, 0xa5, 0x07, 0xcd,
+	0xb1, 0x40, 0x64, 0x4d, 0x97, 0x12, 0x0f, 0x9f, 0x6c, 0x34, 0x0c, 0x24, 0xe1, 0xb9, 0x8c, 0x11,
+	0xd7, 0x23, 0x4c, 0xa2, 0x81, 0xd2, 0x7d, 0x00, 0x5d, 0x0d, 0x2b, 0x12, 0xa3, 0x9f, 0x41, 0xaa,
+	0x63, 0x90, 0x22, 0xfe, 0x77, 0x0d, 0x5a, 0x79, 0xf3, 0x81, 0xf6, 0x65, 0xd1, 0x35, 0x44, 0x42,
+	0xdb, 0xab, 0xf4, 0x26, 0xab, 0x6a, 0xee, 0xc6, 0xea, 0x24, 0x53, 0x5b, 0x7d, 0x61, 0xa8, 0xaf,
+	0xbb, 0x30, 0x34, 0xd6, 0xa5, 0xdb, 0xe6, 0xe3, 0xe9, 0x96, 0x85, 0x13, 0x9a, 0x32, 0x6f, 0x32,
+	0x15, 0xf9, 0xb4, 0x46, 0x0a, 0xa0, 0x92, 0x6e, 0xcd, 0xa7, 0xa5, 0xdb, 0x6a, 0x47, 0xd9, 0x7a,
+	0x6a, 0x47, 0x09, 0xab, 0x3a, 0x4a, 0xf4, 0x63, 0x68, 0xdc, 0xf0, 0x76, 0xc6, 0x6e, 0x2f, 0x6f,
+	0x04, 0xb3, 0x59, 0x3c, 0x94, 0xad, 0x86, 0x09, 0xf5, 0xcb, 0xab, 0xab, 0x77, 0x6b, 0x1a, 0x0d,
+	0x80, 0x26, 0x71, 0x2f, 0x8f, 0x2f, 0x5c, 0xab, 0xc6, 0x57, 0x0f, 0xae, 0xde, 0xbd, 0xb7, 0xea,
+	0xbc, 0xfd, 0x78, 0x7d, 0x3c, 0x1a, 0xbc, 0xb1, 0x1a, 0xf8, 0x02, 0xcc, 0xf3, 0xf8, 0xce, 0x8d,
+	0x58, 0x32, 0xe7, 0xd9, 0x86, 0xd1, 0x64, 0xa2, 0xda, 0x02, 0x3e, 0x46, 0x07, 0xd0, 0xca, 0x9b,
+	0x4f, 0x71, 0xa8, 0x4b, 0xac, 0x2a, 0x56, 0xe0, 0x7f, 0x18, 0xb0, 0x77, 0x3c, 0x9d, 0xd2, 0x28,
+	0xe0, 0x2a, 0xc3, 0x52, 0x06, 0x5d, 0xd0, 0xbd, 0x26, 0x8c, 0xf9, 0x5f, 0x3a, 0x4d, 0xe8, 0xc3,
+	0x79, 0x7c, 0x37, 0x8c, 0x02, 0xfa, 0x51, 0x06, 0x4d, 0x09, 0xe3, 0xfc, 0x4b, 0x79, 0xc4, 0x55,
+	0x67, 0x05, 0x41, 0x87, 0xd0, 0x3e, 0x6c, 0xd2, 0xcc, 0x0e, 0xbb, 0xa1, 0xa7, 0x31, 0xe5, 0x32,
+	0x51, 0xd3, 0x7c, 0xbf, 0x6c, 0xef, 0xac, 0xb9, 0x96, 0x15, 0xb9, 0x84, 0xe1, 0x10, 0x9e, 0x55,
+	0x7c, 0x93, 0xbf, 0xd2, 0x32, 0xe7, 0xb4, 0x86, 0x7c, 0xa3, 0xdc, 0x90, 0xf3, 0xad, 0xbc, 0x94,
+	0x55, 0x5d, 0xd3, 0x31, 0xfc, 0x27, 0x03, 0x90, 0xa4, 0xee, 0x9b, 0x98, 0xd1, 0x75, 0x2c, 0xf6,
+	0xa1, 0x9d, 0x67, 0xb7, 0x9c, 0x48, 0x1d, 0x7a, 0xca, 0x86, 0x5c, 0x8b, 0x94, 0x75, 0x2e, 0x35,
+	0x08, 0xbf, 0x85, 0xdd, 0x92, 0x45, 0x6b, 0x7c, 0xef, 0x43, 0xfb, 0x21, 0x66, 0xf4, 0x2c, 0xf1,
+	0x22, 0x26, 0x33, 0xac, 0x49, 0x74, 0x08, 0xff, 0xd3, 0x80, 0xde, 0x30, 0x4a, 0x99, 0x37, 0x1e,
+	0x5f, 0x47, 0xde, 0x34, 0xbd, 0x8f, 0xd9, 0xff, 0x1a, 0x29, 0x5f, 0x42, 0x97, 0x9b, 0x39, 0x8c,
+	0xfc, 0xf1, 0x2c, 0xa0, 0x81, 0xee, 0xe2, 0xe2, 0x04, 0x7a, 0x09, 0x96, 0x0e, 0x6a, 0xce, 0x2e,
+	0xe0, 0x3c, 0xfd, 0xc6, 0xb7, 0xb7, 0x29, 0x65, 0x32, 0xf5, 0x48, 0x89, 0x5b, 0x18, 0x78, 0xcc,
+	0x13, 0x31, 0xb2, 0x45, 0xc4, 0x58, 0x60, 0x71, 0x44, 0x45, 0x6a, 0x31, 0x89, 0x18, 0xe3, 0x03,
+	0x78, 0xbe, 0xe0, 0xe3, 0x6a, 0xd6, 0x5e, 0xbe, 0x04, 0x53, 0x25, 0x2e, 0xfe, 0x87, 0x9e, 0x0e,
+	0xbf, 0x75, 0x4f, 0xac, 0xcf, 0xd0, 0x2e, 0x74, 0x06, 0x57, 0x97, 0x23, 0xf7, 0x72, 0xf4, 0xdd,
+	0x89, 0x7b, 0x3a, 0xbc, 0x74, 0x4f, 0x2c, 0xe3, 0xe8, 0xcf, 0x00, 0xdb, 0x17, 0x94, 0x79, 0x7c,
+	0xef, 0x6b, 0x5e, 0x5a, 0xd0, 0xd7, 0x60, 0xaa, 0xde, 0x0f, 0xc9, 0x9b, 0x51, 0xe5, 0x35, 0xcb,
+	0xe9, 0x55, 0x61, 0x69, 0xcc, 0xaf, 0x01, 0x8a, 0x67, 0x11, 0xf4, 0x3c, 0x5b, 0xb5, 0xf0, 0x1e,
+	0xe4, 0xd8, 0x8b, 0x13, 0x85, 0x82, 0xe2, 0xc9, 0x42, 0x29, 0x58, 0x78, 0x0f, 0x71, 0xec, 0xc5,
+	0x89, 0x42, 0x41, 0x71, 0xa7, 0x51, 0x0a, 0x16, 0xae, 0x45, 0x8e, 0xbd, 0x38, 0x51, 0x28, 0x28,
+	0x9e, 0x17, 0x94, 0x82, 0x85, 0x57, 0x11, 0xc7, 0x5e, 0x9c, 0x90, 0x0a, 0xbe, 0x06, 0x53, 0xdd,
+	0xe5, 0x15, 0x7d, 0x95, 0xe7, 0x07, 0xa7, 0x57, 0x85, 0xe5, 0xa7, 0xaf, 0xa1, 0xad, 0xdd, 0xc8,
+	0x91, 0xad, 0x96, 0x55, 0xef, 0xfa, 0xce, 0x0f, 0x96, 0xcc, 0x48, 0x1d, 0xaf, 0xa0, 0x21, 0xee,
+	0xb1, 0x08, 0x69, 0x97, 0x5a, 0xf5, 0x9d, 0x55, 0xbd, 0xe8, 0x7e, 0x65, 0xa0, 0xb3, 0xe2, 0xb1,
+	0x52, 0xd1, 0xf6, 0xa2, 0x7c, 0xbc, 0x15, 0xee, 0x56, 0x1d, 0xbe, 0x0b, 0x5b, 0x7a, 0xd7, 0x8c,
+	0xa4, 0x91, 0x4b, 0xfa, 0x7e, 0xc7, 0x59, 0x36, 0x55, 0xf0, 0xa7, 0xae, 0x5e, 0x8a, 0xbf, 0xca,
+	0xa5, 0xce, 0xe9, 0x55, 0x61, 0xf9, 0xe9, 0xaf, 0xa0, 0x95, 0xb7, 0x9f, 0xa8, 0x57, 0xec, 0xa1,
+	0x77, 0xe0, 0xce, 0xf3, 0x05, 0x5c, 0x7e, 0xfd, 0x16, 0x76, 0xca, 0x8d, 0x20, 0xfa, 0x5c, 0xd1,
+	0xbc, 0xa4, 0xe3, 0x74, 0x5e, 0x2c, 0x9f, 0x94, 0xca, 0xde, 0xc0, 0x76, 0x29, 0xc3, 0x23, 0xe9,
+	0xf2, 0xb2, 0x92, 0xe6, 0x7c, 0xbe, 0x74, 0xae, 0x08, 0x0a, 0x2d, 0x5b, 0xa2, 0x3c, 0xf0, 0xaa,
+	0x29, 0x5d, 0x05, 0xc5, 0xb2, 0xd4, 0x7a, 0x09, 0x9d, 0x4a, 0xfe, 0x50, 0x67, 0xbc, 0x3c, 0x75,
+	0x3a, 0x3f, 0x5c, 0x31, 0x2b, 0xf5, 0x7d, 0x05, 0x0d, 0xd1, 0x04, 0xaa, 0x20, 0xd3, 0x1b, 0x59,
+	0x67, 0xb7, 0x84, 0xc9, 0x2f, 0x7e, 0x09, 0x9b, 0xb2, 0x75, 0x45, 0x7b, 0xca, 0x4e, 0xbd, 0xb7,
+	0x75, 0x9e, 0x55, 0xd0, 0x22, 0x1a, 0x54, 0x33, 0xab, 0xa2, 0xa1, 0xd2, 0xef, 0x3a, 0xbd, 0x2a,
+	0x5c, 0x44, 0x43, 0xde, 0xab, 0xa2, 0x7c, 0x51, 0xb9, 0xa1, 0x75, 0x9e, 0x2f, 0xe0, 0xd9, 0xd7,
+	0x37, 0x4d, 0xf1, 0xa0, 0xff, 0xb3, 0xff, 0x0e, 0x00, 0x37, 0x34, 0xea, 0x8e, 0xe1, 0x17, 0x00,
+	0x00,
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// MetadataStoreClient is the client API for MetadataStore service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type MetadataStoreClient interface {
+	ReadFile(ctx context.Context, in *ReadFileRequest, opts ...grpc.CallOption) (*ReadFileResponse, error)
+	ModifyFile(ctx context.Context, in *ModifyFileRequest, opts ...grpc.CallOption) (*ModifyFileResponse, error)
+	DeleteFile(ctx context.Context, in *DeleteFileRequest, opts ...grpc.CallOption) (*DeleteFileResponse, error)
+	GetVersion(ctx context.Context, in *GetVersionRequest, opts ...grpc.CallOption) (*GetVersionResponse, error)
+	// Atomically moves or copies a file to a new name without transferring any blocks. Like
+	// ModifyFile, they are unsuccessful if a version does not match, and return a NOT_FOUND status if
+	// the source does not exist.
+	RenameFile(ctx context.Context, in *RenameFileRequest, opts ...grpc.CallOption) (*RenameFileResponse, error)
+	CopyFile(ctx context.Context, in *CopyFileRequest, opts ...grpc.CallOption) (*CopyFileResponse, error)
+	// Commits several operations on different files atomically: either all of them succeed, or none
+	// do, and readers never observe some without the others.
+	CommitBatch(ctx context.Context, in *CommitBatchRequest, opts ...grpc.CallOption) (*CommitBatchResponse, error)
+	// Streams the changes to files as they are committed. Renames are sent as the deletion of the
+	// source and the creation or modification of the destination. Returns an OUT_OF_RANGE status if
+	// the changes since the requested store version are no longer retained, or if the client falls
+	// too far behind, in which case the client should list the files again and start a new watch.
+	Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (MetadataStore_WatchClient, error)
+	// Reads a retained version of a file. Returns a NOT_FOUND status if the version is not retained.
+	ReadFileVersion(ctx context.Context, in *ReadFileVersionRequest, opts ...grpc.CallOption) (*ReadFileResponse, error)
+	ListVersions(ctx context.Context, in *ListVersionsRequest, opts ...grpc.CallOption) (*ListVersionsResponse, error)
+	// Describes a version of a file without reading its blocks. Returns a NOT_FOUND status if the file
+	// does not exist or the version is not retained.
+	StatFile(ctx context.Context, in *StatFileRequest, opts ...grpc.CallOption) (*StatFileResponse, error)
+	// Lists the files and implicit directories in a directory. File paths are slash-separated, and a
+	// directory exists while any file below it exists.
+	ListFiles(ctx context.Context, in *ListFilesRequest, opts ...grpc.CallOption) (*ListFilesResponse, error)
+	// Deletes the blocks in the block store that are not referenced by any file.
+	CollectGarbage(ctx context.Context, in *CollectGarbageRequest, opts ...grpc.CallOption) (*CollectGarbageResponse, error)
+	// Raft RPCs used between the nodes of a replicated metadata store.
+	AppendEntries(ctx context.Context, in *AppendEntriesRequest, opts ...grpc.CallOption) (*AppendEntriesResponse, error)
+	RequestVote(ctx context.Context, in *RequestVoteRequest, opts ...grpc.CallOption) (*RequestVoteResponse, error)
+	InstallSnapshot(ctx context.Context, in *InstallSnapshotRequest, opts ...grpc.CallOption) (*InstallSnapshotResponse, error)
+	// Used for debugging purposes only.
+	Crash(ctx context.Context, in *CrashRequest, opts ...grpc.CallOption) (*CrashResponse, error)
+	Restore(ctx context.Context, in *RestoreRequest, opts ...grpc.CallOption) (*RestoreResponse, error)
+	IsLeader(ctx context.Context, in *IsLeaderRequest, opts ...grpc.CallOption) (*IsLeaderResponse, error)
+	IsCrashed(ctx context.Context, in *IsCrashedRequest, opts ...grpc.CallOption) (*IsCrashedResponse, error)
+}
+
+type metadataStoreClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewMetadataStoreClient(cc *grpc.ClientConn) MetadataStoreClient {
+	return &metadataStoreClient{cc}
+}
+
+func (c *metadataStoreClient) ReadFile(ctx context.Context, in *ReadFileRequest, opts ...grpc.CallOption) (*ReadFileResponse, error) {
+	out := new(ReadFileResponse)
+	err := c.cc.Invoke(ctx, "/meta.MetadataStore/ReadFile", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataStoreClient) ModifyFile(ctx context.Context, in *ModifyFileRequest, opts ...grpc.CallOption) (*ModifyFileResponse, error) {
+	out := new(ModifyFileResponse)
+	err := c.cc.Invoke(ctx, "/meta.MetadataStore/ModifyFile", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataStoreClient) DeleteFile(ctx context.Context, in *DeleteFileRequest, opts ...grpc.CallOption) (*DeleteFileResponse, error) {
+	out := new(DeleteFileResponse)
+	err := c.cc.Invoke(ctx, "/meta.MetadataStore/DeleteFile", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataStoreClient) GetVersion(ctx context.Context, in *GetVersionRequest, opts ...grpc.CallOption) (*GetVersionResponse, error) {
+	out := new(GetVersionResponse)
+	err := c.cc.Invoke(ctx, "/meta.MetadataStore/GetVersion", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataStoreClient) RenameFile(ctx context.Context, in *RenameFileRequest, opts ...grpc.CallOption) (*RenameFileResponse, error) {
+	out := new(RenameFileResponse)
+	err := c.cc.Invoke(ctx, "/meta.MetadataStore/RenameFile", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataStoreClient) CopyFile(ctx context.Context, in *CopyFileRequest, opts ...grpc.CallOption) (*CopyFileResponse, error) {
+	out := new(CopyFileResponse)
+	err := c.cc.Invoke(ctx, "/meta.MetadataStore/CopyFile", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataStoreClient) CommitBatch(ctx context.Context, in *CommitBatchRequest, opts ...grpc.CallOption) (*CommitBatchResponse, error) {
+	out := new(CommitBatchResponse)
+	err := c.cc.Invoke(ctx, "/meta.MetadataStore/CommitBatch", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataStoreClient) Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (MetadataStore_WatchClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_MetadataStore_serviceDesc.Streams[0], "/meta.MetadataStore/Watch", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &metadataStoreWatchClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type MetadataStore_WatchClient interface {
+	Recv() (*WatchEvent, error)
+	grpc.ClientStream
+}
+
+type metadataStoreWatchClient struct {
+	grpc.ClientStream
+}
+
+func (x *metadataStoreWatchClient) Recv() (*WatchEvent, error) {
+	m := new(WatchEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *metadataStoreClient) ReadFileVersion(ctx context.Context, in *ReadFileVersionRequest, opts ...grpc.CallOption) (*ReadFileResponse, error) {
+	out := new(ReadFileResponse)
+	err := c.cc.Invoke(ctx, "/meta.MetadataStore/ReadFileVersion", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataStoreClient) ListVersions(ctx context.Context, in *ListVersionsRequest, opts ...grpc.CallOption) (*ListVersionsResponse, error) {
+	out := new(ListVersionsResponse)
+	err := c.cc.Invoke(ctx, "/meta.MetadataStore/ListVersions", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataStoreClient) StatFile(ctx context.Context, in *StatFileRequest, opts ...grpc.CallOption) (*StatFileResponse, error) {
+	out := new(StatFileResponse)
+	err := c.cc.Invoke(ctx, "/meta.MetadataStore/StatFile", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataStoreClient) ListFiles(ctx context.Context, in *ListFilesRequest, opts ...grpc.CallOption) (*ListFilesResponse, error) {
+	out := new(ListFilesResponse)
+	err := c.cc.Invoke(ctx, "/meta.MetadataStore/ListFiles", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataStoreClient) CollectGarbage(ctx context.Context, in *CollectGarbageRequest, opts ...grpc.CallOption) (*CollectGarbageResponse, error) {
+	out := new(CollectGarbageResponse)
+	err := c.cc.Invoke(ctx, "/meta.MetadataStore/CollectGarbage", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataStoreClient) AppendEntries(ctx context.Context, in *AppendEntriesRequest, opts ...grpc.CallOption) (*AppendEntriesResponse, error) {
+	out := new(AppendEntriesResponse)
+	err := c.cc.Invoke(ctx, "/meta.MetadataStore/AppendEntries", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataStoreClient) RequestVote(ctx context.Context, in *RequestVoteRequest, opts ...grpc.CallOption) (*RequestVoteResponse, error) {
+	out := new(RequestVoteResponse)
+	err := c.cc.Invoke(ctx, "/meta.MetadataStore/RequestVote", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataStoreClient) InstallSnapshot(ctx context.Context, in *InstallSnapshotRequest, opts ...grpc.CallOption) (*InstallSnapshotResponse, error) {
+	out := new(InstallSnapshotResponse)
+	err := c.cc.Invoke(ctx, "/meta.MetadataStore/InstallSnapshot", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataStoreClient) Crash(ctx context.Context, in *CrashRequest, opts ...grpc.CallOption) (*CrashResponse, error) {
+	out := new(CrashResponse)
+	err := c.cc.Invoke(ctx, "/meta.MetadataStore/Crash", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataStoreClient) Restore(ctx context.Context, in *RestoreRequest, opts ...grpc.CallOption) (*RestoreResponse, error) {
+	out := new(RestoreResponse)
+	err := c.cc.Invoke(ctx, "/meta.MetadataStore/Restore", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataStoreClient) IsLeader(ctx context.Context, in *IsLeaderRequest, opts ...grpc.CallOption) (*IsLeaderResponse, error) {
+	out := new(IsLeaderResponse)
+	err := c.cc.Invoke(ctx, "/meta.MetadataStore/IsLeader", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataStoreClient) IsCrashed(ctx context.Context, in *IsCrashedRequest, opts ...grpc.CallOption) (*IsCrashedResponse, error) {
+	out := new(IsCrashedResponse)
+	err := c.cc.Invoke(ctx, "/meta.MetadataStore/IsCrashed", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// MetadataStoreServer is the server API for MetadataStore service.
+type MetadataStoreServer interface {
+	ReadFile(context.Context, *ReadFileRequest) (*ReadFileResponse, error)
+	ModifyFile(context.Context, *ModifyFileRequest) (*ModifyFileResponse, error)
+	DeleteFile(context.Context, *DeleteFileRequest) (*DeleteFileResponse, error)
+	GetVersion(context.Context, *GetVersionRequest) (*GetVersionResponse, error)
+	// Atomically moves or copies a file to a new name without transferring any blocks. Like
+	// ModifyFile, they are unsuccessful if a version does not match, and return a NOT_FOUND status if
+	// the source does not exist.
+	RenameFile(context.Context, *RenameFileRequest) (*RenameFileResponse, error)
+	CopyFile(context.Context, *CopyFileRequest) (*CopyFileResponse, error)
+	// Commits several operations on different files atomically: either all of them succeed, or none
+	// do, and readers never observe some without the others.
+	CommitBatch(context.Context, *CommitBatchRequest) (*CommitBatchResponse, error)
+	// Streams the changes to files as they are committed. Renames are sent as the deletion of the
+	// source and the creation or modification of the destination. Returns an OUT_OF_RANGE status if
+	// the changes since the requested store version are no longer retained, or if the client falls
+	// too far behind, in which case the client should list the files again and start a new watch.
+	Watch(*WatchRequest, MetadataStore_WatchServer) error
+	// Reads a retained version of a file. Returns a NOT_FOUND status if the version is not retained.
+	ReadFileVersion(context.Context, *ReadFileVersionRequest) (*ReadFileResponse, error)
+	ListVersions(context.Context, *ListVersionsRequest) (*ListVersionsResponse, error)
+	// Describes a version of a file without reading its blocks. Returns a NOT_FOUND status if the file
+	// does not exist or the version is not retained.
+	StatFile(context.Context, *StatFileRequest) (*StatFileResponse, error)
+	// Lists the files and implicit directories in a directory. File paths are slash-separated, and a
+	// directory exists while any file below it exists.
+	ListFiles(context.Context, *ListFilesRequest) (*ListFilesResponse, error)
+	// Deletes the blocks in the block store that are not referenced by any file.
+	CollectGarbage(context.Context, *CollectGarbageRequest) (*CollectGarbageResponse, error)
+	// Raft RPCs used between the nodes of a replicated metadata store.
+	AppendEntries(context.Context, *AppendEntriesRequest) (*AppendEntriesResponse, error)
+	RequestVote(context.Context, *RequestVoteRequest) (*RequestVoteResponse, error)
+	InstallSnapshot(context.Context, *InstallSnapshotRequest) (*InstallSnapshotResponse, error)
+	// Used for debugging purposes only.
+	Crash(context.Context, *CrashRequest) (*CrashResponse, error)
+	Restore(context.Context, *RestoreRequest) (*RestoreResponse, error)
+	IsLeader(context.Context, *IsLeaderRequest) (*IsLeaderResponse, error)
+	IsCrashed(context.Context, *IsCrashedRequest) (*IsCrashedResponse, error)
+}
+
+// UnimplementedMetadataStoreServer can be embedded to have forward compatible implementations.
+type UnimplementedMetadataStoreServer struct {
+}
+
+func (*UnimplementedMetadataStoreServer) ReadFile(ctx context.Context, req *ReadFileRequest) (*ReadFileResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReadFile not implemented")
+}
+func (*UnimplementedMetadataStoreServer) ModifyFile(ctx context.Context, req *ModifyFileRequest) (*ModifyFileResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ModifyFile not implemented")
+}
+func (*UnimplementedMetadataStoreServer) DeleteFile(ctx context.Context, req *DeleteFileRequest) (*DeleteFileResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteFile not implemented")
+}
+func (*UnimplementedMetadataStoreServer) GetVersion(ctx context.Context, req *GetVersionRequest) (*GetVersionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetVersion not implemented")
+}
+func (*UnimplementedMetadataStoreServer) RenameFile(ctx context.Context, req *RenameFileRequest) (*RenameFileResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RenameFile not implemented")
+}
+func (*UnimplementedMetadataStoreServer) CopyFile(ctx context.Context, req *CopyFileRequest) (*CopyFileResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CopyFile not implemented")
+}
+func (*UnimplementedMetadataStoreServer) CommitBatch(ctx context.Context, req *CommitBatchRequest) (*CommitBatchResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CommitBatch not implemented")
+}
+func (*UnimplementedMetadataStoreServer) Watch(req *WatchRequest, srv MetadataStore_WatchServer) error {
+	return status.Errorf(codes.Unimplemented, "method Watch not implemented")
+}
+func (*UnimplementedMetadataStoreServer) ReadFileVersion(ctx context.Context, req *ReadFileVersionRequest) (*ReadFileResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReadFileVersion not implemented")
+}
+func (*UnimplementedMetadataStoreServer) ListVersions(ctx context.Context, req *ListVersionsRequest) (*ListVersionsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListVersions not implemented")
+}
+func (*UnimplementedMetadataStoreServer) StatFile(ctx context.Context, req *StatFileRequest) (*StatFileResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method StatFile not implemented")
+}
+func (*UnimplementedMetadataStoreServer) ListFiles(ctx context.Context, req *ListFilesRequest) (*ListFilesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListFiles not implemented")
+}
+func (*UnimplementedMetadataStoreServer) CollectGarbage(ctx context.Context, req *CollectGarbageRequest) (*CollectGarbageResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CollectGarbage not implemented")
+}
+func (*UnimplementedMetadataStoreServer) AppendEntries(ctx context.Context, req *AppendEntriesRequest) (*AppendEntriesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AppendEntries not implemented")
+}
+func (*UnimplementedMetadataStoreServer) RequestVote(ctx context.Context, req *RequestVoteRequest) (*RequestVoteResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RequestVote not implemented")
+}
+func (*UnimplementedMetadataStoreServer) InstallSnapshot(ctx context.Context, req *InstallSnapshotRequest) (*InstallSnapshotResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method InstallSnapshot not implemented")
+}
+func (*UnimplementedMetadataStoreServer) Crash(ctx context.Context, req *CrashRequest) (*CrashResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Crash not implemented")
+}
+func (*UnimplementedMetadataStoreServer) Restore(ctx context.Context, req *RestoreRequest) (*RestoreResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Restore not implemented")
+}
+func (*UnimplementedMetadataStoreServer) IsLeader(ctx context.Context, req *IsLeaderRequest) (*IsLeaderResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method IsLeader not implemented")
+}
+func (*UnimplementedMetadataStoreServer) IsCrashed(ctx context.Context, req *IsCrashedRequest) (*IsCrashedResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method IsCrashed not implemented")
+}
+
+func RegisterMetadataStoreServer(s *grpc.Server, srv MetadataStoreServer) {
+	s.RegisterService(&_MetadataStore_serviceDesc, srv)
+}
+
+func _MetadataStore_ReadFile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReadFileRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataStoreServer).ReadFile(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/meta.MetadataStore/ReadFile",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataStoreServer).ReadFile(ctx, req.(*ReadFileRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
 func _MetadataStore_ModifyFile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
@@ -576,6 +3141,297 @@ func _MetadataStore_GetVersion_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _MetadataStore_RenameFile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RenameFileRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataStoreServer).RenameFile(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/meta.MetadataStore/RenameFile",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataStoreServer).RenameFile(ctx, req.(*RenameFileRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetadataStore_CopyFile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CopyFileRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataStoreServer).CopyFile(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/meta.MetadataStore/CopyFile",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataStoreServer).CopyFile(ctx, req.(*CopyFileRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetadataStore_CommitBatch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CommitBatchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataStoreServer).CommitBatch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/meta.MetadataStore/CommitBatch",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataStoreServer).CommitBatch(ctx, req.(*CommitBatchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetadataStore_Watch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(MetadataStoreServer).Watch(m, &metadataStoreWatchServer{stream})
+}
+
+type MetadataStore_WatchServer interface {
+	Send(*WatchEvent) error
+	grpc.ServerStream
+}
+
+type metadataStoreWatchServer struct {
+	grpc.ServerStream
+}
+
+func (x *metadataStoreWatchServer) Send(m *WatchEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _MetadataStore_ReadFileVersion_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReadFileVersionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataStoreServer).ReadFileVersion(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/meta.MetadataStore/ReadFileVersion",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataStoreServer).ReadFileVersion(ctx, req.(*ReadFileVersionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetadataStore_ListVersions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListVersionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataStoreServer).ListVersions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/meta.MetadataStore/ListVersions",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataStoreServer).ListVersions(ctx, req.(*ListVersionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetadataStore_StatFile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StatFileRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataStoreServer).StatFile(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/meta.MetadataStore/StatFile",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataStoreServer).StatFile(ctx, req.(*StatFileRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetadataStore_ListFiles_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListFilesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataStoreServer).ListFiles(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/meta.MetadataStore/ListFiles",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataStoreServer).ListFiles(ctx, req.(*ListFilesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetadataStore_CollectGarbage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CollectGarbageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataStoreServer).CollectGarbage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/meta.MetadataStore/CollectGarbage",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataStoreServer).CollectGarbage(ctx, req.(*CollectGarbageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetadataStore_AppendEntries_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AppendEntriesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataStoreServer).AppendEntries(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/meta.MetadataStore/AppendEntries",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataStoreServer).AppendEntries(ctx, req.(*AppendEntriesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetadataStore_RequestVote_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RequestVoteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataStoreServer).RequestVote(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/meta.MetadataStore/RequestVote",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataStoreServer).RequestVote(ctx, req.(*RequestVoteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetadataStore_InstallSnapshot_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(InstallSnapshotRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataStoreServer).InstallSnapshot(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/meta.MetadataStore/InstallSnapshot",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataStoreServer).InstallSnapshot(ctx, req.(*InstallSnapshotRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetadataStore_Crash_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CrashRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataStoreServer).Crash(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/meta.MetadataStore/Crash",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataStoreServer).Crash(ctx, req.(*CrashRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetadataStore_Restore_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RestoreRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataStoreServer).Restore(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/meta.MetadataStore/Restore",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataStoreServer).Restore(ctx, req.(*RestoreRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetadataStore_IsLeader_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(IsLeaderRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataStoreServer).IsLeader(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/meta.MetadataStore/IsLeader",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataStoreServer).IsLeader(ctx, req.(*IsLeaderRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetadataStore_IsCrashed_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(IsCrashedRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataStoreServer).IsCrashed(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/meta.MetadataStore/IsCrashed",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataStoreServer).IsCrashed(ctx, req.(*IsCrashedRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _MetadataStore_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "meta.MetadataStore",
 	HandlerType: (*MetadataStoreServer)(nil),
@@ -596,7 +3452,73 @@ var _MetadataStore_serviceDesc = grpc.ServiceDesc{
 			MethodName: "GetVersion",
 			Handler:    _MetadataStore_GetVersion_Handler,
 		},
+		{
+			MethodName: "RenameFile",
+			Handler:    _MetadataStore_RenameFile_Handler,
+		},
+		{
+			MethodName: "CopyFile",
+			Handler:    _MetadataStore_CopyFile_Handler,
+		},
+		{
+			MethodName: "CommitBatch",
+			Handler:    _MetadataStore_CommitBatch_Handler,
+		},
+		{
+			MethodName: "ReadFileVersion",
+			Handler:    _MetadataStore_ReadFileVersion_Handler,
+		},
+		{
+			MethodName: "ListVersions",
+			Handler:    _MetadataStore_ListVersions_Handler,
+		},
+		{
+			MethodName: "StatFile",
+			Handler:    _MetadataStore_StatFile_Handler,
+		},
+		{
+			MethodName: "ListFiles",
+			Handler:    _MetadataStore_ListFiles_Handler,
+		},
+		{
+			MethodName: "CollectGarbage",
+			Handler:    _MetadataStore_CollectGarbage_Handler,
+		},
+		{
+			MethodName: "AppendEntries",
+			Handler:    _MetadataStore_AppendEntries_Handler,
+		},
+		{
+			MethodName: "RequestVote",
+			Handler:    _MetadataStore_RequestVote_Handler,
+		},
+		{
+			MethodName: "InstallSnapshot",
+			Handler:    _MetadataStore_InstallSnapshot_Handler,
+		},
+		{
+			MethodName: "Crash",
+			Handler:    _MetadataStore_Crash_Handler,
+		},
+		{
+			MethodName: "Restore",
+			Handler:    _MetadataStore_Restore_Handler,
+		},
+		{
+			MethodName: "IsLeader",
+			Handler:    _MetadataStore_IsLeader_Handler,
+		},
+		{
+			MethodName: "IsCrashed",
+			Handler:    _MetadataStore_IsCrashed_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Watch",
+			Handler:       _MetadataStore_Watch_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "service.proto",
 }
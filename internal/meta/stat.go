@@ -1,6 +1,264 @@
 package meta
 
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+)
+
+// Version of the encoding of a Stat, written as its first byte.
+const statEncodingVersion byte = 1
+
+// Flags set in the encoded form of a Stat whose file has been deleted, and of one whose blocks are
+// encrypted.
+const (
+	statFlagDeleted   byte = 1 << 0
+	statFlagEncrypted byte = 1 << 1
+)
+
+var (
+	errMalformedStat       = errors.New("malformed file metadata")
+	errUnknownStatEncoding = errors.New("unknown file metadata encoding")
+)
+
 type Stat struct {
 	version  uint64
 	hashList []string
+
+	// The size of the blocks the file was divided into, and the method used to divide it.
+	blockSize uint64
+	chunking  Chunking
+
+	// The time the version was committed, in nanoseconds since the Unix epoch.
+	modTime int64
+
+	// The retained previous versions of the file, newest first. The previous versions themselves
+	// have no history.
+	history []Stat
+
+	// The attributes of the file's contents reported by the client that uploaded the version.
+	attrs attributes
+}
+
+// Encodes the Stat into a byte slice for storage in a persistent engine. The encoding is the encoding
+// version byte, the version as a big-endian uint64, a flags byte, the number of hashes as a uvarint,
+// and each hash prefixed by its length as a uvarint. This is followed by the block size and chunking
+// method as uvarints, the modification time as a varint, the number of previous versions as a uvarint,
+// and each previous version encoded the same way and prefixed by its length as a uvarint. Last are the
+// attributes: the size and mode as uvarints, the client's modification time as a varint, the creator
+// and digest each prefixed by its length as a uvarint, and the number of block keys as a uvarint
+// followed by each key prefixed by its length as a uvarint. A nil hash list, which marks a deleted
+// file, is distinguished from an empty one by the deleted flag, and nil block keys, which mark
+// unencrypted blocks, from empty ones by the encrypted flag.
+func (s Stat) marshal() []byte {
+	var buf bytes.Buffer
+	var scratch [binary.MaxVarintLen64]byte
+
+	buf.WriteByte(statEncodingVersion)
+
+	var versionBytes [8]byte
+	binary.BigEndian.PutUint64(versionBytes[:], s.version)
+	buf.Write(versionBytes[:])
+
+	var flags byte
+	if s.hashList == nil {
+		flags |= statFlagDeleted
+	}
+	if s.attrs.blockKeys != nil {
+		flags |= statFlagEncrypted
+	}
+	buf.WriteByte(flags)
+
+	n := binary.PutUvarint(scratch[:], uint64(len(s.hashList)))
+	buf.Write(scratch[:n])
+
+	for _, hash := range s.hashList {
+		n := binary.PutUvarint(scratch[:], uint64(len(hash)))
+		buf.Write(scratch[:n])
+		buf.WriteString(hash)
+	}
+
+	n = binary.PutUvarint(scratch[:], s.blockSize)
+	buf.Write(scratch[:n])
+
+	n = binary.PutUvarint(scratch[:], uint64(s.chunking))
+	buf.Write(scratch[:n])
+
+	n = binary.PutVarint(scratch[:], s.modTime)
+	buf.Write(scratch[:n])
+
+	n = binary.PutUvarint(scratch[:], uint64(len(s.history)))
+	buf.Write(scratch[:n])
+
+	for _, prev := range s.history {
+		b := prev.marshal()
+
+		n := binary.PutUvarint(scratch[:], uint64(len(b)))
+		buf.Write(scratch[:n])
+		buf.Write(b)
+	}
+
+	n = binary.PutUvarint(scratch[:], s.attrs.size)
+	buf.Write(scratch[:n])
+
+	n = binary.PutUvarint(scratch[:], uint64(s.attrs.mode))
+	buf.Write(scratch[:n])
+
+	n = binary.PutVarint(scratch[:], s.attrs.mtime)
+	buf.Write(scratch[:n])
+
+	for _, str := range []string{s.attrs.creator, s.attrs.digest} {
+		n := binary.PutUvarint(scratch[:], uint64(len(str)))
+		buf.Write(scratch[:n])
+		buf.WriteString(str)
+	}
+
+	n = binary.PutUvarint(scratch[:], uint64(len(s.attrs.blockKeys)))
+	buf.Write(scratch[:n])
+
+	for _, key := range s.attrs.blockKeys {
+		n := binary.PutUvarint(scratch[:], uint64(len(key)))
+		buf.Write(scratch[:n])
+		buf.Write(key)
+	}
+
+	return buf.Bytes()
+}
+
+// Decodes a Stat previously encoded with marshal.
+func unmarshalStat(b []byte) (Stat, error) {
+	if len(b) < 10 {
+		return Stat{}, errMalformedStat
+	}
+
+	if b[0] != statEncodingVersion {
+		return Stat{}, errUnknownStatEncoding
+	}
+
+	stat := Stat{version: binary.BigEndian.Uint64(b[1:9])}
+	flags := b[9]
+
+	r := bytes.NewReader(b[10:])
+	numHashes, err := binary.ReadUvarint(r)
+	if err != nil {
+		return Stat{}, errMalformedStat
+	}
+
+	// Each hash takes at least one byte for its length, which bounds the allocation below.
+	if numHashes > uint64(r.Len()) {
+		return Stat{}, errMalformedStat
+	}
+
+	if flags&statFlagDeleted == 0 {
+		stat.hashList = make([]string, 0, numHashes)
+	}
+
+	for i := uint64(0); i < numHashes; i++ {
+		hash, err := readString(r)
+		if err != nil {
+			return Stat{}, err
+		}
+
+		stat.hashList = append(stat.hashList, hash)
+	}
+
+	if stat.blockSize, err = binary.ReadUvarint(r); err != nil {
+		return Stat{}, errMalformedStat
+	}
+
+	chunking, err := binary.ReadUvarint(r)
+	if err != nil || chunking > math.MaxInt32 {
+		return Stat{}, errMalformedStat
+	}
+
+	stat.chunking = Chunking(chunking)
+
+	if stat.modTime, err = binary.ReadVarint(r); err != nil {
+		return Stat{}, errMalformedStat
+	}
+
+	numVersions, err := binary.ReadUvarint(r)
+	if err != nil || numVersions > uint64(r.Len()) {
+		return Stat{}, errMalformedStat
+	}
+
+	for i := uint64(0); i < numVersions; i++ {
+		b, err := readString(r)
+		if err != nil {
+			return Stat{}, err
+		}
+
+		prev, err := unmarshalStat([]byte(b))
+		if err != nil {
+			return Stat{}, err
+		}
+
+		stat.history = append(stat.history, prev)
+	}
+
+	if stat.attrs.size, err = binary.ReadUvarint(r); err != nil {
+		return Stat{}, errMalformedStat
+	}
+
+	mode, err := binary.ReadUvarint(r)
+	if err != nil || mode > math.MaxUint32 {
+		return Stat{}, errMalformedStat
+	}
+
+	stat.attrs.mode = uint32(mode)
+
+	if stat.attrs.mtime, err = binary.ReadVarint(r); err != nil {
+		return Stat{}, errMalformedStat
+	}
+
+	if stat.attrs.creator, err = readString(r); err != nil {
+		return Stat{}, err
+	}
+
+	if stat.attrs.digest, err = readString(r); err != nil {
+		return Stat{}, err
+	}
+
+	numKeys, err := binary.ReadUvarint(r)
+	if err != nil || numKeys > uint64(r.Len()) {
+		return Stat{}, errMalformedStat
+	}
+
+	if flags&statFlagEncrypted != 0 {
+		stat.attrs.blockKeys = make([][]byte, 0, numKeys)
+	} else if numKeys != 0 {
+		return Stat{}, errMalformedStat
+	}
+
+	for i := uint64(0); i < numKeys; i++ {
+		key, err := readString(r)
+		if err != nil {
+			return Stat{}, err
+		}
+
+		stat.attrs.blockKeys = append(stat.attrs.blockKeys, []byte(key))
+	}
+
+	if r.Len() != 0 {
+		return Stat{}, errMalformedStat
+	}
+
+	return stat, nil
+}
+
+// Reads a string prefixed by its length as a uvarint.
+func readString(r *bytes.Reader) (string, error) {
+	size, err := binary.ReadUvarint(r)
+	if err != nil || size > uint64(r.Len()) {
+		return "", errMalformedStat
+	}
+
+	b := make([]byte, size)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return "", errMalformedStat
+	}
+
+	return string(b), nil
 }
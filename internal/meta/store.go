@@ -1,32 +1,106 @@
 package meta
 
 import (
+	"bytes"
 	"context"
+	"os"
+	"path/filepath"
 	"surfs/internal/block"
+	"sync"
+	"time"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	log "github.com/sirupsen/logrus"
 )
 
+// Maximum number of hashes sent to the block store in a single HasBlocks request.
+const hasBlocksBatchSize = 4096
+
+var errNotReplicated = status.Error(codes.FailedPrecondition, "metadata store is not replicated")
+
 type MetadataStore struct {
 	// Key-value storage engine for file metadata.
 	engine engine
 
+	// Directory in which file metadata and Raft state are persisted, or empty if they are kept in
+	// memory only.
+	dataDir string
+
+	// Guards the storage engine, so that operations are applied atomically with respect to reads.
+	mtx sync.RWMutex
+
+	// Index of the names of the files that exist, built from the engine on first use and updated as
+	// operations are applied. Guarded by mtx once built.
+	names     *nameIndex
+	namesErr  error
+	namesOnce sync.Once
+
 	// gRPC client to the block store.
 	client block.StoreClient
 
 	// Underlying gRPC connection for block store client.
 	conn *grpc.ClientConn
+
+	// Raft node replicating operations to the other nodes of the cluster, or nil if the metadata
+	// store is not replicated.
+	raft *raft
+
+	// Underlying gRPC connections to the other nodes of the cluster.
+	peerConns []*grpc.ClientConn
+
+	// Options for connections to the block store and the other nodes of the cluster.
+	dialOpts []grpc.DialOption
+
+	// The maximum number of previous versions retained for each file, and the maximum time for which
+	// a previous version is retained after being superseded, or zero to retain versions of any age.
+	retainVersions uint
+	retainAge      time.Duration
+
+	// Distributes applied changes to watches.
+	watch watchHub
 }
 
-// Creates a new Metadata store service.
-func NewStore(blockStoreAddr string) (*MetadataStore, error) {
+// The number of previous versions retained for each file, unless configured otherwise.
+const DefaultRetainVersions = 10
+
+// Name of the file, relative to the data directory, that holds the file metadata.
+const MetadataFilename = "meta.keychain"
+
+// Creates a new Metadata store service. If a data directory is specified, file metadata is persisted
+// in that directory and survives restarts. Otherwise, file metadata is only kept in memory. The dial
+// options secure the connections to the block store and to other nodes; without any, connections are
+// insecure.
+func NewStore(blockStoreAddr string, dataDir string, dialOpts ...grpc.DialOption) (*MetadataStore, error) {
+	if len(dialOpts) == 0 {
+		dialOpts = []grpc.DialOption{grpc.WithInsecure()}
+	}
+
+	var engine engine = newMapEngine()
+	if dataDir != "" {
+		if err := os.MkdirAll(dataDir, 0755); err != nil {
+			return nil, err
+		}
+
+		path := filepath.Join(dataDir, MetadataFilename)
+
+		log.Debugf("Opening metadata file %s...", path)
+
+		kc, err := openKeychainEngine(path)
+		if err != nil {
+			return nil, err
+		}
+
+		engine = kc
+	}
 
 	log.Debugf("Connecting to block store at %s...", blockStoreAddr)
 
-	conn, err := grpc.Dial(blockStoreAddr, grpc.WithInsecure(), grpc.WithBlock())
+	conn, err := grpc.Dial(blockStoreAddr, append([]grpc.DialOption{grpc.WithBlock()}, dialOpts...)...)
 	if err != nil {
+		engine.close()
 		return nil, err
 	}
 	client := block.NewStoreClient(conn)
@@ -34,20 +108,335 @@ func NewStore(blockStoreAddr string) (*MetadataStore, error) {
 	log.Debug("Connected to block store.")
 
 	return &MetadataStore{
-		//files:  make(map[string]Stat),
 		conn:   conn,
 		client: client,
-		engine: newMapEngine(),
+		engine: engine,
+
+		dataDir:  dataDir,
+		dialOpts: dialOpts,
+
+		retainVersions: DefaultRetainVersions,
 	}, nil
 }
 
-// Closes the store. This simply closes the underlying gRPC connection.
+// Sets how many previous versions of each file are retained, and for how long after being superseded.
+// An age of zero retains versions of any age. Every node of a replicated cluster must be configured
+// with the same retention, so that they retain the same versions.
+func (s *MetadataStore) SetRetention(versions uint, age time.Duration) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.retainVersions = versions
+	s.retainAge = age
+}
+
+// Joins the metadata store to a replicated cluster, in which operations are only accepted by the
+// leader and are replicated to the other nodes using Raft. The ID is the address at which clients and
+// other nodes reach this node, and the peers are the addresses of the other nodes. If the store has a
+// data directory, the Raft term, vote and log are persisted in it along with the file metadata.
+func (s *MetadataStore) Replicate(id string, peerAddrs []string) error {
+	peers := make(map[string]raftPeer, len(peerAddrs))
+	for _, addr := range peerAddrs {
+		log.Debugf("Adding peer %s...", addr)
+
+		// Peers are connected to lazily, since they may not have started yet.
+		conn, err := grpc.Dial(addr, s.dialOpts...)
+		if err != nil {
+			return err
+		}
+
+		s.peerConns = append(s.peerConns, conn)
+		peers[addr] = NewMetadataStoreClient(conn)
+	}
+
+	var storage raftStorage = memoryRaftStorage{}
+	if s.dataDir != "" {
+		fs, err := openFileRaftStorage(s.dataDir)
+		if err != nil {
+			return err
+		}
+
+		storage = fs
+	}
+
+	r, err := newRaft(id, peers, storage, s)
+	if err != nil {
+		storage.close()
+		return err
+	}
+
+	s.raft = r
+	s.raft.start()
+
+	return nil
+}
+
+// Closes the store. This stops replication and closes the underlying gRPC connections and storage
+// engine.
 func (s *MetadataStore) Close() error {
-	if s.conn == nil {
+	if s.raft != nil {
+		s.raft.stop()
+	}
+
+	for _, conn := range s.peerConns {
+		if err := conn.Close(); err != nil {
+			return err
+		}
+	}
+
+	if s.conn != nil {
+		if err := s.conn.Close(); err != nil {
+			return err
+		}
+	}
+
+	if s.engine == nil {
+		return nil
+	}
+
+	return s.engine.close()
+}
+
+// Gets the metadata associated with the specified file, without the previous versions that have
+// expired.
+func (s *MetadataStore) getFileMetadata(filename string) (Stat, bool, error) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	st, ok, err := s.engine.getFileMetadata(filename)
+	if err != nil {
+		return Stat{}, false, err
+	}
+
+	return s.unexpired(st, time.Now().UnixNano()), ok, nil
+}
+
+// Checks whether the store may serve client requests. A replicated store only serves clients while
+// it is the leader.
+func (s *MetadataStore) checkLeader(ctx context.Context) error {
+	if s.raft == nil {
+		return nil
+	}
+
+	return s.raft.checkLeader(ctx)
+}
+
+// Commits an operation. If the store is replicated, the operation is committed through the Raft log
+// before being applied. Returns false if the operation was rejected due to a version mismatch.
+func (s *MetadataStore) commit(ctx context.Context, op *Operation) (bool, error) {
+	if s.raft == nil {
+		ok, err := s.apply(logPosition{}, op)
+		if perr, permanent := err.(permanentApplyError); permanent {
+			err = perr.err
+		}
+
+		return ok, err
+	}
+
+	return s.raft.propose(ctx, op)
+}
+
+// Applies an operation to the storage engine. The version check is repeated here, since other
+// operations on the same file may have been applied since the request was validated. Checking and
+// writing under the same lock makes each commit a compare-and-swap on the file's version, so of
+// several concurrent commits of the same version, exactly one succeeds. Returns false if
+// the operation was rejected due to a version mismatch. The files an operation changes are written
+// together, so a batch is applied entirely or not at all. The position of the operation in the Raft
+// log, or the zero position if the store is not replicated, is written along with them.
+func (s *MetadataStore) apply(pos logPosition, op *Operation) (bool, error) {
+	if op.Type == Operation_NOOP {
+		return true, nil
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	ops := []*Operation{op}
+	if op.Type == Operation_BATCH {
+		ops = op.Batch
+	}
+
+	b := s.newBatch(op.Timestamp)
+	for _, o := range ops {
+		ok, err := b.stage(o)
+		if err != nil || !ok {
+			return false, applyError(err)
+		}
+	}
+
+	events, err := b.events()
+	if err != nil {
+		return false, applyError(err)
+	}
+
+	names, err := s.nameIndex()
+	if err != nil {
+		return false, applyError(err)
+	}
+
+	if err := s.engine.setFilesMetadata(b.staged, pos); err != nil {
+		return false, err
+	}
+
+	for filename, st := range b.staged {
+		names.update(filename, st)
+	}
+
+	s.watch.publish(events)
+	return true, nil
+}
+
+// Marks an error applying an operation as permanent if it would recur every time the operation was
+// applied: the operation is invalid, or metadata it reads cannot be decoded. Other errors, such as
+// failures to read the engine, may not recur.
+func applyError(err error) error {
+	if err == errMalformedStat || err == errUnknownStatEncoding || status.Code(err) == codes.InvalidArgument {
+		return permanentApplyError{err: err}
+	}
+
+	return err
+}
+
+// Returns the position of the last Raft log entry whose changes have been written to the engine.
+func (s *MetadataStore) appliedPosition() (logPosition, error) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	return s.engine.getAppliedPosition()
+}
+
+// Returns the metadata of every file, including previous versions and deleted files, encoded as a
+// batch.
+func (s *MetadataStore) snapshot() ([]byte, error) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	filenames, err := s.engine.listFilenames()
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make(map[string]Stat, len(filenames))
+	for _, filename := range filenames {
+		st, ok, err := s.engine.getFileMetadata(filename)
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			stats[filename] = st
+		}
+	}
+
+	return marshalBatch(stats, logPosition{}), nil
+}
+
+// Writes the metadata in a snapshot taken by the leader, along with the position of the last entry it
+// reflects. Metadata is never removed from the engine, and this node has applied a prefix of the
+// entries the snapshot reflects, so every file this node knows of is in the snapshot. Only the files
+// whose metadata differs are written, and their changes are published to watches.
+func (s *MetadataStore) restoreSnapshot(pos logPosition, data []byte) error {
+	stats, _, err := unmarshalBatch(data)
+	if err != nil {
+		return err
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	b := s.newBatch(0)
+	for filename, st := range stats {
+		cur, ok, err := s.engine.getFileMetadata(filename)
+		if err != nil {
+			return err
+		}
+
+		if !ok || !bytes.Equal(cur.marshal(), st.marshal()) {
+			b.staged[filename] = st
+		}
+	}
+
+	events, err := b.events()
+	if err != nil {
+		return err
+	}
+
+	names, err := s.nameIndex()
+	if err != nil {
+		return err
+	}
+
+	if err := s.engine.setFilesMetadata(b.staged, pos); err != nil {
+		return err
+	}
+
+	for filename, st := range b.staged {
+		names.update(filename, st)
+	}
+
+	s.watch.publish(events)
+	return nil
+}
+
+// Reports whether a previous version superseded at the specified time has outlived the retention age.
+func (s *MetadataStore) expired(superseded int64, now int64) bool {
+	return s.retainAge > 0 && now-superseded > int64(s.retainAge)
+}
+
+// Returns the metadata of a file without the previous versions that have outlived the retention age at
+// the specified time. Versions are only removed from the engine when the file next changes, so reads
+// and garbage collection must skip them until then.
+func (s *MetadataStore) unexpired(st Stat, now int64) Stat {
+	superseded := st.modTime
+	for i, prev := range st.history {
+		if s.expired(superseded, now) {
+			st.history = st.history[:i:i]
+			break
+		}
+
+		superseded = prev.modTime
+	}
+
+	return st
+}
+
+// Returns the history of a file once the specified version is superseded at the specified time: the
+// version followed by its own history, pruned to the configured retention.
+func (s *MetadataStore) retainedHistory(prev Stat, now int64) []Stat {
+	// A file that does not exist has no versions to retain.
+	if prev.version == 0 {
 		return nil
 	}
 
-	return s.conn.Close()
+	history := make([]Stat, 0, len(prev.history)+1)
+	history = append(history, prev)
+	history = append(history, prev.history...)
+	history[0].history = nil
+
+	retained := 0
+	for i := range history {
+		if uint(i) >= s.retainVersions {
+			break
+		}
+
+		// A version is superseded when the next version is committed.
+		superseded := now
+		if i > 0 {
+			superseded = history[i-1].modTime
+		}
+
+		if s.expired(superseded, now) {
+			break
+		}
+
+		retained++
+	}
+
+	if retained == 0 {
+		return nil
+	}
+
+	return history[:retained]
 }
 
 // Reads a file from the metadata store. In reality, this RPC returns the hashes of the blocks corresponding to the
@@ -58,31 +447,59 @@ func (s *MetadataStore) ReadFile(ctx context.Context, req *ReadFileRequest) (*Re
 		"filename": req.Filename,
 	}).Debug("Reading file...")
 
+	if err := s.checkLeader(ctx); err != nil {
+		return nil, err
+	}
+
+	filename, err := cleanPath(req.Filename)
+	if err != nil {
+		return nil, err
+	}
+
 	// Even if the file metadata is not found, returning the zero value still works.
 	//st, _ := s.files[req.Filename]
-	st, _, err := s.engine.getFileMetadata(req.Filename)
+	st, _, err := s.getFileMetadata(filename)
 	if err != nil {
 		return nil, err
 	}
 
 	return &ReadFileResponse{
-		Version:  st.version,
-		HashList: st.hashList,
+		Version:   st.version,
+		HashList:  st.hashList,
+		BlockSize: st.blockSize,
+		Chunking:  st.chunking,
+		BlockKeys: st.attrs.blockKeys,
+		Exists:    st.hashList != nil,
 	}, nil
 }
 
 // Modifies the specified file.
 func (s *MetadataStore) ModifyFile(ctx context.Context, req *ModifyFileRequest) (*ModifyFileResponse, error) {
-
 	log.WithFields(log.Fields{
 		"filename": req.Filename,
 		"version":  req.Version,
 	}).Debug("Modifying file...")
 
+	if err := s.checkLeader(ctx); err != nil {
+		return nil, err
+	}
+
+	filename, err := cleanPath(req.Filename)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := Chunking_name[int32(req.Chunking)]; !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "unknown chunking method %v", req.Chunking)
+	}
+
+	if err := validateAttributes(req.Attributes, req.HashList); err != nil {
+		return nil, err
+	}
+
 	// The new version number must be exactly one more than the current version number. If it is not,
 	// then we reject the modification.
-	//oldVersion := s.files[req.Filename].version
-	st, _, err := s.engine.getFileMetadata(req.Filename)
+	st, _, err := s.getFileMetadata(filename)
 	if err != nil {
 		return nil, err
 	}
@@ -90,9 +507,8 @@ func (s *MetadataStore) ModifyFile(ctx context.Context, req *ModifyFileRequest)
 	oldVersion := st.version
 
 	if req.Version != oldVersion+1 {
-
 		log.WithFields(log.Fields{
-			"filename":   req.Filename,
+			"filename":   filename,
 			"newVersion": req.Version,
 			"oldVersion": oldVersion,
 		}).Debug("Did not modify file; invalid new file version.")
@@ -100,51 +516,92 @@ func (s *MetadataStore) ModifyFile(ctx context.Context, req *ModifyFileRequest)
 		return &ModifyFileResponse{Success: false}, nil
 	}
 
+	if st.hashList == nil {
+		if err := s.checkCreate(filename); err != nil {
+			return nil, err
+		}
+	}
+
 	// Check for missing blocks. If there are any blocks missing in the block store, return a list of
 	// those missing blocks to the client. Otherwise, we have all the required blocks, and it is safe
 	// for us to modify the file metadata to point to the new list of blocks.
-	missing := make([]string, 0, 16)
-	for _, hash := range req.HashList {
-		res, err := s.client.HasBlock(context.Background(), &block.HasBlockRequest{
-			Hash: hash,
+	missing, err := s.missingBlocks(ctx, req.HashList)
+	if err != nil {
+		return nil, err
+	}
+
+	// Clients that predate configurable block sizes do not send one, and always divide files into
+	// blocks of the legacy size.
+	blockSize := req.BlockSize
+	if blockSize == 0 {
+		blockSize = block.LegacyBlockSize
+	}
+
+	if len(missing) == 0 {
+		ok, err := s.commit(ctx, &Operation{
+			Type:       Operation_MODIFY,
+			Filename:   filename,
+			Version:    req.Version,
+			HashList:   req.HashList,
+			BlockSize:  blockSize,
+			Chunking:   req.Chunking,
+			Timestamp:  time.Now().UnixNano(),
+			Attributes: req.Attributes,
 		})
 		if err != nil {
 			return nil, err
 		}
 
-		if !res.Success {
-			missing = append(missing, hash)
+		if !ok {
+			log.WithFields(log.Fields{
+				"filename": filename,
+				"version":  req.Version,
+			}).Debug("Did not modify file; file was modified concurrently.")
+
+			return &ModifyFileResponse{Success: false}, nil
 		}
-	}
 
-	if len(missing) == 0 {
 		log.WithFields(log.Fields{
-			"filename": req.Filename,
-			"version":  req.Version,
+			"filename":  filename,
+			"version":   req.Version,
+			"blockSize": blockSize,
+			"chunking":  req.Chunking,
 		}).Debug("Modified file successfully.")
 
-		//s.files[req.Filename] = Stat{
-		//	hashList: req.HashList,
-		//	version:  req.Version,
-		//}
-		if err := s.engine.setFileMetadata(req.Filename, Stat{
-			hashList: req.HashList,
-			version:  req.Version,
-		}); err != nil {
-			return nil, err
-		}
-
 		return &ModifyFileResponse{Success: true}, nil
 	}
 
 	log.WithFields(log.Fields{
-		"filename": req.Filename,
+		"filename": filename,
 		"version":  req.Version,
 	}).Debugf("Did not modify file successfully; missing %d block(s).", len(missing))
 
 	return &ModifyFileResponse{Success: false, MissingHashList: missing}, nil
 }
 
+// Returns the hashes in the hash list whose blocks are missing from the block store. The block store is
+// queried in batches, so that large hash lists do not exceed the maximum message size.
+func (s *MetadataStore) missingBlocks(ctx context.Context, hashList []string) ([]string, error) {
+	missing := make([]string, 0, 16)
+	for start := 0; start < len(hashList); start += hasBlocksBatchSize {
+		end := start + hasBlocksBatchSize
+		if end > len(hashList) {
+			end = len(hashList)
+		}
+
+		res, err := s.client.HasBlocks(ctx, &block.HasBlocksRequest{
+			Hashes: hashList[start:end],
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		missing = append(missing, res.Missing...)
+	}
+
+	return missing, nil
+}
+
 // Deletes the specified file.
 func (s *MetadataStore) DeleteFile(ctx context.Context, req *DeleteFileRequest) (*DeleteFileResponse, error) {
 	log.WithFields(log.Fields{
@@ -152,10 +609,18 @@ func (s *MetadataStore) DeleteFile(ctx context.Context, req *DeleteFileRequest)
 		"version":  req.Version,
 	}).Debug("Deleting file...")
 
+	if err := s.checkLeader(ctx); err != nil {
+		return nil, err
+	}
+
+	filename, err := cleanPath(req.Filename)
+	if err != nil {
+		return nil, err
+	}
+
 	// The new version number must be exactly one more than the current version number. If it is not,
 	// then we reject the deletion.
-	//oldVersion := s.files[req.Filename].version
-	st, _, err := s.engine.getFileMetadata(req.Filename)
+	st, _, err := s.getFileMetadata(filename)
 	if err != nil {
 		return nil, err
 	}
@@ -163,9 +628,8 @@ func (s *MetadataStore) DeleteFile(ctx context.Context, req *DeleteFileRequest)
 	oldVersion := st.version
 
 	if req.Version != oldVersion+1 {
-
 		log.WithFields(log.Fields{
-			"filename":   req.Filename,
+			"filename":   filename,
 			"newVersion": req.Version,
 			"oldVersion": oldVersion,
 		}).Debug("Did not delete file: invalid new file version.")
@@ -173,19 +637,27 @@ func (s *MetadataStore) DeleteFile(ctx context.Context, req *DeleteFileRequest)
 		return &DeleteFileResponse{Success: false}, nil
 	}
 
-	// Deleting the file simply consists of setting its hash list to a nil slice, which is automatically set by
-	// the zero value of stat.
-	//s.files[req.Filename] = Stat{
-	//	version: req.Version,
-	//}
-	if err := s.engine.setFileMetadata(req.Filename, Stat{
-		version: req.Version,
-	}); err != nil {
+	ok, err := s.commit(ctx, &Operation{
+		Type:      Operation_DELETE,
+		Filename:  filename,
+		Version:   req.Version,
+		Timestamp: time.Now().UnixNano(),
+	})
+	if err != nil {
 		return nil, err
 	}
 
+	if !ok {
+		log.WithFields(log.Fields{
+			"filename": filename,
+			"version":  req.Version,
+		}).Debug("Did not delete file: file was modified concurrently.")
+
+		return &DeleteFileResponse{Success: false}, nil
+	}
+
 	log.WithFields(log.Fields{
-		"filename": req.Filename,
+		"filename": filename,
 		"version":  req.Version,
 	}).Debug("Deleted file successfully.")
 
@@ -193,9 +665,16 @@ func (s *MetadataStore) DeleteFile(ctx context.Context, req *DeleteFileRequest)
 }
 
 func (s *MetadataStore) GetVersion(ctx context.Context, req *GetVersionRequest) (*GetVersionResponse, error) {
+	if err := s.checkLeader(ctx); err != nil {
+		return nil, err
+	}
 
-	//version := s.files[req.Filename].version
-	st, _, err := s.engine.getFileMetadata(req.Filename)
+	filename, err := cleanPath(req.Filename)
+	if err != nil {
+		return nil, err
+	}
+
+	st, _, err := s.getFileMetadata(filename)
 	if err != nil {
 		return nil, err
 	}
@@ -211,3 +690,73 @@ func (s *MetadataStore) GetVersion(ctx context.Context, req *GetVersionRequest)
 		Version: version,
 	}, nil
 }
+
+// Handles an AppendEntries RPC from the leader of the cluster.
+func (s *MetadataStore) AppendEntries(ctx context.Context, req *AppendEntriesRequest) (*AppendEntriesResponse, error) {
+	if s.raft == nil {
+		return nil, errNotReplicated
+	}
+
+	return s.raft.appendEntries(req)
+}
+
+// Handles a RequestVote RPC from a candidate for leader of the cluster.
+func (s *MetadataStore) RequestVote(ctx context.Context, req *RequestVoteRequest) (*RequestVoteResponse, error) {
+	if s.raft == nil {
+		return nil, errNotReplicated
+	}
+
+	return s.raft.requestVote(req)
+}
+
+// Handles an InstallSnapshot RPC from the leader of the cluster.
+func (s *MetadataStore) InstallSnapshot(ctx context.Context, req *InstallSnapshotRequest) (*InstallSnapshotResponse, error) {
+	if s.raft == nil {
+		return nil, errNotReplicated
+	}
+
+	return s.raft.installSnapshot(req)
+}
+
+// Simulates a crash of this node. Used for debugging purposes only.
+func (s *MetadataStore) Crash(ctx context.Context, req *CrashRequest) (*CrashResponse, error) {
+	if s.raft == nil {
+		return nil, errNotReplicated
+	}
+
+	s.raft.crash()
+	return &CrashResponse{}, nil
+}
+
+// Restores this node after a simulated crash. Used for debugging purposes only.
+func (s *MetadataStore) Restore(ctx context.Context, req *RestoreRequest) (*RestoreResponse, error) {
+	if s.raft == nil {
+		return nil, errNotReplicated
+	}
+
+	s.raft.restore()
+	return &RestoreResponse{}, nil
+}
+
+// Checks whether this node is the leader of the cluster. A store that is not replicated is always
+// the leader.
+func (s *MetadataStore) IsLeader(ctx context.Context, req *IsLeaderRequest) (*IsLeaderResponse, error) {
+	if s.raft == nil {
+		return &IsLeaderResponse{Leader: true}, nil
+	}
+
+	leader, leaderID := s.raft.isLeader()
+	return &IsLeaderResponse{
+		Leader:   leader,
+		LeaderId: leaderID,
+	}, nil
+}
+
+// Checks whether this node is crashed.
+func (s *MetadataStore) IsCrashed(ctx context.Context, req *IsCrashedRequest) (*IsCrashedResponse, error) {
+	if s.raft == nil {
+		return &IsCrashedResponse{Crashed: false}, nil
+	}
+
+	return &IsCrashedResponse{Crashed: s.raft.isCrashed()}, nil
+}
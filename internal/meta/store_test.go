@@ -3,24 +3,48 @@ package meta
 import (
 	"context"
 	"reflect"
+	"strconv"
 	"surfs/internal/block"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type mockClient struct {
 	blocks map[string][]byte
+
+	// Simulated round-trip time of each call to the block store.
+	latency time.Duration
 }
 
 func (m *mockClient) HasBlock(ctx context.Context, in *block.HasBlockRequest, opts ...grpc.CallOption) (*block.HasBlockResponse, error) {
+	time.Sleep(m.latency)
+
 	_, ok := m.blocks[in.Hash]
 	return &block.HasBlockResponse{
 		Success: ok,
 	}, nil
 }
 
+func (m *mockClient) HasBlocks(ctx context.Context, in *block.HasBlocksRequest, opts ...grpc.CallOption) (*block.HasBlocksResponse, error) {
+	time.Sleep(m.latency)
+
+	missing := make([]string, 0, len(in.Hashes))
+	for _, hash := range in.Hashes {
+		if _, ok := m.blocks[hash]; !ok {
+			missing = append(missing, hash)
+		}
+	}
+
+	return &block.HasBlocksResponse{
+		Missing: missing,
+	}, nil
+}
+
 func (m *mockClient) GetBlock(ctx context.Context, in *block.GetBlockRequest, opts ...grpc.CallOption) (*block.GetBlockResponse, error) {
 	blk, ok := m.blocks[in.Hash]
 
@@ -38,6 +62,30 @@ func (m *mockClient) StoreBlock(ctx context.Context, in *block.StoreBlockRequest
 	}, nil
 }
 
+func (m *mockClient) PutBlocks(ctx context.Context, opts ...grpc.CallOption) (block.Store_PutBlocksClient, error) {
+	return nil, status.Error(codes.Unimplemented, "method PutBlocks not implemented")
+}
+
+func (m *mockClient) GetBlocks(ctx context.Context, in *block.GetBlocksRequest, opts ...grpc.CallOption) (block.Store_GetBlocksClient, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetBlocks not implemented")
+}
+
+func (m *mockClient) ListBlocks(ctx context.Context, in *block.ListBlocksRequest, opts ...grpc.CallOption) (block.Store_ListBlocksClient, error) {
+	return nil, status.Error(codes.Unimplemented, "method ListBlocks not implemented")
+}
+
+func (m *mockClient) DeleteBlocks(ctx context.Context, in *block.DeleteBlocksRequest, opts ...grpc.CallOption) (*block.DeleteBlocksResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method DeleteBlocks not implemented")
+}
+
+func (m *mockClient) GetStats(ctx context.Context, in *block.GetStatsRequest, opts ...grpc.CallOption) (*block.GetStatsResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetStats not implemented")
+}
+
+func (m *mockClient) GetLimits(ctx context.Context, in *block.GetLimitsRequest, opts ...grpc.CallOption) (*block.GetLimitsResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetLimits not implemented")
+}
+
 func expectReadFile(store *MetadataStore, filename string, expected *ReadFileResponse, t *testing.T) {
 	req := &ReadFileRequest{
 		Filename: filename,
@@ -48,6 +96,8 @@ func expectReadFile(store *MetadataStore, filename string, expected *ReadFileRes
 
 	assert.Equal(t, res.Version, expected.Version)
 	assert.ElementsMatch(t, expected.HashList, res.HashList)
+	assert.Equal(t, expected.BlockSize, res.BlockSize)
+	assert.Equal(t, expected.Chunking, res.Chunking)
 }
 
 func TestMetadataStore_ReadFile(t *testing.T) {
@@ -125,6 +175,9 @@ func TestMetadataStore_ModifyFile(t *testing.T) {
 	assert.Equal(t, stat.version, uint64(2))
 	assert.Equal(t, stat.hashList, []string{"hash1", "hash2"})
 
+	// Requests without a block size are from clients using the legacy block size.
+	assert.Equal(t, block.LegacyBlockSize, stat.blockSize)
+
 	// ***************
 	// * END TEST #1 *
 	// ***************
@@ -143,6 +196,50 @@ func TestMetadataStore_ModifyFile(t *testing.T) {
 	// ***************
 	// * END TEST #2 *
 	// ***************
+
+	// *****************************************************
+	// * TEST #3: Test recording the block size of a file. *
+	// *****************************************************
+
+	expectModifyFile(store, &ModifyFileRequest{
+		Filename:  "file3",
+		Version:   1,
+		HashList:  []string{"hash2"},
+		BlockSize: 1 << 20,
+	}, &ModifyFileResponse{Success: true, MissingHashList: nil}, t)
+
+	expectReadFile(store, "file3", &ReadFileResponse{HashList: []string{"hash2"}, Version: 1, BlockSize: 1 << 20}, t)
+
+	// ***************
+	// * END TEST #3 *
+	// ***************
+
+	// **********************************************************
+	// * TEST #4: Test recording the chunking method of a file. *
+	// **********************************************************
+
+	expectModifyFile(store, &ModifyFileRequest{
+		Filename:  "file4",
+		Version:   1,
+		HashList:  []string{"hash1"},
+		BlockSize: 4096,
+		Chunking:  Chunking_CONTENT_DEFINED,
+	}, &ModifyFileResponse{Success: true, MissingHashList: nil}, t)
+
+	expectReadFile(store, "file4", &ReadFileResponse{HashList: []string{"hash1"}, Version: 1, BlockSize: 4096, Chunking: Chunking_CONTENT_DEFINED}, t)
+
+	// Unknown chunking methods are rejected.
+	_, err = store.ModifyFile(context.Background(), &ModifyFileRequest{
+		Filename: "file5",
+		Version:  1,
+		HashList: []string{"hash1"},
+		Chunking: Chunking(7),
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// ***************
+	// * END TEST #4 *
+	// ***************
 }
 
 func expectDeleteFile(store *MetadataStore, req *DeleteFileRequest, expected *DeleteFileResponse, t *testing.T) {
@@ -176,6 +273,33 @@ func TestMetadataStore_DeleteFile(t *testing.T) {
 	expectDeleteFile(store, &DeleteFileRequest{Filename: "file2", Version: 2}, &DeleteFileResponse{Success: false}, t)
 }
 
+func TestMetadataStore_EmptyFile(t *testing.T) {
+	store := &MetadataStore{
+		client: &mockClient{blocks: map[string][]byte{}},
+		engine: newMapEngine(),
+	}
+
+	// An empty hash list arrives as nil from the wire, but creates an empty file rather than deleting it.
+	res, err := store.ModifyFile(context.Background(), &ModifyFileRequest{Filename: "empty", Version: 1})
+	assert.Nil(t, err)
+	assert.True(t, res.Success)
+
+	readRes, err := store.ReadFile(context.Background(), &ReadFileRequest{Filename: "empty"})
+	assert.Nil(t, err)
+	assert.True(t, readRes.Exists)
+	assert.Empty(t, readRes.HashList)
+
+	expectDeleteFile(store, &DeleteFileRequest{Filename: "empty", Version: 2}, &DeleteFileResponse{Success: true}, t)
+
+	readRes, err = store.ReadFile(context.Background(), &ReadFileRequest{Filename: "empty"})
+	assert.Nil(t, err)
+	assert.False(t, readRes.Exists)
+
+	readRes, err = store.ReadFile(context.Background(), &ReadFileRequest{Filename: "missing"})
+	assert.Nil(t, err)
+	assert.False(t, readRes.Exists)
+}
+
 func expectGetVersion(store *MetadataStore, req *GetVersionRequest, expected *GetVersionResponse, t *testing.T) {
 	res, err := store.GetVersion(context.Background(), req)
 	assert.Nil(t, err)
@@ -199,3 +323,61 @@ func TestMetadataStore_GetVersion(t *testing.T) {
 	expectGetVersion(store, &GetVersionRequest{Filename: "file1"}, &GetVersionResponse{Version: 1}, t)
 	expectGetVersion(store, &GetVersionRequest{Filename: "file2"}, &GetVersionResponse{Version: 0}, t)
 }
+
+// Checks for missing blocks with one HasBlock call per hash, as ModifyFile did before HasBlocks was
+// added. Used as the baseline for BenchmarkMetadataStore_ModifyFile.
+func missingBlocksSerial(client block.StoreClient, hashList []string) ([]string, error) {
+	missing := make([]string, 0, 16)
+	for _, hash := range hashList {
+		res, err := client.HasBlock(context.Background(), &block.HasBlockRequest{
+			Hash: hash,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if !res.Success {
+			missing = append(missing, hash)
+		}
+	}
+
+	return missing, nil
+}
+
+func BenchmarkMetadataStore_ModifyFile(b *testing.B) {
+	const numBlocks = 10000
+
+	mock := &mockClient{blocks: map[string][]byte{}, latency: 50 * time.Microsecond}
+	hashList := make([]string, 0, numBlocks)
+	for i := 0; i < numBlocks; i++ {
+		hash := "hash" + strconv.Itoa(i)
+		mock.blocks[hash] = []byte("block")
+		hashList = append(hashList, hash)
+	}
+
+	b.Run("HasBlocks", func(b *testing.B) {
+		store := &MetadataStore{
+			client: mock,
+			engine: newMapEngine(),
+		}
+
+		for i := 0; i < b.N; i++ {
+			res, err := store.ModifyFile(context.Background(), &ModifyFileRequest{
+				Filename: "file1",
+				Version:  uint64(i + 1),
+				HashList: hashList,
+			})
+			if err != nil || !res.Success {
+				b.Fatalf("failed to modify file, %v", err)
+			}
+		}
+	})
+
+	b.Run("HasBlock", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := missingBlocksSerial(mock, hashList); err != nil {
+				b.Fatalf("failed to check for missing blocks, %v", err)
+			}
+		}
+	})
+}
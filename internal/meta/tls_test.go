@@ -0,0 +1,61 @@
+package meta
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"os"
+	"surfs/internal/block"
+	"surfs/internal/transport"
+	"surfs/internal/transport/transporttest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+)
+
+func TestNewStore_MutualTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "surfs")
+	assert.Nil(t, err)
+
+	defer os.RemoveAll(dir)
+
+	ca := transporttest.NewCA(t, dir, "ca")
+	blockCert, blockKey := ca.Issue(t, "block")
+	metaCert, metaKey := ca.Issue(t, "meta")
+
+	bs, err := block.NewStore(dir)
+	assert.Nil(t, err)
+
+	defer bs.Close()
+
+	serverConf := transport.TLSConfig{CertFile: blockCert, KeyFile: blockKey, CAFile: ca.CertFile, ClientAuth: true}
+	serverOpts, err := serverConf.ServerOptions()
+	assert.Nil(t, err)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+
+	server := grpc.NewServer(serverOpts...)
+	block.RegisterStoreServer(server, bs)
+
+	go server.Serve(lis)
+	defer server.Stop()
+
+	_, port, err := net.SplitHostPort(lis.Addr().String())
+	assert.Nil(t, err)
+
+	clientConf := transport.TLSConfig{CertFile: metaCert, KeyFile: metaKey, CAFile: ca.CertFile}
+	dialOpt, err := clientConf.DialOption()
+	assert.Nil(t, err)
+
+	store, err := NewStore(net.JoinHostPort("localhost", port), "", dialOpt)
+	assert.Nil(t, err)
+
+	defer store.Close()
+
+	// The metadata store checks for blocks over the secured connection.
+	res, err := store.ModifyFile(context.Background(), &ModifyFileRequest{Filename: "file1", Version: 1, HashList: []string{"hash1"}})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"hash1"}, res.MissingHashList)
+}
@@ -0,0 +1,194 @@
+package meta
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// The number of recent changes retained for watches that resume from an earlier store version or
+// fall behind.
+const watchHistorySize = 4096
+
+// Distributes committed changes to watches. The zero value is ready to use.
+type watchHub struct {
+	mtx sync.Mutex
+
+	// Random identifier of this run of the node, which store versions are local to. Assigned on first
+	// use.
+	incarnation string
+
+	// The current store version, which is that of the last change.
+	version uint64
+
+	// The most recent changes, oldest first.
+	events []*WatchEvent
+
+	// Closed and replaced whenever changes are published, to wake up waiting watches.
+	changed chan struct{}
+}
+
+// Assigns store versions to the specified changes and publishes them to watches.
+func (h *watchHub) publish(events []*WatchEvent) {
+	if len(events) == 0 {
+		return
+	}
+
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	incarnation := h.incarnationLocked()
+	for _, event := range events {
+		h.version++
+		event.StoreVersion = h.version
+		event.Incarnation = incarnation
+		h.events = append(h.events, event)
+	}
+
+	// Drop the oldest changes, copying the rest so that the backing array does not grow forever.
+	if len(h.events) > watchHistorySize {
+		h.events = append([]*WatchEvent(nil), h.events[len(h.events)-watchHistorySize:]...)
+	}
+
+	if h.changed != nil {
+		close(h.changed)
+		h.changed = nil
+	}
+}
+
+// Returns the changes after the specified store version, and a channel that is closed when more
+// changes are published. Returns an OUT_OF_RANGE status if some of the changes are no longer retained.
+func (h *watchHub) since(version uint64) ([]*WatchEvent, <-chan struct{}, error) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	if version > h.version {
+		return nil, nil, status.Errorf(codes.OutOfRange, "store version %d is in the future; the current version is %d", version, h.version)
+	}
+
+	if h.changed == nil {
+		h.changed = make(chan struct{})
+	}
+
+	if version == h.version {
+		return nil, h.changed, nil
+	}
+
+	oldest := h.version - uint64(len(h.events)) + 1
+	if version+1 < oldest {
+		return nil, nil, status.Errorf(codes.OutOfRange, "changes since store version %d are no longer retained", version)
+	}
+
+	return h.events[version+1-oldest:], h.changed, nil
+}
+
+// Returns the incarnation and the current store version.
+func (h *watchHub) current() (string, uint64) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	return h.incarnationLocked(), h.version
+}
+
+// Returns the incarnation, assigning it if needed. The mutex must be held.
+func (h *watchHub) incarnationLocked() string {
+	if h.incarnation == "" {
+		b := make([]byte, 8)
+		if _, err := rand.Read(b); err != nil {
+			// The incarnation only needs to differ from those of other runs, which the time is likely to.
+			log.Warnf("Failed to generate a watch incarnation, %v", err)
+			binary.BigEndian.PutUint64(b, uint64(time.Now().UnixNano()))
+		}
+
+		h.incarnation = hex.EncodeToString(b)
+	}
+
+	return h.incarnation
+}
+
+// Reports whether a file is at or below the specified normalized path. The empty path is the root,
+// which contains every file.
+func underPath(filename string, dir string) bool {
+	return dir == "" || filename == dir || strings.HasPrefix(filename, dir+"/")
+}
+
+// Streams committed changes to files at or below a path.
+func (s *MetadataStore) Watch(req *WatchRequest, stream MetadataStore_WatchServer) error {
+	ctx := stream.Context()
+
+	log.WithFields(log.Fields{
+		"prefix":       req.Prefix,
+		"sinceVersion": req.SinceVersion,
+		"incarnation":  req.Incarnation,
+	}).Debug("Watching files...")
+
+	if err := s.checkLeader(ctx); err != nil {
+		return err
+	}
+
+	segments, err := splitPath(req.Prefix)
+	if err != nil {
+		return err
+	}
+
+	prefix := strings.Join(segments, "/")
+
+	incarnation, version := s.watch.current()
+	if req.SinceVersion != 0 {
+		if req.Incarnation != "" && req.Incarnation != incarnation {
+			return status.Errorf(codes.OutOfRange, "store versions of incarnation %q are not known to incarnation %q", req.Incarnation, incarnation)
+		}
+
+		version = req.SinceVersion
+	}
+
+	started := false
+	for {
+		events, changed, err := s.watch.since(version)
+		if err != nil {
+			return err
+		}
+
+		// Tell the client where the watch starts once the store version is known to be valid.
+		if !started {
+			start := &WatchEvent{
+				StoreVersion: version,
+				Type:         WatchEvent_START,
+				Incarnation:  incarnation,
+			}
+
+			if err := stream.Send(start); err != nil {
+				return err
+			}
+
+			started = true
+		}
+
+		for _, event := range events {
+			if !underPath(event.Filename, prefix) {
+				continue
+			}
+
+			if err := stream.Send(event); err != nil {
+				return err
+			}
+		}
+
+		if len(events) > 0 {
+			version = events[len(events)-1].StoreVersion
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package meta
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// Serves a metadata store over an in-memory connection, returning a client to it.
+func serveTestStore(t *testing.T, store *MetadataStore) (MetadataStoreClient, func()) {
+	return serveTestStoreWith(t, store, nil, grpc.WithInsecure())
+}
+
+// Serves a metadata store with the specified server options, returning a client dialed with the
+// specified dial options.
+func serveTestStoreWith(t *testing.T, store *MetadataStore, serverOpts []grpc.ServerOption, dialOpts ...grpc.DialOption) (MetadataStoreClient, func()) {
+	lis := bufconn.Listen(1 << 20)
+	server := grpc.NewServer(serverOpts...)
+	RegisterMetadataStoreServer(server, store)
+
+	go server.Serve(lis)
+
+	dialOpts = append(dialOpts, grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+		return lis.Dial()
+	}))
+
+	conn, err := grpc.Dial("bufconn", dialOpts...)
+	assert.Nil(t, err)
+
+	return NewMetadataStoreClient(conn), func() {
+		conn.Close()
+		server.Stop()
+	}
+}
+
+// A summary of a watch event that leaves out the modification time.
+type testEvent struct {
+	storeVersion uint64
+	eventType    WatchEvent_Type
+	filename     string
+	version      uint64
+}
+
+// Receives the first event of a watch, which must tell where it starts, and returns the incarnation.
+func recvStart(t *testing.T, stream MetadataStore_WatchClient, storeVersion uint64) string {
+	event, err := stream.Recv()
+	if !assert.Nil(t, err) {
+		return ""
+	}
+
+	assert.Equal(t, WatchEvent_START, event.Type)
+	assert.Equal(t, storeVersion, event.StoreVersion)
+	assert.NotEmpty(t, event.Incarnation)
+
+	return event.Incarnation
+}
+
+// Receives the specified number of events from a watch.
+func recvEvents(t *testing.T, stream MetadataStore_WatchClient, n int) []testEvent {
+	events := make([]testEvent, 0, n)
+	for i := 0; i < n; i++ {
+		event, err := stream.Recv()
+		if !assert.Nil(t, err) {
+			break
+		}
+
+		assert.NotZero(t, event.ModTime)
+		assert.NotEmpty(t, event.Incarnation)
+		events = append(events, testEvent{event.StoreVersion, event.Type, event.Filename, event.Version})
+	}
+
+	return events
+}
+
+func TestMetadataStore_Watch(t *testing.T) {
+	mock := &mockClient{blocks: map[string][]byte{
+		"hash1": []byte("block1"),
+		"hash2": []byte("block2"),
+	}}
+
+	store := &MetadataStore{
+		client: mock,
+		engine: newMapEngine(),
+	}
+
+	client, cleanup := serveTestStore(t, store)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	all, err := client.Watch(ctx, &WatchRequest{})
+	assert.Nil(t, err)
+
+	docs, err := client.Watch(ctx, &WatchRequest{Prefix: "/docs/"})
+	assert.Nil(t, err)
+
+	// The watches have started once they tell where they start, and receive every change made after.
+	incarnation := recvStart(t, all, 0)
+	assert.Equal(t, incarnation, recvStart(t, docs, 0))
+
+	expectModifyFile(store, &ModifyFileRequest{Filename: "docs/a", Version: 1, HashList: []string{"hash1"}}, &ModifyFileResponse{Success: true}, t)
+	expectModifyFile(store, &ModifyFileRequest{Filename: "docs2", Version: 1, HashList: []string{"hash1"}}, &ModifyFileResponse{Success: true}, t)
+	expectModifyFile(store, &ModifyFileRequest{Filename: "docs/a", Version: 2, HashList: []string{"hash2"}}, &ModifyFileResponse{Success: true}, t)
+
+	renameRes, err := store.RenameFile(context.Background(), &RenameFileRequest{
+		Source:             "docs/a",
+		SourceVersion:      3,
+		Destination:        "b",
+		DestinationVersion: 1,
+	})
+	assert.Nil(t, err)
+	assert.True(t, renameRes.Success)
+
+	// Rejected operations are not changes.
+	expectModifyFile(store, &ModifyFileRequest{Filename: "b", Version: 1, HashList: []string{"hash1"}}, &ModifyFileResponse{Success: false}, t)
+
+	assert.Equal(t, []testEvent{
+		{1, WatchEvent_CREATE, "docs/a", 1},
+		{2, WatchEvent_CREATE, "docs2", 1},
+		{3, WatchEvent_MODIFY, "docs/a", 2},
+		{4, WatchEvent_CREATE, "b", 1},
+		{5, WatchEvent_DELETE, "docs/a", 3},
+	}, recvEvents(t, all, 5))
+
+	assert.Equal(t, []testEvent{
+		{1, WatchEvent_CREATE, "docs/a", 1},
+		{3, WatchEvent_MODIFY, "docs/a", 2},
+		{5, WatchEvent_DELETE, "docs/a", 3},
+	}, recvEvents(t, docs, 3))
+
+	// A watch can resume after the last change it received.
+	resumed, err := client.Watch(ctx, &WatchRequest{SinceVersion: 3, Incarnation: incarnation})
+	assert.Nil(t, err)
+
+	recvStart(t, resumed, 3)
+	assert.Equal(t, []testEvent{
+		{4, WatchEvent_CREATE, "b", 1},
+		{5, WatchEvent_DELETE, "docs/a", 3},
+	}, recvEvents(t, resumed, 2))
+
+	future, err := client.Watch(ctx, &WatchRequest{SinceVersion: 6})
+	assert.Nil(t, err)
+
+	_, err = future.Recv()
+	assert.Equal(t, codes.OutOfRange, status.Code(err))
+
+	// Store versions of another incarnation, such as before a restart, cannot be resumed from.
+	restarted, err := client.Watch(ctx, &WatchRequest{SinceVersion: 3, Incarnation: "other"})
+	assert.Nil(t, err)
+
+	_, err = restarted.Recv()
+	assert.Equal(t, codes.OutOfRange, status.Code(err))
+
+	// A new watch tells the current store version, so files listed afterwards can be kept up to date.
+	current, err := client.Watch(ctx, &WatchRequest{})
+	assert.Nil(t, err)
+
+	recvStart(t, current, 5)
+}
+
+func TestWatchHub_History(t *testing.T) {
+	var hub watchHub
+
+	for i := 0; i < watchHistorySize+10; i++ {
+		hub.publish([]*WatchEvent{{Filename: "file1", Version: uint64(i + 1)}})
+	}
+
+	incarnation, version := hub.current()
+	assert.NotEmpty(t, incarnation)
+	assert.Equal(t, uint64(watchHistorySize+10), version)
+
+	// The oldest changes are no longer retained.
+	_, _, err := hub.since(5)
+	assert.Equal(t, codes.OutOfRange, status.Code(err))
+
+	events, changed, err := hub.since(10)
+	assert.Nil(t, err)
+	assert.Len(t, events, watchHistorySize)
+	assert.Equal(t, uint64(11), events[0].StoreVersion)
+
+	events, _, err = hub.since(version)
+	assert.Nil(t, err)
+	assert.Empty(t, events)
+
+	hub.publish([]*WatchEvent{{Filename: "file2"}})
+
+	select {
+	case <-changed:
+	default:
+		t.Fatalf("publishing did not wake up watches")
+	}
+}
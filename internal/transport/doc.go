@@ -0,0 +1,3 @@
+// Package transport configures the security of the gRPC connections between Surfs services and
+// clients.
+package transport
@@ -0,0 +1,122 @@
+package transport
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// TLSConfig configures TLS for the connections a process accepts and makes. It is read from the [tls]
+// section of a configuration file. If neither a certificate nor a CA is configured, connections are
+// not encrypted.
+type TLSConfig struct {
+	// PEM files holding the certificate and private key the process presents. Servers must have one.
+	// Clients present theirs to servers that require client certificates.
+	CertFile string
+	KeyFile  string
+
+	// PEM file holding the certificates of the CAs trusted to sign the certificates of the other side
+	// of a connection. If empty, clients trust the system's CAs.
+	CAFile string
+
+	// If set, servers require clients to present a certificate signed by one of the CAs.
+	ClientAuth bool
+
+	// The name clients expect in the certificates of servers, instead of the host they dial.
+	ServerName string
+}
+
+// Reports whether TLS is enabled.
+func (c TLSConfig) Enabled() bool {
+	return c.CertFile != "" || c.CAFile != ""
+}
+
+// Reads the CA certificates into a pool.
+func (c TLSConfig) caPool() (*x509.CertPool, error) {
+	b, err := ioutil.ReadFile(c.CAFile)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(b) {
+		return nil, fmt.Errorf("no certificates found in %s", c.CAFile)
+	}
+
+	return pool, nil
+}
+
+// Returns the server options that make a gRPC server accept TLS connections, or no options if TLS is
+// disabled.
+func (c TLSConfig) ServerOptions() ([]grpc.ServerOption, error) {
+	if !c.Enabled() {
+		return nil, nil
+	}
+
+	if c.CertFile == "" || c.KeyFile == "" {
+		return nil, errors.New("TLS requires a certificate and key for the server")
+	}
+
+	cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	conf := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if c.ClientAuth {
+		if c.CAFile == "" {
+			return nil, errors.New("verifying client certificates requires a CA")
+		}
+
+		conf.ClientCAs, err = c.caPool()
+		if err != nil {
+			return nil, err
+		}
+
+		conf.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return []grpc.ServerOption{grpc.Creds(credentials.NewTLS(conf))}, nil
+}
+
+// Returns the dial option that secures a connection to a gRPC server, which is an insecure connection
+// if TLS is disabled.
+func (c TLSConfig) DialOption() (grpc.DialOption, error) {
+	if !c.Enabled() {
+		return grpc.WithInsecure(), nil
+	}
+
+	conf := &tls.Config{
+		ServerName: c.ServerName,
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if c.CAFile != "" {
+		pool, err := c.caPool()
+		if err != nil {
+			return nil, err
+		}
+
+		conf.RootCAs = pool
+	}
+
+	if c.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+
+		conf.Certificates = []tls.Certificate{cert}
+	}
+
+	return grpc.WithTransportCredentials(credentials.NewTLS(conf)), nil
+}
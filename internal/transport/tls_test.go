@@ -0,0 +1,124 @@
+package transport
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"os"
+	"surfs/internal/transport/transporttest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// Starts a gRPC server with the specified TLS configuration, returning its address.
+func serveTestServer(t *testing.T, conf TLSConfig) (string, func()) {
+	opts, err := conf.ServerOptions()
+	assert.Nil(t, err)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+
+	server := grpc.NewServer(opts...)
+	healthpb.RegisterHealthServer(server, health.NewServer())
+
+	go server.Serve(lis)
+
+	_, port, err := net.SplitHostPort(lis.Addr().String())
+	assert.Nil(t, err)
+
+	return net.JoinHostPort("localhost", port), server.Stop
+}
+
+// Makes a call to the server at the specified address with the specified TLS configuration.
+func checkServer(t *testing.T, addr string, conf TLSConfig) error {
+	opt, err := conf.DialOption()
+	assert.Nil(t, err)
+
+	conn, err := grpc.Dial(addr, opt)
+	assert.Nil(t, err)
+
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err = healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	return err
+}
+
+func TestTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "surfs")
+	assert.Nil(t, err)
+
+	defer os.RemoveAll(dir)
+
+	ca := transporttest.NewCA(t, dir, "ca")
+	otherCA := transporttest.NewCA(t, dir, "other-ca")
+	serverCert, serverKey := ca.Issue(t, "server")
+	clientCert, clientKey := ca.Issue(t, "client")
+	otherCert, otherKey := otherCA.Issue(t, "other")
+
+	t.Run("server", func(t *testing.T) {
+		addr, stop := serveTestServer(t, TLSConfig{CertFile: serverCert, KeyFile: serverKey})
+		defer stop()
+
+		assert.Nil(t, checkServer(t, addr, TLSConfig{CAFile: ca.CertFile}))
+
+		// The server's certificate must be signed by a trusted CA, and match the server's name.
+		assert.Equal(t, codes.Unavailable, status.Code(checkServer(t, addr, TLSConfig{CAFile: otherCA.CertFile})))
+		assert.Equal(t, codes.Unavailable, status.Code(checkServer(t, addr, TLSConfig{CAFile: ca.CertFile, ServerName: "surfs.example.com"})))
+		assert.Equal(t, codes.Unavailable, status.Code(checkServer(t, addr, TLSConfig{})))
+	})
+
+	t.Run("mutual", func(t *testing.T) {
+		addr, stop := serveTestServer(t, TLSConfig{CertFile: serverCert, KeyFile: serverKey, CAFile: ca.CertFile, ClientAuth: true})
+		defer stop()
+
+		assert.Nil(t, checkServer(t, addr, TLSConfig{CertFile: clientCert, KeyFile: clientKey, CAFile: ca.CertFile}))
+
+		// Clients without a certificate signed by the CA are rejected.
+		assert.Equal(t, codes.Unavailable, status.Code(checkServer(t, addr, TLSConfig{CAFile: ca.CertFile})))
+		assert.Equal(t, codes.Unavailable, status.Code(checkServer(t, addr, TLSConfig{CertFile: otherCert, KeyFile: otherKey, CAFile: ca.CertFile})))
+	})
+
+	t.Run("insecure", func(t *testing.T) {
+		addr, stop := serveTestServer(t, TLSConfig{})
+		defer stop()
+
+		assert.Nil(t, checkServer(t, addr, TLSConfig{}))
+		assert.Equal(t, codes.Unavailable, status.Code(checkServer(t, addr, TLSConfig{CAFile: ca.CertFile})))
+	})
+}
+
+func TestTLSConfig_Invalid(t *testing.T) {
+	dir, err := ioutil.TempDir("", "surfs")
+	assert.Nil(t, err)
+
+	defer os.RemoveAll(dir)
+
+	ca := transporttest.NewCA(t, dir, "ca")
+	cert, key := ca.Issue(t, "server")
+
+	invalid := []TLSConfig{
+		{CAFile: ca.CertFile},
+		{CertFile: cert},
+		{CertFile: cert, KeyFile: key, ClientAuth: true},
+		{CertFile: cert, KeyFile: key, ClientAuth: true, CAFile: key},
+		{CertFile: key, KeyFile: cert},
+	}
+
+	for _, conf := range invalid {
+		_, err := conf.ServerOptions()
+		assert.NotNil(t, err, "%+v", conf)
+	}
+
+	_, err = TLSConfig{CAFile: "missing.pem"}.DialOption()
+	assert.NotNil(t, err)
+}